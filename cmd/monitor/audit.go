@@ -0,0 +1,42 @@
+package main
+
+import (
+	"cs-projects-eth-collar/pkg/audit"
+	"cs-projects-eth-collar/pkg/config"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runAuditCommand 审计日志相关命令，未指定 -file 时校验配置中的 audit.file
+//
+//	monitor audit verify [-config conf/config.yaml] [-file audit.jsonl]
+func runAuditCommand(args []string) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, "usage: monitor audit verify [-config conf/config.yaml] [-file audit.jsonl]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("audit verify", flag.ExitOnError)
+	configPath := fs.String("config", "conf/config.yaml", "Path to configuration file")
+	file := fs.String("file", "", "Path to audit log (default: audit.file from the config)")
+	_ = fs.Parse(args[1:])
+
+	if *file == "" {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+			return 1
+		}
+		*file = cfg.Audit.File
+	}
+
+	count, err := audit.Verify(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit log %s verification failed after %d valid entries: %v\n", *file, count, err)
+		return 1
+	}
+
+	fmt.Printf("audit log %s verified: %d entries, hash chain intact\n", *file, count)
+	return 0
+}
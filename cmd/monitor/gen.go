@@ -0,0 +1,71 @@
+package main
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/config"
+	"cs-projects-eth-collar/pkg/dashboards"
+	"cs-projects-eth-collar/pkg/metrics"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// runGenCommand 根据已注册的指标和配置的规则阈值生成 Grafana 仪表盘和 Prometheus 告警规则
+//
+//	monitor gen dashboards [-config conf/config.yaml] [-out .]
+func runGenCommand(args []string) int {
+	if len(args) == 0 || args[0] != "dashboards" {
+		fmt.Fprintln(os.Stderr, "usage: monitor gen dashboards [-config FILE] [-out DIR]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("gen dashboards", flag.ExitOnError)
+	configPath := fs.String("config", "conf/config.yaml", "Path to configuration file")
+	outDir := fs.String("out", ".", "Directory to write grafana-dashboard.json and rules.yaml")
+	_ = fs.Parse(args[1:])
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return 1
+	}
+
+	// 只用于读取指标定义，不推送
+	defs := metrics.NewMetrics(types.PrometheusConfig{}, zap.NewNop()).Definitions()
+	rules, err := dashboards.BuildRules(cfg, defs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build rules: %v\n", err)
+		return 1
+	}
+	rulesYAML, err := dashboards.WriteRules(rules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	dashboard, err := dashboards.WriteDashboard(defs, rules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create output directory: %v\n", err)
+		return 1
+	}
+	for _, file := range []struct {
+		name    string
+		content []byte
+	}{{"grafana-dashboard.json", dashboard}, {"rules.yaml", rulesYAML}} {
+		path := filepath.Join(*outDir, file.name)
+		if err := os.WriteFile(path, file.content, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", path, err)
+			return 1
+		}
+		fmt.Println(path)
+	}
+	fmt.Fprintf(os.Stderr, "generated %d panels and %d alert rules\n", len(defs), len(rules))
+	return 0
+}
@@ -1,21 +1,62 @@
 package main
 
 import (
+	"context"
+	"cs-projects-eth-collar/pkg/admin"
+	"cs-projects-eth-collar/pkg/audit"
 	"cs-projects-eth-collar/pkg/config"
 	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/election"
+	"cs-projects-eth-collar/pkg/expiry"
+	"cs-projects-eth-collar/pkg/funding"
+	"cs-projects-eth-collar/pkg/hedge"
+	"cs-projects-eth-collar/pkg/history"
+	"cs-projects-eth-collar/pkg/instruments"
+	"cs-projects-eth-collar/pkg/legs"
 	"cs-projects-eth-collar/pkg/logger"
 	"cs-projects-eth-collar/pkg/metrics"
 	"cs-projects-eth-collar/pkg/monitor"
+	"cs-projects-eth-collar/pkg/notify"
+	"cs-projects-eth-collar/pkg/pnl"
+	"cs-projects-eth-collar/pkg/reconcile"
+	"cs-projects-eth-collar/pkg/remediation"
+	"cs-projects-eth-collar/pkg/report"
+	"cs-projects-eth-collar/pkg/roll"
+	"cs-projects-eth-collar/pkg/state"
+	"cs-projects-eth-collar/pkg/tracing"
+	"cs-projects-eth-collar/pkg/venue"
+	"cs-projects-eth-collar/pkg/venue/bybit"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "topup":
+			os.Exit(runTopUpCommand(os.Args[2:]))
+		case "audit":
+			os.Exit(runAuditCommand(os.Args[2:]))
+		case "roll":
+			os.Exit(runRollCommand(os.Args[2:]))
+		case "pnl":
+			os.Exit(runPnLCommand(os.Args[2:]))
+		case "report":
+			os.Exit(runReportCommand(os.Args[2:]))
+		case "gen":
+			os.Exit(runGenCommand(os.Args[2:]))
+		}
+	}
+
 	configPath := flag.String("config", "conf/config.yaml", "Path to configuration file")
 	flag.Parse()
 
@@ -26,18 +67,293 @@ func main() {
 
 	// 打印配置信息以调试
 	log.Printf("Loaded config - Monitor interval: %d seconds, Account: %s", cfg.Monitor.Interval, cfg.Monitor.Account)
-	log.Printf("Deribit config - TestNet: %t", cfg.Deribit.TestNet)
+	log.Printf("Deribit config - TestNet: %t, BaseURL: %s", cfg.Deribit.TestNet, cfg.Deribit.BaseURL)
 
-	zapLogger, err := logger.NewLogger(cfg.Log)
+	// 各模块使用 Named 子 logger，log.levels 按模块名设置级别
+	zapLogger, logLevels, err := logger.NewLogger(cfg.Log)
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
-	defer zapLogger.Sync()
+	if err := metrics.ValidateOnShutdown(cfg.Prometheus.PushGateway.OnShutdown); err != nil {
+		zapLogger.Fatal("Invalid Prometheus config", zap.Error(err))
+	}
+
+	// 链路追踪：每个监控周期一个 trace，导出到 OTLP/HTTP collector 或标准输出
+	shutdownTracing, err := tracing.Setup(cfg.Tracing, zapLogger.Named("tracing"))
+	if err != nil {
+		zapLogger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
 
 	// 初始化服务组件
-	deribitClient := deribit.NewClient(cfg.Deribit)                 // 创建 Deribit API 客户端
-	metricsService := metrics.NewMetrics(cfg.Prometheus, zapLogger) // 创建 Prometheus 指标服务
-	monitorService := monitor.NewService(cfg.Monitor, deribitClient, metricsService, zapLogger)
+	metricsService := metrics.NewMetrics(cfg.Prometheus, zapLogger.Named("metrics")) // 创建 Prometheus 指标服务
+	// 创建 Deribit API 客户端，请求耗时和错误计入指标，deribit 模块为 debug 时记录每个请求
+	deribitClient, err := deribit.NewClient(cfg.Deribit, deribit.WithObserver(metricsService), deribit.WithLogger(zapLogger.Named("deribit")))
+	if err != nil {
+		zapLogger.Fatal("Failed to create Deribit client", zap.Error(err))
+	}
+	notifier := notify.NewNotifier(cfg.Notify, zapLogger.Named("notify")) // 创建通知器（日志 + webhook）
+	monitorOptions := []monitor.Option{monitor.WithCycleObserver(metricsService)}
+	clients := []*deribit.Client{deribitClient} // 退出等待超时后取消进行中的请求
+
+	// 告警状态和退出状态：退出时保存，启动时恢复各模块的告警状态
+	var stateStore *state.Store
+	if cfg.Shutdown.StateFile != "" {
+		stateStore, err = state.Open(cfg.Shutdown.StateFile)
+		if err != nil {
+			zapLogger.Fatal("Failed to open state file", zap.Error(err))
+		}
+		if previous := stateStore.Previous(); previous != nil {
+			zapLogger.Info("Previous run",
+				zap.Time("stopped_at", previous.StoppedAt),
+				zap.String("reason", previous.Reason),
+				zap.Time("last_success_at", previous.LastSuccessAt),
+				zap.String("last_error", previous.LastError),
+			)
+		}
+	}
+	restoreState := func(name string, component state.Component) {
+		if stateStore == nil {
+			return
+		}
+		if err := stateStore.Register(name, component); err != nil {
+			zapLogger.Warn("Failed to restore alert state", zap.String("component", name), zap.Error(err))
+		}
+	}
+
+	// 审计日志：记录每次评估、发送的通知和补充操作；启用自动补充保证金时必须开启
+	var auditLogger *audit.Logger
+	if cfg.Audit.Enabled || cfg.Remediation.Enabled {
+		auditLogger, err = audit.NewLogger(cfg.Audit.File)
+		if err != nil {
+			zapLogger.Fatal("Failed to open audit log", zap.Error(err))
+		}
+		defer auditLogger.Close()
+
+		notifier = notify.WithRecorder(notifier, auditLogger)
+		monitorOptions = append(monitorOptions, monitor.WithAuditLogger(auditLogger))
+		zapLogger.Info("Audit log enabled", zap.String("file", cfg.Audit.File))
+	}
+
+	// 高可用主备模式：只有 leader 执行监控周期、推送指标和发送通知
+	var elector *election.Elector
+	if cfg.HA.Enabled {
+		backend, err := election.NewBackend(cfg.HA)
+		if err != nil {
+			zapLogger.Fatal("Failed to create leader election backend", zap.Error(err))
+		}
+		elector, err = election.NewElector(cfg.HA, backend, notifier, zapLogger.Named("ha"))
+		if err != nil {
+			zapLogger.Fatal("Failed to create leader election", zap.Error(err))
+		}
+		notifier = notify.WithGate(notifier, elector.IsLeader)
+		monitorOptions = append(monitorOptions, monitor.WithLeadership(elector))
+		zapLogger.Info("High availability enabled",
+			zap.String("identity", elector.Identity()),
+			zap.String("backend", cfg.HA.Backend),
+			zap.Int("lease_seconds", cfg.HA.LeaseSeconds),
+		)
+	}
+
+	// Delta 对冲建议（仅建议，不下单）
+	if cfg.Hedge.Enabled {
+		engine := hedge.NewEngine(cfg.Hedge, deribitClient, metricsService, notifier, zapLogger.Named("hedge"))
+		restoreState("hedge", engine)
+		monitorOptions = append(monitorOptions, monitor.WithHooks(monitor.Hook("hedge", func(cycle *monitor.Cycle) error {
+			_, err := engine.Evaluate(cycle.Account, cycle.Summaries)
+			return err
+		})))
+		zapLogger.Info("Delta hedge recommendations enabled (dry run only)",
+			zap.String("instrument", cfg.Hedge.Instrument),
+			zap.Float64("target_delta", cfg.Hedge.TargetDelta),
+			zap.Float64("band", cfg.Hedge.Band),
+		)
+	}
+
+	// 持仓腿行情指标：标记价格、隐含波动率、买卖价差、持仓量
+	if cfg.Legs.Enabled {
+		tracker := legs.NewTracker(cfg.Legs.Currency, deribitClient, metricsService, zapLogger.Named("legs"))
+		monitorOptions = append(monitorOptions, monitor.WithHooks(monitor.Hook("legs", func(cycle *monitor.Cycle) error {
+			_, err := tracker.Evaluate(cycle.Account)
+			return err
+		})))
+	}
+
+	// 合约信息缓存：展期计划和到期提醒共用
+	var catalog *instruments.Catalog
+	if cfg.Roll.Enabled || cfg.Expiry.Enabled {
+		catalog = instruments.NewCatalog(deribitClient, cfg.Instruments, zapLogger.Named("instruments"))
+	}
+
+	// 领口展期计划（仅建议，不下单）
+	if cfg.Roll.Enabled {
+		planner := roll.NewPlanner(cfg.Roll, deribitClient, catalog, notifier, zapLogger.Named("roll"))
+		restoreState("roll", planner)
+		monitorOptions = append(monitorOptions, monitor.WithHooks(monitor.Hook("roll", func(cycle *monitor.Cycle) error {
+			_, err := planner.Evaluate(cycle.Account)
+			return err
+		})))
+		zapLogger.Info("Collar roll planner enabled (dry run only)",
+			zap.Int("roll_window_days", cfg.Roll.RollWindowDays),
+			zap.Float64("put_delta_target", cfg.Roll.PutDeltaTarget),
+			zap.Float64("call_delta_target", cfg.Roll.CallDeltaTarget),
+		)
+	}
+
+	// 持仓到期提醒和交割确认
+	if cfg.Expiry.Enabled {
+		tracker, err := expiry.NewTracker(cfg.Expiry, deribitClient, catalog, notifier, metricsService, zapLogger.Named("expiry"))
+		if err != nil {
+			zapLogger.Fatal("Failed to create expiry tracker", zap.Error(err))
+		}
+		restoreState("expiry", tracker)
+		monitorOptions = append(monitorOptions, monitor.WithHooks(monitor.Hook("expiry", func(cycle *monitor.Cycle) error {
+			_, err := tracker.Evaluate(cycle.Account, cycle.Summaries)
+			return err
+		})))
+		zapLogger.Info("Expiry reminders enabled", zap.Strings("reminders", cfg.Expiry.Reminders))
+	}
+
+	// 账户流水导入和盈亏归因；导入的流水同时用于资金费的实际成本
+	var historyStore *history.Store
+	if cfg.PnL.Enabled {
+		store, err := history.Open(cfg.History.File)
+		if err != nil {
+			zapLogger.Fatal("Failed to open history store", zap.Error(err))
+		}
+		defer store.Close()
+		historyStore = store
+
+		ingester := history.NewIngester(deribitClient, store, cfg.History.LookbackDays)
+		interval := time.Duration(cfg.PnL.IngestIntervalSeconds) * time.Second
+		tracker := pnl.NewTracker(cfg.PnL.Currencies, interval, ingester, store, metricsService, zapLogger.Named("pnl"))
+		monitorOptions = append(monitorOptions, monitor.WithHooks(monitor.Hook("pnl", func(cycle *monitor.Cycle) error {
+			_, err := tracker.Evaluate(cycle.Account)
+			return err
+		})))
+		zapLogger.Info("PnL attribution enabled", zap.String("history", cfg.History.File), zap.Strings("currencies", cfg.PnL.Currencies))
+	}
+
+	// 永续合约资金费成本
+	if cfg.Funding.Enabled {
+		var fundingOptions []funding.Option
+		if historyStore != nil {
+			fundingOptions = append(fundingOptions, funding.WithTransactions(historyStore))
+		}
+		tracker := funding.NewTracker(cfg.Funding, deribitClient, notifier, metricsService, zapLogger.Named("funding"), fundingOptions...)
+		restoreState("funding", tracker)
+		monitorOptions = append(monitorOptions, monitor.WithHooks(monitor.Hook("funding", func(cycle *monitor.Cycle) error {
+			_, err := tracker.Evaluate(cycle.Account)
+			return err
+		})))
+		zapLogger.Info("Funding cost tracking enabled", zap.String("instrument", cfg.Funding.Instrument), zap.Float64("max_daily_cost_usd", cfg.Funding.MaxDailyCostUSD), zap.Bool("settlements", historyStore != nil))
+	}
+
+	// 补充请求对账：充值、提现和划转记录与需要补充的 ETH 对账
+	var reconciler *reconcile.Reconciler
+	if cfg.Reconcile.Enabled {
+		reconciler, err = reconcile.NewReconciler(cfg.Reconcile, deribitClient, notifier, metricsService, zapLogger.Named("reconcile"))
+		if err != nil {
+			zapLogger.Fatal("Failed to create top-up reconciler", zap.Error(err))
+		}
+		// 不需要补充时也要运行，以关闭未完成的请求
+		monitorOptions = append(monitorOptions, monitor.WithHooks(monitor.RequireIndexPrice(monitor.Hook("reconcile", func(cycle *monitor.Cycle) error {
+			_, err := reconciler.Evaluate(cycle.Account, cycle.Evaluation.RequiredETH)
+			return err
+		}))))
+		zapLogger.Info("Top-up reconciliation enabled", zap.String("file", cfg.Reconcile.File), zap.Int("due_seconds", cfg.Reconcile.DueSeconds))
+	}
+
+	// 跨交易所敞口汇总：Deribit 始终包含，其他交易所按配置启用
+	if cfg.Venues.Enabled {
+		venues := []venue.Venue{venue.NewDeribit(deribitClient)}
+		if cfg.Venues.Bybit.Enabled {
+			venues = append(venues, bybit.NewClient(cfg.Venues.Bybit))
+		}
+		venueMonitor := venue.NewMonitor(cfg.Venues.Currency, venues, metricsService, zapLogger.Named("venue"))
+		monitorOptions = append(monitorOptions, monitor.WithHooks(monitor.Hook("venue", func(cycle *monitor.Cycle) error {
+			_, err := venueMonitor.Evaluate(cycle.Account)
+			return err
+		})))
+		zapLogger.Info("Cross-venue exposure enabled", zap.String("currency", cfg.Venues.Currency), zap.Int("venues", len(venues)))
+	}
+
+	// 管理接口（审批等）
+	var adminServer *admin.Server
+	if cfg.Admin.Enabled {
+		adminServer = admin.NewServer(cfg.Admin, zapLogger.Named("admin"))
+		logLevels.RegisterHandlers(adminServer, zapLogger.Named("admin"))
+		if elector != nil {
+			elector.RegisterHandlers(adminServer)
+		}
+	}
+
+	// 自动补充保证金：从资金账户划转，默认演练，需要两名操作员审批
+	if cfg.Remediation.Enabled {
+		// 资金账户与监控账户必须在同一环境，沿用同一地址和传输配置
+		fundingConfig := cfg.Remediation.FundingAccount
+		fundingConfig.BaseURL = cfg.Deribit.BaseURL
+		fundingConfig.TestNet = cfg.Deribit.TestNet
+		fundingConfig.HTTP = cfg.Deribit.HTTP
+		fundingClient, err := deribit.NewClient(fundingConfig, deribit.WithObserver(metricsService), deribit.WithLogger(zapLogger.Named("deribit.funding")))
+		if err != nil {
+			zapLogger.Fatal("Failed to create funding account client", zap.Error(err))
+		}
+		clients = append(clients, fundingClient)
+		executor, err := remediation.NewExecutor(cfg.Remediation, fundingClient, auditLogger, notifier, zapLogger.Named("remediation"))
+		if err != nil {
+			zapLogger.Fatal("Failed to create collateral top-up executor", zap.Error(err))
+		}
+		// 需要补充 ETH 时生成补充提案，提案经操作员审批后才会执行
+		monitorOptions = append(monitorOptions, monitor.WithHooks(monitor.RequireIndexPrice(monitor.Hook("topup", func(cycle *monitor.Cycle) error {
+			evaluation := cycle.Evaluation
+			if evaluation.RequiredETH <= 0 {
+				return nil
+			}
+			reason := fmt.Sprintf("mm_ratio=%.4f eth_equity_usd=%.2f", evaluation.MMRatio, evaluation.ETHEquityUSD)
+			_, err := executor.Propose(cycle.Account, evaluation.RequiredETH, reason)
+			return err
+		}))))
+		if adminServer != nil {
+			executor.RegisterHandlers(adminServer)
+		} else {
+			zapLogger.Warn("Collateral top-up enabled but admin API disabled; proposals cannot be approved")
+		}
+		zapLogger.Info("Collateral top-up enabled",
+			zap.Bool("dry_run", cfg.Remediation.DryRun),
+			zap.String("transfer_type", cfg.Remediation.TransferType),
+			zap.Float64("max_per_transfer", cfg.Remediation.MaxPerTransfer),
+			zap.Float64("max_per_day", cfg.Remediation.MaxPerDay),
+		)
+	}
+
+	// 定时日报、周报：写入文件并通过通知渠道发送
+	var scheduler *report.Scheduler
+	if cfg.Report.Enabled {
+		sources, err := reportSources(cfg)
+		if err != nil {
+			zapLogger.Fatal("Failed to prepare report sources", zap.Error(err))
+		}
+		var schedulerOptions []report.SchedulerOption
+		if elector != nil {
+			// 报告目录在实例间共享，只由 leader 生成
+			schedulerOptions = append(schedulerOptions, report.WithLeadership(elector.IsLeader))
+		}
+		scheduler, err = report.NewScheduler(cfg.Report, cfg.Monitor.Account, sources, notifier, zapLogger.Named("report"), schedulerOptions...)
+		if err != nil {
+			zapLogger.Fatal("Failed to create report scheduler", zap.Error(err))
+		}
+		zapLogger.Info("Scheduled reports enabled", zap.Strings("periods", cfg.Report.Periods), zap.String("format", cfg.Report.Format), zap.Int("hour_utc", cfg.Report.Hour))
+	}
+
+	if reconciler != nil && adminServer != nil {
+		reconciler.RegisterHandlers(adminServer)
+	}
+
+	monitorService := monitor.NewService(cfg.Monitor, deribitClient, metricsService, zapLogger.Named("monitor"), monitorOptions...)
+
+	if adminServer != nil {
+		adminServer.Start()
+	}
 
 	zapLogger.Info("Starting Deribit position monitor")
 
@@ -52,12 +368,105 @@ func main() {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
+	// 选举在监控周期之前开始，首次获取锁成功后下一个周期即可执行
+	if elector != nil {
+		go elector.Start()
+	}
+
+	// 后台任务：退出时等待它们结束后再保存状态
+	var workers sync.WaitGroup
+	workers.Add(1)
 	go func() {
+		defer workers.Done()
 		if err := monitorService.Start(); err != nil {
 			zapLogger.Fatal("Monitor service failed", zap.Error(err))
 		}
 	}()
+	if scheduler != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			scheduler.Start()
+		}()
+	}
 
-	<-c
-	zapLogger.Info("Shutting down monitor")
+	sig := <-c
+	zapLogger.Info("Shutting down monitor", zap.String("signal", sig.String()))
+	timeout := time.Duration(cfg.Shutdown.TimeoutSeconds) * time.Second
+
+	// 停止接受管理请求，等待进行中的审批完成
+	if adminServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		if err := adminServer.Shutdown(ctx); err != nil {
+			zapLogger.Warn("Failed to shut down admin API", zap.Error(err))
+		}
+		cancel()
+	}
+
+	// 等待进行中的监控周期和报告完成，超时后取消进行中的 Deribit 请求
+	monitorService.Stop()
+	if scheduler != nil {
+		scheduler.Stop()
+	}
+	if !waitTimeout(&workers, timeout) {
+		zapLogger.Warn("Shutdown timed out, cancelling in-flight requests", zap.Duration("timeout", timeout))
+		for _, client := range clients {
+			client.Close()
+		}
+		if !waitTimeout(&workers, 5*time.Second) {
+			zapLogger.Error("Workers did not exit after cancelling requests, saving state anyway")
+		}
+	}
+
+	// 保存退出状态和告警状态
+	status := monitorService.Status()
+	status.StoppedAt = time.Now().UTC()
+	status.Reason = sig.String()
+	// 状态文件在实例间共享，follower 保存会覆盖 leader 的告警状态
+	if stateStore != nil && elector != nil && !elector.IsLeader() {
+		zapLogger.Info("Not the leader, skipping state save", zap.String("file", cfg.Shutdown.StateFile))
+	} else if stateStore != nil {
+		if err := stateStore.Save(status); err != nil {
+			zapLogger.Error("Failed to save state", zap.Error(err))
+		} else {
+			zapLogger.Info("Saved state", zap.String("file", cfg.Shutdown.StateFile))
+		}
+	}
+
+	// PushGateway 分组：保留、标记停止或删除；备用实例不推送，避免覆盖 leader 的指标
+	if cfg.Prometheus.Enabled && (elector == nil || elector.IsLeader()) {
+		if err := metricsService.Shutdown(cfg.Monitor.Account); err != nil {
+			zapLogger.Error("Failed to finalize PushGateway group", zap.Error(err))
+		}
+	}
+
+	// 最后一次推送之后释放锁，备用实例在下一次重试时接管
+	if elector != nil {
+		elector.Stop()
+	}
+
+	// 发送尚未导出的 span
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		zapLogger.Warn("Failed to flush traces", zap.Error(err))
+	}
+
+	zapLogger.Info("Monitor stopped", zap.Int("cycles", status.Cycles))
+	_ = zapLogger.Sync()
+}
+
+// waitTimeout 等待所有后台任务结束，超时返回 false
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"cs-projects-eth-collar/pkg/config"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/history"
+	"cs-projects-eth-collar/pkg/pnl"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// runPnLCommand 输出最近几天按类别（或按合约）的盈亏归因
+//
+//	monitor pnl [-config conf/config.yaml] [-days 7] [-currency ETH] [-by type|instrument] [-ingest] [-json]
+func runPnLCommand(args []string) int {
+	fs := flag.NewFlagSet("pnl", flag.ExitOnError)
+	configPath := fs.String("config", "conf/config.yaml", "Path to configuration file")
+	days := fs.Int("days", 7, "Number of UTC days to include, ending today")
+	currency := fs.String("currency", "", "Currency to report (default: all pnl.currencies)")
+	by := fs.String("by", "type", "Breakdown: type or instrument")
+	ingest := fs.Bool("ingest", false, "Ingest the latest transaction log from Deribit before reporting")
+	asJSON := fs.Bool("json", false, "Print the attribution as JSON")
+	_ = fs.Parse(args)
+
+	if *by != "type" && *by != "instrument" {
+		fmt.Fprintf(os.Stderr, "unknown breakdown %q, use type or instrument\n", *by)
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return 1
+	}
+	currencies := cfg.PnL.Currencies
+	if *currency != "" {
+		currencies = []string{strings.ToUpper(*currency)}
+	}
+
+	store, err := history.Open(cfg.History.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open history: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	if *ingest {
+		client, err := deribit.NewClient(cfg.Deribit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create Deribit client: %v\n", err)
+			return 1
+		}
+		ingester := history.NewIngester(client, store, cfg.History.LookbackDays)
+		for _, c := range currencies {
+			added, err := ingester.Ingest(c)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to ingest %s transaction log: %v\n", c, err)
+				return 1
+			}
+			fmt.Fprintf(os.Stderr, "ingested %d new %s transactions\n", added, c)
+		}
+	}
+
+	from := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-*days)
+	var result []pnl.Day
+	for _, c := range currencies {
+		result = append(result, pnl.Attribute(store.Transactions(c, from, time.Time{}))...)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	} else {
+		err = pnl.WriteReport(os.Stdout, result, *by == "instrument")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return 1
+	}
+	return 0
+}
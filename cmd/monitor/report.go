@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/config"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/instruments"
+	"cs-projects-eth-collar/pkg/notify"
+	"cs-projects-eth-collar/pkg/report"
+	"cs-projects-eth-collar/pkg/roll"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// runReportCommand 根据本地历史数据生成风险报告
+//
+//	monitor report [-config conf/config.yaml] [-period daily|weekly] [-format html|markdown|csv] [-to 2024-12-20T08:00:00Z] [-out file] [-notify] [-json]
+func runReportCommand(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	configPath := fs.String("config", "conf/config.yaml", "Path to configuration file")
+	period := fs.String("period", report.PeriodDaily, "Report period: daily or weekly")
+	format := fs.String("format", "", "Output format: html, markdown or csv (default: report.format)")
+	toFlag := fs.String("to", "", "End of the report period, RFC 3339 (default: now)")
+	out := fs.String("out", "", "Write the report to this file instead of stdout")
+	send := fs.Bool("notify", false, "Also send a summary (and the -out path) through the notification sinks")
+	asJSON := fs.Bool("json", false, "Print the report data as JSON")
+	_ = fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return 1
+	}
+	if *format == "" {
+		*format = cfg.Report.Format
+	}
+	if err := report.ValidateFormat(*format); err != nil && !*asJSON {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	to := time.Now()
+	if *toFlag != "" {
+		if to, err = time.Parse(time.RFC3339, *toFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -to: %v\n", err)
+			return 2
+		}
+	}
+
+	sources, err := reportSources(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	r, err := report.Generate(cfg.Monitor.Account, *period, to, sources)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate report: %v\n", err)
+		return 1
+	}
+
+	var buf bytes.Buffer
+	if *asJSON {
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(r)
+	} else {
+		err = r.Render(&buf, *format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to render report: %v\n", err)
+		return 1
+	}
+	if *out != "" {
+		err = os.WriteFile(*out, buf.Bytes(), 0o644)
+	} else {
+		_, err = os.Stdout.Write(buf.Bytes())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return 1
+	}
+
+	if *send {
+		// 通知只带摘要和文件路径，完整报告不进入通知渠道和审计日志
+		message := r.Summary()
+		if *out != "" {
+			message += "\nReport: " + *out
+		}
+		n := notify.Notification{
+			Source:   "report",
+			Severity: notify.SeverityInfo,
+			Title:    r.Title(),
+			Message:  message,
+			Fields:   map[string]interface{}{"account": r.Account, "period": r.Period, "format": *format, "from": r.From, "to": r.To},
+		}
+		if err := notify.NewNotifier(cfg.Notify, zap.NewNop()).Notify(n); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to deliver report: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// reportSources 按配置确定报告的数据来源；启用展期计划时从 Deribit 读取当前领口
+func reportSources(cfg *types.Config) (report.Sources, error) {
+	rules := cfg.Monitor.Rules
+	if rules == (types.RulesConfig{}) {
+		rules = types.DefaultRules()
+	}
+	sources := report.Sources{
+		HistoryFile:      cfg.History.File,
+		Currencies:       cfg.PnL.Currencies,
+		StressShocks:     cfg.Report.StressShocks,
+		MMRatioThreshold: rules.MMRatioThreshold,
+	}
+	if cfg.Audit.Enabled || cfg.Remediation.Enabled {
+		sources.AuditFile = cfg.Audit.File
+	}
+	if cfg.Reconcile.Enabled {
+		sources.ReconcileFile = cfg.Reconcile.File
+	}
+	if cfg.Roll.Enabled {
+		client, err := deribit.NewClient(cfg.Deribit)
+		if err != nil {
+			return sources, fmt.Errorf("failed to create Deribit client: %w", err)
+		}
+		catalog := instruments.NewCatalog(client, cfg.Instruments, zap.NewNop())
+		sources.Collar = roll.NewPlanner(cfg.Roll, client, catalog, nil, zap.NewNop())
+	}
+	return sources, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"cs-projects-eth-collar/pkg/config"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/instruments"
+	"cs-projects-eth-collar/pkg/roll"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// runRollCommand 立即生成领口展期计划并输出报告，不论是否进入展期窗口；从不下单
+//
+//	monitor roll [-config conf/config.yaml] [-json]
+func runRollCommand(args []string) int {
+	fs := flag.NewFlagSet("roll", flag.ExitOnError)
+	configPath := fs.String("config", "conf/config.yaml", "Path to configuration file")
+	asJSON := fs.Bool("json", false, "Print the plan as JSON")
+	_ = fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return 1
+	}
+	client, err := deribit.NewClient(cfg.Deribit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create Deribit client: %v\n", err)
+		return 1
+	}
+
+	catalog := instruments.NewCatalog(client, cfg.Instruments, zap.NewNop())
+	plan, err := roll.NewPlanner(cfg.Roll, client, catalog, nil, zap.NewNop()).Plan()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build roll plan: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(plan)
+	} else {
+		err = plan.WriteReport(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// runTopUpCommand 通过管理接口查看和审批补充保证金提案
+//
+//	monitor topup list
+//	monitor topup approve -id <proposal-id>
+//	monitor topup reject -id <proposal-id>
+func runTopUpCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: monitor topup <list|approve|reject> [-id ID] [-admin URL] [-token TOKEN]")
+		return 2
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("topup "+action, flag.ExitOnError)
+	adminURL := fs.String("admin", "http://127.0.0.1:8081", "Admin API base URL")
+	token := fs.String("token", os.Getenv("MONITOR_ADMIN_TOKEN"), "Operator token (default $MONITOR_ADMIN_TOKEN)")
+	id := fs.String("id", "", "Top-up proposal ID")
+	_ = fs.Parse(args[1:])
+
+	var method, path string
+	switch action {
+	case "list":
+		method, path = "GET", "/topups"
+	case "approve", "reject":
+		if *id == "" {
+			fmt.Fprintln(os.Stderr, "-id is required")
+			return 2
+		}
+		method, path = "POST", "/topups/"+*id+"/"+action
+	default:
+		fmt.Fprintf(os.Stderr, "unknown topup action %q\n", action)
+		return 2
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(*adminURL, "/")+path, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create request: %v\n", err)
+		return 1
+	}
+	req.Header.Set("Authorization", "Bearer "+*token)
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Println(strings.TrimSpace(string(body)))
+	if resp.StatusCode != http.StatusOK {
+		return 1
+	}
+	return 0
+}
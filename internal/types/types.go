@@ -1,21 +1,109 @@
 package types
 
+import "time"
+
 type Config struct {
-	Deribit    DeribitConfig    `yaml:"deribit" mapstructure:"deribit"`
-	Monitor    MonitorConfig    `yaml:"monitor" mapstructure:"monitor"`
-	Prometheus PrometheusConfig `yaml:"prometheus" mapstructure:"prometheus"`
-	Log        LogConfig        `yaml:"log" mapstructure:"log"`
+	Deribit     DeribitConfig     `yaml:"deribit" mapstructure:"deribit"`
+	Monitor     MonitorConfig     `yaml:"monitor" mapstructure:"monitor"`
+	Prometheus  PrometheusConfig  `yaml:"prometheus" mapstructure:"prometheus"`
+	Log         LogConfig         `yaml:"log" mapstructure:"log"`
+	Notify      NotifyConfig      `yaml:"notify" mapstructure:"notify"`
+	Hedge       HedgeConfig       `yaml:"hedge" mapstructure:"hedge"`
+	Remediation RemediationConfig `yaml:"remediation" mapstructure:"remediation"`
+	Admin       AdminConfig       `yaml:"admin" mapstructure:"admin"`
+	Audit       AuditConfig       `yaml:"audit" mapstructure:"audit"`
+	Venues      VenuesConfig      `yaml:"venues" mapstructure:"venues"`
+	Instruments InstrumentsConfig `yaml:"instruments" mapstructure:"instruments"`
+	Legs        LegsConfig        `yaml:"legs" mapstructure:"legs"`
+	Roll        RollConfig        `yaml:"roll" mapstructure:"roll"`
+	Expiry      ExpiryConfig      `yaml:"expiry" mapstructure:"expiry"`
+	History     HistoryConfig     `yaml:"history" mapstructure:"history"`
+	PnL         PnLConfig         `yaml:"pnl" mapstructure:"pnl"`
+	Funding     FundingConfig     `yaml:"funding" mapstructure:"funding"`
+	Reconcile   ReconcileConfig   `yaml:"reconcile" mapstructure:"reconcile"`
+	Report      ReportConfig      `yaml:"report" mapstructure:"report"`
+	Tracing     TracingConfig     `yaml:"tracing" mapstructure:"tracing"`
+	Shutdown    ShutdownConfig    `yaml:"shutdown" mapstructure:"shutdown"`
+	HA          HAConfig          `yaml:"ha" mapstructure:"ha"`
 }
 
 type DeribitConfig struct {
-	APIKey    string `yaml:"api_key" mapstructure:"api_key"`
-	APISecret string `yaml:"api_secret" mapstructure:"api_secret"`
-	TestNet   bool   `yaml:"test_net" mapstructure:"test_net"`
+	APIKey    string         `yaml:"api_key" mapstructure:"api_key"`
+	APISecret string         `yaml:"api_secret" mapstructure:"api_secret"`
+	BaseURL   string         `yaml:"base_url" mapstructure:"base_url"` // 自定义 API 地址（代理、录制器、模拟服务），为空或为生产地址时由 test_net 决定
+	TestNet   bool           `yaml:"test_net" mapstructure:"test_net"`
+	HTTP      HTTPConfig     `yaml:"http" mapstructure:"http"`         // HTTP 传输配置
+	Cassette  CassetteConfig `yaml:"cassette" mapstructure:"cassette"` // HTTP 录制/回放
+}
+
+// CassetteConfig HTTP 录制/回放配置，用于把真实响应固化为回归测试数据
+type CassetteConfig struct {
+	Mode       string   `yaml:"mode" mapstructure:"mode"`               // 为空不启用；record: 录制；replay: 回放
+	File       string   `yaml:"file" mapstructure:"file"`               // cassette 文件
+	RedactKeys []string `yaml:"redact_keys" mapstructure:"redact_keys"` // 额外需要脱敏的字段名
+}
+
+// HTTPConfig Deribit 客户端的 HTTP 传输配置
+type HTTPConfig struct {
+	TimeoutSeconds         int    `yaml:"timeout_seconds" mapstructure:"timeout_seconds"`                     // 单次请求超时，默认 30 秒
+	ProxyURL               string `yaml:"proxy_url" mapstructure:"proxy_url"`                                 // HTTP(S) 代理，为空时使用 HTTPS_PROXY 等环境变量
+	CAFile                 string `yaml:"ca_file" mapstructure:"ca_file"`                                     // 信任的根证书（PEM），设置后替代系统根证书
+	CertFile               string `yaml:"cert_file" mapstructure:"cert_file"`                                 // 客户端证书（PEM）
+	KeyFile                string `yaml:"key_file" mapstructure:"key_file"`                                   // 客户端证书私钥（PEM）
+	MaxIdleConns           int    `yaml:"max_idle_conns" mapstructure:"max_idle_conns"`                       // 最大空闲连接数
+	MaxIdleConnsPerHost    int    `yaml:"max_idle_conns_per_host" mapstructure:"max_idle_conns_per_host"`     // 每个主机最大空闲连接数
+	MaxConnsPerHost        int    `yaml:"max_conns_per_host" mapstructure:"max_conns_per_host"`               // 每个主机最大连接数，0 表示不限制
+	IdleConnTimeoutSeconds int    `yaml:"idle_conn_timeout_seconds" mapstructure:"idle_conn_timeout_seconds"` // 空闲连接保留时间
 }
 
 type MonitorConfig struct {
-	Interval int    `yaml:"interval_seconds" mapstructure:"interval_seconds"`
-	Account  string `yaml:"account" mapstructure:"account"`
+	Interval int         `yaml:"interval_seconds" mapstructure:"interval_seconds"`
+	Account  string      `yaml:"account" mapstructure:"account"`
+	Rules    RulesConfig `yaml:"rules" mapstructure:"rules"` // 补充 ETH 规则阈值
+}
+
+// RulesConfig 补充 ETH 规则的阈值和目标
+//
+// 监控评估和 `monitor gen dashboards` 生成的告警规则都读取这里，阈值只需配置一处。
+type RulesConfig struct {
+	MMRatioThreshold   float64 `yaml:"mm_ratio_threshold" mapstructure:"mm_ratio_threshold"`       // 维持保证金比率超过此值时触发
+	MMRatioTarget      float64 `yaml:"mm_ratio_target" mapstructure:"mm_ratio_target"`             // 补充 ETH 后的目标维持保证金比率
+	ETHEquityLossUSD   float64 `yaml:"eth_equity_loss_usd" mapstructure:"eth_equity_loss_usd"`     // ETH 权益美元价值低于此值时触发（负数）
+	ETHEquityTargetETH float64 `yaml:"eth_equity_target_eth" mapstructure:"eth_equity_target_eth"` // 补充后的目标 ETH 权益数量
+}
+
+// DefaultRules 默认规则：MM > 50% 补至 30%，ETH 权益 < -70 万美元补至 200 ETH
+func DefaultRules() RulesConfig {
+	return RulesConfig{MMRatioThreshold: 0.5, MMRatioTarget: 0.3, ETHEquityLossUSD: -700000, ETHEquityTargetETH: 200}
+}
+
+// WithDefaults 逐项补齐未配置的规则；MM 比率的阈值和目标必须为正，非正值按未配置处理（目标为 0 时补充数量为无穷大）
+func (r RulesConfig) WithDefaults() RulesConfig {
+	defaults := DefaultRules()
+	if r.MMRatioThreshold <= 0 {
+		r.MMRatioThreshold = defaults.MMRatioThreshold
+	}
+	if r.MMRatioTarget <= 0 {
+		r.MMRatioTarget = defaults.MMRatioTarget
+	}
+	if r.ETHEquityLossUSD == 0 {
+		r.ETHEquityLossUSD = defaults.ETHEquityLossUSD
+	}
+	if r.ETHEquityTargetETH == 0 {
+		r.ETHEquityTargetETH = defaults.ETHEquityTargetETH
+	}
+	return r
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled" mapstructure:"enabled"`
+	Exporter    string            `yaml:"exporter" mapstructure:"exporter"`         // otlphttp：发送到 collector；stdout：输出到标准输出
+	Endpoint    string            `yaml:"endpoint" mapstructure:"endpoint"`         // OTLP/HTTP 地址（host:port），默认本地 collector
+	Insecure    bool              `yaml:"insecure" mapstructure:"insecure"`         // 使用 HTTP 而不是 HTTPS
+	Headers     map[string]string `yaml:"headers" mapstructure:"headers"`           // 额外的请求头，如认证令牌
+	ServiceName string            `yaml:"service_name" mapstructure:"service_name"` // service.name 资源属性
+	SampleRatio float64           `yaml:"sample_ratio" mapstructure:"sample_ratio"` // 采样比例，1 表示全部采样
 }
 
 // PrometheusConfig Prometheus 指标服务配置
@@ -30,11 +118,196 @@ type PushGatewayConfig struct {
 	JobName  string            `yaml:"job_name" mapstructure:"job_name"` // 任务名称
 	Instance string            `yaml:"instance" mapstructure:"instance"` // 实例标识
 	Labels   map[string]string `yaml:"labels" mapstructure:"labels"`     // 额外的标签
+	// 退出时对已推送分组的处理：keep 保留最后的值；stale 推送停止时间并保留；delete 删除分组
+	OnShutdown string `yaml:"on_shutdown" mapstructure:"on_shutdown"`
+}
+
+// NotifyConfig 通知配置
+type NotifyConfig struct {
+	Webhooks []WebhookConfig `yaml:"webhooks" mapstructure:"webhooks"` // webhook 通知地址列表
+}
+
+// WebhookConfig 单个 webhook 配置
+type WebhookConfig struct {
+	Name    string            `yaml:"name" mapstructure:"name"`       // 名称，仅用于日志
+	URL     string            `yaml:"url" mapstructure:"url"`         // POST 地址
+	Headers map[string]string `yaml:"headers" mapstructure:"headers"` // 额外的请求头（如鉴权）
+}
+
+// LegsConfig 持仓腿行情指标配置
+type LegsConfig struct {
+	Enabled  bool   `yaml:"enabled" mapstructure:"enabled"`
+	Currency string `yaml:"currency" mapstructure:"currency"`
+}
+
+// RollConfig 领口展期计划配置（只生成建议，从不下单）
+type RollConfig struct {
+	Enabled               bool    `yaml:"enabled" mapstructure:"enabled"`
+	Currency              string  `yaml:"currency" mapstructure:"currency"`
+	PutDeltaTarget        float64 `yaml:"put_delta_target" mapstructure:"put_delta_target"`               // 新看跌期权的目标 Delta（负数）
+	CallDeltaTarget       float64 `yaml:"call_delta_target" mapstructure:"call_delta_target"`             // 新看涨期权的目标 Delta
+	ZeroCostTolerance     float64 `yaml:"zero_cost_tolerance" mapstructure:"zero_cost_tolerance"`         // 新领口每张合约净权利金的绝对值不超过该值（币计价）视为零成本
+	MinDaysToExpiry       int     `yaml:"min_days_to_expiry" mapstructure:"min_days_to_expiry"`           // 候选到期日距今的最少天数
+	MaxDaysToExpiry       int     `yaml:"max_days_to_expiry" mapstructure:"max_days_to_expiry"`           // 候选到期日距今的最多天数，0 表示不限
+	RollWindowDays        int     `yaml:"roll_window_days" mapstructure:"roll_window_days"`               // 当前领口距到期不超过该天数时开始生成计划
+	MaxProposals          int     `yaml:"max_proposals" mapstructure:"max_proposals"`                     // 最多列出的方案数
+	NotifyIntervalSeconds int     `yaml:"notify_interval_seconds" mapstructure:"notify_interval_seconds"` // 展期窗口内重新生成计划并通知的间隔
+}
+
+// ExpiryConfig 持仓到期提醒和交割确认配置
+type ExpiryConfig struct {
+	Enabled                bool     `yaml:"enabled" mapstructure:"enabled"`
+	Currency               string   `yaml:"currency" mapstructure:"currency"`
+	Reminders              []string `yaml:"reminders" mapstructure:"reminders"`                               // 到期前的提醒时间（Go duration，如 168h、24h、1h）
+	SettlementGraceSeconds int      `yaml:"settlement_grace_seconds" mapstructure:"settlement_grace_seconds"` // 到期后等待多久开始查询交割记录
+	ConfirmTimeoutSeconds  int      `yaml:"confirm_timeout_seconds" mapstructure:"confirm_timeout_seconds"`   // 到期后超过该时间仍未查到交割记录时告警
+}
+
+// HistoryConfig 历史数据存储配置
+type HistoryConfig struct {
+	File         string `yaml:"file" mapstructure:"file"`                   // 账户流水文件（JSONL，只追加）
+	LookbackDays int    `yaml:"lookback_days" mapstructure:"lookback_days"` // 首次导入时回溯的天数
+}
+
+// PnLConfig 盈亏归因配置
+type PnLConfig struct {
+	Enabled               bool     `yaml:"enabled" mapstructure:"enabled"`
+	Currencies            []string `yaml:"currencies" mapstructure:"currencies"`                           // 导入流水的币种
+	IngestIntervalSeconds int      `yaml:"ingest_interval_seconds" mapstructure:"ingest_interval_seconds"` // 导入流水的间隔
+}
+
+// FundingConfig 永续合约资金费跟踪配置
+type FundingConfig struct {
+	Enabled               bool    `yaml:"enabled" mapstructure:"enabled"`
+	Currency              string  `yaml:"currency" mapstructure:"currency"`                               // 仓位币种
+	Instrument            string  `yaml:"instrument" mapstructure:"instrument"`                           // 永续合约，如 ETH-PERPETUAL
+	ContractUnit          string  `yaml:"contract_unit" mapstructure:"contract_unit"`                     // 仓位数量单位：usd（反向合约）或 base（线性合约，币数量）
+	MaxDailyCostUSD       float64 `yaml:"max_daily_cost_usd" mapstructure:"max_daily_cost_usd"`           // 每日资金费支出超过该值时告警，0 表示不告警
+	NotifyIntervalSeconds int     `yaml:"notify_interval_seconds" mapstructure:"notify_interval_seconds"` // 持续超出时重复通知的间隔
+}
+
+// ReconcileConfig 充值、提现和划转记录与补充 ETH 建议的对账配置
+type ReconcileConfig struct {
+	Enabled                 bool    `yaml:"enabled" mapstructure:"enabled"`
+	Currency                string  `yaml:"currency" mapstructure:"currency"`                                   // 对账币种，与补充建议一致（ETH）
+	File                    string  `yaml:"file" mapstructure:"file"`                                           // 补充请求和资金流水的状态文件（JSON）
+	DueSeconds              int     `yaml:"due_seconds" mapstructure:"due_seconds"`                             // 补充请求发出后多久未到账视为逾期
+	EscalateIntervalSeconds int     `yaml:"escalate_interval_seconds" mapstructure:"escalate_interval_seconds"` // 逾期后重复升级通知的间隔
+	Tolerance               float64 `yaml:"tolerance" mapstructure:"tolerance"`                                 // 到账达到请求数量的 (1 - tolerance) 即视为完成
+	FetchCount              int     `yaml:"fetch_count" mapstructure:"fetch_count"`                             // 每个周期读取最近的记录条数
+	RetentionDays           int     `yaml:"retention_days" mapstructure:"retention_days"`                       // 已关闭的请求和资金流水的保留天数
+}
+
+// ReportConfig 定时风险报告配置
+type ReportConfig struct {
+	Enabled   bool     `yaml:"enabled" mapstructure:"enabled"`
+	Periods   []string `yaml:"periods" mapstructure:"periods"`       // daily / weekly
+	Format    string   `yaml:"format" mapstructure:"format"`         // html / markdown / csv
+	Hour      int      `yaml:"hour" mapstructure:"hour"`             // 生成报告的时刻（UTC 小时），报告覆盖到该时刻为止
+	Weekday   string   `yaml:"weekday" mapstructure:"weekday"`       // 周报的生成日，如 monday
+	OutputDir string   `yaml:"output_dir" mapstructure:"output_dir"` // 报告文件目录，已存在的报告不重复生成
+	Notify    bool     `yaml:"notify" mapstructure:"notify"`         // 是否通过通知渠道发送报告摘要和文件路径
+
+	StressShocks []float64 `yaml:"stress_shocks" mapstructure:"stress_shocks"` // 压力测试的 ETH 价格变动比例，如 -0.2 表示下跌 20%
+}
+
+// InstrumentsConfig 合约信息缓存配置
+type InstrumentsConfig struct {
+	Currencies             []string `yaml:"currencies" mapstructure:"currencies"`                             // 缓存的币种
+	RefreshIntervalSeconds int      `yaml:"refresh_interval_seconds" mapstructure:"refresh_interval_seconds"` // 定期刷新间隔
+	MissRefreshSeconds     int      `yaml:"miss_refresh_seconds" mapstructure:"miss_refresh_seconds"`         // 查询未命中触发刷新的最小间隔
+}
+
+// VenuesConfig 跨交易所敞口汇总配置，Deribit（deribit 段的账户）始终包含在内
+type VenuesConfig struct {
+	Enabled  bool        `yaml:"enabled" mapstructure:"enabled"`
+	Currency string      `yaml:"currency" mapstructure:"currency"` // 汇总敞口的币种
+	Bybit    BybitConfig `yaml:"bybit" mapstructure:"bybit"`
+}
+
+// BybitConfig Bybit v5 统一账户配置（只读 API 密钥即可）
+type BybitConfig struct {
+	Enabled        bool   `yaml:"enabled" mapstructure:"enabled"`
+	APIKey         string `yaml:"api_key" mapstructure:"api_key"`
+	APISecret      string `yaml:"api_secret" mapstructure:"api_secret"`
+	BaseURL        string `yaml:"base_url" mapstructure:"base_url"`             // 为空时使用生产地址
+	RecvWindowMs   int    `yaml:"recv_window_ms" mapstructure:"recv_window_ms"` // 签名有效窗口（毫秒）
+	TimeoutSeconds int    `yaml:"timeout_seconds" mapstructure:"timeout_seconds"`
+}
+
+// HedgeConfig Delta 对冲建议配置（只生成建议，从不下单）
+type HedgeConfig struct {
+	Enabled               bool    `yaml:"enabled" mapstructure:"enabled"`                                 // 是否启用对冲建议
+	Currency              string  `yaml:"currency" mapstructure:"currency"`                               // 监控的货币，如 ETH
+	TargetDelta           float64 `yaml:"target_delta" mapstructure:"target_delta"`                       // 目标 Delta（币数量）
+	Band                  float64 `yaml:"band" mapstructure:"band"`                                       // 允许偏离目标的幅度（币数量），超出后给出建议
+	Instrument            string  `yaml:"instrument" mapstructure:"instrument"`                           // 对冲合约，如 ETH-PERPETUAL 或 ETH-27DEC24
+	ContractSize          float64 `yaml:"contract_size" mapstructure:"contract_size"`                     // 合约面值，建议数量按此取整
+	ContractUnit          string  `yaml:"contract_unit" mapstructure:"contract_unit"`                     // 合约数量单位：usd（反向合约）或 base（线性合约，币数量）
+	NotifyIntervalSeconds int     `yaml:"notify_interval_seconds" mapstructure:"notify_interval_seconds"` // 持续超出区间时重复通知的间隔
 }
 
+// RemediationConfig 自动补充保证金配置
+// 从资金账户向被监控账户划转，默认只演练（dry_run），执行前需要多名操作员审批
+type RemediationConfig struct {
+	Enabled              bool          `yaml:"enabled" mapstructure:"enabled"`                               // 是否启用
+	DryRun               bool          `yaml:"dry_run" mapstructure:"dry_run"`                               // 只演练不划转，默认 true
+	Currency             string        `yaml:"currency" mapstructure:"currency"`                             // 划转币种，如 ETH
+	FundingAccount       DeribitConfig `yaml:"funding_account" mapstructure:"funding_account"`               // 资金账户的 API 凭证
+	TransferType         string        `yaml:"transfer_type" mapstructure:"transfer_type"`                   // subaccount 或 user
+	Destination          string        `yaml:"destination" mapstructure:"destination"`                       // 子账户 ID 或用户名
+	MaxPerTransfer       float64       `yaml:"max_per_transfer" mapstructure:"max_per_transfer"`             // 单笔上限，必须为正数
+	MaxPerDay            float64       `yaml:"max_per_day" mapstructure:"max_per_day"`                       // 每日（UTC）上限，必须为正数
+	RequiredApprovals    int           `yaml:"required_approvals" mapstructure:"required_approvals"`         // 需要的不同操作员审批数
+	ApprovalTTLSeconds   int           `yaml:"approval_ttl_seconds" mapstructure:"approval_ttl_seconds"`     // 待审批提案的有效期
+	CooldownSeconds      int           `yaml:"cooldown_seconds" mapstructure:"cooldown_seconds"`             // 提案过期、演练、执行或失败后，再次生成提案前等待的时间
+	RejectionChangeRatio float64       `yaml:"rejection_change_ratio" mapstructure:"rejection_change_ratio"` // 提案被拒绝后，需要补充的数量相对变化超过该比例才再次生成
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	Enabled bool   `yaml:"enabled" mapstructure:"enabled"` // 是否启用审计日志（启用自动补充保证金时强制启用）
+	File    string `yaml:"file" mapstructure:"file"`       // 哈希链 JSONL 文件
+}
+
+// AdminConfig 管理接口配置
+type AdminConfig struct {
+	Enabled   bool             `yaml:"enabled" mapstructure:"enabled"`     // 是否启用管理接口
+	Listen    string           `yaml:"listen" mapstructure:"listen"`       // 监听地址
+	Operators []OperatorConfig `yaml:"operators" mapstructure:"operators"` // 操作员及其令牌
+}
+
+// OperatorConfig 操作员，管理接口通过令牌识别操作员身份
+type OperatorConfig struct {
+	Name  string `yaml:"name" mapstructure:"name"`
+	Token string `yaml:"token" mapstructure:"token"`
+}
+
+// LogConfig 日志配置
 type LogConfig struct {
-	Level string `yaml:"level"`
-	File  string `yaml:"file"`
+	Level    string            `yaml:"level" mapstructure:"level"`       // 全局日志级别
+	File     string            `yaml:"file" mapstructure:"file"`         // 日志文件（JSON），为空时只输出到标准输出
+	Format   string            `yaml:"format" mapstructure:"format"`     // 标准输出格式：json 或 console
+	Levels   map[string]string `yaml:"levels" mapstructure:"levels"`     // 按模块设置级别，如 deribit: debug，未设置的模块使用全局级别
+	Rotation LogRotationConfig `yaml:"rotation" mapstructure:"rotation"` // 日志文件轮转
+	Sampling LogSamplingConfig `yaml:"sampling" mapstructure:"sampling"` // 重复日志采样
+}
+
+// LogRotationConfig 日志文件轮转，按大小或时间轮转，旧文件带时间戳后缀
+type LogRotationConfig struct {
+	MaxSizeMB  int    `yaml:"max_size_mb" mapstructure:"max_size_mb"`   // 单个文件达到该大小（MB）时轮转
+	Interval   string `yaml:"interval" mapstructure:"interval"`         // 按时间轮转：hourly 或 daily（UTC 整点），为空时只按大小轮转
+	MaxBackups int    `yaml:"max_backups" mapstructure:"max_backups"`   // 保留的旧文件数，0 表示不限制
+	MaxAgeDays int    `yaml:"max_age_days" mapstructure:"max_age_days"` // 旧文件保留天数，0 表示不限制
+	Compress   bool   `yaml:"compress" mapstructure:"compress"`         // gzip 压缩旧文件
+}
+
+// LogSamplingConfig 日志采样：每个周期内同一级别、同一消息的日志只输出前 Initial 条，之后每 Thereafter 条输出一条
+type LogSamplingConfig struct {
+	Enabled     bool `yaml:"enabled" mapstructure:"enabled"`
+	TickSeconds int  `yaml:"tick_seconds" mapstructure:"tick_seconds"` // 采样周期（秒）
+	Initial     int  `yaml:"initial" mapstructure:"initial"`
+	Thereafter  int  `yaml:"thereafter" mapstructure:"thereafter"`
 }
 
 type AccountSummary struct {
@@ -157,3 +430,361 @@ type AccountSummaries struct {
 	InteruserTransfersEnabled        bool                      `json:"interuser_transfers_enabled,omitempty"`
 	ReferrerID                       string                    `json:"referrer_id,omitempty"`
 }
+
+// Position Deribit 仓位（private/get_positions 返回的单个元素）
+type Position struct {
+	InstrumentName            string  `json:"instrument_name"`
+	Kind                      string  `json:"kind"`      // future / option / spot ...
+	Direction                 string  `json:"direction"` // buy / sell / zero
+	Size                      float64 `json:"size"`      // 期货为 USD（反向合约）或币数量，期权为币数量
+	SizeCurrency              float64 `json:"size_currency,omitempty"`
+	AveragePrice              float64 `json:"average_price"`
+	MarkPrice                 float64 `json:"mark_price"`
+	IndexPrice                float64 `json:"index_price"`
+	SettlementPrice           float64 `json:"settlement_price,omitempty"`
+	Delta                     float64 `json:"delta"`
+	Gamma                     float64 `json:"gamma,omitempty"`
+	Vega                      float64 `json:"vega,omitempty"`
+	Theta                     float64 `json:"theta,omitempty"`
+	InitialMargin             float64 `json:"initial_margin"`
+	MaintenanceMargin         float64 `json:"maintenance_margin"`
+	OpenOrdersMargin          float64 `json:"open_orders_margin"`
+	FloatingProfitLoss        float64 `json:"floating_profit_loss"`
+	RealizedProfitLoss        float64 `json:"realized_profit_loss"`
+	TotalProfitLoss           float64 `json:"total_profit_loss"`
+	EstimatedLiquidationPrice float64 `json:"estimated_liquidation_price,omitempty"`
+	Leverage                  int     `json:"leverage,omitempty"`
+}
+
+// Instrument Deribit 合约（public/get_instruments 返回的单个元素）
+type Instrument struct {
+	InstrumentName       string  `json:"instrument_name"`
+	InstrumentID         int64   `json:"instrument_id"`
+	Kind                 string  `json:"kind"`            // future / option / spot / future_combo / option_combo
+	InstrumentType       string  `json:"instrument_type"` // reversed（反向）/ linear（线性）
+	BaseCurrency         string  `json:"base_currency"`
+	QuoteCurrency        string  `json:"quote_currency"`
+	CounterCurrency      string  `json:"counter_currency"`
+	SettlementCurrency   string  `json:"settlement_currency"`
+	SettlementPeriod     string  `json:"settlement_period"` // day / week / month / perpetual
+	PriceIndex           string  `json:"price_index"`
+	OptionType           string  `json:"option_type,omitempty"` // call / put
+	Strike               float64 `json:"strike,omitempty"`
+	ExpirationTimestamp  int64   `json:"expiration_timestamp"` // 毫秒
+	CreationTimestamp    int64   `json:"creation_timestamp"`   // 毫秒
+	ContractSize         float64 `json:"contract_size"`
+	TickSize             float64 `json:"tick_size"`
+	MinTradeAmount       float64 `json:"min_trade_amount"`
+	IsActive             bool    `json:"is_active"`
+	MakerCommission      float64 `json:"maker_commission"`
+	TakerCommission      float64 `json:"taker_commission"`
+	BlockTradeCommission float64 `json:"block_trade_commission,omitempty"`
+	FutureType           string  `json:"future_type,omitempty"`
+	RFQ                  bool    `json:"rfq"`
+}
+
+// BookSummary 合约行情摘要（public/get_book_summary_by_currency 返回的单个元素）
+type BookSummary struct {
+	InstrumentName         string   `json:"instrument_name"`
+	BaseCurrency           string   `json:"base_currency"`
+	QuoteCurrency          string   `json:"quote_currency"`
+	BidPrice               *float64 `json:"bid_price"` // 无报价时为 null
+	AskPrice               *float64 `json:"ask_price"`
+	MidPrice               *float64 `json:"mid_price"`
+	MarkPrice              float64  `json:"mark_price"`
+	MarkIV                 float64  `json:"mark_iv,omitempty"` // 期权标记隐含波动率（百分比）
+	Last                   *float64 `json:"last"`
+	Low                    *float64 `json:"low"`
+	High                   *float64 `json:"high"`
+	PriceChange            *float64 `json:"price_change"`
+	Volume                 float64  `json:"volume"`
+	VolumeUSD              float64  `json:"volume_usd"`
+	VolumeNotional         float64  `json:"volume_notional,omitempty"`
+	OpenInterest           float64  `json:"open_interest"`
+	UnderlyingPrice        float64  `json:"underlying_price,omitempty"`
+	UnderlyingIndex        string   `json:"underlying_index,omitempty"`
+	InterestRate           float64  `json:"interest_rate,omitempty"`
+	EstimatedDeliveryPrice float64  `json:"estimated_delivery_price,omitempty"`
+	Funding8h              float64  `json:"funding_8h,omitempty"`
+	CurrentFunding         float64  `json:"current_funding,omitempty"`
+	CreationTimestamp      int64    `json:"creation_timestamp"`
+}
+
+// Settlement 结算记录（private/get_settlement_history_by_currency 返回的单个元素）
+type Settlement struct {
+	Type              string  `json:"type"` // settlement（每日结算）/ delivery（到期交割）/ bankruptcy
+	Timestamp         int64   `json:"timestamp"`
+	InstrumentName    string  `json:"instrument_name,omitempty"`
+	Position          float64 `json:"position,omitempty"`
+	MarkPrice         float64 `json:"mark_price,omitempty"`
+	IndexPrice        float64 `json:"index_price,omitempty"`
+	ProfitLoss        float64 `json:"profit_loss,omitempty"`         // 交割产生的盈亏（币计价）
+	SessionProfitLoss float64 `json:"session_profit_loss,omitempty"` // 本结算周期的盈亏
+	Funding           float64 `json:"funding,omitempty"`
+	SessionBankruptcy float64 `json:"session_bankruptcy,omitempty"`
+	SessionTax        float64 `json:"session_tax,omitempty"`
+	SessionTaxRate    float64 `json:"session_tax_rate,omitempty"`
+	Socialized        float64 `json:"socialized,omitempty"`
+}
+
+// SettlementHistory 结算记录分页结果
+type SettlementHistory struct {
+	Settlements  []Settlement `json:"settlements"`
+	Continuation string       `json:"continuation,omitempty"`
+}
+
+// TransactionLogEntry 账户流水（private/get_transaction_log 返回的单条记录）
+//
+// 金额均以 Currency 计价：Cashflow 为交易、结算或交割产生的现金流（不含手续费），Commission 为手续费（正数为支出），
+// Change = Cashflow - Commission 为余额变化；InterestPL 为永续合约资金费。
+type TransactionLogEntry struct {
+	ID               int64   `json:"id"`
+	UserSeq          int64   `json:"user_seq,omitempty"`
+	Timestamp        int64   `json:"timestamp"`
+	Type             string  `json:"type"` // trade / settlement / delivery / transfer / deposit / withdrawal / swap / correction ...
+	Currency         string  `json:"currency"`
+	InstrumentName   string  `json:"instrument_name,omitempty"`
+	Side             string  `json:"side,omitempty"`
+	Amount           float64 `json:"amount,omitempty"`
+	Price            float64 `json:"price,omitempty"`
+	PriceCurrency    string  `json:"price_currency,omitempty"`
+	MarkPrice        float64 `json:"mark_price,omitempty"`
+	IndexPrice       float64 `json:"index_price,omitempty"`
+	Position         float64 `json:"position,omitempty"`
+	Cashflow         float64 `json:"cashflow"`
+	Change           float64 `json:"change"`
+	Commission       float64 `json:"commission,omitempty"`
+	Balance          float64 `json:"balance"`
+	Equity           float64 `json:"equity,omitempty"`
+	SessionUPL       float64 `json:"session_upl,omitempty"`
+	SessionRPL       float64 `json:"session_rpl,omitempty"`
+	InterestPL       float64 `json:"interest_pl,omitempty"`
+	TotalInterestPL  float64 `json:"total_interest_pl,omitempty"`
+	FeeBalance       float64 `json:"fee_balance,omitempty"`
+	TradeID          string  `json:"trade_id,omitempty"`
+	OrderID          string  `json:"order_id,omitempty"`
+	Info             string  `json:"info,omitempty"`
+	ProfitAsCashflow bool    `json:"profit_as_cashflow,omitempty"`
+}
+
+// TransactionLog 账户流水分页结果，Continuation 为 nil 表示没有更多记录
+type TransactionLog struct {
+	Logs         []TransactionLogEntry `json:"logs"`
+	Continuation *int64                `json:"continuation"`
+}
+
+// FundingRate 永续合约资金费率历史（public/get_funding_rate_history 返回的单个元素，每小时一条）
+type FundingRate struct {
+	Timestamp      int64   `json:"timestamp"`
+	IndexPrice     float64 `json:"index_price"`
+	PrevIndexPrice float64 `json:"prev_index_price"`
+	Interest8h     float64 `json:"interest_8h"` // 按当前费率折算的 8 小时资金费率
+	Interest1h     float64 `json:"interest_1h"` // 过去 1 小时的资金费率
+}
+
+// Greeks 期权希腊值
+type Greeks struct {
+	Delta float64 `json:"delta"`
+	Gamma float64 `json:"gamma"`
+	Vega  float64 `json:"vega"`
+	Theta float64 `json:"theta"`
+	Rho   float64 `json:"rho"`
+}
+
+// TickerStats 24 小时统计
+type TickerStats struct {
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	PriceChange float64 `json:"price_change"`
+	Volume      float64 `json:"volume"`
+	VolumeUSD   float64 `json:"volume_usd"`
+}
+
+// Ticker 合约行情（public/ticker 返回）
+// 期权价格以标的币种计（如 ETH），没有买单或卖单时对应的价格为 0
+type Ticker struct {
+	InstrumentName         string      `json:"instrument_name"`
+	Timestamp              int64       `json:"timestamp"`
+	State                  string      `json:"state"` // open / closed
+	IndexPrice             float64     `json:"index_price"`
+	UnderlyingPrice        float64     `json:"underlying_price,omitempty"`
+	UnderlyingIndex        string      `json:"underlying_index,omitempty"`
+	MarkPrice              float64     `json:"mark_price"`
+	MarkIV                 float64     `json:"mark_iv,omitempty"` // 百分比
+	BidIV                  float64     `json:"bid_iv,omitempty"`
+	AskIV                  float64     `json:"ask_iv,omitempty"`
+	BestBidPrice           float64     `json:"best_bid_price"`
+	BestBidAmount          float64     `json:"best_bid_amount"`
+	BestAskPrice           float64     `json:"best_ask_price"`
+	BestAskAmount          float64     `json:"best_ask_amount"`
+	LastPrice              float64     `json:"last_price"`
+	OpenInterest           float64     `json:"open_interest"`
+	SettlementPrice        float64     `json:"settlement_price,omitempty"`
+	MinPrice               float64     `json:"min_price"`
+	MaxPrice               float64     `json:"max_price"`
+	InterestRate           float64     `json:"interest_rate,omitempty"`
+	EstimatedDeliveryPrice float64     `json:"estimated_delivery_price,omitempty"`
+	CurrentFunding         float64     `json:"current_funding,omitempty"`
+	Funding8h              float64     `json:"funding_8h,omitempty"`
+	Greeks                 *Greeks     `json:"greeks,omitempty"` // 仅期权
+	Stats                  TickerStats `json:"stats"`
+}
+
+// OrderBook 订单簿快照（public/get_order_book 返回），包含与 Ticker 相同的行情字段
+type OrderBook struct {
+	Ticker
+	ChangeID int64        `json:"change_id"`
+	Bids     [][2]float64 `json:"bids"` // [价格, 数量]，价格从高到低
+	Asks     [][2]float64 `json:"asks"` // [价格, 数量]，价格从低到高
+}
+
+// Deposit 充值记录（private/get_deposits 返回的单个元素）
+type Deposit struct {
+	Address           string  `json:"address"`
+	Amount            float64 `json:"amount"`
+	Currency          string  `json:"currency"`
+	State             string  `json:"state"` // pending / completed / rejected / replaced
+	TransactionID     string  `json:"transaction_id"`
+	ReceivedTimestamp int64   `json:"received_timestamp"`
+	UpdatedTimestamp  int64   `json:"updated_timestamp"`
+}
+
+// Deposits private/get_deposits 的返回结果
+type Deposits struct {
+	Count int       `json:"count"`
+	Data  []Deposit `json:"data"`
+}
+
+// Withdrawal 提现记录（private/get_withdrawals 返回的单个元素）
+type Withdrawal struct {
+	ID                 int64   `json:"id"`
+	Address            string  `json:"address"`
+	Amount             float64 `json:"amount"`
+	Currency           string  `json:"currency"`
+	Fee                float64 `json:"fee"`
+	State              string  `json:"state"` // unconfirmed / confirmed / cancelled / completed / interrupted / rejected
+	TransactionID      string  `json:"transaction_id"`
+	CreatedTimestamp   int64   `json:"created_timestamp"`
+	ConfirmedTimestamp int64   `json:"confirmed_timestamp"`
+	UpdatedTimestamp   int64   `json:"updated_timestamp"`
+}
+
+// Withdrawals private/get_withdrawals 的返回结果
+type Withdrawals struct {
+	Count int          `json:"count"`
+	Data  []Withdrawal `json:"data"`
+}
+
+// Transfers private/get_transfers 的返回结果
+type Transfers struct {
+	Count int        `json:"count"`
+	Data  []Transfer `json:"data"`
+}
+
+// Transfer Deribit 资金划转记录（submit_transfer_* / get_transfers 返回）
+type Transfer struct {
+	ID               int64   `json:"id"`
+	Amount           float64 `json:"amount"`
+	Currency         string  `json:"currency"`
+	Direction        string  `json:"direction"`  // payment / income
+	OtherSide        string  `json:"other_side"` // 对方账户
+	State            string  `json:"state"`      // prepared / confirmed / cancelled ...
+	Type             string  `json:"type"`       // user / subaccount
+	CreatedTimestamp int64   `json:"created_timestamp"`
+	UpdatedTimestamp int64   `json:"updated_timestamp"`
+}
+
+// ShutdownConfig 退出配置
+type ShutdownConfig struct {
+	TimeoutSeconds int    `yaml:"timeout_seconds" mapstructure:"timeout_seconds"` // 等待进行中的监控周期完成的时间，超时后取消进行中的请求
+	StateFile      string `yaml:"state_file" mapstructure:"state_file"`           // 告警状态和退出状态文件，为空时不保存
+}
+
+// MonitorStatus 监控服务的运行状态，退出时保存
+type MonitorStatus struct {
+	Account       string    `json:"account"`
+	Cycles        int       `json:"cycles"`          // 已执行的监控周期数
+	LastCycleAt   time.Time `json:"last_cycle_at"`   // 上一个周期结束的时间
+	LastSuccessAt time.Time `json:"last_success_at"` // 上一个成功周期结束的时间
+	LastError     string    `json:"last_error,omitempty"`
+	StoppedAt     time.Time `json:"stopped_at"`
+	Reason        string    `json:"reason"` // 退出原因，如收到的信号
+}
+
+// HAConfig 高可用主备模式：多个实例通过锁后端选出 leader，只有 leader 执行监控周期、推送指标和发送通知
+type HAConfig struct {
+	Enabled      bool               `yaml:"enabled" mapstructure:"enabled"`
+	Identity     string             `yaml:"identity" mapstructure:"identity"`           // 实例标识，为空时使用主机名和进程号
+	Backend      string             `yaml:"backend" mapstructure:"backend"`             // 锁后端：file、kubernetes、etcd
+	LeaseSeconds int                `yaml:"lease_seconds" mapstructure:"lease_seconds"` // 租约时长，leader 失联后最迟 lease_seconds + retry_seconds 由其他实例接管
+	RetrySeconds int                `yaml:"retry_seconds" mapstructure:"retry_seconds"` // 获取和续约的间隔，不超过 lease_seconds 的一半
+	File         HAFileConfig       `yaml:"file" mapstructure:"file"`
+	Kubernetes   HAKubernetesConfig `yaml:"kubernetes" mapstructure:"kubernetes"`
+	Etcd         HAEtcdConfig       `yaml:"etcd" mapstructure:"etcd"`
+}
+
+// HAFileConfig 本机文件锁，同一主机上的多个实例
+type HAFileConfig struct {
+	Path string `yaml:"path" mapstructure:"path"`
+}
+
+// HAKubernetesConfig Kubernetes Lease（coordination.k8s.io/v1），字段为空时使用集群内的 service account 配置
+type HAKubernetesConfig struct {
+	APIServer string `yaml:"api_server" mapstructure:"api_server"` // API 地址，为空时使用 KUBERNETES_SERVICE_HOST 和 KUBERNETES_SERVICE_PORT
+	Namespace string `yaml:"namespace" mapstructure:"namespace"`   // 为空时读取 service account 的 namespace
+	Name      string `yaml:"name" mapstructure:"name"`             // Lease 名称
+	TokenFile string `yaml:"token_file" mapstructure:"token_file"` // 为空时使用 service account 令牌
+	CAFile    string `yaml:"ca_file" mapstructure:"ca_file"`       // 为空时使用 service account 的 CA 证书
+}
+
+// HAEtcdConfig etcd v3 JSON 网关（租约和事务），etcd 3.4 及以上默认开启
+type HAEtcdConfig struct {
+	Endpoint       string `yaml:"endpoint" mapstructure:"endpoint"`               // 如 http://127.0.0.1:2379
+	Key            string `yaml:"key" mapstructure:"key"`                         // 锁的键
+	TimeoutSeconds int    `yaml:"timeout_seconds" mapstructure:"timeout_seconds"` // 单次请求超时
+}
+
+// LegMetrics 单条持仓腿的行情指标值
+type LegMetrics struct {
+	Instrument     string
+	Kind           string
+	Size           float64 // 有符号，空头为负
+	MarkPrice      float64
+	MarkIV         float64 // 百分比，仅期权
+	OpenInterest   float64
+	HasQuote       bool // 买卖双边都有报价，否则不更新价差指标
+	Spread         float64
+	RelativeSpread float64
+}
+
+// PnLMetrics 单个币种当日的盈亏归因指标值（币计价）
+type PnLMetrics struct {
+	Currency     string
+	ByType       map[string]float64 // 类别 -> 金额，包含所有类别
+	ByInstrument map[string]float64 // 合约名 -> 盈亏（不含转账），只有转账的合约不出现
+	PnL          float64
+}
+
+// FundingMetrics 永续合约资金费指标值（USD，正数为支付）
+type FundingMetrics struct {
+	Currency          string
+	Instrument        string
+	Rate8h            float64
+	AnnualizedRate    float64
+	PositionUSD       float64
+	RealizedUSD24h    float64
+	ProjectedDailyUSD float64
+	AnnualizedCostUSD float64
+	MaxDailyCostUSD   float64
+}
+
+// VenueMetrics 单个交易所（或跨交易所合计）的指标值
+type VenueMetrics struct {
+	Venue                string
+	Up                   bool // 读取失败时为 false，只更新 venue_up
+	NetDelta             float64
+	DerivativesDelta     float64
+	EquityUSD            float64
+	MaintenanceMarginUSD float64
+	HeadroomUSD          float64
+}
@@ -0,0 +1,161 @@
+// Package account 把各币种的账户摘要汇总为账户级别的 USD 权益和保证金。
+//
+// Deribit 有两类保证金模式：
+//   - 跨币种保证金（cross_sm / cross_pm，cross_collateral_enabled）：所有币种共用一个保证金池，
+//     摘要中的 total_*_usd 字段就是账户级别的值，每个币种的摘要都会重复携带，不能累加
+//   - 分币种保证金（segregated_sm / segregated_pm）：每个币种独立计算权益和保证金（以该币种计价），
+//     账户级别的值需要按指数价格换算成 USD 后求和；每个币种单独强平，MM 比率取各币种中最高的一个
+//
+// 组合保证金（portfolio_margining_enabled）只影响保证金的计算方式，不改变上述汇总方式。
+package account
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"sort"
+	"strings"
+)
+
+// Mode 账户的汇总方式
+type Mode string
+
+const (
+	ModeCrossCollateral Mode = "cross_collateral" // 跨币种保证金
+	ModeSegregated      Mode = "segregated"       // 分币种保证金
+)
+
+// stablecoins 按 1 USD 计价的币种，无需查询指数价格
+var stablecoins = map[string]bool{
+	"USDC": true,
+	"USDT": true,
+	"USD":  true,
+}
+
+// CurrencyBreakdown 单个币种的权益和保证金
+type CurrencyBreakdown struct {
+	Currency             string  `json:"currency"`
+	MarginModel          string  `json:"margin_model"`
+	PortfolioMargining   bool    `json:"portfolio_margining"`
+	PriceUSD             float64 `json:"price_usd"`
+	Equity               float64 `json:"equity"`
+	EquityUSD            float64 `json:"equity_usd"`
+	InitialMargin        float64 `json:"initial_margin"`
+	InitialMarginUSD     float64 `json:"initial_margin_usd"`
+	MaintenanceMargin    float64 `json:"maintenance_margin"`
+	MaintenanceMarginUSD float64 `json:"maintenance_margin_usd"`
+	MMRatio              float64 `json:"mm_ratio"` // 该币种的维持保证金 / 权益，以币种本身计价，不依赖价格
+}
+
+// Equity 账户级别的汇总结果
+type Equity struct {
+	Mode                 Mode                `json:"mode"`
+	MarginModel          string              `json:"margin_model"`
+	PortfolioMargining   bool                `json:"portfolio_margining"`
+	EquityUSD            float64             `json:"equity_usd"`
+	InitialMarginUSD     float64             `json:"initial_margin_usd"`
+	MaintenanceMarginUSD float64             `json:"maintenance_margin_usd"`
+	MMRatio              float64             `json:"mm_ratio"`                 // 维持保证金 / 权益；分币种保证金取最高的币种
+	WorstCurrency        string              `json:"worst_currency,omitempty"` // 分币种保证金下 MM 比率最高的币种
+	Currencies           []CurrencyBreakdown `json:"currencies"`
+	MissingPrices        []string            `json:"missing_prices,omitempty"` // 有余额但缺少价格、未计入 USD 汇总的币种
+}
+
+// PriceNeeded 判断汇总该币种时是否需要查询指数价格
+func PriceNeeded(summary types.CurrencySummary) bool {
+	if stablecoins[strings.ToUpper(summary.Currency)] {
+		return false
+	}
+	return summary.Equity != 0 || summary.InitialMargin != 0 || summary.MaintenanceMargin != 0
+}
+
+// Aggregate 汇总账户权益和保证金，prices 为币种（大写）到 USD 指数价格的映射
+func Aggregate(summaries []types.CurrencySummary, prices map[string]float64) *Equity {
+	result := &Equity{Mode: ModeSegregated}
+
+	var totals *types.CurrencySummary
+	for i := range summaries {
+		summary := &summaries[i]
+		if summary.CrossCollateralEnabled || strings.HasPrefix(summary.MarginModel, "cross") {
+			result.Mode = ModeCrossCollateral
+		}
+		if summary.PortfolioMarginingEnabled || strings.HasSuffix(summary.MarginModel, "_pm") {
+			result.PortfolioMargining = true
+		}
+		if result.MarginModel == "" {
+			result.MarginModel = summary.MarginModel
+		}
+		if totals == nil && (summary.TotalEquityUSD != 0 || summary.TotalMaintenanceMarginUSD != 0) {
+			totals = summary
+		}
+	}
+
+	var sumEquityUSD, sumInitialMarginUSD, sumMaintenanceMarginUSD float64
+	for _, summary := range summaries {
+		currency := strings.ToUpper(summary.Currency)
+		price, ok := prices[currency]
+		if stablecoins[currency] {
+			price, ok = 1, true
+		}
+		if !ok && PriceNeeded(summary) {
+			result.MissingPrices = append(result.MissingPrices, currency)
+		}
+
+		breakdown := CurrencyBreakdown{
+			Currency:             currency,
+			MarginModel:          summary.MarginModel,
+			PortfolioMargining:   summary.PortfolioMarginingEnabled,
+			PriceUSD:             price,
+			Equity:               summary.Equity,
+			EquityUSD:            summary.Equity * price,
+			InitialMargin:        summary.InitialMargin,
+			InitialMarginUSD:     summary.InitialMargin * price,
+			MaintenanceMargin:    summary.MaintenanceMargin,
+			MaintenanceMarginUSD: summary.MaintenanceMargin * price,
+		}
+		breakdown.MMRatio = currencyMMRatio(summary)
+		result.Currencies = append(result.Currencies, breakdown)
+
+		sumEquityUSD += breakdown.EquityUSD
+		sumInitialMarginUSD += breakdown.InitialMarginUSD
+		sumMaintenanceMarginUSD += breakdown.MaintenanceMarginUSD
+	}
+	sort.Slice(result.Currencies, func(i, j int) bool {
+		return result.Currencies[i].Currency < result.Currencies[j].Currency
+	})
+
+	// 跨币种保证金账户优先使用 Deribit 给出的账户级别值；缺失时退回按币种换算求和
+	if result.Mode == ModeCrossCollateral && totals != nil {
+		result.EquityUSD = totals.TotalEquityUSD
+		result.InitialMarginUSD = totals.TotalInitialMarginUSD
+		result.MaintenanceMarginUSD = totals.TotalMaintenanceMarginUSD
+	} else {
+		result.EquityUSD = sumEquityUSD
+		result.InitialMarginUSD = sumInitialMarginUSD
+		result.MaintenanceMarginUSD = sumMaintenanceMarginUSD
+	}
+
+	if result.Mode == ModeSegregated {
+		// 各币种单独强平，USD 合计会掩盖单个币种的风险
+		for _, currency := range result.Currencies {
+			if currency.MaintenanceMargin > 0 && currency.MMRatio > result.MMRatio {
+				result.MMRatio = currency.MMRatio
+				result.WorstCurrency = currency.Currency
+			}
+		}
+	} else if result.EquityUSD != 0 {
+		result.MMRatio = result.MaintenanceMarginUSD / result.EquityUSD
+	}
+
+	return result
+}
+
+// currencyMMRatio 单个币种的维持保证金比率；有维持保证金但权益不为正时按 100%（强平线）计
+func currencyMMRatio(summary types.CurrencySummary) float64 {
+	switch {
+	case summary.Equity > 0:
+		return summary.MaintenanceMargin / summary.Equity
+	case summary.MaintenanceMargin > 0:
+		return 1
+	default:
+		return 0
+	}
+}
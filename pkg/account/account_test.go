@@ -0,0 +1,73 @@
+package account
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateCrossCollateral(t *testing.T) {
+	// 跨币种保证金：每个币种都携带相同的账户级别值，不能累加
+	summaries := []types.CurrencySummary{
+		{Currency: "BTC", MarginModel: "cross_pm", CrossCollateralEnabled: true, PortfolioMarginingEnabled: true,
+			Equity: 1, MaintenanceMargin: 0.1, TotalEquityUSD: 500000, TotalMaintenanceMarginUSD: 100000, TotalInitialMarginUSD: 150000},
+		{Currency: "ETH", MarginModel: "cross_pm", CrossCollateralEnabled: true, PortfolioMarginingEnabled: true,
+			Equity: 100, MaintenanceMargin: 10, TotalEquityUSD: 500000, TotalMaintenanceMarginUSD: 100000, TotalInitialMarginUSD: 150000},
+	}
+	equity := Aggregate(summaries, map[string]float64{"BTC": 60000, "ETH": 3000})
+
+	assert.Equal(t, ModeCrossCollateral, equity.Mode)
+	assert.True(t, equity.PortfolioMargining)
+	assert.Equal(t, 500000.0, equity.EquityUSD)
+	assert.Equal(t, 100000.0, equity.MaintenanceMarginUSD)
+	assert.Equal(t, 150000.0, equity.InitialMarginUSD)
+	assert.InDelta(t, 0.2, equity.MMRatio, 1e-12)
+	assert.Len(t, equity.Currencies, 2)
+	assert.Equal(t, 300000.0, equity.Currencies[1].EquityUSD)
+}
+
+func TestAggregateSegregated(t *testing.T) {
+	// 分币种保证金：按指数价格换算后求和，稳定币按 1 USD
+	summaries := []types.CurrencySummary{
+		{Currency: "ETH", MarginModel: "segregated_sm", Equity: 100, InitialMargin: 20, MaintenanceMargin: 10},
+		{Currency: "USDC", MarginModel: "segregated_sm", Equity: 50000, InitialMargin: 1000, MaintenanceMargin: 500},
+		{Currency: "BTC", MarginModel: "segregated_sm", Equity: 2, MaintenanceMargin: 0.5},
+	}
+	equity := Aggregate(summaries, map[string]float64{"ETH": 3000})
+
+	assert.Equal(t, ModeSegregated, equity.Mode)
+	assert.False(t, equity.PortfolioMargining)
+	assert.Equal(t, 350000.0, equity.EquityUSD)
+	assert.Equal(t, 61000.0, equity.InitialMarginUSD)
+	assert.Equal(t, 30500.0, equity.MaintenanceMarginUSD)
+	assert.Equal(t, []string{"BTC"}, equity.MissingPrices)
+}
+
+func TestAggregateSegregatedWorstCurrency(t *testing.T) {
+	// 分币种保证金：每个币种单独强平，MM 比率取最高的币种，而不是 USD 合计的比率
+	summaries := []types.CurrencySummary{
+		{Currency: "ETH", MarginModel: "segregated_sm", Equity: 100, MaintenanceMargin: 10},
+		{Currency: "USDC", MarginModel: "segregated_sm", Equity: 1000000, MaintenanceMargin: 10000},
+		{Currency: "BTC", MarginModel: "segregated_sm", Equity: 1, MaintenanceMargin: 0.8},
+	}
+	equity := Aggregate(summaries, map[string]float64{"ETH": 3000, "BTC": 60000})
+
+	assert.InDelta(t, 88000.0/1360000, equity.MaintenanceMarginUSD/equity.EquityUSD, 1e-12)
+	assert.InDelta(t, 0.8, equity.MMRatio, 1e-12)
+	assert.Equal(t, "BTC", equity.WorstCurrency)
+	assert.InDelta(t, 0.1, equity.Currencies[1].MMRatio, 1e-12)
+	assert.InDelta(t, 0.01, equity.Currencies[2].MMRatio, 1e-12)
+
+	// 缺少价格时 USD 合计不完整，但币种自身的比率不依赖价格，仍计入最高值
+	equity = Aggregate(summaries, map[string]float64{"ETH": 3000})
+	assert.Equal(t, []string{"BTC"}, equity.MissingPrices)
+	assert.InDelta(t, 0.8, equity.MMRatio, 1e-12)
+	assert.Equal(t, "BTC", equity.WorstCurrency)
+
+	// 有维持保证金但权益为负，按 100% 计
+	summaries[0].Equity = -5
+	equity = Aggregate(summaries, map[string]float64{"ETH": 3000, "BTC": 60000})
+	assert.Equal(t, 1.0, equity.MMRatio)
+	assert.Equal(t, "ETH", equity.WorstCurrency)
+}
@@ -0,0 +1,108 @@
+// Package admin 提供运维管理 HTTP 接口，所有接口都需要操作员令牌认证
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"cs-projects-eth-collar/internal/types"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type operatorKey struct{}
+
+// Server 管理接口服务
+type Server struct {
+	config types.AdminConfig
+	mux    *http.ServeMux
+	server *http.Server
+	logger *zap.Logger
+}
+
+func NewServer(config types.AdminConfig, logger *zap.Logger) *Server {
+	mux := http.NewServeMux()
+	return &Server{
+		config: config,
+		mux:    mux,
+		server: &http.Server{
+			Addr:              config.Listen,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		logger: logger,
+	}
+}
+
+// Handle 注册需要操作员认证的接口，pattern 使用 net/http 的路由语法（如 "POST /topups/{id}/approve"）
+func (s *Server) Handle(pattern string, handler http.HandlerFunc) {
+	s.mux.Handle(pattern, s.authenticate(handler))
+}
+
+// Start 在后台启动 HTTP 服务
+func (s *Server) Start() {
+	go func() {
+		s.logger.Info("Starting admin API", zap.String("listen", s.config.Listen))
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Admin API stopped", zap.Error(err))
+		}
+	}()
+}
+
+// Shutdown 停止 HTTP 服务，等待进行中的请求完成
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// authenticate 通过 Bearer 令牌识别操作员，并把操作员名称放入请求上下文
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		operator := s.lookupOperator(token)
+		if operator == "" {
+			WriteError(w, http.StatusUnauthorized, errors.New("invalid or missing operator token"))
+			return
+		}
+
+		s.logger.Info("Admin API request",
+			zap.String("operator", operator),
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+		)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), operatorKey{}, operator)))
+	})
+}
+
+func (s *Server) lookupOperator(token string) string {
+	if token == "" {
+		return ""
+	}
+	for _, operator := range s.config.Operators {
+		if operator.Token != "" && subtle.ConstantTimeCompare([]byte(operator.Token), []byte(token)) == 1 {
+			return operator.Name
+		}
+	}
+	return ""
+}
+
+// Operator 返回已认证的操作员名称
+func Operator(r *http.Request) string {
+	operator, _ := r.Context().Value(operatorKey{}).(string)
+	return operator
+}
+
+// WriteJSON 以 JSON 写出响应
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// WriteError 以 {"error": "..."} 写出错误响应
+func WriteError(w http.ResponseWriter, status int, err error) {
+	WriteJSON(w, status, map[string]string{"error": err.Error()})
+}
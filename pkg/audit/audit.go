@@ -0,0 +1,243 @@
+// Package audit 记录需要事后追溯的决策和操作，每条记录一行 JSON，只追加不修改。
+//
+// 记录之间以哈希链相连：每条记录保存上一条记录的哈希，并对自身内容（含上一条哈希）计算 SHA-256，
+// 任何一条记录被修改、删除或插入都会导致之后的链校验失败，可用 Verify 检查。
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry 一条审计记录
+type Entry struct {
+	Seq       int64           `json:"seq"` // 从 1 开始连续递增
+	Timestamp time.Time       `json:"timestamp"`
+	Type      string          `json:"type"` // 记录类型，如 "evaluation"、"topup.proposed"
+	Data      json.RawMessage `json:"data"`
+	PrevHash  string          `json:"prev_hash"` // 上一条记录的哈希，第一条为空
+	Hash      string          `json:"hash"`      // 本条记录的哈希
+}
+
+// computeHash 计算记录哈希，覆盖除 Hash 外的全部字段
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	content, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Logger 追加写入审计日志
+//
+// 多个进程可以共用同一个文件（如高可用模式下同一主机上的实例）：每次追加前对文件加排他锁，
+// 并在文件被其他进程追加过时从最后一条记录重新读取序号和哈希，保证哈希链连续。
+type Logger struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	size     int64 // 本进程最后一次读写后的文件大小，与当前大小不同时说明其他进程追加过
+	seq      int64
+	lastHash string
+}
+
+// NewLogger 以追加方式打开审计日志文件，文件不存在时创建；已有记录时从最后一条继续哈希链
+func NewLogger(path string) (*Logger, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %s: %w", path, err)
+	}
+	l := &Logger{path: path, file: file}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock audit log %s: %w", path, err)
+	}
+	defer unlockFile(file)
+	if err := l.syncTail(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read existing audit log %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Path 返回审计日志文件路径
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// Record 追加一条记录，data 会被序列化为 JSON
+func (l *Logger) Record(entryType string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit data: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := lockFile(l.file); err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	defer unlockFile(l.file)
+	if err := l.syncTail(); err != nil {
+		return fmt.Errorf("failed to read audit log tail: %w", err)
+	}
+
+	entry := Entry{
+		Seq:       l.seq + 1,
+		Timestamp: time.Now().UTC(),
+		Type:      entryType,
+		Data:      raw,
+		PrevHash:  l.lastHash,
+	}
+	if entry.Hash, err = entry.computeHash(); err != nil {
+		return fmt.Errorf("failed to hash audit entry: %w", err)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	line = append(line, '\n')
+	if _, err := l.file.Write(line); err != nil {
+		l.size = -1 // 可能写入了部分内容，下次重新读取
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+
+	l.size += int64(len(line))
+	l.seq = entry.Seq
+	l.lastHash = entry.Hash
+	return nil
+}
+
+// syncTail 文件大小与上次读写后不同时重新读取最后一条记录，调用方需持有文件锁
+func (l *Logger) syncTail() error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == l.size {
+		return nil
+	}
+
+	last, err := readLastEntry(l.file, info.Size())
+	if err != nil {
+		return err
+	}
+	l.seq, l.lastHash = 0, ""
+	if last != nil {
+		l.seq, l.lastHash = last.Seq, last.Hash
+	}
+	l.size = info.Size()
+	return nil
+}
+
+// readLastEntry 从文件末尾向前读取最后一条记录，文件为空时返回 nil
+func readLastEntry(file *os.File, size int64) (*Entry, error) {
+	const chunk = 4096
+	var tail []byte
+	for offset := size; offset > 0; {
+		n := min(chunk, offset)
+		offset -= n
+		buf := make([]byte, n)
+		if _, err := file.ReadAt(buf, offset); err != nil {
+			return nil, err
+		}
+		tail = append(buf, tail...)
+		if bytes.Contains(bytes.TrimRight(tail, "\n"), []byte{'\n'}) {
+			break
+		}
+	}
+
+	line := bytes.TrimRight(tail, "\n")
+	if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+		line = line[i+1:]
+	}
+	if len(line) == 0 {
+		return nil, nil
+	}
+	var entry Entry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal last audit entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// Close 关闭审计日志文件
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// ReadEntries 读取审计日志中的全部记录（不校验哈希链）
+func ReadEntries(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: failed to unmarshal audit entry: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// ErrChainBroken 哈希链校验失败
+var ErrChainBroken = errors.New("audit chain broken")
+
+// Verify 校验审计日志的哈希链，返回已校验的记录数
+// 出错时返回的错误包含第一条不一致记录的序号
+func Verify(path string) (int, error) {
+	entries, err := ReadEntries(path)
+	if err != nil {
+		return 0, err
+	}
+
+	prevHash := ""
+	for i, entry := range entries {
+		if entry.Seq != int64(i+1) {
+			return i, fmt.Errorf("%w: entry %d has seq %d", ErrChainBroken, i+1, entry.Seq)
+		}
+		if entry.PrevHash != prevHash {
+			return i, fmt.Errorf("%w: entry %d prev_hash does not match previous entry", ErrChainBroken, entry.Seq)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return i, fmt.Errorf("failed to hash entry %d: %w", entry.Seq, err)
+		}
+		if hash != entry.Hash {
+			return i, fmt.Errorf("%w: entry %d content does not match its hash", ErrChainBroken, entry.Seq)
+		}
+		prevHash = entry.Hash
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeEntries(t *testing.T, path string, n int) {
+	logger, err := NewLogger(path)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, logger.Record("evaluation", map[string]interface{}{"mm_ratio": 0.1 * float64(i)}))
+	}
+}
+
+func TestVerifyIntactChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeEntries(t, path, 3)
+	// 重新打开后继续同一条链
+	writeEntries(t, path, 2)
+
+	count, err := Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeEntries(t, path, 3)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	tampered := strings.Replace(string(content), `"mm_ratio":0.1`, `"mm_ratio":0.9`, 1)
+	require.NoError(t, os.WriteFile(path, []byte(tampered), 0o600))
+
+	count, err := Verify(path)
+	assert.ErrorIs(t, err, ErrChainBroken)
+	assert.Equal(t, 1, count)
+}
+
+func TestVerifyDetectsDeletion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeEntries(t, path, 3)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(string(content), "\n")
+	require.NoError(t, os.WriteFile(path, []byte(lines[0]+lines[2]), 0o600))
+
+	_, err = Verify(path)
+	assert.ErrorIs(t, err, ErrChainBroken)
+}
+
+func TestLoggersShareFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	first, err := NewLogger(path)
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := NewLogger(path)
+	require.NoError(t, err)
+	defer second.Close()
+
+	// 两个实例交替写入同一个文件，每次追加前从文件末尾接上哈希链；较长的记录跨越多个读取块
+	require.NoError(t, first.Record("evaluation", map[string]interface{}{"mm_ratio": 0.1}))
+	require.NoError(t, second.Record("evaluation", map[string]interface{}{"note": strings.Repeat("x", 10000)}))
+	require.NoError(t, first.Record("evaluation", map[string]interface{}{"mm_ratio": 0.2}))
+	require.NoError(t, first.Record("evaluation", map[string]interface{}{"mm_ratio": 0.3}))
+	require.NoError(t, second.Record("evaluation", map[string]interface{}{"mm_ratio": 0.4}))
+
+	count, err := Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package audit
+
+import "os"
+
+// lockFile 非类 Unix 系统不加锁，审计日志只能由一个进程写入
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -12,14 +12,122 @@ func LoadConfig(configPath string) (*types.Config, error) {
 
 	viper.SetDefault("deribit.base_url", "https://www.deribit.com/api/v2")
 	viper.SetDefault("deribit.test_net", false)
+	viper.SetDefault("deribit.http.timeout_seconds", 30)
+	viper.SetDefault("deribit.http.max_idle_conns", 100)
+	viper.SetDefault("deribit.http.max_idle_conns_per_host", 10)
+	viper.SetDefault("deribit.http.idle_conn_timeout_seconds", 90)
+	viper.SetDefault("deribit.cassette.file", "deribit.cassette.json")
 	viper.SetDefault("monitor.interval_seconds", 30)
 	viper.SetDefault("monitor.account", "default")
+	viper.SetDefault("monitor.rules.mm_ratio_threshold", types.DefaultRules().MMRatioThreshold)
+	viper.SetDefault("monitor.rules.mm_ratio_target", types.DefaultRules().MMRatioTarget)
+	viper.SetDefault("monitor.rules.eth_equity_loss_usd", types.DefaultRules().ETHEquityLossUSD)
+	viper.SetDefault("monitor.rules.eth_equity_target_eth", types.DefaultRules().ETHEquityTargetETH)
 	viper.SetDefault("prometheus.enabled", true)
 	viper.SetDefault("prometheus.push_gateway.url", "http://localhost:9091")
 	viper.SetDefault("prometheus.push_gateway.job_name", "deribit-monitor")
 	viper.SetDefault("prometheus.push_gateway.instance", "default")
+	viper.SetDefault("prometheus.push_gateway.on_shutdown", "stale")
 	viper.SetDefault("log.level", "info")
 	viper.SetDefault("log.file", "monitor.log")
+	viper.SetDefault("log.format", "json")
+	viper.SetDefault("log.rotation.max_size_mb", 100)
+	viper.SetDefault("log.rotation.max_backups", 10)
+	viper.SetDefault("log.rotation.max_age_days", 30)
+	viper.SetDefault("log.rotation.compress", true)
+	viper.SetDefault("log.sampling.enabled", true)
+	viper.SetDefault("log.sampling.tick_seconds", 1)
+	viper.SetDefault("log.sampling.initial", 100)
+	viper.SetDefault("log.sampling.thereafter", 100)
+	viper.SetDefault("hedge.enabled", false)
+	viper.SetDefault("hedge.currency", "ETH")
+	viper.SetDefault("hedge.target_delta", 0)
+	viper.SetDefault("hedge.band", 10)
+	viper.SetDefault("hedge.instrument", "ETH-PERPETUAL")
+	viper.SetDefault("hedge.contract_size", 1)
+	viper.SetDefault("hedge.contract_unit", "usd")
+	viper.SetDefault("hedge.notify_interval_seconds", 3600)
+	viper.SetDefault("remediation.enabled", false)
+	viper.SetDefault("remediation.dry_run", true)
+	viper.SetDefault("remediation.currency", "ETH")
+	viper.SetDefault("remediation.transfer_type", "subaccount")
+	viper.SetDefault("remediation.required_approvals", 2)
+	viper.SetDefault("remediation.approval_ttl_seconds", 3600)
+	viper.SetDefault("remediation.cooldown_seconds", 1800)
+	viper.SetDefault("remediation.rejection_change_ratio", 0.25)
+	viper.SetDefault("audit.enabled", true)
+	viper.SetDefault("audit.file", "audit.jsonl")
+	viper.SetDefault("admin.enabled", false)
+	viper.SetDefault("admin.listen", "127.0.0.1:8081")
+	viper.SetDefault("legs.enabled", false)
+	viper.SetDefault("legs.currency", "ETH")
+	viper.SetDefault("roll.enabled", false)
+	viper.SetDefault("roll.currency", "ETH")
+	viper.SetDefault("roll.put_delta_target", -0.25)
+	viper.SetDefault("roll.call_delta_target", 0.25)
+	viper.SetDefault("roll.zero_cost_tolerance", 0.002)
+	viper.SetDefault("roll.min_days_to_expiry", 30)
+	viper.SetDefault("roll.max_days_to_expiry", 120)
+	viper.SetDefault("roll.roll_window_days", 7)
+	viper.SetDefault("roll.max_proposals", 3)
+	viper.SetDefault("roll.notify_interval_seconds", 86400)
+	viper.SetDefault("expiry.enabled", false)
+	viper.SetDefault("expiry.currency", "ETH")
+	viper.SetDefault("expiry.reminders", []string{"168h", "24h", "1h"})
+	viper.SetDefault("expiry.settlement_grace_seconds", 300)
+	viper.SetDefault("expiry.confirm_timeout_seconds", 3600)
+	viper.SetDefault("history.file", "history.jsonl")
+	viper.SetDefault("history.lookback_days", 30)
+	viper.SetDefault("pnl.enabled", false)
+	viper.SetDefault("pnl.currencies", []string{"ETH"})
+	viper.SetDefault("pnl.ingest_interval_seconds", 300)
+	viper.SetDefault("funding.enabled", false)
+	viper.SetDefault("funding.currency", "ETH")
+	viper.SetDefault("funding.instrument", "ETH-PERPETUAL")
+	viper.SetDefault("funding.contract_unit", "usd")
+	viper.SetDefault("funding.max_daily_cost_usd", 0)
+	viper.SetDefault("funding.notify_interval_seconds", 3600)
+	viper.SetDefault("reconcile.enabled", false)
+	viper.SetDefault("reconcile.currency", "ETH")
+	viper.SetDefault("reconcile.file", "topups.json")
+	viper.SetDefault("reconcile.due_seconds", 3600)
+	viper.SetDefault("reconcile.escalate_interval_seconds", 3600)
+	viper.SetDefault("reconcile.tolerance", 0.01)
+	viper.SetDefault("reconcile.fetch_count", 50)
+	viper.SetDefault("reconcile.retention_days", 30)
+	viper.SetDefault("report.enabled", false)
+	viper.SetDefault("report.periods", []string{"daily"})
+	viper.SetDefault("report.format", "html")
+	viper.SetDefault("report.hour", 8)
+	viper.SetDefault("report.weekday", "monday")
+	viper.SetDefault("report.output_dir", "reports")
+	viper.SetDefault("report.notify", true)
+	viper.SetDefault("report.stress_shocks", []float64{-0.3, -0.2, -0.1, 0.1, 0.2})
+	viper.SetDefault("instruments.currencies", []string{"ETH"})
+	viper.SetDefault("instruments.refresh_interval_seconds", 3600)
+	viper.SetDefault("instruments.miss_refresh_seconds", 60)
+	viper.SetDefault("venues.enabled", false)
+	viper.SetDefault("venues.currency", "ETH")
+	viper.SetDefault("venues.bybit.base_url", "https://api.bybit.com")
+	viper.SetDefault("venues.bybit.recv_window_ms", 5000)
+	viper.SetDefault("venues.bybit.timeout_seconds", 30)
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.exporter", "otlphttp")
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+	viper.SetDefault("tracing.insecure", true)
+	viper.SetDefault("tracing.service_name", "deribit-monitor")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("shutdown.timeout_seconds", 30)
+	viper.SetDefault("shutdown.state_file", "state.json")
+	viper.SetDefault("ha.enabled", false)
+	viper.SetDefault("ha.backend", "file")
+	viper.SetDefault("ha.lease_seconds", 15)
+	viper.SetDefault("ha.retry_seconds", 5)
+	viper.SetDefault("ha.file.path", "deribit-monitor.lock")
+	viper.SetDefault("ha.kubernetes.name", "deribit-monitor")
+	viper.SetDefault("ha.etcd.endpoint", "http://127.0.0.1:2379")
+	viper.SetDefault("ha.etcd.key", "/deribit-monitor/leader")
+	viper.SetDefault("ha.etcd.timeout_seconds", 5)
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
@@ -0,0 +1,197 @@
+package dashboards
+
+import (
+	"cs-projects-eth-collar/pkg/metrics"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DashboardUID 生成的 Grafana 仪表盘 UID，重新导入时覆盖同一个仪表盘
+const DashboardUID = "deribit-eth-collar"
+
+const (
+	panelWidth  = 12
+	panelHeight = 8
+	gridColumns = 24
+)
+
+// section 仪表盘中的一组面板，按指标名前缀归类
+type section struct {
+	title    string
+	prefixes []string
+}
+
+// sections 面板分组，按顺序匹配，未匹配的指标归入第一组
+var sections = []section{
+	{title: "账户与保证金"},
+	{title: "希腊值", prefixes: []string{"deribit_options_", "deribit_delta_"}},
+	{title: "Delta 对冲", prefixes: []string{"deribit_hedge_"}},
+	{title: "持仓腿", prefixes: []string{"deribit_leg_"}},
+	{title: "到期", prefixes: []string{"deribit_expiry_"}},
+	{title: "盈亏归因", prefixes: []string{"deribit_pnl_"}},
+	{title: "永续合约资金费", prefixes: []string{"deribit_funding_"}},
+	{title: "补充请求对账", prefixes: []string{"deribit_topup_", "deribit_account_flows_"}},
+	{title: "跨交易所", prefixes: []string{"venue_"}},
+	{title: "监控程序自身", prefixes: []string{"deribit_api_", "deribit_auth_", "monitor_"}},
+}
+
+func sectionOf(name string) int {
+	for i, s := range sections {
+		for _, prefix := range s.prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// WriteDashboard 生成 Grafana 仪表盘 JSON：每个指标一个时序面板，按模块分行，规则阈值画在对应面板上
+func WriteDashboard(defs []metrics.Definition, rules []Rule) ([]byte, error) {
+	thresholds := make(map[string][]Rule)
+	for _, rule := range rules {
+		if rule.Metric != "" && strings.HasPrefix(rule.Expr, rule.Metric+" ") {
+			thresholds[rule.Metric] = append(thresholds[rule.Metric], rule)
+		}
+	}
+
+	grouped := make([][]metrics.Definition, len(sections))
+	for _, def := range defs {
+		i := sectionOf(def.Name)
+		grouped[i] = append(grouped[i], def)
+	}
+
+	var panels []map[string]interface{}
+	id, y := 1, 0
+	for i, group := range grouped {
+		if len(group) == 0 {
+			continue
+		}
+		panels = append(panels, map[string]interface{}{
+			"id":        id,
+			"type":      "row",
+			"title":     sections[i].title,
+			"collapsed": false,
+			"gridPos":   gridPos(0, y, gridColumns, 1),
+			"panels":    []interface{}{},
+		})
+		id++
+		y++
+		for j, def := range group {
+			x := (j % (gridColumns / panelWidth)) * panelWidth
+			if j > 0 && x == 0 {
+				y += panelHeight
+			}
+			panels = append(panels, timeseriesPanel(id, def, thresholds[def.Name], gridPos(x, y, panelWidth, panelHeight)))
+			id++
+		}
+		y += panelHeight
+	}
+
+	dashboard := map[string]interface{}{
+		"uid":           DashboardUID,
+		"title":         "Deribit ETH Collar",
+		"description":   "由 `monitor gen dashboards` 生成，请修改配置后重新生成，不要手动编辑",
+		"tags":          []string{"deribit", "generated"},
+		"timezone":      "utc",
+		"schemaVersion": 39,
+		"version":       1,
+		"editable":      true,
+		"refresh":       "1m",
+		"time":          map[string]string{"from": "now-24h", "to": "now"},
+		"templating": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{
+					"name":  "datasource",
+					"label": "Data source",
+					"type":  "datasource",
+					"query": "prometheus",
+				},
+				map[string]interface{}{
+					"name":       "account",
+					"label":      "Account",
+					"type":       "query",
+					"datasource": datasource(),
+					"query":      "label_values(deribit_maintenance_margin_ratio, account)",
+					"refresh":    2,
+					"includeAll": true,
+					"multi":      true,
+				},
+			},
+		},
+		"panels": panels,
+	}
+
+	data, err := json.MarshalIndent(dashboard, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode dashboard: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func timeseriesPanel(id int, def metrics.Definition, rules []Rule, pos map[string]int) map[string]interface{} {
+	selector := ""
+	if slices.Contains(def.Labels, "account") {
+		selector = `{account=~"$account"}`
+	}
+	var legend []string
+	for _, label := range def.Labels {
+		legend = append(legend, "{{"+label+"}}")
+	}
+
+	// 计数器画每秒速率，直方图画 p95
+	expr := def.Name + selector
+	switch def.Type {
+	case metrics.TypeCounter:
+		expr = fmt.Sprintf("rate(%s[5m])", expr)
+	case metrics.TypeHistogram:
+		expr = fmt.Sprintf("histogram_quantile(0.95, sum by (%s) (rate(%s_bucket%s[5m])))",
+			strings.Join(append([]string{"le"}, def.Labels...), ", "), def.Name, selector)
+	}
+
+	// 没有告警阈值时只有基准色；有阈值时超过（或低于）阈值显示为红色
+	steps := []map[string]interface{}{{"color": "green", "value": nil}}
+	thresholdStyle := "off"
+	for _, rule := range rules {
+		thresholdStyle = "line"
+		if strings.HasPrefix(rule.Expr, rule.Metric+" <") {
+			steps = []map[string]interface{}{{"color": "red", "value": nil}, {"color": "green", "value": rule.Threshold}}
+		} else {
+			steps = append(steps, map[string]interface{}{"color": "red", "value": rule.Threshold})
+		}
+	}
+
+	return map[string]interface{}{
+		"id":          id,
+		"type":        "timeseries",
+		"title":       def.Name,
+		"description": def.Help,
+		"datasource":  datasource(),
+		"gridPos":     pos,
+		"targets": []interface{}{
+			map[string]interface{}{
+				"refId":        "A",
+				"datasource":   datasource(),
+				"expr":         expr,
+				"legendFormat": strings.Join(legend, " "),
+			},
+		},
+		"fieldConfig": map[string]interface{}{
+			"defaults": map[string]interface{}{
+				"custom":     map[string]interface{}{"thresholdsStyle": map[string]string{"mode": thresholdStyle}},
+				"thresholds": map[string]interface{}{"mode": "absolute", "steps": steps},
+			},
+			"overrides": []interface{}{},
+		},
+	}
+}
+
+func datasource() map[string]string {
+	return map[string]string{"type": "prometheus", "uid": "${datasource}"}
+}
+
+func gridPos(x, y, w, h int) map[string]int {
+	return map[string]int{"x": x, "y": y, "w": w, "h": h}
+}
@@ -0,0 +1,160 @@
+package dashboards
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/metrics"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func definitions() []metrics.Definition {
+	return metrics.NewMetrics(types.PrometheusConfig{}, zap.NewNop()).Definitions()
+}
+
+func alertNames(rules []Rule) []string {
+	var names []string
+	for _, rule := range rules {
+		names = append(names, rule.Alert)
+	}
+	return names
+}
+
+func TestBuildRulesUsesConfiguredThresholds(t *testing.T) {
+	cfg := &types.Config{Monitor: types.MonitorConfig{
+		Interval: 60,
+		Rules:    types.RulesConfig{MMRatioThreshold: 0.6, MMRatioTarget: 0.35, ETHEquityLossUSD: -500000, ETHEquityTargetETH: 150},
+	}}
+
+	rules, err := BuildRules(cfg, definitions())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"HighMaintenanceMarginRatio", "ETHEquityLoss", "MonitorMetricsStale", "MonitorCycleFailing", "IndexPriceMissing"}, alertNames(rules))
+	assert.Equal(t, "deribit_maintenance_margin_ratio > 0.6", rules[0].Expr)
+	assert.Contains(t, rules[0].Annotations["description"], "超过 60% 阈值，需补充 ETH 至 35%")
+	assert.Equal(t, "deribit_eth_equity_usd < -500000", rules[1].Expr)
+	assert.Equal(t, "time() - deribit_metrics_collection_timestamp > 300", rules[2].Expr)
+	assert.Equal(t, "time() - monitor_last_success_timestamp_seconds > 300", rules[3].Expr)
+	assert.Equal(t, "deribit_missing_index_prices > 0", rules[4].Expr)
+}
+
+func TestBuildRulesDefaultsAndOptionalModules(t *testing.T) {
+	cfg := &types.Config{
+		Hedge:     types.HedgeConfig{Enabled: true, Currency: "ETH", Band: 10},
+		Funding:   types.FundingConfig{Enabled: true, MaxDailyCostUSD: 500},
+		Reconcile: types.ReconcileConfig{Enabled: true, DueSeconds: 3600},
+		Expiry:    types.ExpiryConfig{Enabled: true, Reminders: []string{"168h", "1h", "24h"}},
+		Venues:    types.VenuesConfig{Enabled: true},
+	}
+
+	rules, err := BuildRules(cfg, definitions())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"HighMaintenanceMarginRatio", "ETHEquityLoss", "MonitorMetricsStale", "MonitorCycleFailing", "IndexPriceMissing",
+		"DeltaOutsideBand", "FundingCostHigh", "TopUpOverdue", "PositionExpiringSoon", "VenueDown"}, alertNames(rules))
+
+	// 未配置 monitor.rules 时使用与监控评估相同的默认阈值
+	assert.Equal(t, "deribit_maintenance_margin_ratio > 0.5", rules[0].Expr)
+	assert.Equal(t, "deribit_eth_equity_usd < -700000", rules[1].Expr)
+	assert.Equal(t, "abs(deribit_hedge_delta_deviation) > 10", rules[5].Expr)
+	assert.Equal(t, "deribit_funding_realized_usd_24h > 500 or deribit_funding_projected_daily_usd > 500", rules[6].Expr)
+	assert.Equal(t, "deribit_expiry_seconds < 3600 and deribit_expiry_positions > 0", rules[8].Expr)
+}
+
+func TestBuildRulesRejectsUnregisteredMetric(t *testing.T) {
+	defs := definitions()
+	for i, def := range defs {
+		if def.Name == "deribit_maintenance_margin_ratio" {
+			defs = append(defs[:i], defs[i+1:]...)
+			break
+		}
+	}
+	_, err := BuildRules(&types.Config{}, defs)
+	assert.ErrorContains(t, err, "unregistered metric deribit_maintenance_margin_ratio")
+}
+
+func TestWriteRules(t *testing.T) {
+	rules, err := BuildRules(&types.Config{}, definitions())
+	require.NoError(t, err)
+	data, err := WriteRules(rules)
+	require.NoError(t, err)
+
+	var parsed struct {
+		Groups []struct {
+			Name  string `yaml:"name"`
+			Rules []struct {
+				Alert  string            `yaml:"alert"`
+				Expr   string            `yaml:"expr"`
+				Labels map[string]string `yaml:"labels"`
+			} `yaml:"rules"`
+		} `yaml:"groups"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &parsed))
+	require.Len(t, parsed.Groups, 1)
+	assert.Equal(t, GroupName, parsed.Groups[0].Name)
+	require.Len(t, parsed.Groups[0].Rules, 5)
+	assert.Equal(t, "critical", parsed.Groups[0].Rules[0].Labels["severity"])
+}
+
+func TestWriteDashboard(t *testing.T) {
+	defs := definitions()
+	rules, err := BuildRules(&types.Config{}, defs)
+	require.NoError(t, err)
+	data, err := WriteDashboard(defs, rules)
+	require.NoError(t, err)
+
+	var dashboard struct {
+		UID    string `json:"uid"`
+		Panels []struct {
+			Type        string `json:"type"`
+			Title       string `json:"title"`
+			FieldConfig struct {
+				Defaults struct {
+					Thresholds struct {
+						Steps []struct {
+							Color string   `json:"color"`
+							Value *float64 `json:"value"`
+						} `json:"steps"`
+					} `json:"thresholds"`
+				} `json:"defaults"`
+			} `json:"fieldConfig"`
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	require.NoError(t, json.Unmarshal(data, &dashboard))
+	assert.Equal(t, DashboardUID, dashboard.UID)
+
+	// 每个已注册指标一个面板
+	panels := make(map[string]int)
+	for i, panel := range dashboard.Panels {
+		if panel.Type == "timeseries" {
+			panels[panel.Title] = i
+		}
+	}
+	assert.Len(t, panels, len(defs))
+	for _, def := range defs {
+		assert.Contains(t, panels, def.Name)
+	}
+
+	mm := dashboard.Panels[panels["deribit_maintenance_margin_ratio"]]
+	assert.Equal(t, `deribit_maintenance_margin_ratio{account=~"$account"}`, mm.Targets[0].Expr)
+	steps := mm.FieldConfig.Defaults.Thresholds.Steps
+	require.Len(t, steps, 2)
+	assert.Equal(t, "red", steps[1].Color)
+	assert.Equal(t, 0.5, *steps[1].Value)
+
+	// 低于阈值告警的指标，阈值以下为红色
+	equity := dashboard.Panels[panels["deribit_eth_equity_usd"]].FieldConfig.Defaults.Thresholds.Steps
+	require.Len(t, equity, 2)
+	assert.Equal(t, "red", equity[0].Color)
+	assert.Equal(t, -700000.0, *equity[1].Value)
+
+	// 计数器画速率，直方图画 p95
+	assert.Equal(t, "rate(deribit_api_errors_total[5m])", dashboard.Panels[panels["deribit_api_errors_total"]].Targets[0].Expr)
+	assert.Equal(t, `histogram_quantile(0.95, sum by (le, account, result) (rate(monitor_cycle_duration_seconds_bucket{account=~"$account"}[5m])))`,
+		dashboard.Panels[panels["monitor_cycle_duration_seconds"]].Targets[0].Expr)
+}
@@ -0,0 +1,221 @@
+// Package dashboards 根据已注册的指标和配置的规则阈值生成 Grafana 仪表盘和 Prometheus 告警规则。
+//
+// 告警阈值读取配置（monitor.rules、hedge.band、funding.max_daily_cost_usd 等），
+// 与监控评估使用同一份配置；规则引用的指标必须已注册，指标改名时生成会失败而不是输出失效的规则。
+package dashboards
+
+import (
+	"bytes"
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/metrics"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GroupName 生成的 Prometheus 规则组名
+const GroupName = "deribit-eth-collar"
+
+// Rule 一条 Prometheus 告警规则
+type Rule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+
+	// 阈值所在的指标，仪表盘在对应面板上画出阈值；Metric 为空表示没有单一阈值
+	Metric    string  `yaml:"-"`
+	Threshold float64 `yaml:"-"`
+}
+
+// ruleFile Prometheus rules.yaml 的结构
+type ruleFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// BuildRules 根据配置生成告警规则，只为启用的模块生成
+func BuildRules(cfg *types.Config, defs []metrics.Definition) ([]Rule, error) {
+	rules := cfg.Monitor.Rules.WithDefaults()
+	interval := cfg.Monitor.Interval
+	if interval <= 0 {
+		interval = 30
+	}
+
+	result := []Rule{
+		thresholdRule("HighMaintenanceMarginRatio", "deribit_maintenance_margin_ratio", ">", rules.MMRatioThreshold, "critical",
+			"维持保证金比率过高",
+			fmt.Sprintf("账户 {{ $labels.account }} 的维持保证金比率为 {{ $value | humanizePercentage }}，超过 %s 阈值，需补充 ETH 至 %s",
+				percent(rules.MMRatioThreshold), percent(rules.MMRatioTarget))),
+		thresholdRule("ETHEquityLoss", "deribit_eth_equity_usd", "<", rules.ETHEquityLossUSD, "critical",
+			"ETH 权益亏损过大",
+			fmt.Sprintf("账户 {{ $labels.account }} 的 ETH 权益为 ${{ $value | humanize }}，低于 %s 美元阈值，需补充 ETH 至 %s ETH",
+				formatFloat(rules.ETHEquityLossUSD), formatFloat(rules.ETHEquityTargetETH))),
+		{
+			Alert:  "MonitorMetricsStale",
+			Expr:   fmt.Sprintf("time() - deribit_metrics_collection_timestamp > %d", 5*interval),
+			For:    "1m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "监控指标未更新",
+				"description": fmt.Sprintf("账户 {{ $labels.account }} 的指标已超过 %d 秒（5 个监控周期）未更新", 5*interval),
+			},
+			Metric: "deribit_metrics_collection_timestamp",
+		},
+		{
+			Alert:  "MonitorCycleFailing",
+			Expr:   fmt.Sprintf("time() - monitor_last_success_timestamp_seconds > %d", 5*interval),
+			For:    "1m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "监控周期持续失败",
+				"description": fmt.Sprintf("账户 {{ $labels.account }} 已超过 %d 秒（5 个监控周期）没有成功完成监控周期，检查 deribit_api_errors_total 和监控日志", 5*interval),
+			},
+			Metric: "monitor_last_success_timestamp_seconds",
+		},
+		{
+			Alert:  "IndexPriceMissing",
+			Expr:   "deribit_missing_index_prices > 0",
+			For:    "5m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "缺少币种指数价格",
+				"description": "账户 {{ $labels.account }} 有 {{ $value }} 个有余额的币种缺少指数价格，未计入 USD 汇总，检查监控日志",
+			},
+			Metric: "deribit_missing_index_prices",
+		},
+	}
+
+	if cfg.Hedge.Enabled && cfg.Hedge.Band > 0 {
+		rule := thresholdRule("DeltaOutsideBand", "deribit_hedge_delta_deviation", ">", cfg.Hedge.Band, "warning",
+			"Delta 超出对冲区间",
+			fmt.Sprintf("账户 {{ $labels.account }} 的 %s Delta 偏离目标 {{ $value }}，超出 ±%s 区间",
+				cfg.Hedge.Currency, formatFloat(cfg.Hedge.Band)))
+		rule.Expr = fmt.Sprintf("abs(deribit_hedge_delta_deviation) > %s", formatFloat(cfg.Hedge.Band))
+		result = append(result, rule)
+	}
+	if cfg.Funding.Enabled && cfg.Funding.MaxDailyCostUSD > 0 {
+		limit := formatFloat(cfg.Funding.MaxDailyCostUSD)
+		rule := thresholdRule("FundingCostHigh", "deribit_funding_projected_daily_usd", ">", cfg.Funding.MaxDailyCostUSD, "warning",
+			"永续合约资金费成本过高",
+			fmt.Sprintf("账户 {{ $labels.account }} 的 {{ $labels.instrument }} 每日资金费成本为 ${{ $value | humanize }}，超过 %s 美元阈值", limit))
+		rule.Expr = fmt.Sprintf("deribit_funding_realized_usd_24h > %s or deribit_funding_projected_daily_usd > %s", limit, limit)
+		result = append(result, rule)
+	}
+	if cfg.Reconcile.Enabled {
+		result = append(result, Rule{
+			Alert:  "TopUpOverdue",
+			Expr:   "deribit_topup_overdue_seconds > 0",
+			Labels: map[string]string{"severity": "critical"},
+			Annotations: map[string]string{
+				"summary":     "补充 ETH 请求逾期未到账",
+				"description": fmt.Sprintf("账户 {{ $labels.account }} 的补充请求已逾期 {{ $value | humanizeDuration }}（到账期限 %d 秒）", cfg.Reconcile.DueSeconds),
+			},
+			Metric: "deribit_topup_overdue_seconds",
+		})
+	}
+	if cfg.Expiry.Enabled && len(cfg.Expiry.Reminders) > 0 {
+		nearest, err := nearestReminder(cfg.Expiry.Reminders)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Rule{
+			Alert:  "PositionExpiringSoon",
+			Expr:   fmt.Sprintf("deribit_expiry_seconds < %d and deribit_expiry_positions > 0", int64(nearest.Seconds())),
+			Labels: map[string]string{"severity": "info"},
+			Annotations: map[string]string{
+				"summary":     "持仓即将到期",
+				"description": fmt.Sprintf("账户 {{ $labels.account }} 在 {{ $labels.expiry }} 到期的持仓将在 {{ $value | humanizeDuration }} 后到期（提醒时间 %s）", nearest),
+			},
+			Metric: "deribit_expiry_seconds",
+		})
+	}
+	if cfg.Venues.Enabled {
+		result = append(result, Rule{
+			Alert:  "VenueDown",
+			Expr:   `venue_up{venue!="all"} == 0`,
+			For:    "5m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "交易所数据读取失败",
+				"description": "账户 {{ $labels.account }} 无法读取 {{ $labels.venue }} 的数据，跨交易所汇总不完整",
+			},
+			Metric: "venue_up",
+		})
+	}
+
+	if err := checkMetrics(result, defs); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// WriteRules 生成 Prometheus rules.yaml
+func WriteRules(rules []Rule) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# 由 `monitor gen dashboards` 生成，请修改配置后重新生成，不要手动编辑\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(ruleFile{Groups: []ruleGroup{{Name: GroupName, Rules: rules}}}); err != nil {
+		return nil, fmt.Errorf("failed to encode rules: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode rules: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func thresholdRule(alert, metric, op string, threshold float64, severity, summary, description string) Rule {
+	return Rule{
+		Alert:       alert,
+		Expr:        fmt.Sprintf("%s %s %s", metric, op, formatFloat(threshold)),
+		For:         "5m",
+		Labels:      map[string]string{"severity": severity},
+		Annotations: map[string]string{"summary": summary, "description": description},
+		Metric:      metric,
+		Threshold:   threshold,
+	}
+}
+
+// checkMetrics 规则引用的指标必须已注册
+func checkMetrics(rules []Rule, defs []metrics.Definition) error {
+	registered := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		registered[def.Name] = true
+	}
+	for _, rule := range rules {
+		if rule.Metric != "" && !registered[rule.Metric] {
+			return fmt.Errorf("rule %s references unregistered metric %s", rule.Alert, rule.Metric)
+		}
+	}
+	return nil
+}
+
+func nearestReminder(reminders []string) (time.Duration, error) {
+	var nearest time.Duration
+	for _, value := range reminders {
+		reminder, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid expiry reminder %q: %w", value, err)
+		}
+		if nearest == 0 || reminder < nearest {
+			nearest = reminder
+		}
+	}
+	return nearest, nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func percent(v float64) string {
+	return formatFloat(v*100) + "%"
+}
@@ -0,0 +1,297 @@
+// Package cassette 为 Deribit 客户端的 HTTP 传输提供录制和回放。
+//
+// 录制模式下请求照常发出，请求/响应对脱敏后写入 cassette 文件；回放模式下不访问网络，
+// 按方法、路径、查询参数和请求体匹配录制的交互并返回录制的响应。
+// 这样可以把一次真实的生产响应固化为回归测试数据。
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	ModeRecord = "record" // 转发请求并录制
+	ModeReplay = "replay" // 只从 cassette 回放
+
+	// Redacted 替换敏感值的占位符
+	Redacted = "REDACTED"
+)
+
+// DefaultRedactKeys 默认脱敏的字段名（查询参数、JSON 请求体和响应体中的键，大小写不敏感）
+var DefaultRedactKeys = []string{
+	"access_token",
+	"refresh_token",
+	"client_id",
+	"client_secret",
+	"signature",
+	"password",
+	"email",
+	"deposit_address",
+}
+
+// redactHeaders 需要脱敏的请求/响应头
+var redactHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// Request 录制的请求
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response 录制的响应
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Interaction 一次请求/响应
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette 录制的交互集合
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Load 读取 cassette 文件
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save 写入 cassette 文件
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// Recorder 录制/回放的 http.RoundTripper
+type Recorder struct {
+	mode       string
+	path       string
+	next       http.RoundTripper
+	redactKeys map[string]bool
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool // 回放模式下已使用的交互，重复请求依次返回后续录制
+}
+
+// New 创建录制器
+// 录制模式下 next 为实际的传输，每次交互后立即写入文件（进程异常退出也不会丢失）；
+// 回放模式下从 path 加载 cassette，next 不会被使用
+func New(mode, path string, next http.RoundTripper, extraRedactKeys ...string) (*Recorder, error) {
+	r := &Recorder{
+		mode:       mode,
+		path:       path,
+		next:       next,
+		redactKeys: make(map[string]bool),
+	}
+	for _, key := range append(append([]string(nil), DefaultRedactKeys...), extraRedactKeys...) {
+		r.redactKeys[strings.ToLower(key)] = true
+	}
+
+	switch mode {
+	case ModeRecord:
+		if next == nil {
+			r.next = http.DefaultTransport
+		}
+		r.cassette = &Cassette{}
+	case ModeReplay:
+		c, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = c
+		r.used = make([]bool, len(c.Interactions))
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+	return r, nil
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := r.redactRequest(req, body)
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.redactHeader(resp.Header),
+			Body:       r.redactBody(respBody),
+		},
+	})
+	if err := r.cassette.Save(r.path); err != nil {
+		return nil, fmt.Errorf("failed to save cassette: %w", err)
+	}
+	return resp, nil
+}
+
+// replay 返回第一条尚未使用且与请求匹配的录制
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !matches(interaction.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+
+		header := interaction.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s: no recorded interaction for %s %s", r.path, recorded.Method, recorded.URL)
+}
+
+// matches 比较方法、路径、查询参数（与顺序无关）和请求体；录制时的主机不参与比较，便于换地址回放
+func matches(recorded, actual Request) bool {
+	if recorded.Method != actual.Method || recorded.Body != actual.Body {
+		return false
+	}
+	a, errA := url.Parse(recorded.URL)
+	b, errB := url.Parse(actual.URL)
+	if errA != nil || errB != nil {
+		return recorded.URL == actual.URL
+	}
+	return trimBase(a.Path) == trimBase(b.Path) && a.Query().Encode() == b.Query().Encode()
+}
+
+// trimBase 只保留 API 方法部分（如 /private/get_account_summaries），忽略不同环境的路径前缀
+func trimBase(path string) string {
+	if i := strings.Index(path, "/api/v2"); i >= 0 {
+		return path[i+len("/api/v2"):]
+	}
+	return path
+}
+
+func (r *Recorder) redactRequest(req *http.Request, body []byte) Request {
+	u := *req.URL
+	query := u.Query()
+	for key := range query {
+		if r.redactKeys[strings.ToLower(key)] {
+			query.Set(key, Redacted)
+		}
+	}
+	u.RawQuery = query.Encode() // 按键排序，使录制文件稳定
+
+	return Request{
+		Method: req.Method,
+		URL:    u.String(),
+		Header: r.redactHeader(req.Header),
+		Body:   r.redactBody(body),
+	}
+}
+
+func (r *Recorder) redactHeader(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	redacted := header.Clone()
+	for _, name := range redactHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, Redacted)
+		}
+	}
+	return redacted
+}
+
+// redactBody 对 JSON 内容按键脱敏，非 JSON 内容原样保留
+func (r *Recorder) redactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return string(body)
+	}
+	redacted, err := json.Marshal(r.redactValue(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(redacted)
+}
+
+func (r *Recorder) redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if r.redactKeys[strings.ToLower(key)] {
+				value[key] = Redacted
+			} else {
+				value[key] = r.redactValue(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = r.redactValue(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,112 @@
+package cassette_test
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/deribit/cassette"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	srv := fakederibit.NewServer()
+	defer srv.Close()
+	srv.SetState(fakederibit.State{
+		Summaries: types.AccountSummaries{
+			Username: "collar",
+			Email:    "collar@example.com",
+			Summaries: []types.CurrencySummary{
+				{Currency: "ETH", Equity: 240.5, MaintenanceMargin: 35.2, MarginModel: "cross_pm", DepositAddress: "0xabc"},
+			},
+		},
+		IndexPrices: map[string]float64{"eth_usd": 3000},
+	})
+	file := filepath.Join(t.TempDir(), "session.cassette.json")
+
+	recordClient, err := deribit.NewClient(types.DeribitConfig{
+		APIKey:    srv.ClientID,
+		APISecret: srv.ClientSecret,
+		BaseURL:   srv.URL(),
+		Cassette:  types.CassetteConfig{Mode: cassette.ModeRecord, File: file},
+	})
+	require.NoError(t, err)
+	recorded, err := recordClient.GetAccountSummaries(true)
+	require.NoError(t, err)
+	price, err := recordClient.GetIndexPrice("eth")
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	for _, secret := range []string{srv.ClientSecret, srv.ClientID, "collar@example.com", "0xabc"} {
+		assert.NotContains(t, string(content), secret)
+	}
+	assert.Contains(t, string(content), cassette.Redacted)
+
+	// 回放时服务已关闭，只能从 cassette 取得响应
+	srv.Close()
+	replayClient, err := deribit.NewClient(types.DeribitConfig{
+		APIKey:    "any",
+		APISecret: "any",
+		BaseURL:   "http://127.0.0.1:1",
+		Cassette:  types.CassetteConfig{Mode: cassette.ModeReplay, File: file},
+	})
+	require.NoError(t, err)
+
+	replayed, err := replayClient.GetAccountSummaries(true)
+	require.NoError(t, err)
+	assert.Equal(t, recorded.Summaries[0].Equity, replayed.Summaries[0].Equity)
+	assert.Equal(t, cassette.Redacted, replayed.Email)
+
+	replayedPrice, err := replayClient.GetIndexPrice("eth")
+	require.NoError(t, err)
+	assert.Equal(t, price, replayedPrice)
+}
+
+func TestReplayUnknownRequest(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "empty.cassette.json")
+	require.NoError(t, (&cassette.Cassette{}).Save(file))
+
+	recorder, err := cassette.New(cassette.ModeReplay, file, nil)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "https://www.deribit.com/api/v2/public/test", nil)
+	require.NoError(t, err)
+	_, err = recorder.RoundTrip(req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no recorded interaction")
+}
+
+func TestReplaySequence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sequence.cassette.json")
+	interaction := func(body string) cassette.Interaction {
+		return cassette.Interaction{
+			Request:  cassette.Request{Method: http.MethodGet, URL: "https://www.deribit.com/api/v2/public/get_index_price?index_name=eth_usd"},
+			Response: cassette.Response{StatusCode: http.StatusOK, Body: body},
+		}
+	}
+	require.NoError(t, (&cassette.Cassette{Interactions: []cassette.Interaction{
+		interaction(`{"result":{"index_price":3000}}`),
+		interaction(`{"result":{"index_price":2900}}`),
+	}}).Save(file))
+
+	recorder, err := cassette.New(cassette.ModeReplay, file, nil)
+	require.NoError(t, err)
+
+	// 相同请求依次返回后续录制；录制时的主机不参与匹配
+	for _, want := range []string{"3000", "2900"} {
+		req, err := http.NewRequest(http.MethodGet, "http://localhost:9999/api/v2/public/get_index_price?index_name=eth_usd", nil)
+		require.NoError(t, err)
+		resp, err := recorder.RoundTrip(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), want)
+	}
+}
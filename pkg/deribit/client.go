@@ -2,13 +2,17 @@ package deribit
 
 import (
 	"bytes"
+	"context"
 	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit/cassette"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"sync"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 type Client struct {
@@ -19,34 +23,66 @@ type Client struct {
 	accessToken    string
 	tokenExpiresAt time.Time
 	authMutex      sync.RWMutex
+	observer       Observer    // 请求指标，为 nil 时不记录
+	logger         *zap.Logger // 请求调试日志，为 nil 时不记录
+
+	ctx    context.Context // 所有请求的上下文，Close 时取消
+	cancel context.CancelFunc
 }
 
+// APIError Deribit 返回的错误码，说明请求被拒绝、没有执行
 type APIError struct {
 	Message string `json:"message"`
 	Code    int    `json:"code"`
 }
 
-func NewClient(config types.DeribitConfig) *Client {
-	var baseURL string
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: %s (code: %d)", e.Message, e.Code)
+}
 
-	// 测试网
-	if config.TestNet {
-		baseURL = "https://test.deribit.com/api/v2"
-	} else {
-		baseURL = "https://www.deribit.com/api/v2"
+// NewClient 创建 Deribit API 客户端
+// API 地址优先使用 config.BaseURL（可指向代理、录制器或模拟服务），HTTP 传输按 config.HTTP 配置
+func NewClient(config types.DeribitConfig, opts ...Option) (*Client, error) {
+	httpClient, err := newHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure HTTP transport: %w", err)
 	}
 
-	return &Client{
-		apiKey:    config.APIKey,
-		apiSecret: config.APISecret,
-		baseURL:   baseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+	// 录制/回放：包装底层传输，录制内容会脱敏
+	if config.Cassette.Mode != "" {
+		recorder, err := cassette.New(config.Cassette.Mode, config.Cassette.File, httpClient.Transport, config.Cassette.RedactKeys...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up cassette: %w", err)
+		}
+		httpClient.Transport = recorder
 	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		apiKey:     config.APIKey,
+		apiSecret:  config.APISecret,
+		baseURL:    resolveBaseURL(config),
+		httpClient: httpClient,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Close 取消进行中的请求，之后的请求立即失败；退出时在等待超时后调用
+func (c *Client) Close() {
+	c.cancel()
 }
 
 func (c *Client) Authenticate() error {
+	return c.authenticate(context.Background())
+}
+
+// authenticate 刷新访问令牌，span 挂在 ctx 下
+func (c *Client) authenticate(ctx context.Context) (err error) {
 	c.authMutex.Lock()
 	defer c.authMutex.Unlock()
 
@@ -67,12 +103,27 @@ func (c *Client) Authenticate() error {
 		return fmt.Errorf("failed to marshal auth request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/", bytes.NewBuffer(jsonData))
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create auth request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	// 每次认证都是一次令牌刷新，记录请求结果和刷新结果
+	call := c.startCall(ctx, "public/auth", nil)
+	defer func() {
+		call.finish(err)
+		if c.observer != nil {
+			refresh := "success"
+			if call.result != ResultSuccess {
+				refresh = "failure"
+			}
+			c.observer.ObserveAuthRefresh(refresh)
+		}
+	}()
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("auth HTTP request failed: %w", err)
@@ -84,7 +135,12 @@ func (c *Client) Authenticate() error {
 		return fmt.Errorf("failed to read auth response: %w", err)
 	}
 
+	call.parse(resp.StatusCode, responseBody)
 	if resp.StatusCode != http.StatusOK {
+		call.result = ResultHTTPError
+		if call.meta.Error != nil {
+			call.result = ResultAPIError
+		}
 		return fmt.Errorf("auth HTTP error %d: %s", resp.StatusCode, string(responseBody))
 	}
 
@@ -97,12 +153,15 @@ func (c *Client) Authenticate() error {
 	}
 
 	if err := json.Unmarshal(responseBody, &authResponse); err != nil {
+		call.result = ResultDecodeError
 		return fmt.Errorf("failed to unmarshal auth response: %w", err)
 	}
 
 	if authResponse.Error != nil {
+		call.result, call.meta.Error = ResultAPIError, authResponse.Error
 		return fmt.Errorf("authentication API error: %s (code: %d)", authResponse.Error.Message, authResponse.Error.Code)
 	}
+	call.result = ResultSuccess
 
 	c.accessToken = authResponse.Result.AccessToken
 	// 设置过期时间，提前1分钟过期以避免边界情况
@@ -123,13 +182,13 @@ func (c *Client) isTokenValid() bool {
 	return c.accessToken != "" && time.Now().Before(c.tokenExpiresAt)
 }
 
-func (c *Client) ensureAuthenticated() error {
+func (c *Client) ensureAuthenticated(ctx context.Context) error {
 	// 如果未认证或token过期则重新认证
 	if c.isTokenValid() {
 		return nil
 	}
 
-	return c.Authenticate()
+	return c.authenticate(ctx)
 }
 
 func (c *Client) GetAccountSummary(currency string) (*types.AccountSummary, error) {
@@ -143,7 +202,7 @@ func (c *Client) GetAccountSummary(currency string) (*types.AccountSummary, erro
 		Error  *APIError            `json:"error"`
 	}
 
-	if err := c.makePrivateRequest("GET", endpoint, params, &response); err != nil {
+	if err := c.makePrivateRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
 		return nil, err
 	}
 
@@ -154,19 +213,236 @@ func (c *Client) GetAccountSummary(currency string) (*types.AccountSummary, erro
 	return &response.Result, nil
 }
 
-func (c *Client) GetPositions(currency string) ([]interface{}, error) {
+// GetPositions 获取指定货币的仓位，kind 可选（future / option / spot ...），省略时返回全部类型
+func (c *Client) GetPositions(currency string, kind ...string) ([]types.Position, error) {
 	endpoint := "/private/get_positions"
 	params := map[string]interface{}{
 		"currency": currency,
-		"kind":     "future",
+	}
+	if len(kind) > 0 && kind[0] != "" {
+		params["kind"] = kind[0]
+	}
+
+	var response struct {
+		Result []types.Position `json:"result"`
+		Error  *APIError        `json:"error"`
+	}
+
+	if err := c.makePrivateRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != nil {
+		return nil, fmt.Errorf("API error: %s (code: %d)", response.Error.Message, response.Error.Code)
+	}
+
+	return response.Result, nil
+}
+
+// GetInstruments 获取指定货币的合约列表，kind 为空时返回全部类型，expired 为 true 时返回最近到期的合约
+func (c *Client) GetInstruments(currency, kind string, expired bool) ([]types.Instrument, error) {
+	endpoint := "/public/get_instruments"
+	params := map[string]interface{}{
+		"currency": currency,
+	}
+	if kind != "" {
+		params["kind"] = kind
+	}
+	if expired {
+		params["expired"] = true
+	}
+
+	var response struct {
+		Result []types.Instrument `json:"result"`
+		Error  *APIError          `json:"error"`
+	}
+
+	if err := c.makePublicRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != nil {
+		return nil, fmt.Errorf("API error: %s (code: %d)", response.Error.Message, response.Error.Code)
+	}
+
+	return response.Result, nil
+}
+
+// GetBookSummaryByCurrency 获取指定货币所有合约的行情摘要，kind 为空时返回全部类型
+func (c *Client) GetBookSummaryByCurrency(currency, kind string) ([]types.BookSummary, error) {
+	endpoint := "/public/get_book_summary_by_currency"
+	params := map[string]interface{}{
+		"currency": currency,
+	}
+	if kind != "" {
+		params["kind"] = kind
+	}
+
+	var response struct {
+		Result []types.BookSummary `json:"result"`
+		Error  *APIError           `json:"error"`
+	}
+
+	if err := c.makePublicRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != nil {
+		return nil, fmt.Errorf("API error: %s (code: %d)", response.Error.Message, response.Error.Code)
+	}
+
+	return response.Result, nil
+}
+
+// GetTicker 获取合约行情：标记价格、最优买卖价、隐含波动率、希腊值和持仓量
+func (c *Client) GetTicker(instrumentName string) (*types.Ticker, error) {
+	endpoint := "/public/ticker"
+	params := map[string]interface{}{
+		"instrument_name": instrumentName,
+	}
+
+	var response struct {
+		Result types.Ticker `json:"result"`
+		Error  *APIError    `json:"error"`
+	}
+
+	if err := c.makePublicRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != nil {
+		return nil, fmt.Errorf("API error: %s (code: %d)", response.Error.Message, response.Error.Code)
+	}
+
+	return &response.Result, nil
+}
+
+// GetOrderBook 获取订单簿快照，depth 为每侧的档位数，0 表示使用交易所默认值
+func (c *Client) GetOrderBook(instrumentName string, depth int) (*types.OrderBook, error) {
+	endpoint := "/public/get_order_book"
+	params := map[string]interface{}{
+		"instrument_name": instrumentName,
+	}
+	if depth > 0 {
+		params["depth"] = depth
+	}
+
+	var response struct {
+		Result types.OrderBook `json:"result"`
+		Error  *APIError       `json:"error"`
+	}
+
+	if err := c.makePublicRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != nil {
+		return nil, fmt.Errorf("API error: %s (code: %d)", response.Error.Message, response.Error.Code)
+	}
+
+	return &response.Result, nil
+}
+
+// GetSettlementHistoryByCurrency 获取最近的结算记录（按时间倒序），settlementType 为空时返回全部类型，count 为 0 时使用交易所默认值
+func (c *Client) GetSettlementHistoryByCurrency(currency, settlementType string, count int) (*types.SettlementHistory, error) {
+	endpoint := "/private/get_settlement_history_by_currency"
+	params := map[string]interface{}{
+		"currency": currency,
+	}
+	if settlementType != "" {
+		params["type"] = settlementType
+	}
+	if count > 0 {
+		params["count"] = count
+	}
+
+	var response struct {
+		Result types.SettlementHistory `json:"result"`
+		Error  *APIError               `json:"error"`
+	}
+
+	if err := c.makePrivateRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != nil {
+		return nil, fmt.Errorf("API error: %s (code: %d)", response.Error.Message, response.Error.Code)
+	}
+
+	return &response.Result, nil
+}
+
+// GetTransactionLog 获取 [start, end] 时间范围内的账户流水（按时间倒序），continuation 为上一页返回的值，首页传 0
+func (c *Client) GetTransactionLog(currency string, start, end time.Time, count int, continuation int64) (*types.TransactionLog, error) {
+	endpoint := "/private/get_transaction_log"
+	params := map[string]interface{}{
+		"currency":        currency,
+		"start_timestamp": start.UnixMilli(),
+		"end_timestamp":   end.UnixMilli(),
+	}
+	if count > 0 {
+		params["count"] = count
+	}
+	if continuation > 0 {
+		params["continuation"] = continuation
 	}
 
 	var response struct {
-		Result []interface{} `json:"result"`
-		Error  *APIError     `json:"error"`
+		Result types.TransactionLog `json:"result"`
+		Error  *APIError            `json:"error"`
 	}
 
-	if err := c.makePrivateRequest("GET", endpoint, params, &response); err != nil {
+	if err := c.makePrivateRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != nil {
+		return nil, fmt.Errorf("API error: %s (code: %d)", response.Error.Message, response.Error.Code)
+	}
+
+	return &response.Result, nil
+}
+
+// GetFundingRateValue 获取永续合约在 [start, end] 内的累计资金费率
+func (c *Client) GetFundingRateValue(instrumentName string, start, end time.Time) (float64, error) {
+	endpoint := "/public/get_funding_rate_value"
+	params := map[string]interface{}{
+		"instrument_name": instrumentName,
+		"start_timestamp": start.UnixMilli(),
+		"end_timestamp":   end.UnixMilli(),
+	}
+
+	var response struct {
+		Result float64   `json:"result"`
+		Error  *APIError `json:"error"`
+	}
+
+	if err := c.makePublicRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
+		return 0, err
+	}
+
+	if response.Error != nil {
+		return 0, fmt.Errorf("API error: %s (code: %d)", response.Error.Message, response.Error.Code)
+	}
+
+	return response.Result, nil
+}
+
+// GetFundingRateHistory 获取永续合约在 [start, end] 内每小时的资金费率
+func (c *Client) GetFundingRateHistory(instrumentName string, start, end time.Time) ([]types.FundingRate, error) {
+	endpoint := "/public/get_funding_rate_history"
+	params := map[string]interface{}{
+		"instrument_name": instrumentName,
+		"start_timestamp": start.UnixMilli(),
+		"end_timestamp":   end.UnixMilli(),
+	}
+
+	var response struct {
+		Result []types.FundingRate `json:"result"`
+		Error  *APIError           `json:"error"`
+	}
+
+	if err := c.makePublicRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
 		return nil, err
 	}
 
@@ -178,6 +454,11 @@ func (c *Client) GetPositions(currency string) ([]interface{}, error) {
 }
 
 func (c *Client) GetIndexPrice(currency string) (float64, error) {
+	return c.GetIndexPriceContext(context.Background(), currency)
+}
+
+// GetIndexPriceContext 获取指数价格，请求的 span 挂在 ctx 下，ctx 取消时请求中止
+func (c *Client) GetIndexPriceContext(ctx context.Context, currency string) (float64, error) {
 	// 获取指数价格 (现货价格)
 	endpoint := "/public/get_index_price"
 	params := map[string]interface{}{
@@ -191,7 +472,7 @@ func (c *Client) GetIndexPrice(currency string) (float64, error) {
 		Error *APIError `json:"error"`
 	}
 
-	if err := c.makePublicRequest("GET", endpoint, params, &response); err != nil {
+	if err := c.makePublicRequest(ctx, "GET", endpoint, params, &response); err != nil {
 		return 0, err
 	}
 
@@ -203,6 +484,11 @@ func (c *Client) GetIndexPrice(currency string) (float64, error) {
 }
 
 func (c *Client) GetAccountSummaries(extended ...bool) (*types.AccountSummaries, error) {
+	return c.GetAccountSummariesContext(context.Background(), extended...)
+}
+
+// GetAccountSummariesContext 获取所有币种的账户摘要，请求的 span 挂在 ctx 下，ctx 取消时请求中止
+func (c *Client) GetAccountSummariesContext(ctx context.Context, extended ...bool) (*types.AccountSummaries, error) {
 	endpoint := "/private/get_account_summaries"
 	params := map[string]interface{}{}
 
@@ -217,7 +503,7 @@ func (c *Client) GetAccountSummaries(extended ...bool) (*types.AccountSummaries,
 		Error  *APIError              `json:"error"`
 	}
 
-	if err := c.makePrivateRequest("GET", endpoint, params, &response); err != nil {
+	if err := c.makePrivateRequest(ctx, "GET", endpoint, params, &response); err != nil {
 		return nil, err
 	}
 
@@ -228,24 +514,133 @@ func (c *Client) GetAccountSummaries(extended ...bool) (*types.AccountSummaries,
 	return &response.Result, nil
 }
 
-func (c *Client) makePublicRequest(method, endpoint string, params map[string]interface{}, result interface{}) error {
-	return c.makeRequest(method, endpoint, params, result, false)
+// SubmitTransferToSubaccount 从当前账户向子账户划转资金
+func (c *Client) SubmitTransferToSubaccount(currency string, amount float64, destination int64) (*types.Transfer, error) {
+	endpoint := "/private/submit_transfer_to_subaccount"
+	params := map[string]interface{}{
+		"currency":    currency,
+		"amount":      amount,
+		"destination": destination,
+	}
+
+	return c.submitTransfer(endpoint, params)
+}
+
+// SubmitTransferToUser 从当前账户向其他用户划转资金，destination 为对方的用户名或地址
+func (c *Client) SubmitTransferToUser(currency string, amount float64, destination string) (*types.Transfer, error) {
+	endpoint := "/private/submit_transfer_to_user"
+	params := map[string]interface{}{
+		"currency":    currency,
+		"amount":      amount,
+		"destination": destination,
+	}
+
+	return c.submitTransfer(endpoint, params)
+}
+
+// GetDeposits 获取充值记录，按时间倒序，count 为 0 时使用 API 默认值
+func (c *Client) GetDeposits(currency string, count, offset int) (*types.Deposits, error) {
+	var result types.Deposits
+	if err := c.getFunds("/private/get_deposits", currency, count, offset, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetWithdrawals 获取提现记录，按时间倒序，count 为 0 时使用 API 默认值
+func (c *Client) GetWithdrawals(currency string, count, offset int) (*types.Withdrawals, error) {
+	var result types.Withdrawals
+	if err := c.getFunds("/private/get_withdrawals", currency, count, offset, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetTransfers 获取划转记录（转入和转出），按时间倒序，count 为 0 时使用 API 默认值
+func (c *Client) GetTransfers(currency string, count, offset int) (*types.Transfers, error) {
+	var result types.Transfers
+	if err := c.getFunds("/private/get_transfers", currency, count, offset, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// getFunds 充值、提现和划转记录共用的分页请求
+func (c *Client) getFunds(endpoint, currency string, count, offset int, result interface{}) error {
+	params := map[string]interface{}{
+		"currency": currency,
+	}
+	if count > 0 {
+		params["count"] = count
+	}
+	if offset > 0 {
+		params["offset"] = offset
+	}
+
+	response := struct {
+		Result interface{} `json:"result"`
+		Error  *APIError   `json:"error"`
+	}{Result: result}
+
+	if err := c.makePrivateRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
+		return err
+	}
+
+	if response.Error != nil {
+		return fmt.Errorf("API error: %s (code: %d)", response.Error.Message, response.Error.Code)
+	}
+
+	return nil
+}
+
+func (c *Client) submitTransfer(endpoint string, params map[string]interface{}) (*types.Transfer, error) {
+	var response struct {
+		Result types.Transfer `json:"result"`
+		Error  *APIError      `json:"error"`
+	}
+
+	if err := c.makePrivateRequest(context.Background(), "GET", endpoint, params, &response); err != nil {
+		return nil, err
+	}
+
+	// 划转需要区分被拒绝和结果未知，直接返回 *APIError
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	return &response.Result, nil
+}
+
+func (c *Client) makePublicRequest(ctx context.Context, method, endpoint string, params map[string]interface{}, result interface{}) error {
+	return c.makeRequest(ctx, method, endpoint, params, result, false)
 }
 
-func (c *Client) makePrivateRequest(method, endpoint string, params map[string]interface{}, result interface{}) error {
+func (c *Client) makePrivateRequest(ctx context.Context, method, endpoint string, params map[string]interface{}, result interface{}) error {
 	// 确保认证
-	if err := c.ensureAuthenticated(); err != nil {
+	if err := c.ensureAuthenticated(ctx); err != nil {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
 
-	return c.makeRequest(method, endpoint, params, result, true)
+	return c.makeRequest(ctx, method, endpoint, params, result, true)
+}
+
+// requestContext 合并调用方的 ctx 和客户端的 ctx，任一取消时请求中止（Close 取消所有进行中的请求）
+func (c *Client) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(c.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
 }
 
-func (c *Client) makeRequest(method, endpoint string, params map[string]interface{}, result interface{}, isPrivate bool) error {
+func (c *Client) makeRequest(ctx context.Context, method, endpoint string, params map[string]interface{}, result interface{}, isPrivate bool) (err error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
 	// 构建完整的 URL
 	var fullURL string
 	var req *http.Request
-	var err error
 
 	if method == "GET" && len(params) > 0 {
 		fullURL = c.baseURL + endpoint + "?"
@@ -253,17 +648,17 @@ func (c *Client) makeRequest(method, endpoint string, params map[string]interfac
 			fullURL += fmt.Sprintf("%s=%v&", k, v)
 		}
 		fullURL = fullURL[:len(fullURL)-1]
-		req, err = http.NewRequest(method, fullURL, nil)
+		req, err = http.NewRequestWithContext(ctx, method, fullURL, nil)
 	} else if method == "GET" {
 		fullURL = c.baseURL + endpoint
-		req, err = http.NewRequest(method, fullURL, nil)
+		req, err = http.NewRequestWithContext(ctx, method, fullURL, nil)
 	} else {
 		fullURL = c.baseURL + endpoint
 		jsonData, jsonErr := json.Marshal(params)
 		if jsonErr != nil {
 			return fmt.Errorf("failed to marshal params: %w", jsonErr)
 		}
-		req, err = http.NewRequest(method, fullURL, bytes.NewBuffer(jsonData))
+		req, err = http.NewRequestWithContext(ctx, method, fullURL, bytes.NewBuffer(jsonData))
 		if err == nil {
 			req.Header.Set("Content-Type", "application/json")
 		}
@@ -285,7 +680,10 @@ func (c *Client) makeRequest(method, endpoint string, params map[string]interfac
 		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
-	// 执行请求
+	// 执行请求，记录耗时、结果和 span
+	call := c.startCall(ctx, endpoint, params)
+	defer func() { call.finish(err) }()
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("HTTP request failed to %s: %w", fullURL, err)
@@ -298,15 +696,33 @@ func (c *Client) makeRequest(method, endpoint string, params map[string]interfac
 		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// 检查 HTTP 状态码
+	// 检查 HTTP 状态码，Deribit 的错误响应带有错误码
+	call.parse(resp.StatusCode, responseBody)
 	if resp.StatusCode != http.StatusOK {
+		call.result = ResultHTTPError
+		if call.meta.Error != nil {
+			call.result = ResultAPIError
+		}
+		// 4xx 且带有错误码时请求被拒绝，返回 *APIError；5xx 时请求可能已执行
+		var rejected struct {
+			Error *APIError `json:"error"`
+		}
+		if resp.StatusCode < http.StatusInternalServerError && json.Unmarshal(responseBody, &rejected) == nil && rejected.Error != nil {
+			return fmt.Errorf("HTTP error %d for %s: %w", resp.StatusCode, fullURL, rejected.Error)
+		}
 		return fmt.Errorf("HTTP error %d for %s: %s", resp.StatusCode, fullURL, string(responseBody))
 	}
 
 	// 解析 JSON 响应
 	if err := json.Unmarshal(responseBody, result); err != nil {
+		call.result = ResultDecodeError
 		return fmt.Errorf("failed to unmarshal response: %w, body: %s", err, string(responseBody))
 	}
 
+	// 200 响应中的 Deribit 错误由调用方返回，这里只记录
+	call.result = ResultSuccess
+	if call.meta.Error != nil {
+		call.result = ResultAPIError
+	}
 	return nil
 }
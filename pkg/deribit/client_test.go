@@ -2,65 +2,403 @@ package deribit
 
 import (
 	"cs-projects-eth-collar/internal/types"
-	"fmt"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"errors"
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func setupTestClient(t *testing.T) *Client {
-	cfg := types.DeribitConfig{
-		APIKey:    "",
-		APISecret: "",
-		TestNet:   false,
+func testState() fakederibit.State {
+	return fakederibit.State{
+		Summaries: types.AccountSummaries{
+			ID:       1001,
+			Username: "collar",
+			Email:    "collar@example.com",
+			Summaries: []types.CurrencySummary{
+				{Currency: "BTC", Balance: 1.5, Equity: 1.5, MaintenanceMargin: 0.1, MarginModel: "cross_pm", CrossCollateralEnabled: true,
+					TotalEquityUSD: 1200000, TotalMaintenanceMarginUSD: 360000},
+				{Currency: "ETH", Balance: 250, Equity: 240.5, MaintenanceMargin: 35.2, MarginBalance: 230, DeltaTotal: 12.3,
+					MarginModel: "cross_pm", CrossCollateralEnabled: true, TotalEquityUSD: 1200000, TotalMaintenanceMarginUSD: 360000,
+					OptionsGammaMap: map[string]float64{"eth_27dec24": 0.01}},
+			},
+		},
+		Positions: []types.Position{
+			{InstrumentName: "ETH-PERPETUAL", Kind: "future", Direction: "sell", Size: -30000, Delta: -10},
+			{InstrumentName: "ETH-27DEC24-2500-P", Kind: "option", Direction: "buy", Size: 100, Delta: -25},
+			{InstrumentName: "BTC-PERPETUAL", Kind: "future", Direction: "buy", Size: 1000, Delta: 0.01},
+		},
+		IndexPrices: map[string]float64{"eth_usd": 3000, "btc_usd": 60000},
 	}
-	return NewClient(cfg)
+}
+
+func setupTestClient(t *testing.T) (*Client, *fakederibit.Server) {
+	srv := fakederibit.NewServer()
+	t.Cleanup(srv.Close)
+	srv.SetState(testState())
+
+	client, err := NewClient(types.DeribitConfig{
+		APIKey:    srv.ClientID,
+		APISecret: srv.ClientSecret,
+		BaseURL:   srv.URL(),
+	})
+	require.NoError(t, err)
+	return client, srv
 }
 
 func TestGetIndexPrice(t *testing.T) {
-	client := setupTestClient(t)
+	client, srv := setupTestClient(t)
+	srv.PricePath("eth_usd", 3000, 2800)
+
 	price, err := client.GetIndexPrice("eth")
-	assert.Nil(t, err)
-	fmt.Printf("%+v\n", price)
+	require.NoError(t, err)
+	assert.Equal(t, 3000.0, price)
+
+	price, err = client.GetIndexPrice("eth")
+	require.NoError(t, err)
+	assert.Equal(t, 2800.0, price)
+
+	// 价格路径用完后保持最后一个值
+	price, err = client.GetIndexPrice("eth")
+	require.NoError(t, err)
+	assert.Equal(t, 2800.0, price)
 }
 
 func TestGetAccountSummary(t *testing.T) {
-	client := setupTestClient(t)
+	client, _ := setupTestClient(t)
 	summary, err := client.GetAccountSummary("ETH")
 
-	assert.Nil(t, err)
-	fmt.Printf("%+v", summary)
+	require.NoError(t, err)
+	assert.Equal(t, "ETH", summary.Currency)
+	assert.Equal(t, 240.5, summary.Equity)
+	assert.Equal(t, 35.2, summary.MaintenanceMargin)
 }
 
 func TestGetAccountSummaries(t *testing.T) {
-	client := setupTestClient(t)
+	client, srv := setupTestClient(t)
 	summaries, err := client.GetAccountSummaries()
 
-	assert.Nil(t, err)
-	assert.NotNil(t, summaries)
-	assert.Greater(t, len(summaries.Summaries), 0)
+	require.NoError(t, err)
+	require.Len(t, summaries.Summaries, 2)
+	eth := summaries.Summaries[1]
+	assert.Equal(t, "ETH", eth.Currency)
+	assert.Equal(t, 1200000.0, eth.TotalEquityUSD)
+	assert.Equal(t, 12.3, eth.DeltaTotal)
+	assert.Equal(t, 0.01, eth.OptionsGammaMap["eth_27dec24"])
 
-	fmt.Printf("Change Margin Model API Limit: %+v\n", summaries.ChangeMarginModelAPILimit)
-	fmt.Printf("Number of currency summaries: %d\n", len(summaries.Summaries))
+	// 令牌有效期内不会重复认证
+	_, err = client.GetAccountSummaries()
+	require.NoError(t, err)
+	assert.Equal(t, 1, srv.Calls("public/auth"))
+}
 
-	// 打印前几个货币的信息
-	for i, summary := range summaries.Summaries {
-		if i >= 3 { // 只打印前3个
-			break
-		}
-		fmt.Printf("Currency: %s, Balance: %.6f, Maintenance Margin: %.6f\n",
-			summary.Currency, summary.Balance, summary.MaintenanceMargin)
+func TestGetAccountSummariesExtended(t *testing.T) {
+	client, _ := setupTestClient(t)
+	summaries, err := client.GetAccountSummaries(true)
+
+	require.NoError(t, err)
+	assert.Equal(t, "collar", summaries.Username)
+	assert.Equal(t, "collar@example.com", summaries.Email)
+}
+
+func TestGetPositions(t *testing.T) {
+	client, _ := setupTestClient(t)
+
+	futures, err := client.GetPositions("ETH", "future")
+	require.NoError(t, err)
+	require.Len(t, futures, 1)
+	assert.Equal(t, "ETH-PERPETUAL", futures[0].InstrumentName)
+
+	all, err := client.GetPositions("ETH")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestAPIErrorIsReturned(t *testing.T) {
+	client, srv := setupTestClient(t)
+	srv.InjectFault("private/get_account_summaries", fakederibit.Fault{
+		Code:    fakederibit.ErrCodeTooManyRequests,
+		Message: "too_many_requests",
+		Times:   1,
+	})
+
+	_, err := client.GetAccountSummaries()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too_many_requests")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, fakederibit.ErrCodeTooManyRequests, apiErr.Code)
+
+	// 故障只生效一次
+	_, err = client.GetAccountSummaries()
+	assert.NoError(t, err)
+
+	// 5xx 时请求可能已执行，不返回 *APIError
+	srv.InjectFault("private/get_account_summaries", fakederibit.Fault{
+		Code:       fakederibit.ErrCodeTooManyRequests,
+		Message:    "internal",
+		HTTPStatus: http.StatusBadGateway,
+		Times:      1,
+	})
+	_, err = client.GetAccountSummaries()
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &apiErr))
+}
+
+func TestInvalidCredentials(t *testing.T) {
+	client, _ := setupTestClient(t)
+	client.apiSecret = "wrong"
+
+	_, err := client.GetAccountSummaries()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "authentication failed")
+}
+
+func TestLatencyExceedsTimeout(t *testing.T) {
+	client, srv := setupTestClient(t)
+	client.httpClient.Timeout = 50 * time.Millisecond
+	srv.InjectFault("public/get_index_price", fakederibit.Fault{Latency: 200 * time.Millisecond, Times: 1})
+
+	_, err := client.GetIndexPrice("eth")
+	assert.Error(t, err)
+}
+
+func TestWebSocketSubscription(t *testing.T) {
+	_, srv := setupTestClient(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial(srv.WebSocketURL(), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "public/subscribe",
+		"params":  map[string]interface{}{"channels": []string{"deribit_price_index.eth_usd", "user.portfolio.eth"}},
+	}))
+
+	var subscribed struct {
+		ID     int      `json:"id"`
+		Result []string `json:"result"`
 	}
+	require.NoError(t, conn.ReadJSON(&subscribed))
+	// 私有频道需要 private/subscribe
+	assert.Equal(t, []string{"deribit_price_index.eth_usd"}, subscribed.Result)
+
+	srv.SetIndexPrice("eth_usd", 3100)
+
+	var notification struct {
+		Method string `json:"method"`
+		Params struct {
+			Channel string `json:"channel"`
+			Data    struct {
+				Price float64 `json:"price"`
+			} `json:"data"`
+		} `json:"params"`
+	}
+	require.NoError(t, conn.ReadJSON(&notification))
+	assert.Equal(t, "subscription", notification.Method)
+	assert.Equal(t, "deribit_price_index.eth_usd", notification.Params.Channel)
+	assert.Equal(t, 3100.0, notification.Params.Data.Price)
 }
 
-func TestGetAccountSummariesExtended(t *testing.T) {
-	client := setupTestClient(t)
+// 回放按 Deribit API 文档的响应结构手工构造的 cassette（字段和嵌套与文档示例一致，数值为虚构），
+// 确保 types 能解析完整的响应结构；不是录制的生产响应
+func TestReplayRecordedAccountSummaries(t *testing.T) {
+	client, err := NewClient(types.DeribitConfig{
+		APIKey:    "replay",
+		APISecret: "replay",
+		Cassette:  types.CassetteConfig{Mode: "replay", File: "testdata/account_summaries.cassette.json"},
+	})
+	require.NoError(t, err)
+
 	summaries, err := client.GetAccountSummaries(true)
+	require.NoError(t, err)
+	assert.Equal(t, "collar_desk", summaries.Username)
+	require.Len(t, summaries.Summaries, 2)
+
+	eth := summaries.Summaries[1]
+	assert.Equal(t, "ETH", eth.Currency)
+	assert.Equal(t, "cross_pm", eth.MarginModel)
+	assert.True(t, eth.CrossCollateralEnabled)
+	assert.Equal(t, 152.3301, eth.MaintenanceMargin)
+	assert.Equal(t, 612345.18, eth.TotalMaintenanceMarginUSD)
+	assert.Equal(t, 1843210.52, eth.TotalEquityUSD)
+	assert.Equal(t, 0.0301, eth.OptionsGammaMap["eth_27dec24"])
+
+	price, err := client.GetIndexPrice("eth")
+	require.NoError(t, err)
+	assert.Equal(t, 3512.47, price)
+}
+
+func TestGetInstruments(t *testing.T) {
+	client, srv := setupTestClient(t)
+	expiry := time.Now().Add(24 * time.Hour).UnixMilli()
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Instruments = []types.Instrument{
+			{InstrumentName: "ETH-PERPETUAL", Kind: "future", BaseCurrency: "ETH", ExpirationTimestamp: 32503708800000},
+			{InstrumentName: "ETH-TEST-3000-C", Kind: "option", BaseCurrency: "ETH", OptionType: "call", Strike: 3000, ExpirationTimestamp: expiry},
+			{InstrumentName: "ETH-OLD-3000-C", Kind: "option", BaseCurrency: "ETH", ExpirationTimestamp: time.Now().Add(-time.Hour).UnixMilli()},
+			{InstrumentName: "BTC-PERPETUAL", Kind: "future", BaseCurrency: "BTC", ExpirationTimestamp: 32503708800000},
+		}
+	})
+
+	options, err := client.GetInstruments("ETH", "option", false)
+	require.NoError(t, err)
+	require.Len(t, options, 1)
+	assert.Equal(t, "ETH-TEST-3000-C", options[0].InstrumentName)
+	assert.Equal(t, 3000.0, options[0].Strike)
+
+	all, err := client.GetInstruments("ETH", "", false)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	expired, err := client.GetInstruments("ETH", "option", true)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "ETH-OLD-3000-C", expired[0].InstrumentName)
+}
+
+func TestGetBookSummaryByCurrency(t *testing.T) {
+	client, srv := setupTestClient(t)
+	bid, ask := 0.0105, 0.0115
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Instruments = []types.Instrument{
+			{InstrumentName: "ETH-PERPETUAL", Kind: "future", BaseCurrency: "ETH"},
+			{InstrumentName: "ETH-27DEC24-2500-P", Kind: "option", BaseCurrency: "ETH"},
+		}
+		state.Books = []types.BookSummary{
+			{InstrumentName: "ETH-PERPETUAL", BaseCurrency: "ETH", MarkPrice: 3001.5, OpenInterest: 120000000},
+			{InstrumentName: "ETH-27DEC24-2500-P", BaseCurrency: "ETH", BidPrice: &bid, AskPrice: &ask, MarkPrice: 0.011, MarkIV: 62.5},
+		}
+	})
+
+	books, err := client.GetBookSummaryByCurrency("ETH", "option")
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	assert.Equal(t, 62.5, books[0].MarkIV)
+	require.NotNil(t, books[0].BidPrice)
+	assert.Equal(t, 0.0105, *books[0].BidPrice)
+
+	all, err := client.GetBookSummaryByCurrency("ETH", "")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.Nil(t, all[0].BidPrice)
+}
+
+func TestGetTickerAndOrderBook(t *testing.T) {
+	client, srv := setupTestClient(t)
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.OrderBooks = map[string]types.OrderBook{
+			"ETH-27DEC24-2500-P": {
+				Ticker: types.Ticker{MarkPrice: 0.0125, MarkIV: 62.5, BestBidPrice: 0.012, BestAskPrice: 0.013, OpenInterest: 1500,
+					Greeks: &types.Greeks{Delta: -0.25, Vega: 3.1}},
+				Bids: [][2]float64{{0.012, 50}, {0.0115, 100}},
+				Asks: [][2]float64{{0.013, 40}, {0.0135, 80}},
+			},
+		}
+	})
+
+	ticker, err := client.GetTicker("ETH-27DEC24-2500-P")
+	require.NoError(t, err)
+	assert.Equal(t, "ETH-27DEC24-2500-P", ticker.InstrumentName)
+	assert.Equal(t, 62.5, ticker.MarkIV)
+	require.NotNil(t, ticker.Greeks)
+	assert.Equal(t, -0.25, ticker.Greeks.Delta)
+
+	book, err := client.GetOrderBook("ETH-27DEC24-2500-P", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0.013, book.BestAskPrice)
+	assert.Equal(t, [][2]float64{{0.012, 50}}, book.Bids)
+	assert.Equal(t, [][2]float64{{0.013, 40}}, book.Asks)
+
+	_, err = client.GetTicker("ETH-UNKNOWN")
+	assert.Error(t, err)
+}
+
+func TestGetSettlementHistoryByCurrency(t *testing.T) {
+	client, srv := setupTestClient(t)
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Settlements = []types.Settlement{
+			{Type: "settlement", Timestamp: 1735286400000, InstrumentName: "ETH-PERPETUAL", SessionProfitLoss: 0.5},
+			{Type: "delivery", Timestamp: 1735286400100, InstrumentName: "ETH-27DEC24-2500-P", Position: 100, ProfitLoss: 0},
+			{Type: "delivery", Timestamp: 1735286400200, InstrumentName: "ETH-27DEC24-3500-C", Position: -100, IndexPrice: 3600, ProfitLoss: -2.7778},
+			{Type: "delivery", Timestamp: 1735286400300, InstrumentName: "BTC-27DEC24-90000-C", Position: 1},
+		}
+	})
+
+	history, err := client.GetSettlementHistoryByCurrency("ETH", "delivery", 0)
+	require.NoError(t, err)
+	require.Len(t, history.Settlements, 2)
+	// 按时间倒序
+	assert.Equal(t, "ETH-27DEC24-3500-C", history.Settlements[0].InstrumentName)
+	assert.Equal(t, -2.7778, history.Settlements[0].ProfitLoss)
+
+	history, err = client.GetSettlementHistoryByCurrency("ETH", "", 1)
+	require.NoError(t, err)
+	require.Len(t, history.Settlements, 1)
+	assert.Equal(t, "delivery", history.Settlements[0].Type)
+}
+
+func TestGetFundingRates(t *testing.T) {
+	client, srv := setupTestClient(t)
+	start := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.FundingRates = map[string][]types.FundingRate{
+			"ETH-PERPETUAL": {
+				{Timestamp: start.UnixMilli(), Interest1h: 0.0001, Interest8h: 0.0008},
+				{Timestamp: start.Add(time.Hour).UnixMilli(), Interest1h: 0.0002, Interest8h: 0.0012},
+				{Timestamp: start.Add(2 * time.Hour).UnixMilli(), Interest1h: -0.0001, Interest8h: 0.0004},
+			},
+		}
+	})
+
+	history, err := client.GetFundingRateHistory("ETH-PERPETUAL", start.Add(time.Hour), start.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 0.0012, history[0].Interest8h)
+
+	value, err := client.GetFundingRateValue("ETH-PERPETUAL", start, start.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0001, value, 1e-12) // 不含 start 时刻那一条
+
+	_, err = client.GetFundingRateValue("BTC-PERPETUAL", start, start.Add(time.Hour))
+	assert.Error(t, err)
+}
+
+func TestGetDepositsWithdrawalsAndTransfers(t *testing.T) {
+	client, srv := setupTestClient(t)
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Deposits = []types.Deposit{
+			{Amount: 1, Currency: "ETH", State: "completed", TransactionID: "0x1", ReceivedTimestamp: 1000},
+			{Amount: 2, Currency: "ETH", State: "completed", TransactionID: "0x2", ReceivedTimestamp: 2000},
+			{Amount: 3, Currency: "BTC", State: "completed", TransactionID: "0x3", ReceivedTimestamp: 3000},
+		}
+		state.Withdrawals = []types.Withdrawal{{ID: 7, Amount: 4, Currency: "ETH", State: "completed", CreatedTimestamp: 1500}}
+		state.Transfers = []types.Transfer{{ID: 9, Amount: 35, Currency: "ETH", Direction: "income", State: "confirmed", CreatedTimestamp: 2500}}
+	})
+
+	deposits, err := client.GetDeposits("ETH", 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, deposits.Count)
+	require.Len(t, deposits.Data, 1)
+	assert.Equal(t, "0x2", deposits.Data[0].TransactionID) // 按时间倒序
+
+	deposits, err = client.GetDeposits("ETH", 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "0x1", deposits.Data[0].TransactionID)
 
-	assert.Nil(t, err)
-	assert.NotNil(t, summaries)
+	withdrawals, err := client.GetWithdrawals("ETH", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, withdrawals.Data, 1)
+	assert.Equal(t, int64(7), withdrawals.Data[0].ID)
 
-	// 在extended模式下，应该有更多的账户信息
-	fmt.Printf("Extended info - Username: %s, Email: %s\n", summaries.Username, summaries.Email)
-	fmt.Printf("Number of currency summaries: %d\n", len(summaries.Summaries))
+	transfers, err := client.GetTransfers("ETH", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, transfers.Data, 1)
+	assert.Equal(t, "income", transfers.Data[0].Direction)
 }
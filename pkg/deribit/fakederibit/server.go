@@ -0,0 +1,720 @@
+// Package fakederibit 提供一个本地的 Deribit API 模拟服务，用于离线、确定性的测试。
+//
+// 服务基于 httptest，同时支持 HTTP（GET /api/v2/{public,private}/<method> 以及 POST /api/v2/ 的 JSON-RPC）
+// 和 WebSocket（/ws/api/v2 的 JSON-RPC 与订阅推送）。测试通过 State 设置账户数据，
+// 并可以编排价格路径、保证金突增、错误码和延迟等场景：
+//
+//	srv := fakederibit.NewServer()
+//	defer srv.Close()
+//	srv.SetState(fakederibit.State{...})
+//	srv.PricePath("eth_usd", 3000, 2800, 2500)
+//	srv.InjectFault("private/get_account_summaries", fakederibit.Fault{Code: 10028, Message: "too_many_requests", Times: 1})
+//
+//	client, _ := deribit.NewClient(types.DeribitConfig{BaseURL: srv.URL(), APIKey: srv.ClientID, APISecret: srv.ClientSecret})
+package fakederibit
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认凭证
+	DefaultClientID     = "fake-client-id"
+	DefaultClientSecret = "fake-client-secret"
+
+	// Deribit 错误码
+	ErrCodeUnauthorized       = 13009 // invalid_token / unauthorized
+	ErrCodeInvalidCredentials = 13004 // invalid_credentials
+	ErrCodeMethodNotFound     = -32601
+	ErrCodeInvalidParams      = -32602
+	ErrCodeTooManyRequests    = 10028
+
+	apiPrefix = "/api/v2"
+	wsPath    = "/ws/api/v2"
+)
+
+// State 模拟账户的当前状态
+type State struct {
+	Summaries    types.AccountSummaries         // get_account_summaries / get_account_summary 返回的数据
+	Positions    []types.Position               // get_positions 返回的数据，按合约名前缀匹配币种
+	IndexPrices  map[string]float64             // 指数名（如 eth_usd）到价格
+	Instruments  []types.Instrument             // get_instruments 返回的数据，按 base_currency 和 kind 过滤
+	Books        []types.BookSummary            // get_book_summary_by_currency 返回的数据，按 base_currency 过滤，kind 按对应合约过滤
+	OrderBooks   map[string]types.OrderBook     // 合约名到订单簿，ticker 返回其中的行情字段
+	Settlements  []types.Settlement             // get_settlement_history_by_currency 返回的数据，按合约名前缀匹配币种，按时间倒序返回
+	Transactions []types.TransactionLogEntry    // get_transaction_log 返回的数据，按 currency 和时间范围过滤，按 ID 倒序分页
+	FundingRates map[string][]types.FundingRate // 合约名到每小时资金费率，get_funding_rate_value 返回 (start, end] 内 interest_1h 之和
+	Deposits     []types.Deposit                // get_deposits 返回的数据，以下三项按 currency 过滤，按时间倒序分页
+	Withdrawals  []types.Withdrawal             // get_withdrawals 返回的数据
+	Transfers    []types.Transfer               // get_transfers 返回的数据
+}
+
+// Fault 注入到某个方法的故障
+type Fault struct {
+	Code       int           // Deribit 错误码，0 表示不返回错误（只注入延迟）
+	Message    string        // 错误信息
+	HTTPStatus int           // HTTP 状态码，默认 400
+	Latency    time.Duration // 响应前的延迟
+	Times      int           // 生效次数，<=0 表示一直生效
+}
+
+// RPCError JSON-RPC 错误
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server 模拟的 Deribit 服务
+type Server struct {
+	ClientID     string
+	ClientSecret string
+	TokenTTL     time.Duration // 签发令牌的有效期，默认 15 分钟
+
+	httpServer *httptest.Server
+
+	mu         sync.Mutex
+	state      State
+	pricePaths map[string][]float64
+	faults     map[string][]Fault
+	calls      map[string]int
+	tokens     map[string]time.Time
+	tokenSeq   int
+	wsConns    map[*wsConn]bool
+}
+
+// NewServer 创建并启动模拟服务
+func NewServer() *Server {
+	s := &Server{
+		ClientID:     DefaultClientID,
+		ClientSecret: DefaultClientSecret,
+		TokenTTL:     15 * time.Minute,
+		state:        State{IndexPrices: map[string]float64{}},
+		pricePaths:   make(map[string][]float64),
+		faults:       make(map[string][]Fault),
+		calls:        make(map[string]int),
+		tokens:       make(map[string]time.Time),
+		wsConns:      make(map[*wsConn]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(wsPath, s.handleWebSocket)
+	mux.HandleFunc(apiPrefix+"/", s.handleHTTP)
+	s.httpServer = httptest.NewServer(mux)
+	return s
+}
+
+// URL HTTP API 的基础地址（与 DeribitConfig.BaseURL 的格式一致）
+func (s *Server) URL() string {
+	return s.httpServer.URL + apiPrefix
+}
+
+// WebSocketURL WebSocket API 地址
+func (s *Server) WebSocketURL() string {
+	return "ws" + strings.TrimPrefix(s.httpServer.URL, "http") + wsPath
+}
+
+// Close 关闭服务和所有 WebSocket 连接
+func (s *Server) Close() {
+	s.mu.Lock()
+	for conn := range s.wsConns {
+		conn.close()
+	}
+	s.mu.Unlock()
+	s.httpServer.Close()
+}
+
+// SetState 替换账户状态
+func (s *Server) SetState(state State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state.IndexPrices == nil {
+		state.IndexPrices = map[string]float64{}
+	}
+	s.state = state
+}
+
+// UpdateState 在锁内修改账户状态
+func (s *Server) UpdateState(update func(state *State)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(&s.state)
+}
+
+// SetIndexPrice 设置指数价格，并推送给订阅了 deribit_price_index.<index> 的连接
+func (s *Server) SetIndexPrice(index string, price float64) {
+	s.mu.Lock()
+	s.state.IndexPrices[index] = price
+	s.mu.Unlock()
+	s.publishIndexPrice(index, price)
+}
+
+// PricePath 编排价格路径：之后每次查询该指数价格依次返回 prices 中的值，用完后保持最后一个值
+func (s *Server) PricePath(index string, prices ...float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pricePaths[index] = append([]float64(nil), prices...)
+}
+
+// MarginSpike 把指定币种的维持保证金（含账户级别 USD 值）放大 factor 倍，模拟保证金突增
+func (s *Server) MarginSpike(currency string, factor float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.state.Summaries.Summaries {
+		summary := &s.state.Summaries.Summaries[i]
+		if strings.EqualFold(summary.Currency, currency) || summary.CrossCollateralEnabled {
+			summary.TotalMaintenanceMarginUSD *= factor
+		}
+		if strings.EqualFold(summary.Currency, currency) {
+			summary.MaintenanceMargin *= factor
+			summary.ProjectedMaintenanceMargin *= factor
+		}
+	}
+}
+
+// InjectFault 为方法（如 "private/get_account_summaries"）注入故障，多次注入按顺序生效
+func (s *Server) InjectFault(method string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[method] = append(s.faults[method], fault)
+}
+
+// ClearFaults 清除所有注入的故障
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = make(map[string][]Fault)
+}
+
+// ExpireTokens 使所有已签发令牌失效，模拟令牌过期
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]time.Time)
+}
+
+// Calls 返回某个方法被调用的次数
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// handleHTTP 处理 HTTP 请求：REST 风格路径或 JSON-RPC
+func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	usIn := time.Now().UnixMicro()
+
+	var id interface{}
+	method := strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix), "/")
+	params := map[string]interface{}{}
+
+	switch {
+	case method == "" && r.Method == http.MethodPost:
+		var request rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeHTTP(w, http.StatusBadRequest, newResponse(nil, nil, &RPCError{Code: ErrCodeInvalidParams, Message: err.Error()}, usIn))
+			return
+		}
+		id, method = request.ID, request.Method
+		if request.Params != nil {
+			params = request.Params
+		}
+	case r.Method == http.MethodGet:
+		for key, values := range r.URL.Query() {
+			params[key] = values[0]
+		}
+	default:
+		body, _ := io.ReadAll(r.Body)
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &params); err != nil {
+				writeHTTP(w, http.StatusBadRequest, newResponse(nil, nil, &RPCError{Code: ErrCodeInvalidParams, Message: err.Error()}, usIn))
+				return
+			}
+		}
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	result, rpcErr, status := s.dispatch(method, params, token, nil)
+	writeHTTP(w, status, newResponse(id, result, rpcErr, usIn))
+}
+
+// dispatch 执行一个 API 方法，conn 非空表示来自 WebSocket
+func (s *Server) dispatch(method string, params map[string]interface{}, token string, conn *wsConn) (interface{}, *RPCError, int) {
+	fault, faulted := s.takeFault(method)
+	if fault.Latency > 0 {
+		time.Sleep(fault.Latency)
+	}
+	if faulted && fault.Code != 0 {
+		status := fault.HTTPStatus
+		if status == 0 {
+			status = http.StatusBadRequest
+		}
+		return nil, &RPCError{Code: fault.Code, Message: fault.Message}, status
+	}
+
+	if strings.HasPrefix(method, "private/") {
+		if token == "" {
+			token, _ = params["access_token"].(string)
+		}
+		if !s.tokenValid(token) && (conn == nil || !conn.authenticated()) {
+			return nil, &RPCError{Code: ErrCodeUnauthorized, Message: "unauthorized"}, http.StatusBadRequest
+		}
+	}
+
+	switch method {
+	case "public/auth":
+		return s.auth(params, conn)
+	case "public/test":
+		return map[string]string{"version": "fakederibit"}, nil, http.StatusOK
+	case "public/get_index_price":
+		return s.indexPrice(params)
+	case "private/get_account_summaries":
+		return s.accountSummaries(), nil, http.StatusOK
+	case "private/get_account_summary":
+		return s.accountSummary(params)
+	case "private/get_positions":
+		return s.positions(params), nil, http.StatusOK
+	case "private/get_settlement_history_by_currency":
+		return s.settlements(params), nil, http.StatusOK
+	case "private/get_transaction_log":
+		return s.transactionLog(params), nil, http.StatusOK
+	case "private/get_deposits", "private/get_withdrawals", "private/get_transfers":
+		return s.funds(method, params), nil, http.StatusOK
+	case "public/get_funding_rate_history", "public/get_funding_rate_value":
+		return s.fundingRates(method, params)
+	case "public/get_instruments":
+		return s.instruments(params), nil, http.StatusOK
+	case "public/get_book_summary_by_currency":
+		return s.bookSummaries(params), nil, http.StatusOK
+	case "public/ticker", "public/get_order_book":
+		return s.orderBook(method, params)
+	case "public/subscribe", "private/subscribe":
+		if conn == nil {
+			return nil, &RPCError{Code: ErrCodeMethodNotFound, Message: "subscriptions require WebSocket"}, http.StatusBadRequest
+		}
+		return conn.subscribe(channelsParam(params), method == "private/subscribe"), nil, http.StatusOK
+	case "public/unsubscribe", "private/unsubscribe":
+		if conn == nil {
+			return nil, &RPCError{Code: ErrCodeMethodNotFound, Message: "subscriptions require WebSocket"}, http.StatusBadRequest
+		}
+		return conn.unsubscribe(channelsParam(params)), nil, http.StatusOK
+	default:
+		return nil, &RPCError{Code: ErrCodeMethodNotFound, Message: "Method not found"}, http.StatusBadRequest
+	}
+}
+
+func (s *Server) takeFault(method string) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[method]++
+
+	faults := s.faults[method]
+	if len(faults) == 0 {
+		return Fault{}, false
+	}
+	fault := faults[0]
+	if fault.Times > 0 {
+		faults[0].Times--
+		if faults[0].Times == 0 {
+			s.faults[method] = faults[1:]
+		}
+	}
+	return fault, true
+}
+
+func (s *Server) tokenValid(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.tokens[token]
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (s *Server) auth(params map[string]interface{}, conn *wsConn) (interface{}, *RPCError, int) {
+	clientID, _ := params["client_id"].(string)
+	clientSecret, _ := params["client_secret"].(string)
+	if params["grant_type"] != "client_credentials" || clientID != s.ClientID || clientSecret != s.ClientSecret {
+		return nil, &RPCError{Code: ErrCodeInvalidCredentials, Message: "invalid_credentials"}, http.StatusBadRequest
+	}
+
+	s.mu.Lock()
+	s.tokenSeq++
+	token := fmt.Sprintf("fake-access-token-%d", s.tokenSeq)
+	s.tokens[token] = time.Now().Add(s.TokenTTL)
+	s.mu.Unlock()
+
+	if conn != nil {
+		conn.setAuthenticated()
+	}
+
+	return map[string]interface{}{
+		"access_token":  token,
+		"refresh_token": "fake-refresh-token",
+		"expires_in":    int64(s.TokenTTL / time.Second),
+		"scope":         "account:read trade:read wallet:read",
+		"token_type":    "bearer",
+	}, nil, http.StatusOK
+}
+
+func (s *Server) indexPrice(params map[string]interface{}) (interface{}, *RPCError, int) {
+	index, _ := params["index_name"].(string)
+
+	s.mu.Lock()
+	if path := s.pricePaths[index]; len(path) > 0 {
+		s.state.IndexPrices[index] = path[0]
+		if len(path) > 1 {
+			s.pricePaths[index] = path[1:]
+		} else {
+			delete(s.pricePaths, index)
+		}
+	}
+	price, ok := s.state.IndexPrices[index]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, &RPCError{Code: ErrCodeInvalidParams, Message: "Invalid params: index_name " + index}, http.StatusBadRequest
+	}
+	s.publishIndexPrice(index, price)
+	return map[string]float64{"index_price": price, "estimated_delivery_price": price}, nil, http.StatusOK
+}
+
+func (s *Server) accountSummaries() types.AccountSummaries {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summaries := s.state.Summaries
+	summaries.Summaries = append([]types.CurrencySummary(nil), s.state.Summaries.Summaries...)
+	return summaries
+}
+
+func (s *Server) accountSummary(params map[string]interface{}) (interface{}, *RPCError, int) {
+	currency, _ := params["currency"].(string)
+	for _, summary := range s.accountSummaries().Summaries {
+		if strings.EqualFold(summary.Currency, currency) {
+			return summary, nil, http.StatusOK
+		}
+	}
+	return nil, &RPCError{Code: ErrCodeInvalidParams, Message: "Invalid params: currency " + currency}, http.StatusBadRequest
+}
+
+func (s *Server) positions(params map[string]interface{}) []types.Position {
+	currency, _ := params["currency"].(string)
+	kind, _ := params["kind"].(string)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	positions := []types.Position{}
+	for _, position := range s.state.Positions {
+		if currency != "" && currency != "any" && !strings.HasPrefix(strings.ToUpper(position.InstrumentName), strings.ToUpper(currency)) {
+			continue
+		}
+		if kind != "" && position.Kind != kind {
+			continue
+		}
+		positions = append(positions, position)
+	}
+	return positions
+}
+
+// settlements 按币种和类型过滤结算记录，按时间倒序返回最多 count 条（默认 20）
+func (s *Server) settlements(params map[string]interface{}) types.SettlementHistory {
+	currency, _ := params["currency"].(string)
+	settlementType, _ := params["type"].(string)
+	count := 20
+	if value, err := strconv.Atoi(fmt.Sprint(params["count"])); err == nil && value > 0 {
+		count = value
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settlements := []types.Settlement{}
+	for _, settlement := range s.state.Settlements {
+		if currency != "" && currency != "any" && settlement.InstrumentName != "" &&
+			!strings.HasPrefix(strings.ToUpper(settlement.InstrumentName), strings.ToUpper(currency)) {
+			continue
+		}
+		if settlementType != "" && settlement.Type != settlementType {
+			continue
+		}
+		settlements = append(settlements, settlement)
+	}
+	sort.SliceStable(settlements, func(i, j int) bool { return settlements[i].Timestamp > settlements[j].Timestamp })
+	if len(settlements) > count {
+		settlements = settlements[:count]
+	}
+	return types.SettlementHistory{Settlements: settlements}
+}
+
+// transactionLog 按币种和时间范围过滤账户流水，按 ID 倒序每页返回 count 条（默认 100），continuation 为下一页的起始 ID
+func (s *Server) transactionLog(params map[string]interface{}) types.TransactionLog {
+	currency, _ := params["currency"].(string)
+	start, _ := strconv.ParseInt(fmt.Sprint(params["start_timestamp"]), 10, 64)
+	end, err := strconv.ParseInt(fmt.Sprint(params["end_timestamp"]), 10, 64)
+	if err != nil {
+		end = math.MaxInt64
+	}
+	continuation, _ := strconv.ParseInt(fmt.Sprint(params["continuation"]), 10, 64)
+	count := 100
+	if value, err := strconv.Atoi(fmt.Sprint(params["count"])); err == nil && value > 0 {
+		count = value
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	logs := []types.TransactionLogEntry{}
+	for _, entry := range s.state.Transactions {
+		if currency != "" && !strings.EqualFold(entry.Currency, currency) {
+			continue
+		}
+		if entry.Timestamp < start || entry.Timestamp > end || (continuation > 0 && entry.ID > continuation) {
+			continue
+		}
+		logs = append(logs, entry)
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].ID > logs[j].ID })
+
+	result := types.TransactionLog{Logs: logs}
+	if len(logs) > count {
+		next := logs[count].ID
+		result.Logs = logs[:count]
+		result.Continuation = &next
+	}
+	return result
+}
+
+// funds 按币种过滤充值、提现或划转记录，按时间倒序返回 offset 之后最多 count 条（默认 10）
+func (s *Server) funds(method string, params map[string]interface{}) interface{} {
+	currency, _ := params["currency"].(string)
+	count := 10
+	if value, err := strconv.Atoi(fmt.Sprint(params["count"])); err == nil && value > 0 {
+		count = value
+	}
+	offset, _ := strconv.Atoi(fmt.Sprint(params["offset"]))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	page := func(total int) (int, int) {
+		start := min(max(offset, 0), total)
+		return start, min(start+count, total)
+	}
+	switch method {
+	case "private/get_deposits":
+		var deposits []types.Deposit
+		for _, d := range s.state.Deposits {
+			if strings.EqualFold(d.Currency, currency) {
+				deposits = append(deposits, d)
+			}
+		}
+		sort.SliceStable(deposits, func(i, j int) bool { return deposits[i].ReceivedTimestamp > deposits[j].ReceivedTimestamp })
+		start, end := page(len(deposits))
+		return types.Deposits{Count: len(deposits), Data: append([]types.Deposit{}, deposits[start:end]...)}
+	case "private/get_withdrawals":
+		var withdrawals []types.Withdrawal
+		for _, w := range s.state.Withdrawals {
+			if strings.EqualFold(w.Currency, currency) {
+				withdrawals = append(withdrawals, w)
+			}
+		}
+		sort.SliceStable(withdrawals, func(i, j int) bool { return withdrawals[i].CreatedTimestamp > withdrawals[j].CreatedTimestamp })
+		start, end := page(len(withdrawals))
+		return types.Withdrawals{Count: len(withdrawals), Data: append([]types.Withdrawal{}, withdrawals[start:end]...)}
+	default:
+		var transfers []types.Transfer
+		for _, t := range s.state.Transfers {
+			if strings.EqualFold(t.Currency, currency) {
+				transfers = append(transfers, t)
+			}
+		}
+		sort.SliceStable(transfers, func(i, j int) bool { return transfers[i].CreatedTimestamp > transfers[j].CreatedTimestamp })
+		start, end := page(len(transfers))
+		return types.Transfers{Count: len(transfers), Data: append([]types.Transfer{}, transfers[start:end]...)}
+	}
+}
+
+// fundingRates 返回 [start, end] 内的资金费率历史，或 (start, end] 内 interest_1h 之和（每条覆盖截至其时间戳的 1 小时）
+func (s *Server) fundingRates(method string, params map[string]interface{}) (interface{}, *RPCError, int) {
+	instrument, _ := params["instrument_name"].(string)
+	start, _ := strconv.ParseInt(fmt.Sprint(params["start_timestamp"]), 10, 64)
+	end, _ := strconv.ParseInt(fmt.Sprint(params["end_timestamp"]), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, ok := s.state.FundingRates[instrument]
+	if !ok {
+		return nil, &RPCError{Code: ErrCodeInvalidParams, Message: "Invalid params: instrument_name " + instrument}, http.StatusBadRequest
+	}
+	rates := []types.FundingRate{}
+	var value float64
+	for _, rate := range history {
+		if rate.Timestamp < start || rate.Timestamp > end {
+			continue
+		}
+		rates = append(rates, rate)
+		if rate.Timestamp > start {
+			value += rate.Interest1h
+		}
+	}
+	if method == "public/get_funding_rate_value" {
+		return value, nil, http.StatusOK
+	}
+	return rates, nil, http.StatusOK
+}
+
+// instruments 返回未到期的合约；expired=true 时返回已到期的合约
+func (s *Server) instruments(params map[string]interface{}) []types.Instrument {
+	currency, _ := params["currency"].(string)
+	kind, _ := params["kind"].(string)
+	expired := fmt.Sprint(params["expired"]) == "true"
+	now := time.Now().UnixMilli()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	instruments := []types.Instrument{}
+	for _, instrument := range s.state.Instruments {
+		if currency != "" && currency != "any" && !strings.EqualFold(instrument.BaseCurrency, currency) {
+			continue
+		}
+		if kind != "" && instrument.Kind != kind {
+			continue
+		}
+		isExpired := instrument.ExpirationTimestamp > 0 && instrument.ExpirationTimestamp <= now
+		if isExpired != expired {
+			continue
+		}
+		instruments = append(instruments, instrument)
+	}
+	return instruments
+}
+
+func (s *Server) bookSummaries(params map[string]interface{}) []types.BookSummary {
+	currency, _ := params["currency"].(string)
+	kind, _ := params["kind"].(string)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kinds := make(map[string]string)
+	for _, instrument := range s.state.Instruments {
+		kinds[instrument.InstrumentName] = instrument.Kind
+	}
+	books := []types.BookSummary{}
+	for _, book := range s.state.Books {
+		if currency != "" && currency != "any" && !strings.EqualFold(book.BaseCurrency, currency) {
+			continue
+		}
+		if kind != "" && kinds[book.InstrumentName] != kind {
+			continue
+		}
+		books = append(books, book)
+	}
+	return books
+}
+
+// orderBook 返回订单簿，depth 截断每侧档位；ticker 只返回行情字段
+func (s *Server) orderBook(method string, params map[string]interface{}) (interface{}, *RPCError, int) {
+	name, _ := params["instrument_name"].(string)
+
+	s.mu.Lock()
+	book, ok := s.state.OrderBooks[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, &RPCError{Code: ErrCodeInvalidParams, Message: "Invalid params: instrument_name " + name}, http.StatusBadRequest
+	}
+	book.InstrumentName = name
+	if method == "public/ticker" {
+		return book.Ticker, nil, http.StatusOK
+	}
+
+	if depth, err := strconv.Atoi(fmt.Sprint(params["depth"])); err == nil && depth > 0 {
+		if len(book.Bids) > depth {
+			book.Bids = book.Bids[:depth]
+		}
+		if len(book.Asks) > depth {
+			book.Asks = book.Asks[:depth]
+		}
+	}
+	if book.Bids == nil {
+		book.Bids = [][2]float64{}
+	}
+	if book.Asks == nil {
+		book.Asks = [][2]float64{}
+	}
+	return book, nil, http.StatusOK
+}
+
+func (s *Server) publishIndexPrice(index string, price float64) {
+	s.Publish("deribit_price_index."+index, map[string]interface{}{
+		"index_name": index,
+		"price":      price,
+		"timestamp":  time.Now().UnixMilli(),
+	})
+}
+
+type rpcRequest struct {
+	JSONRPC string                 `json:"jsonrpc"`
+	ID      interface{}            `json:"id"`
+	Method  string                 `json:"method"`
+	Params  map[string]interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      interface{} `json:"id,omitempty"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *RPCError   `json:"error,omitempty"`
+	UsIn    int64       `json:"usIn"`
+	UsOut   int64       `json:"usOut"`
+	UsDiff  int64       `json:"usDiff"`
+	Testnet bool        `json:"testnet"`
+}
+
+func newResponse(id, result interface{}, rpcErr *RPCError, usIn int64) rpcResponse {
+	usOut := time.Now().UnixMicro()
+	return rpcResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result:  result,
+		Error:   rpcErr,
+		UsIn:    usIn,
+		UsOut:   usOut,
+		UsDiff:  usOut - usIn,
+		Testnet: true,
+	}
+}
+
+func writeHTTP(w http.ResponseWriter, status int, response rpcResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// channelsParam 解析订阅参数中的 channels，兼容 JSON 数组和逗号分隔的字符串
+func channelsParam(params map[string]interface{}) []string {
+	var channels []string
+	switch value := params["channels"].(type) {
+	case []interface{}:
+		for _, channel := range value {
+			if name, ok := channel.(string); ok {
+				channels = append(channels, name)
+			}
+		}
+	case string:
+		channels = strings.Split(value, ",")
+	}
+	return channels
+}
@@ -0,0 +1,142 @@
+package fakederibit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsConn 一个 WebSocket 连接及其订阅
+type wsConn struct {
+	conn *websocket.Conn
+
+	mu            sync.Mutex
+	authed        bool
+	subscriptions map[string]bool
+}
+
+// handleWebSocket 处理 WebSocket JSON-RPC：请求与 HTTP 相同的方法，另外支持订阅推送
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &wsConn{conn: conn, subscriptions: make(map[string]bool)}
+	s.mu.Lock()
+	s.wsConns[c] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.wsConns, c)
+		s.mu.Unlock()
+		c.close()
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		usIn := time.Now().UnixMicro()
+		var request rpcRequest
+		if err := json.Unmarshal(message, &request); err != nil {
+			c.write(newResponse(nil, nil, &RPCError{Code: ErrCodeInvalidParams, Message: err.Error()}, usIn))
+			continue
+		}
+		if request.Params == nil {
+			request.Params = map[string]interface{}{}
+		}
+
+		result, rpcErr, _ := s.dispatch(request.Method, request.Params, "", c)
+		c.write(newResponse(request.ID, result, rpcErr, usIn))
+	}
+}
+
+// Publish 向订阅了 channel 的连接推送数据，格式与 Deribit 的 subscription 通知一致
+func (s *Server) Publish(channel string, data interface{}) {
+	s.mu.Lock()
+	conns := make([]*wsConn, 0, len(s.wsConns))
+	for conn := range s.wsConns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+
+	notification := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "subscription",
+		"params": map[string]interface{}{
+			"channel": channel,
+			"data":    data,
+		},
+	}
+	for _, conn := range conns {
+		if conn.subscribed(channel) {
+			conn.write(notification)
+		}
+	}
+}
+
+func (c *wsConn) write(v interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.WriteJSON(v)
+}
+
+func (c *wsConn) close() {
+	_ = c.conn.Close()
+}
+
+func (c *wsConn) authenticated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.authed
+}
+
+func (c *wsConn) setAuthenticated() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.authed = true
+}
+
+// subscribe 订阅频道，user.* 私有频道只能通过 private/subscribe 订阅
+func (c *wsConn) subscribe(channels []string, private bool) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	subscribed := []string{}
+	for _, channel := range channels {
+		if strings.HasPrefix(channel, "user.") && !private {
+			continue
+		}
+		c.subscriptions[channel] = true
+		subscribed = append(subscribed, channel)
+	}
+	return subscribed
+}
+
+func (c *wsConn) unsubscribe(channels []string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, channel := range channels {
+		delete(c.subscriptions, channel)
+	}
+	return channels
+}
+
+func (c *wsConn) subscribed(channel string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subscriptions[channel]
+}
@@ -0,0 +1,132 @@
+package deribit
+
+import (
+	"context"
+	"cs-projects-eth-collar/pkg/tracing"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// 请求结果，用作请求耗时指标的 result 标签
+const (
+	ResultSuccess      = "success"       // 成功
+	ResultAPIError     = "api_error"     // Deribit 返回错误（含错误码）
+	ResultHTTPError    = "http_error"    // 非 200 且响应中没有 Deribit 错误
+	ResultNetworkError = "network_error" // 连接、超时或读取响应失败
+	ResultDecodeError  = "decode_error"  // 响应无法解析
+)
+
+// Observer 接收客户端自身的请求指标，*metrics.Metrics 满足此接口
+type Observer interface {
+	ObserveRequest(method, result string, duration time.Duration)
+	ObserveAPIError(method string, code int)
+	ObserveAuthRefresh(result string)
+}
+
+// Option 客户端可选配置
+type Option func(*Client)
+
+// WithObserver 记录每个请求的耗时和结果、Deribit 错误码以及令牌刷新
+func WithObserver(observer Observer) Option {
+	return func(c *Client) {
+		c.observer = observer
+	}
+}
+
+// WithLogger 以 debug 级别记录每个请求的方法、结果和耗时
+func WithLogger(logger *zap.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// responseMeta JSON-RPC 响应中的错误和 Deribit 服务端时间戳（微秒）
+type responseMeta struct {
+	Error  *APIError `json:"error"`
+	UsIn   int64     `json:"usIn"`   // 服务端收到请求的时间
+	UsOut  int64     `json:"usOut"`  // 服务端发出响应的时间
+	UsDiff int64     `json:"usDiff"` // 服务端处理耗时
+}
+
+// requestCall 一次请求的指标和 span，result 默认为网络错误，拿到响应后更新
+type requestCall struct {
+	client *Client
+	method string // API 方法名，如 private/get_positions
+	start  time.Time
+	span   trace.Span
+	result string
+	status int
+	meta   responseMeta
+}
+
+// startCall 开始记录一次请求，span 挂在 ctx 中的 span 下
+func (c *Client) startCall(ctx context.Context, method string, params map[string]interface{}) *requestCall {
+	method = strings.TrimPrefix(method, "/")
+	attrs := []attribute.KeyValue{attribute.String("rpc.system", "jsonrpc"), attribute.String("rpc.method", method)}
+	for _, key := range []string{"currency", "instrument_name", "index_name"} {
+		if value, ok := params[key]; ok {
+			attrs = append(attrs, attribute.String(key, fmt.Sprint(value)))
+		}
+	}
+	_, span := tracing.Start(ctx, method, attrs...)
+	return &requestCall{
+		client: c,
+		method: method,
+		start:  time.Now(),
+		span:   span,
+		result: ResultNetworkError,
+	}
+}
+
+// parse 解析响应中的 Deribit 错误和服务端时间戳，不记录指标、日志和 span 时跳过
+func (r *requestCall) parse(status int, body []byte) {
+	r.status = status
+	if r.client.observer == nil && r.client.logger == nil && !r.span.IsRecording() {
+		return
+	}
+	_ = json.Unmarshal(body, &r.meta)
+}
+
+// finish 记录请求指标、调试日志并结束 span
+func (r *requestCall) finish(err error) {
+	duration := time.Since(r.start)
+	if observer := r.client.observer; observer != nil {
+		observer.ObserveRequest(r.method, r.result, duration)
+		if r.meta.Error != nil {
+			observer.ObserveAPIError(r.method, r.meta.Error.Code)
+		}
+	}
+
+	if logger := r.client.logger; logger != nil {
+		logger.Debug("Deribit request",
+			zap.String("method", r.method),
+			zap.String("result", r.result),
+			zap.Int("status", r.status),
+			zap.Duration("duration", duration),
+			zap.Int64("server_us", r.meta.UsDiff),
+			zap.Error(err),
+		)
+	}
+
+	r.span.SetAttributes(attribute.String("deribit.result", r.result))
+	if r.status != 0 {
+		r.span.SetAttributes(attribute.Int("http.response.status_code", r.status))
+	}
+	if r.meta.UsIn != 0 {
+		r.span.SetAttributes(
+			attribute.Int64("deribit.us_in", r.meta.UsIn),
+			attribute.Int64("deribit.us_out", r.meta.UsOut),
+			attribute.Int64("deribit.us_diff", r.meta.UsDiff),
+		)
+	}
+	if r.meta.Error != nil {
+		r.span.SetAttributes(attribute.Int("deribit.error_code", r.meta.Error.Code))
+	}
+	tracing.End(r.span, err)
+}
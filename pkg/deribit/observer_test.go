@@ -0,0 +1,79 @@
+package deribit
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingObserver 记录客户端上报的请求指标
+type recordingObserver struct {
+	mu        sync.Mutex
+	requests  []string // method/result
+	apiErrors []string // method/code
+	refreshes []string
+}
+
+func (o *recordingObserver) ObserveRequest(method, result string, duration time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.requests = append(o.requests, method+"/"+result)
+}
+
+func (o *recordingObserver) ObserveAPIError(method string, code int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.apiErrors = append(o.apiErrors, method+"/"+strconv.Itoa(code))
+}
+
+func (o *recordingObserver) ObserveAuthRefresh(result string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.refreshes = append(o.refreshes, result)
+}
+
+func TestObserver(t *testing.T) {
+	srv := fakederibit.NewServer()
+	t.Cleanup(srv.Close)
+	srv.SetState(testState())
+	observer := &recordingObserver{}
+	client, err := NewClient(types.DeribitConfig{APIKey: srv.ClientID, APISecret: srv.ClientSecret, BaseURL: srv.URL()}, WithObserver(observer))
+	require.NoError(t, err)
+
+	_, err = client.GetIndexPrice("eth")
+	require.NoError(t, err)
+	_, err = client.GetAccountSummaries()
+	require.NoError(t, err)
+
+	srv.InjectFault("private/get_account_summaries", fakederibit.Fault{Code: fakederibit.ErrCodeTooManyRequests, Message: "too_many_requests", Times: 1})
+	_, err = client.GetAccountSummaries()
+	require.Error(t, err)
+
+	assert.Equal(t, []string{
+		"public/get_index_price/success",
+		"public/auth/success",
+		"private/get_account_summaries/success",
+		"private/get_account_summaries/api_error",
+	}, observer.requests)
+	assert.Equal(t, []string{"private/get_account_summaries/" + strconv.Itoa(fakederibit.ErrCodeTooManyRequests)}, observer.apiErrors)
+	assert.Equal(t, []string{"success"}, observer.refreshes)
+}
+
+func TestObserverAuthFailure(t *testing.T) {
+	srv := fakederibit.NewServer()
+	t.Cleanup(srv.Close)
+	observer := &recordingObserver{}
+	client, err := NewClient(types.DeribitConfig{APIKey: srv.ClientID, APISecret: "wrong", BaseURL: srv.URL()}, WithObserver(observer))
+	require.NoError(t, err)
+
+	require.Error(t, client.Authenticate())
+	assert.Equal(t, []string{"public/auth/api_error"}, observer.requests)
+	assert.Len(t, observer.apiErrors, 1)
+	assert.Equal(t, []string{"failure"}, observer.refreshes)
+}
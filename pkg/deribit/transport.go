@@ -0,0 +1,90 @@
+package deribit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"cs-projects-eth-collar/internal/types"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	ProductionURL = "https://www.deribit.com/api/v2"
+	TestNetURL    = "https://test.deribit.com/api/v2"
+)
+
+// resolveBaseURL 确定 API 地址：自定义地址优先；为空或为默认生产地址时由 TestNet 决定
+func resolveBaseURL(config types.DeribitConfig) string {
+	if config.BaseURL != "" && config.BaseURL != ProductionURL {
+		return config.BaseURL
+	}
+	if config.TestNet {
+		return TestNetURL
+	}
+	return ProductionURL
+}
+
+// newHTTPClient 根据传输配置创建 HTTP 客户端（代理、TLS 根证书、客户端证书、连接池、超时）
+func newHTTPClient(config types.HTTPConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", config.ProxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	if config.IdleConnTimeoutSeconds > 0 {
+		transport.IdleConnTimeout = time.Duration(config.IdleConnTimeoutSeconds) * time.Second
+	}
+	transport.DialContext = (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+
+	timeout := 30 * time.Second
+	if config.TimeoutSeconds > 0 {
+		timeout = time.Duration(config.TimeoutSeconds) * time.Second
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}, nil
+}
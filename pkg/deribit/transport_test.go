@@ -0,0 +1,63 @@
+package deribit
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveBaseURL(t *testing.T) {
+	cases := []struct {
+		name   string
+		config types.DeribitConfig
+		want   string
+	}{
+		{"default", types.DeribitConfig{}, ProductionURL},
+		{"testnet", types.DeribitConfig{TestNet: true}, TestNetURL},
+		{"testnet with default base url", types.DeribitConfig{TestNet: true, BaseURL: ProductionURL}, TestNetURL},
+		{"custom base url", types.DeribitConfig{TestNet: true, BaseURL: "http://127.0.0.1:8080/api/v2"}, "http://127.0.0.1:8080/api/v2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, resolveBaseURL(tc.config))
+		})
+	}
+}
+
+func TestCustomCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":{"index_price":3000}}`))
+	}))
+	defer srv.Close()
+
+	// 未信任自签名证书时请求失败
+	client, err := NewClient(types.DeribitConfig{BaseURL: srv.URL})
+	require.NoError(t, err)
+	_, err = client.GetIndexPrice("eth")
+	assert.Error(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, certPEM, 0o600))
+
+	client, err = NewClient(types.DeribitConfig{BaseURL: srv.URL, HTTP: types.HTTPConfig{CAFile: caFile, TimeoutSeconds: 5}})
+	require.NoError(t, err)
+	price, err := client.GetIndexPrice("eth")
+	require.NoError(t, err)
+	assert.Equal(t, 3000.0, price)
+}
+
+func TestInvalidTransportConfig(t *testing.T) {
+	_, err := NewClient(types.DeribitConfig{HTTP: types.HTTPConfig{CAFile: "/nonexistent/ca.pem"}})
+	assert.Error(t, err)
+
+	_, err = NewClient(types.DeribitConfig{HTTP: types.HTTPConfig{CertFile: "/nonexistent/cert.pem", KeyFile: "/nonexistent/key.pem"}})
+	assert.Error(t, err)
+}
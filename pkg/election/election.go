@@ -0,0 +1,232 @@
+// Package election 高可用主备模式的 leader 选举。
+//
+// 多个实例定期通过锁后端获取或续约同一把锁，持有锁的实例为 leader，执行监控周期、推送指标和发送通知，
+// 其他实例待命。leader 退出时释放锁，其他实例在下一次重试时接管；leader 失联时锁在租约到期后释放，
+// 因此最迟 lease + retry 内完成接管。
+package election
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/admin"
+	"cs-projects-eth-collar/pkg/notify"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 锁后端
+const (
+	BackendFile       = "file"
+	BackendKubernetes = "kubernetes"
+	BackendEtcd       = "etcd"
+)
+
+// Backend 锁后端
+type Backend interface {
+	// TryAcquire 获取或续约锁，返回当前持有者；持有者为 identity 时本实例为 leader
+	TryAcquire(identity string, lease time.Duration) (holder string, err error)
+	// Release 释放本实例持有的锁，使其他实例立即接管
+	Release(identity string) error
+}
+
+// NewBackend 按配置创建锁后端
+func NewBackend(config types.HAConfig) (Backend, error) {
+	switch config.Backend {
+	case BackendFile:
+		return NewFileLock(config.File.Path), nil
+	case BackendKubernetes:
+		return NewKubernetesLease(config.Kubernetes)
+	case BackendEtcd:
+		return NewEtcdLock(config.Etcd), nil
+	default:
+		return nil, fmt.Errorf("unknown ha backend %q, use file, kubernetes or etcd", config.Backend)
+	}
+}
+
+// Status 选举状态，管理接口返回此结构
+type Status struct {
+	Identity  string    `json:"identity"`
+	Leader    bool      `json:"leader"`
+	Holder    string    `json:"holder"`     // 最近一次看到的锁持有者
+	RenewedAt time.Time `json:"renewed_at"` // 本实例最近一次成功续约的时间
+}
+
+// Elector 定期获取或续约锁，维护本实例是否为 leader
+type Elector struct {
+	backend  Backend
+	identity string
+	lease    time.Duration
+	retry    time.Duration
+	notifier notify.Notifier
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu        sync.RWMutex
+	leader    bool
+	holder    string
+	renewedAt time.Time
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewElector 创建选举，notifier 可为 nil；identity 为空时使用主机名和进程号
+func NewElector(config types.HAConfig, backend Backend, notifier notify.Notifier, logger *zap.Logger) (*Elector, error) {
+	lease := time.Duration(config.LeaseSeconds) * time.Second
+	retry := time.Duration(config.RetrySeconds) * time.Second
+	if retry <= 0 || lease < 2*retry {
+		return nil, fmt.Errorf("ha.lease_seconds (%d) must be at least twice ha.retry_seconds (%d)", config.LeaseSeconds, config.RetrySeconds)
+	}
+
+	identity := config.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hostname for ha identity: %w", err)
+		}
+		identity = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	return &Elector{
+		backend:  backend,
+		identity: identity,
+		lease:    lease,
+		retry:    retry,
+		notifier: notifier,
+		logger:   logger,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Identity 本实例标识
+func (e *Elector) Identity() string {
+	return e.identity
+}
+
+// IsLeader 本实例是否为 leader
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Status 返回选举状态
+func (e *Elector) Status() Status {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return Status{Identity: e.identity, Leader: e.leader, Holder: e.holder, RenewedAt: e.renewedAt}
+}
+
+// Start 立即尝试获取锁，之后每隔 retry 获取或续约，阻塞运行直到 Stop 被调用
+func (e *Elector) Start() {
+	defer close(e.done)
+	e.logger.Info("Starting leader election",
+		zap.String("identity", e.identity),
+		zap.Duration("lease", e.lease),
+		zap.Duration("retry", e.retry),
+	)
+
+	ticker := time.NewTicker(e.retry)
+	defer ticker.Stop()
+	for {
+		e.tick()
+		select {
+		case <-ticker.C:
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// Stop 停止续约并释放本实例持有的锁，在最后一次推送指标之后调用
+func (e *Elector) Stop() {
+	e.stopOnce.Do(func() { close(e.stop) })
+	<-e.done
+
+	if !e.IsLeader() {
+		return
+	}
+	e.setLeader(false, "")
+	if err := e.backend.Release(e.identity); err != nil {
+		e.logger.Warn("Failed to release leader lock", zap.Error(err))
+		return
+	}
+	e.logger.Info("Released leader lock", zap.String("identity", e.identity))
+}
+
+// tick 获取或续约一次锁
+func (e *Elector) tick() {
+	start := e.now()
+	holder, err := e.backend.TryAcquire(e.identity, e.lease)
+	if err != nil {
+		e.logger.Warn("Failed to acquire or renew leader lock", zap.Error(err))
+		// 续约失败时在租约到期前让出，避免与接管的实例同时工作
+		e.mu.RLock()
+		expiring := e.leader && !start.Add(e.retry).Before(e.renewedAt.Add(e.lease))
+		e.mu.RUnlock()
+		if expiring {
+			e.setLeader(false, "")
+		}
+		return
+	}
+
+	if holder == e.identity {
+		e.mu.Lock()
+		e.renewedAt = start // 以请求开始的时间计算租约，偏保守
+		e.mu.Unlock()
+	}
+	e.setLeader(holder == e.identity, holder)
+}
+
+// setLeader 更新状态，成为 leader 时发送通知
+func (e *Elector) setLeader(leader bool, holder string) {
+	e.mu.Lock()
+	was, previous := e.leader, e.holder
+	e.leader = leader
+	if holder != "" {
+		e.holder = holder
+	}
+	e.mu.Unlock()
+
+	switch {
+	case leader && !was:
+		e.logger.Info("Became leader", zap.String("identity", e.identity), zap.String("previous_holder", previous))
+		if e.notifier == nil {
+			return
+		}
+		n := notify.Notification{
+			Source:   "ha",
+			Severity: notify.SeverityInfo,
+			Title:    fmt.Sprintf("Monitor instance %s is now leader", e.identity),
+			Message:  "This instance now runs monitoring cycles, pushes metrics and sends notifications",
+			Fields: map[string]interface{}{
+				"identity":        e.identity,
+				"previous_holder": previous,
+			},
+		}
+		if previous != "" && previous != e.identity {
+			n.Message = fmt.Sprintf("Took over from %s; this instance now runs monitoring cycles, pushes metrics and sends notifications", previous)
+		}
+		if err := e.notifier.Notify(n); err != nil {
+			e.logger.Error("Failed to send leader notification", zap.Error(err))
+		}
+	case !leader && was:
+		e.logger.Warn("Lost leadership, standing by", zap.String("identity", e.identity), zap.String("holder", holder))
+	}
+}
+
+// RegisterHandlers 注册高可用相关的管理接口
+//
+//	GET /ha/leader 查看本实例是否为 leader 及当前持有者
+func (e *Elector) RegisterHandlers(server *admin.Server) {
+	server.Handle("GET /ha/leader", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, e.Status())
+	})
+}
@@ -0,0 +1,164 @@
+package election
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/election/fakelock"
+	"cs-projects-eth-collar/pkg/notify/notifytest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testConfig = types.HAConfig{LeaseSeconds: 15, RetrySeconds: 5}
+
+// newTestElector 创建时钟可控的选举
+func newTestElector(t *testing.T, identity string, backend Backend, now *time.Time) (*Elector, *notifytest.Notifier) {
+	config := testConfig
+	config.Identity = identity
+	notifier := &notifytest.Notifier{}
+	elector, err := NewElector(config, backend, notifier, zap.NewNop())
+	require.NoError(t, err)
+	elector.now = func() time.Time { return *now }
+	return elector, notifier
+}
+
+func TestFileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.lock")
+	a, b := NewFileLock(path), NewFileLock(path)
+
+	holder, err := a.TryAcquire("a", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "a", holder)
+	holder, err = b.TryAcquire("b", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "a", holder)
+	holder, err = a.TryAcquire("a", time.Minute) // 续约
+	require.NoError(t, err)
+	assert.Equal(t, "a", holder)
+
+	require.NoError(t, a.Release("a"))
+	holder, err = b.TryAcquire("b", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "b", holder)
+}
+
+func TestEtcdTakeoverAfterLeaseExpires(t *testing.T) {
+	srv := fakelock.NewServer()
+	t.Cleanup(srv.Close)
+	config := types.HAEtcdConfig{Endpoint: srv.URL(), Key: "/monitor/leader"}
+	now := time.Date(2024, 12, 27, 8, 0, 0, 0, time.UTC)
+	a, aNotifier := newTestElector(t, "a", NewEtcdLock(config), &now)
+	b, bNotifier := newTestElector(t, "b", NewEtcdLock(config), &now)
+
+	a.tick()
+	b.tick()
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+	assert.Equal(t, "a", b.Status().Holder)
+	require.Len(t, aNotifier.Notifications, 1)
+	assert.Equal(t, "Monitor instance a is now leader", aNotifier.Notifications[0].Title)
+
+	// a 停止续约（主机宕机），租约到期后 b 在下一次重试时接管
+	srv.Advance(10 * time.Second)
+	b.tick()
+	assert.False(t, b.IsLeader())
+	srv.Advance(5 * time.Second)
+	b.tick()
+	assert.True(t, b.IsLeader())
+	require.Len(t, bNotifier.Notifications, 1)
+	assert.Contains(t, bNotifier.Notifications[0].Message, "Took over from a")
+
+	// a 恢复后发现锁已被 b 持有
+	a.tick()
+	assert.False(t, a.IsLeader())
+	assert.Equal(t, "b", a.Status().Holder)
+}
+
+func TestKubernetesLeaseTakeover(t *testing.T) {
+	srv := fakelock.NewServer()
+	t.Cleanup(srv.Close)
+	config := types.HAKubernetesConfig{APIServer: srv.URL(), Namespace: "monitoring", Name: "deribit-monitor"}
+	now := time.Date(2024, 12, 27, 8, 0, 0, 0, time.UTC)
+	newLease := func() *KubernetesLease {
+		lease, err := NewKubernetesLease(config)
+		require.NoError(t, err)
+		lease.now = func() time.Time { return now }
+		return lease
+	}
+	a, _ := newTestElector(t, "a", newLease(), &now)
+	b, _ := newTestElector(t, "b", newLease(), &now)
+
+	a.tick()
+	b.tick()
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+	assert.Equal(t, "a", srv.Holder("monitoring/deribit-monitor"))
+
+	// a 持续续约时 b 不会接管
+	for i := 0; i < 4; i++ {
+		now = now.Add(5 * time.Second)
+		a.tick()
+		b.tick()
+	}
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+
+	// a 停止续约，b 观察到 Lease 15 秒未变化后接管
+	now = now.Add(10 * time.Second)
+	b.tick()
+	assert.False(t, b.IsLeader())
+	now = now.Add(5 * time.Second)
+	b.tick()
+	assert.True(t, b.IsLeader())
+	assert.Equal(t, "b", srv.Holder("monitoring/deribit-monitor"))
+
+	a.tick()
+	assert.False(t, a.IsLeader())
+}
+
+func TestElectorStepsDownWhenBackendUnavailable(t *testing.T) {
+	srv := fakelock.NewServer()
+	t.Cleanup(srv.Close)
+	now := time.Date(2024, 12, 27, 8, 0, 0, 0, time.UTC)
+	elector, _ := newTestElector(t, "a", NewEtcdLock(types.HAEtcdConfig{Endpoint: srv.URL(), Key: "leader"}), &now)
+	elector.tick()
+	require.True(t, elector.IsLeader())
+
+	// 续约失败时保持 leader，直到下一次重试会超过租约
+	srv.SetDown(true)
+	now = now.Add(5 * time.Second)
+	elector.tick()
+	assert.True(t, elector.IsLeader())
+	now = now.Add(5 * time.Second)
+	elector.tick()
+	assert.False(t, elector.IsLeader())
+}
+
+func TestStopReleasesLock(t *testing.T) {
+	srv := fakelock.NewServer()
+	t.Cleanup(srv.Close)
+	config := testConfig
+	config.Identity = "a"
+	elector, err := NewElector(config, NewEtcdLock(types.HAEtcdConfig{Endpoint: srv.URL(), Key: "/leader"}), nil, zap.NewNop())
+	require.NoError(t, err)
+
+	go elector.Start()
+	require.Eventually(t, elector.IsLeader, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "a", srv.Holder("/leader"))
+
+	elector.Stop()
+	assert.False(t, elector.IsLeader())
+	assert.Empty(t, srv.Holder("/leader"))
+}
+
+func TestNewElectorValidation(t *testing.T) {
+	_, err := NewElector(types.HAConfig{LeaseSeconds: 5, RetrySeconds: 5}, NewFileLock("unused"), nil, zap.NewNop())
+	assert.ErrorContains(t, err, "must be at least twice")
+
+	_, err = NewBackend(types.HAConfig{Backend: "zookeeper"})
+	assert.ErrorContains(t, err, `unknown ha backend "zookeeper"`)
+}
@@ -0,0 +1,202 @@
+package election
+
+import (
+	"bytes"
+	"cs-projects-eth-collar/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EtcdLock etcd v3 JSON 网关（/v3/...）上的锁：键的值为持有者，键绑定本实例的租约
+//
+// 获取：申请租约后用事务在键不存在（create_revision == 0）时写入，否则读取当前持有者；
+// 续约：对租约发送 keepalive，租约过期后重新申请；释放：撤销租约，绑定的键随之删除。
+type EtcdLock struct {
+	endpoint   string
+	key        []byte
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	leaseID int64 // 本实例当前的租约，0 表示没有
+}
+
+// etcdKeyValue etcd 键值，key 和 value 在 JSON 中为 base64
+type etcdKeyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+	Lease int64  `json:"lease,string"`
+}
+
+// etcdTxnResponse 事务结果，失败分支为读取当前键
+type etcdTxnResponse struct {
+	Succeeded bool `json:"succeeded"`
+	Responses []struct {
+		ResponseRange struct {
+			Kvs []etcdKeyValue `json:"kvs"`
+		} `json:"response_range"`
+	} `json:"responses"`
+}
+
+// etcdLeaseResponse 租约申请和 keepalive 的结果，TTL 为 0 表示租约已过期
+type etcdLeaseResponse struct {
+	ID  int64 `json:"ID,string"`
+	TTL int64 `json:"TTL,string"`
+}
+
+// NewEtcdLock 创建 etcd 锁
+func NewEtcdLock(config types.HAEtcdConfig) *EtcdLock {
+	timeout := config.TimeoutSeconds
+	if timeout <= 0 {
+		timeout = 5
+	}
+	key := config.Key
+	if !strings.HasPrefix(key, "/") {
+		key = "/" + key
+	}
+	return &EtcdLock{
+		endpoint:   strings.TrimRight(config.Endpoint, "/"),
+		key:        []byte(key),
+		httpClient: &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}
+}
+
+// TryAcquire 续约本实例持有的锁，锁不存在时创建，被其他实例持有时返回持有者
+func (l *EtcdLock) TryAcquire(identity string, lease time.Duration) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.keepAlive(int64(math.Ceil(lease.Seconds()))); err != nil {
+		return "", err
+	}
+
+	// 键不存在时写入并绑定租约，否则读取当前持有者
+	txn := map[string]interface{}{
+		"compare": []map[string]interface{}{
+			{"key": l.key, "target": "CREATE", "result": "EQUAL", "create_revision": "0"},
+		},
+		"success": []map[string]interface{}{
+			{"request_put": map[string]interface{}{"key": l.key, "value": []byte(identity), "lease": fmt.Sprint(l.leaseID)}},
+		},
+		"failure": []map[string]interface{}{
+			{"request_range": map[string]interface{}{"key": l.key}},
+		},
+	}
+	var resp etcdTxnResponse
+	if err := l.post("/v3/kv/txn", txn, &resp); err != nil {
+		return "", err
+	}
+	if resp.Succeeded {
+		return identity, nil
+	}
+
+	if len(resp.Responses) == 0 || len(resp.Responses[0].ResponseRange.Kvs) == 0 {
+		return "", nil // 刚好过期，下次重试时获取
+	}
+	kv := resp.Responses[0].ResponseRange.Kvs[0]
+	if string(kv.Value) == identity && kv.Lease != l.leaseID {
+		return "", nil // 同名实例重启前留下的键，等旧租约过期后获取
+	}
+	return string(kv.Value), nil
+}
+
+// keepAlive 续约本实例的租约，没有租约或租约已过期时申请新的租约
+func (l *EtcdLock) keepAlive(ttl int64) error {
+	if l.leaseID != 0 {
+		var resp struct {
+			Result etcdLeaseResponse `json:"result"`
+		}
+		if err := l.post("/v3/lease/keepalive", map[string]interface{}{"ID": fmt.Sprint(l.leaseID)}, &resp); err != nil {
+			return err
+		}
+		if resp.Result.TTL > 0 {
+			return nil
+		}
+		l.leaseID = 0
+	}
+
+	var resp etcdLeaseResponse
+	if err := l.post("/v3/lease/grant", map[string]interface{}{"TTL": fmt.Sprint(ttl)}, &resp); err != nil {
+		return err
+	}
+	if resp.ID == 0 {
+		return fmt.Errorf("etcd lease grant returned no lease ID")
+	}
+	l.leaseID = resp.ID
+	return nil
+}
+
+// Release 撤销本实例的租约，绑定的键随之删除；租约已过期时忽略
+func (l *EtcdLock) Release(identity string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.leaseID == 0 {
+		return nil
+	}
+
+	err := l.post("/v3/lease/revoke", map[string]interface{}{"ID": fmt.Sprint(l.leaseID)}, nil)
+	var statusErr *etcdStatusError
+	if errors.As(err, &statusErr) && statusErr.status == http.StatusNotFound {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	l.leaseID = 0
+	return nil
+}
+
+// etcdStatusError 网关返回的非 200 响应
+type etcdStatusError struct {
+	path    string
+	status  int
+	message string
+}
+
+func (e *etcdStatusError) Error() string {
+	return fmt.Sprintf("etcd %s failed with HTTP %d: %s", e.path, e.status, e.message)
+}
+
+// post 以 JSON 发送请求；keepalive 是流式接口，只读取第一条结果
+func (l *EtcdLock) post(path string, body interface{}, result interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal etcd request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, l.endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create etcd request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("etcd request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var status struct {
+			Message string `json:"message"`
+		}
+		message := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &status) == nil && status.Message != "" {
+			message = status.Message
+		}
+		return &etcdStatusError{path: path, status: resp.StatusCode, message: message}
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to unmarshal etcd %s response: %w", path, err)
+	}
+	return nil
+}
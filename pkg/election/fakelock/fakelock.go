@@ -0,0 +1,327 @@
+// Package fakelock 测试用的本地锁服务，实现 Kubernetes Lease 和 etcd v3 JSON 网关中选举用到的部分
+package fakelock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Server 本地锁服务
+type Server struct {
+	server *httptest.Server
+
+	mu          sync.Mutex
+	offset      time.Duration                     // Advance 推进的时间，用于 etcd 租约过期
+	leases      map[string]map[string]interface{} // "namespace/name" -> Lease
+	version     int
+	keys        map[string]etcdKey
+	etcdLeases  map[int64]etcdLease
+	nextLeaseID int64
+	down        bool
+}
+
+type etcdKey struct {
+	value string
+	lease int64
+}
+
+type etcdLease struct {
+	ttl     int64
+	expires time.Time
+}
+
+// NewServer 启动本地锁服务
+func NewServer() *Server {
+	s := &Server{
+		leases:     make(map[string]map[string]interface{}),
+		keys:       make(map[string]etcdKey),
+		etcdLeases: make(map[int64]etcdLease),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/coordination.k8s.io/v1/namespaces/{namespace}/leases", s.handleLeases)
+	mux.HandleFunc("/apis/coordination.k8s.io/v1/namespaces/{namespace}/leases/{name}", s.handleLease)
+	mux.HandleFunc("POST /v3/lease/grant", s.handleLeaseGrant)
+	mux.HandleFunc("POST /v3/lease/keepalive", s.handleLeaseKeepAlive)
+	mux.HandleFunc("POST /v3/lease/revoke", s.handleLeaseRevoke)
+	mux.HandleFunc("POST /v3/kv/txn", s.handleTxn)
+	s.server = httptest.NewServer(s.unavailable(mux))
+	return s
+}
+
+// URL 服务地址
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close 关闭服务
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Advance 推进服务端时间，etcd 租约按推进后的时间过期
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+}
+
+// SetDown 模拟服务不可用，所有请求返回 503
+func (s *Server) SetDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+// Holder 返回 etcd 键或 Lease（"namespace/name"）的当前持有者
+func (s *Server) Holder(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLeases()
+	if k, ok := s.keys[key]; ok {
+		return k.value
+	}
+	if lease, ok := s.leases[key]; ok {
+		spec, _ := lease["spec"].(map[string]interface{})
+		holder, _ := spec["holderIdentity"].(string)
+		return holder
+	}
+	return ""
+}
+
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+func (s *Server) unavailable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		down := s.down
+		s.mu.Unlock()
+		if down {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleLeases 创建 Lease，已存在时返回 409
+func (s *Server) handleLeases(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var lease map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&lease); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	metadata, _ := lease["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	key := r.PathValue("namespace") + "/" + name
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.leases[key]; ok {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"kind": "Status", "reason": "AlreadyExists"})
+		return
+	}
+	s.store(key, lease)
+	writeJSON(w, http.StatusCreated, lease)
+}
+
+// handleLease 读取和更新 Lease，更新时 resourceVersion 不一致返回 409
+func (s *Server) handleLease(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("namespace") + "/" + r.PathValue("name")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.leases[key]
+	switch r.Method {
+	case http.MethodGet:
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"kind": "Status", "reason": "NotFound"})
+			return
+		}
+		writeJSON(w, http.StatusOK, current)
+	case http.MethodPut:
+		var lease map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&lease); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"kind": "Status", "reason": "NotFound"})
+			return
+		}
+		if resourceVersion(lease) != resourceVersion(current) {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"kind": "Status", "reason": "Conflict"})
+			return
+		}
+		s.store(key, lease)
+		writeJSON(w, http.StatusOK, lease)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// store 保存 Lease 并分配新的 resourceVersion
+func (s *Server) store(key string, lease map[string]interface{}) {
+	s.version++
+	metadata, _ := lease["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+		lease["metadata"] = metadata
+	}
+	metadata["resourceVersion"] = strconv.Itoa(s.version)
+	s.leases[key] = lease
+}
+
+func resourceVersion(lease map[string]interface{}) string {
+	metadata, _ := lease["metadata"].(map[string]interface{})
+	version, _ := metadata["resourceVersion"].(string)
+	return version
+}
+
+// expireLeases 删除已过期的 etcd 租约和绑定的键
+func (s *Server) expireLeases() {
+	for id, lease := range s.etcdLeases {
+		if !s.now().Before(lease.expires) {
+			s.revokeLease(id)
+		}
+	}
+}
+
+func (s *Server) revokeLease(id int64) {
+	delete(s.etcdLeases, id)
+	for key, k := range s.keys {
+		if k.lease == id {
+			delete(s.keys, key)
+		}
+	}
+}
+
+// handleLeaseGrant 申请租约，ID 和 TTL 与 etcd 网关一样以字符串返回
+func (s *Server) handleLeaseGrant(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TTL int64 `json:"TTL,string"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextLeaseID++
+	id := s.nextLeaseID
+	s.etcdLeases[id] = etcdLease{ttl: req.TTL, expires: s.now().Add(time.Duration(req.TTL) * time.Second)}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ID": strconv.FormatInt(id, 10), "TTL": strconv.FormatInt(req.TTL, 10)})
+}
+
+// handleLeaseKeepAlive 续约，租约已过期时结果中没有 TTL（与 etcd 一样视为 0）
+func (s *Server) handleLeaseKeepAlive(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"ID,string"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLeases()
+	result := map[string]interface{}{"ID": strconv.FormatInt(req.ID, 10)}
+	if lease, ok := s.etcdLeases[req.ID]; ok {
+		lease.expires = s.now().Add(time.Duration(lease.ttl) * time.Second)
+		s.etcdLeases[req.ID] = lease
+		result["TTL"] = strconv.FormatInt(lease.ttl, 10)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": result})
+}
+
+// handleLeaseRevoke 撤销租约并删除绑定的键，租约不存在时返回 404
+func (s *Server) handleLeaseRevoke(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"ID,string"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLeases()
+	if _, ok := s.etcdLeases[req.ID]; !ok {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"code": 5, "message": "etcdserver: requested lease not found"})
+		return
+	}
+	s.revokeLease(req.ID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// handleTxn 只支持选举用到的事务：比较键的 create_revision 是否为 0，成功时写入，失败时读取
+func (s *Server) handleTxn(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Compare []struct {
+			Key            []byte `json:"key"`
+			Target         string `json:"target"`
+			CreateRevision int64  `json:"create_revision,string"`
+		} `json:"compare"`
+		Success []struct {
+			RequestPut struct {
+				Key   []byte `json:"key"`
+				Value []byte `json:"value"`
+				Lease int64  `json:"lease,string"`
+			} `json:"request_put"`
+		} `json:"success"`
+		Failure []struct {
+			RequestRange struct {
+				Key []byte `json:"key"`
+			} `json:"request_range"`
+		} `json:"failure"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Compare) != 1 || req.Compare[0].Target != "CREATE" || req.Compare[0].CreateRevision != 0 || len(req.Success) != 1 || len(req.Failure) != 1 {
+		http.Error(w, "unsupported txn", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLeases()
+	key := string(req.Compare[0].Key)
+	if current, exists := s.keys[key]; exists {
+		kv := map[string]interface{}{"key": []byte(key), "value": []byte(current.value), "lease": strconv.FormatInt(current.lease, 10)}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"responses": []map[string]interface{}{{"response_range": map[string]interface{}{"kvs": []interface{}{kv}, "count": "1"}}},
+		})
+		return
+	}
+
+	put := req.Success[0].RequestPut
+	if _, ok := s.etcdLeases[put.Lease]; !ok {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"code": 5, "message": "etcdserver: requested lease not found"})
+		return
+	}
+	s.keys[string(put.Key)] = etcdKey{value: string(put.Value), lease: put.Lease}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"succeeded": true,
+		"responses": []map[string]interface{}{{"response_put": map[string]interface{}{}}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,75 @@
+//go:build unix
+
+package election
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileLock 本机文件锁（flock），同一主机上的多个实例使用
+//
+// 锁由内核维护，持有锁的进程退出后立即释放，不依赖租约；文件内容为当前持有者的标识。
+type FileLock struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File // 持有锁时打开的文件
+}
+
+// NewFileLock 创建文件锁
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path}
+}
+
+// TryAcquire 非阻塞地获取锁，已持有时直接返回；lease 不使用
+func (l *FileLock) TryAcquire(identity string, lease time.Duration) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		return identity, nil
+	}
+
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open lock file %s: %w", l.path, err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			holder, _ := io.ReadAll(file)
+			return strings.TrimSpace(string(holder)), nil
+		}
+		return "", fmt.Errorf("failed to lock %s: %w", l.path, err)
+	}
+
+	err = file.Truncate(0)
+	if err == nil {
+		_, err = file.WriteAt([]byte(identity+"\n"), 0)
+	}
+	if err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write lock holder to %s: %w", l.path, err)
+	}
+	l.file = file
+	return identity, nil
+}
+
+// Release 清空持有者并释放锁
+func (l *FileLock) Release(identity string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	_ = l.file.Truncate(0)
+	err := l.file.Close() // 关闭文件即释放 flock
+	l.file = nil
+	return err
+}
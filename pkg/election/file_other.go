@@ -0,0 +1,24 @@
+//go:build !unix
+
+package election
+
+import (
+	"errors"
+	"time"
+)
+
+// FileLock 本机文件锁，仅支持类 Unix 系统
+type FileLock struct{}
+
+// NewFileLock 创建文件锁
+func NewFileLock(path string) *FileLock {
+	return &FileLock{}
+}
+
+func (l *FileLock) TryAcquire(identity string, lease time.Duration) (string, error) {
+	return "", errors.New("file lock backend is only supported on unix")
+}
+
+func (l *FileLock) Release(identity string) error {
+	return nil
+}
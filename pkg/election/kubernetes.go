@@ -0,0 +1,254 @@
+package election
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"cs-projects-eth-collar/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 集群内 service account 文件
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	microTimeLayout   = "2006-01-02T15:04:05.000000Z07:00" // Lease 的 MicroTime 格式
+)
+
+// Lease coordination.k8s.io/v1 Lease 中用到的字段
+type Lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   LeaseMetadata `json:"metadata"`
+	Spec       LeaseSpec     `json:"spec"`
+}
+
+// LeaseMetadata Lease 元数据，更新时带上 resourceVersion 实现比较后写入
+type LeaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// LeaseSpec Lease 内容
+type LeaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+// KubernetesLease Kubernetes Lease 上的锁
+//
+// 与 client-go 的 leaderelection 相同，以本地观察到 Lease 最后一次变化的时间判断过期，不依赖实例之间的时钟同步；
+// 写入带 resourceVersion，多个实例同时接管时只有一个成功。
+type KubernetesLease struct {
+	url        string // Lease 所在集合的地址
+	name       string
+	namespace  string
+	tokenFile  string
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu         sync.Mutex
+	observed   string    // 最近一次看到的 resourceVersion
+	observedAt time.Time // 看到该版本的本地时间
+}
+
+// NewKubernetesLease 创建 Lease 锁，未配置的字段使用集群内的 service account
+func NewKubernetesLease(config types.HAKubernetesConfig) (*KubernetesLease, error) {
+	apiServer := config.APIServer
+	if apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("ha.kubernetes.api_server is empty and not running in a cluster")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+	}
+
+	namespace := config.Namespace
+	if namespace == "" {
+		data, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("ha.kubernetes.namespace is empty and service account namespace unavailable: %w", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	tokenFile := config.TokenFile
+	if tokenFile == "" && config.APIServer == "" {
+		tokenFile = serviceAccountDir + "/token"
+	}
+	caFile := config.CAFile
+	if caFile == "" && config.APIServer == "" {
+		caFile = serviceAccountDir + "/ca.crt"
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kubernetes CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in Kubernetes CA file %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: pool}
+	}
+
+	return &KubernetesLease{
+		url:        fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", strings.TrimRight(apiServer, "/"), namespace),
+		name:       config.Name,
+		namespace:  namespace,
+		tokenFile:  tokenFile,
+		httpClient: &http.Client{Transport: transport, Timeout: 10 * time.Second},
+		now:        time.Now,
+	}, nil
+}
+
+// TryAcquire 读取 Lease：不存在时创建；本实例持有或已过期时更新；否则返回持有者
+func (k *KubernetesLease) TryAcquire(identity string, lease time.Duration) (string, error) {
+	current, err := k.get()
+	if err != nil {
+		return "", err
+	}
+
+	now := k.now()
+	renewTime := now.UTC().Format(microTimeLayout)
+	duration := int(math.Ceil(lease.Seconds()))
+	if current == nil {
+		created := Lease{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   LeaseMetadata{Name: k.name, Namespace: k.namespace},
+			Spec:       LeaseSpec{HolderIdentity: identity, LeaseDurationSeconds: duration, AcquireTime: renewTime, RenewTime: renewTime},
+		}
+		return k.write(http.MethodPost, k.url, created)
+	}
+
+	k.mu.Lock()
+	if current.Metadata.ResourceVersion != k.observed {
+		k.observed, k.observedAt = current.Metadata.ResourceVersion, now
+	}
+	leaseDuration := time.Duration(current.Spec.LeaseDurationSeconds) * time.Second
+	expired := current.Spec.HolderIdentity == "" || !now.Before(k.observedAt.Add(leaseDuration))
+	k.mu.Unlock()
+
+	holder := current.Spec.HolderIdentity
+	if holder != identity && !expired {
+		return holder, nil
+	}
+
+	updated := *current
+	updated.Spec.HolderIdentity = identity
+	updated.Spec.LeaseDurationSeconds = duration
+	updated.Spec.RenewTime = renewTime
+	if holder != identity {
+		updated.Spec.AcquireTime = renewTime
+		updated.Spec.LeaseTransitions++
+	}
+	return k.write(http.MethodPut, k.url+"/"+k.name, updated)
+}
+
+// Release 清空持有者，使其他实例立即接管；已被其他实例持有时忽略
+func (k *KubernetesLease) Release(identity string) error {
+	current, err := k.get()
+	if err != nil || current == nil || current.Spec.HolderIdentity != identity {
+		return err
+	}
+	released := *current
+	released.Spec.HolderIdentity = ""
+	_, err = k.write(http.MethodPut, k.url+"/"+k.name, released)
+	return err
+}
+
+// get 读取 Lease，不存在时返回 nil
+func (k *KubernetesLease) get() (*Lease, error) {
+	status, body, err := k.do(http.MethodGet, k.url+"/"+k.name, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to get lease %s/%s: HTTP %d: %s", k.namespace, k.name, status, body)
+	}
+	var lease Lease
+	if err := json.Unmarshal(body, &lease); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal lease: %w", err)
+	}
+	return &lease, nil
+}
+
+// write 创建或更新 Lease，成功时返回新的持有者；冲突说明其他实例先写入，持有者未知，返回空字符串
+func (k *KubernetesLease) write(method, target string, lease Lease) (string, error) {
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal lease: %w", err)
+	}
+	status, body, err := k.do(method, target, data)
+	if err != nil {
+		return "", err
+	}
+	switch status {
+	case http.StatusOK, http.StatusCreated:
+		var written Lease
+		if err := json.Unmarshal(body, &written); err != nil {
+			return "", fmt.Errorf("failed to unmarshal lease: %w", err)
+		}
+		k.mu.Lock()
+		k.observed, k.observedAt = written.Metadata.ResourceVersion, k.now()
+		k.mu.Unlock()
+		return written.Spec.HolderIdentity, nil
+	case http.StatusConflict:
+		return "", nil
+	default:
+		return "", fmt.Errorf("failed to write lease %s/%s: HTTP %d: %s", k.namespace, k.name, status, body)
+	}
+}
+
+func (k *KubernetesLease) do(method, target string, data []byte) (int, []byte, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create Kubernetes request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// 令牌会轮换，每次请求重新读取
+	if k.tokenFile != "" {
+		token, err := os.ReadFile(k.tokenFile)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read Kubernetes token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("kubernetes request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read Kubernetes response: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
@@ -0,0 +1,417 @@
+// Package expiry 跟踪持仓合约的到期时间。
+//
+// Deribit 期权和期货在到期日 08:00 UTC 交割，卖出的看涨期权被行权或交割产生的大额盈亏会直接影响保证金。
+// 本模块按配置在到期前发出提醒（默认 7 天、1 天、1 小时），到期后通过 get_settlement_history_by_currency
+// 查询交割记录，确认交割盈亏和当前保证金状态。
+package expiry
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/instruments"
+	"cs-projects-eth-collar/pkg/notify"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// settlementHistoryCount 每次查询的交割记录条数，足以覆盖同一到期日的所有合约
+const settlementHistoryCount = 100
+
+// Reader 读取仓位和结算记录，*deribit.Client 满足此接口
+type Reader interface {
+	GetPositions(currency string, kind ...string) ([]types.Position, error)
+	GetSettlementHistoryByCurrency(currency, settlementType string, count int) (*types.SettlementHistory, error)
+}
+
+// Catalog 查询合约的到期时间，*instruments.Catalog 满足此接口
+type Catalog interface {
+	Get(name string) (types.Instrument, bool, error)
+}
+
+// Sink 接收到期指标，*metrics.Metrics 满足此接口
+type Sink interface {
+	UpdateExpiryMetrics(account, currency string, secondsToExpiry, positions map[string]float64)
+}
+
+// Expiry 同一到期时间的持仓
+type Expiry struct {
+	Time        time.Time `json:"time"`
+	Instruments []string  `json:"instruments"`
+}
+
+// Label 到期日标签
+func (e Expiry) Label() string {
+	return Label(e.Time)
+}
+
+// Label 到期日标签，如 27dec24
+func Label(t time.Time) string {
+	return strings.ToLower(t.UTC().Format("2Jan06"))
+}
+
+// pendingSettlement 已到期、等待确认交割的持仓
+type pendingSettlement struct {
+	expiry      time.Time
+	instruments []string
+}
+
+// Tracker 每个监控周期检查持仓的到期时间，发送提醒并确认交割
+type Tracker struct {
+	currency       string
+	reminders      []time.Duration // 从大到小排序
+	grace          time.Duration
+	confirmTimeout time.Duration
+	reader         Reader
+	catalog        Catalog
+	notifier       notify.Notifier
+	metrics        Sink
+	logger         *zap.Logger
+	now            func() time.Time
+
+	held     map[time.Time][]string // 上一个周期持有的未到期合约
+	reminded map[time.Time]int      // 每个到期时间已发送的最近一次提醒（reminders 的下标）
+	pending  []pendingSettlement
+}
+
+// NewTracker 创建到期跟踪，metrics 可为 nil
+func NewTracker(config types.ExpiryConfig, reader Reader, catalog Catalog, notifier notify.Notifier, metrics Sink, logger *zap.Logger) (*Tracker, error) {
+	var reminders []time.Duration
+	for _, value := range config.Reminders {
+		reminder, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry reminder %q: %w", value, err)
+		}
+		if reminder <= 0 {
+			return nil, fmt.Errorf("expiry reminder %q must be positive", value)
+		}
+		reminders = append(reminders, reminder)
+	}
+	sort.Slice(reminders, func(i, j int) bool { return reminders[i] > reminders[j] })
+
+	return &Tracker{
+		currency:       strings.ToUpper(config.Currency),
+		reminders:      reminders,
+		grace:          time.Duration(config.SettlementGraceSeconds) * time.Second,
+		confirmTimeout: time.Duration(config.ConfirmTimeoutSeconds) * time.Second,
+		reader:         reader,
+		catalog:        catalog,
+		notifier:       notifier,
+		metrics:        metrics,
+		logger:         logger,
+		now:            time.Now,
+		held:           make(map[time.Time][]string),
+		reminded:       make(map[time.Time]int),
+	}, nil
+}
+
+// Evaluate 读取持仓的到期时间，按需发送到期提醒，并确认已到期持仓的交割；返回未到期的持仓，按到期时间排序
+func (t *Tracker) Evaluate(account string, summaries *types.AccountSummaries) ([]Expiry, error) {
+	now := t.now()
+	expiries, err := t.upcoming()
+	if err != nil {
+		return nil, err
+	}
+
+	// 上个周期持有、现在已到期的合约进入待确认列表
+	current := make(map[time.Time][]string)
+	for expiry, names := range t.held {
+		if !now.Before(expiry) {
+			t.pending = append(t.pending, pendingSettlement{expiry: expiry, instruments: names})
+			t.logger.Info("Positions expired, waiting for settlement",
+				zap.Time("expiry", expiry),
+				zap.Strings("instruments", names),
+			)
+		}
+	}
+
+	var open []Expiry
+	for _, e := range expiries {
+		if !now.Before(e.Time) {
+			continue // 已到期、交割中的合约
+		}
+		current[e.Time] = e.Instruments
+		open = append(open, e)
+		t.remind(account, e, now)
+	}
+	t.held = current
+	for expiry := range t.reminded {
+		if _, ok := current[expiry]; !ok {
+			delete(t.reminded, expiry)
+		}
+	}
+
+	t.confirm(account, summaries, now)
+
+	if t.metrics != nil {
+		seconds := make(map[string]float64, len(open))
+		positions := make(map[string]float64, len(open))
+		for _, e := range open {
+			seconds[e.Label()] = e.Time.Sub(now).Seconds()
+			positions[e.Label()] = float64(len(e.Instruments))
+		}
+		t.metrics.UpdateExpiryMetrics(account, t.currency, seconds, positions)
+	}
+	return open, nil
+}
+
+// upcoming 按到期时间汇总非零仓位，永续合约不计入
+func (t *Tracker) upcoming() ([]Expiry, error) {
+	positions, err := t.reader.GetPositions(t.currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	byExpiry := make(map[time.Time][]string)
+	for _, position := range positions {
+		if position.Size == 0 || (position.Kind != "option" && position.Kind != "future") {
+			continue
+		}
+		instrument, ok, err := t.catalog.Get(position.InstrumentName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", position.InstrumentName, err)
+		}
+		if !ok {
+			t.logger.Warn("Position not found in instrument catalog", zap.String("instrument", position.InstrumentName))
+			continue
+		}
+		expiry := instruments.Expiry(instrument)
+		if expiry.IsZero() {
+			continue
+		}
+		byExpiry[expiry] = append(byExpiry[expiry], position.InstrumentName)
+	}
+
+	expiries := make([]Expiry, 0, len(byExpiry))
+	for expiry, names := range byExpiry {
+		sort.Strings(names)
+		expiries = append(expiries, Expiry{Time: expiry, Instruments: names})
+	}
+	sort.Slice(expiries, func(i, j int) bool { return expiries[i].Time.Before(expiries[j].Time) })
+	return expiries, nil
+}
+
+// remind 剩余时间进入新的提醒档位时发送提醒；同时越过多个档位（如启动时）只发送最近的一档
+func (t *Tracker) remind(account string, e Expiry, now time.Time) {
+	left := e.Time.Sub(now)
+	level := -1
+	for i, reminder := range t.reminders {
+		if left <= reminder {
+			level = i
+		}
+	}
+	last, ok := t.reminded[e.Time]
+	if level < 0 || (ok && level <= last) {
+		return
+	}
+	t.reminded[e.Time] = level
+
+	severity := notify.SeverityInfo
+	if left <= 24*time.Hour {
+		severity = notify.SeverityWarning
+	}
+	t.send(notify.Notification{
+		Source:   "expiry",
+		Severity: severity,
+		Title:    fmt.Sprintf("%s positions expire in %s", t.currency, formatDuration(left)),
+		Message: fmt.Sprintf("%d position(s) expire at %s: %s",
+			len(e.Instruments), e.Time.Format("2006-01-02 15:04 UTC"), strings.Join(e.Instruments, ", ")),
+		Fields: map[string]interface{}{
+			"account":     account,
+			"expiry":      e.Label(),
+			"expires_at":  e.Time,
+			"instruments": e.Instruments,
+			"reminder":    t.reminders[level].String(),
+		},
+	})
+}
+
+// confirm 到期超过 settlement_grace_seconds 后查询交割记录；查到所有合约的交割后发送确认，超时未查到则告警
+func (t *Tracker) confirm(account string, summaries *types.AccountSummaries, now time.Time) {
+	var due []pendingSettlement
+	var waiting []pendingSettlement
+	for _, p := range t.pending {
+		if now.Sub(p.expiry) >= t.grace {
+			due = append(due, p)
+		} else {
+			waiting = append(waiting, p)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	history, err := t.reader.GetSettlementHistoryByCurrency(t.currency, "delivery", settlementHistoryCount)
+	if err != nil {
+		t.logger.Error("Failed to get settlement history", zap.Error(err))
+		t.pending = append(waiting, due...)
+		return
+	}
+
+	for _, p := range due {
+		settled, missing := match(p, history.Settlements)
+		switch {
+		case len(missing) == 0:
+			t.confirmed(account, p, settled, summaries)
+		case now.Sub(p.expiry) >= t.confirmTimeout:
+			t.send(notify.Notification{
+				Source:   "expiry",
+				Severity: notify.SeverityWarning,
+				Title:    fmt.Sprintf("%s settlement for %s not confirmed", t.currency, Label(p.expiry)),
+				Message: fmt.Sprintf("No delivery record after %s for: %s",
+					formatDuration(now.Sub(p.expiry)), strings.Join(missing, ", ")),
+				Fields: map[string]interface{}{
+					"account":     account,
+					"expiry":      Label(p.expiry),
+					"instruments": missing,
+				},
+			})
+		default:
+			waiting = append(waiting, p)
+		}
+	}
+	t.pending = waiting
+}
+
+// match 找出到期后各合约的交割记录，返回找到的记录和缺少记录的合约
+func match(p pendingSettlement, settlements []types.Settlement) ([]types.Settlement, []string) {
+	byInstrument := make(map[string]types.Settlement)
+	for _, settlement := range settlements {
+		if settlement.Timestamp < p.expiry.UnixMilli() {
+			continue
+		}
+		if _, ok := byInstrument[settlement.InstrumentName]; !ok {
+			byInstrument[settlement.InstrumentName] = settlement
+		}
+	}
+
+	var settled []types.Settlement
+	var missing []string
+	for _, name := range p.instruments {
+		if settlement, ok := byInstrument[name]; ok {
+			settled = append(settled, settlement)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	return settled, missing
+}
+
+// confirmed 发送交割确认，附带交割盈亏和当前保证金状态
+func (t *Tracker) confirmed(account string, p pendingSettlement, settled []types.Settlement, summaries *types.AccountSummaries) {
+	var profitLoss float64
+	deliveries := make([]map[string]interface{}, 0, len(settled))
+	for _, settlement := range settled {
+		profitLoss += settlement.ProfitLoss
+		deliveries = append(deliveries, map[string]interface{}{
+			"instrument":  settlement.InstrumentName,
+			"position":    settlement.Position,
+			"index_price": settlement.IndexPrice,
+			"profit_loss": settlement.ProfitLoss,
+		})
+	}
+
+	n := notify.Notification{
+		Source:   "expiry",
+		Severity: notify.SeverityInfo,
+		Title:    fmt.Sprintf("%s %s settlement confirmed", t.currency, Label(p.expiry)),
+		Fields: map[string]interface{}{
+			"account":     account,
+			"expiry":      Label(p.expiry),
+			"profit_loss": profitLoss,
+			"deliveries":  deliveries,
+		},
+	}
+	message := fmt.Sprintf("%d position(s) delivered, profit/loss %.4f %s", len(settled), profitLoss, t.currency)
+
+	if summary := findSummary(summaries, t.currency); summary != nil {
+		n.Fields["equity"] = summary.Equity
+		n.Fields["margin_balance"] = summary.MarginBalance
+		n.Fields["maintenance_margin"] = summary.MaintenanceMargin
+		if summary.MarginBalance > 0 {
+			ratio := summary.MaintenanceMargin / summary.MarginBalance
+			n.Fields["mm_ratio"] = ratio
+			message += fmt.Sprintf("; equity %.4f %s, maintenance margin %.4f, MM ratio %.2f%%", summary.Equity, t.currency, summary.MaintenanceMargin, ratio*100)
+		}
+	}
+	if profitLoss < 0 {
+		n.Severity = notify.SeverityWarning
+	}
+	n.Message = message
+	t.send(n)
+}
+
+func (t *Tracker) send(n notify.Notification) {
+	if t.notifier == nil {
+		return
+	}
+	if err := t.notifier.Notify(n); err != nil {
+		t.logger.Error("Failed to send expiry notification", zap.Error(err))
+	}
+}
+
+func findSummary(summaries *types.AccountSummaries, currency string) *types.CurrencySummary {
+	if summaries == nil {
+		return nil
+	}
+	for i := range summaries.Summaries {
+		if strings.EqualFold(summaries.Summaries[i].Currency, currency) {
+			return &summaries.Summaries[i]
+		}
+	}
+	return nil
+}
+
+// formatDuration 以天、小时或分钟显示剩余时间
+func formatDuration(d time.Duration) string {
+	switch {
+	case d > 24*time.Hour:
+		return fmt.Sprintf("%.1f days", d.Hours()/24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%.1f hours", d.Hours())
+	default:
+		return fmt.Sprintf("%.0f minutes", math.Max(d.Minutes(), 0))
+	}
+}
+
+// alertState 已发送的提醒和待确认的交割，退出时保存
+type alertState struct {
+	Held     map[time.Time][]string `json:"held"`
+	Reminded map[time.Time]int      `json:"reminded"`
+	Pending  []Expiry               `json:"pending"`
+}
+
+// MarshalState 导出告警状态
+func (t *Tracker) MarshalState() ([]byte, error) {
+	state := alertState{Held: t.held, Reminded: t.reminded}
+	for _, p := range t.pending {
+		state.Pending = append(state.Pending, Expiry{Time: p.expiry, Instruments: p.instruments})
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalState 恢复上次退出时的告警状态，重启后不重复发送已发送的提醒，并继续确认停机期间到期的持仓
+func (t *Tracker) UnmarshalState(data []byte) error {
+	var state alertState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	// 到期时间作为 map 的键，统一为 UTC 以便与合约信息中的时间比较
+	t.held = make(map[time.Time][]string, len(state.Held))
+	for expiry, names := range state.Held {
+		t.held[expiry.UTC()] = names
+	}
+	t.reminded = make(map[time.Time]int, len(state.Reminded))
+	for expiry, level := range state.Reminded {
+		t.reminded[expiry.UTC()] = level
+	}
+	t.pending = nil
+	for _, p := range state.Pending {
+		t.pending = append(t.pending, pendingSettlement{expiry: p.Time.UTC(), instruments: p.Instruments})
+	}
+	return nil
+}
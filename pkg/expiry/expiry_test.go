@@ -0,0 +1,196 @@
+package expiry
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"cs-projects-eth-collar/pkg/notify"
+	"cs-projects-eth-collar/pkg/notify/notifytest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var expiryDec = time.Date(2024, 12, 27, 8, 0, 0, 0, time.UTC)
+
+type stubCatalog map[string]types.Instrument
+
+func (c stubCatalog) Get(name string) (types.Instrument, bool, error) {
+	instrument, ok := c[name]
+	return instrument, ok, nil
+}
+
+func newTestTracker(t *testing.T) (*Tracker, *fakederibit.Server, *notifytest.Notifier, *time.Time) {
+	srv := fakederibit.NewServer()
+	t.Cleanup(srv.Close)
+	srv.SetState(fakederibit.State{
+		Positions: []types.Position{
+			{InstrumentName: "ETH-27DEC24-2500-P", Kind: "option", Direction: "buy", Size: 100},
+			{InstrumentName: "ETH-27DEC24-3500-C", Kind: "option", Direction: "sell", Size: -100},
+			{InstrumentName: "ETH-PERPETUAL", Kind: "future", Direction: "sell", Size: -30000},
+		},
+	})
+	client, err := deribit.NewClient(types.DeribitConfig{BaseURL: srv.URL(), APIKey: srv.ClientID, APISecret: srv.ClientSecret})
+	require.NoError(t, err)
+
+	catalog := stubCatalog{
+		"ETH-27DEC24-2500-P": {InstrumentName: "ETH-27DEC24-2500-P", Kind: "option", ExpirationTimestamp: expiryDec.UnixMilli()},
+		"ETH-27DEC24-3500-C": {InstrumentName: "ETH-27DEC24-3500-C", Kind: "option", ExpirationTimestamp: expiryDec.UnixMilli()},
+		"ETH-PERPETUAL":      {InstrumentName: "ETH-PERPETUAL", Kind: "future", ExpirationTimestamp: 32503708800000},
+	}
+	config := types.ExpiryConfig{
+		Currency:               "eth",
+		Reminders:              []string{"1h", "168h", "24h"},
+		SettlementGraceSeconds: 300,
+		ConfirmTimeoutSeconds:  3600,
+	}
+	notifier := &notifytest.Notifier{}
+	tracker, err := NewTracker(config, client, catalog, notifier, nil, zap.NewNop())
+	require.NoError(t, err)
+
+	now := expiryDec.Add(-10 * 24 * time.Hour)
+	tracker.now = func() time.Time { return now }
+	return tracker, srv, notifier, &now
+}
+
+func TestRemindersFireOncePerLevel(t *testing.T) {
+	tracker, _, notifier, now := newTestTracker(t)
+
+	expiries, err := tracker.Evaluate("desk", nil)
+	require.NoError(t, err)
+	require.Len(t, expiries, 1) // 永续合约不计入
+	assert.Equal(t, "27dec24", expiries[0].Label())
+	assert.Equal(t, []string{"ETH-27DEC24-2500-P", "ETH-27DEC24-3500-C"}, expiries[0].Instruments)
+	assert.Empty(t, notifier.Notifications)
+
+	for _, step := range []time.Duration{7 * 24 * time.Hour, 6 * 24 * time.Hour, 24 * time.Hour, 23 * time.Hour, 30 * time.Minute} {
+		*now = expiryDec.Add(-step)
+		_, err := tracker.Evaluate("desk", nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{
+		"ETH positions expire in 7.0 days",
+		"ETH positions expire in 24.0 hours",
+		"ETH positions expire in 30 minutes",
+	}, notifier.Titles())
+	assert.Equal(t, notify.SeverityInfo, notifier.Notifications[0].Severity)
+	assert.Equal(t, notify.SeverityWarning, notifier.Notifications[1].Severity)
+}
+
+func TestReminderSkipsPassedLevels(t *testing.T) {
+	tracker, _, notifier, now := newTestTracker(t)
+	*now = expiryDec.Add(-2 * time.Hour)
+
+	_, err := tracker.Evaluate("desk", nil)
+	require.NoError(t, err)
+	require.Len(t, notifier.Notifications, 1)
+	assert.Equal(t, "24h0m0s", notifier.Notifications[0].Fields["reminder"])
+}
+
+func TestSettlementConfirmed(t *testing.T) {
+	tracker, srv, notifier, now := newTestTracker(t)
+	*now = expiryDec.Add(-30 * time.Minute)
+	_, err := tracker.Evaluate("desk", nil)
+	require.NoError(t, err)
+	notifier.Notifications = nil
+
+	// 到期后持仓消失，宽限期内不查询
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Positions = state.Positions[2:]
+	})
+	*now = expiryDec.Add(time.Minute)
+	expiries, err := tracker.Evaluate("desk", nil)
+	require.NoError(t, err)
+	assert.Empty(t, expiries)
+	assert.Empty(t, notifier.Notifications)
+
+	// 只有一条交割记录时继续等待
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Settlements = []types.Settlement{
+			{Type: "delivery", Timestamp: expiryDec.UnixMilli() + 100, InstrumentName: "ETH-27DEC24-2500-P", Position: 100},
+		}
+	})
+	*now = expiryDec.Add(10 * time.Minute)
+	_, err = tracker.Evaluate("desk", nil)
+	require.NoError(t, err)
+	assert.Empty(t, notifier.Notifications)
+
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Settlements = append(state.Settlements,
+			types.Settlement{Type: "delivery", Timestamp: expiryDec.UnixMilli() + 200, InstrumentName: "ETH-27DEC24-3500-C", Position: -100, IndexPrice: 3600, ProfitLoss: -2.7778})
+	})
+	summaries := &types.AccountSummaries{Summaries: []types.CurrencySummary{
+		{Currency: "ETH", Equity: 497.2, MarginBalance: 497.2, MaintenanceMargin: 124.3},
+	}}
+	*now = expiryDec.Add(15 * time.Minute)
+	_, err = tracker.Evaluate("desk", summaries)
+	require.NoError(t, err)
+	require.Len(t, notifier.Notifications, 1)
+
+	n := notifier.Notifications[0]
+	assert.Equal(t, "ETH 27dec24 settlement confirmed", n.Title)
+	assert.Equal(t, notify.SeverityWarning, n.Severity) // 交割亏损
+	assert.Equal(t, -2.7778, n.Fields["profit_loss"])
+	assert.InDelta(t, 0.25, n.Fields["mm_ratio"], 1e-3)
+	assert.Contains(t, n.Message, "MM ratio 25.00%")
+
+	// 确认后不再查询、不再通知
+	*now = expiryDec.Add(2 * time.Hour)
+	_, err = tracker.Evaluate("desk", summaries)
+	require.NoError(t, err)
+	assert.Len(t, notifier.Notifications, 1)
+}
+
+func TestSettlementNotConfirmed(t *testing.T) {
+	tracker, srv, notifier, now := newTestTracker(t)
+	*now = expiryDec.Add(-30 * time.Minute)
+	_, err := tracker.Evaluate("desk", nil)
+	require.NoError(t, err)
+	notifier.Notifications = nil
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Positions = nil
+	})
+
+	*now = expiryDec.Add(time.Hour)
+	_, err = tracker.Evaluate("desk", nil)
+	require.NoError(t, err)
+	require.Len(t, notifier.Notifications, 1)
+	assert.Equal(t, "ETH settlement for 27dec24 not confirmed", notifier.Notifications[0].Title)
+	assert.Equal(t, []string{"ETH-27DEC24-2500-P", "ETH-27DEC24-3500-C"}, notifier.Notifications[0].Fields["instruments"])
+}
+
+func TestInvalidReminder(t *testing.T) {
+	_, err := NewTracker(types.ExpiryConfig{Reminders: []string{"7d"}}, nil, nil, nil, nil, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestStateSurvivesRestart(t *testing.T) {
+	tracker, srv, notifier, now := newTestTracker(t)
+	*now = expiryDec.Add(-2 * time.Hour)
+	_, err := tracker.Evaluate("desk", nil)
+	require.NoError(t, err)
+	require.Len(t, notifier.Notifications, 1)
+	data, err := tracker.MarshalState()
+	require.NoError(t, err)
+
+	// 重启：同一提醒级别不再发送；停机期间到期的持仓继续确认交割
+	restarted, _, restartedNotifier, restartedNow := newTestTracker(t)
+	require.NoError(t, restarted.UnmarshalState(data))
+	restarted.reader = tracker.reader // 使用同一个模拟服务
+	*restartedNow = expiryDec.Add(-90 * time.Minute)
+	_, err = restarted.Evaluate("desk", nil)
+	require.NoError(t, err)
+	assert.Empty(t, restartedNotifier.Notifications)
+
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Positions = nil
+	})
+	*restartedNow = expiryDec.Add(time.Hour)
+	_, err = restarted.Evaluate("desk", nil)
+	require.NoError(t, err)
+	require.Len(t, restartedNotifier.Notifications, 1)
+	assert.Equal(t, "ETH settlement for 27dec24 not confirmed", restartedNotifier.Notifications[0].Title)
+}
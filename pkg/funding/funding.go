@@ -0,0 +1,315 @@
+// Package funding 跟踪永续合约的资金费率，并根据账户的永续仓位计算已实现和预计的资金费成本。
+//
+// 资金费率为正时多头向空头支付。成本以 USD 计，正数表示账户支付，负数表示账户收取：
+//   - 已实现：有账户流水（WithTransactions）时为过去 24 小时结算流水中该合约的实际资金费（interest_pl）；
+//     没有流水或没有该合约的结算记录时按过去 24 小时的累计资金费率（public/get_funding_rate_value）× 当前仓位估算，标记为估算值
+//   - 预计：最新的 8 小时资金费率（public/get_funding_rate_history 的 interest_8h）× 3 × 当前仓位
+//
+// 估算值在过去 24 小时内调仓时与实际资金费会有差异；流水只包含已导入的记录，导入间隔内的结算在下次导入后计入。
+package funding
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/notify"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ContractUnitUSD  = "usd"  // 反向合约，数量以 USD 计
+	ContractUnitBase = "base" // 线性合约，数量以币计
+
+	periodsPerDay = 3 // 每天 3 个 8 小时资金费周期
+	daysPerYear   = 365
+)
+
+// Reader 资金费模块需要的只读数据源，*deribit.Client 满足此接口
+type Reader interface {
+	GetPositions(currency string, kind ...string) ([]types.Position, error)
+	GetFundingRateValue(instrumentName string, start, end time.Time) (float64, error)
+	GetFundingRateHistory(instrumentName string, start, end time.Time) ([]types.FundingRate, error)
+}
+
+// Sink 接收资金费指标，*metrics.Metrics 满足此接口
+type Sink interface {
+	UpdateFundingMetrics(account string, cost types.FundingMetrics)
+}
+
+// TransactionSource 已导入的账户流水，*history.Store 满足此接口
+type TransactionSource interface {
+	Transactions(currency string, from, to time.Time) []types.TransactionLogEntry
+}
+
+// Cost 一次资金费评估结果
+type Cost struct {
+	Currency          string  `json:"currency"`
+	Instrument        string  `json:"instrument"`
+	IndexPrice        float64 `json:"index_price"`
+	PositionUSD       float64 `json:"position_usd"`        // 永续仓位名义价值（有符号，多头为正）
+	Rate8h            float64 `json:"rate_8h"`             // 最新的 8 小时资金费率
+	AnnualizedRate    float64 `json:"annualized_rate"`     // Rate8h × 3 × 365
+	Realized24hRate   float64 `json:"realized_24h_rate"`   // 过去 24 小时的累计资金费率
+	RealizedUSD24h    float64 `json:"realized_usd_24h"`    // 过去 24 小时的资金费成本（正数为支付）
+	RealizedEstimated bool    `json:"realized_estimated"`  // RealizedUSD24h 按当前仓位估算，而不是来自结算流水
+	ProjectedDailyUSD float64 `json:"projected_daily_usd"` // 按最新费率预计的每日资金费成本
+	AnnualizedCostUSD float64 `json:"annualized_cost_usd"` // 按最新费率预计的年化资金费成本
+	MaxDailyCostUSD   float64 `json:"max_daily_cost_usd"`  // 告警阈值，0 表示不告警
+	Breached          bool    `json:"breached"`
+}
+
+// DailyCostUSD 用于告警的每日成本：已实现和预计中较大的一个
+func (c *Cost) DailyCostUSD() float64 {
+	return math.Max(c.RealizedUSD24h, c.ProjectedDailyUSD)
+}
+
+// Metrics 转换为指标值
+func (c *Cost) Metrics() types.FundingMetrics {
+	return types.FundingMetrics{
+		Currency:          c.Currency,
+		Instrument:        c.Instrument,
+		Rate8h:            c.Rate8h,
+		AnnualizedRate:    c.AnnualizedRate,
+		PositionUSD:       c.PositionUSD,
+		RealizedUSD24h:    c.RealizedUSD24h,
+		ProjectedDailyUSD: c.ProjectedDailyUSD,
+		AnnualizedCostUSD: c.AnnualizedCostUSD,
+		MaxDailyCostUSD:   c.MaxDailyCostUSD,
+	}
+}
+
+// Calculate 根据仓位、最新 8 小时费率和过去 24 小时累计费率计算资金费成本（纯函数）
+func Calculate(config types.FundingConfig, positions []types.Position, rate8h, realized24hRate float64) *Cost {
+	cost := &Cost{
+		Currency:          config.Currency,
+		Instrument:        config.Instrument,
+		Rate8h:            rate8h,
+		AnnualizedRate:    rate8h * periodsPerDay * daysPerYear,
+		Realized24hRate:   realized24hRate,
+		RealizedEstimated: true,
+		MaxDailyCostUSD:   config.MaxDailyCostUSD,
+	}
+
+	for _, position := range positions {
+		if position.InstrumentName != config.Instrument {
+			continue
+		}
+		size := math.Abs(position.Size)
+		if strings.ToLower(config.ContractUnit) == ContractUnitBase {
+			size *= position.IndexPrice
+		}
+		if position.Direction == "sell" {
+			size = -size
+		}
+		cost.PositionUSD += size
+		cost.IndexPrice = position.IndexPrice
+	}
+
+	cost.RealizedUSD24h = cost.PositionUSD * realized24hRate
+	cost.ProjectedDailyUSD = cost.PositionUSD * rate8h * periodsPerDay
+	cost.AnnualizedCostUSD = cost.ProjectedDailyUSD * daysPerYear
+	cost.Breached = config.MaxDailyCostUSD > 0 && cost.DailyCostUSD() > config.MaxDailyCostUSD
+	return cost
+}
+
+// ApplySettlements 用结算流水中该合约的实际资金费替换估算的已实现成本，没有该合约的结算流水时保留估算值
+// interest_pl 以结算币种计：反向合约为币，按 indexPrice 换算成 USD；线性合约为 USDC
+func (c *Cost) ApplySettlements(config types.FundingConfig, entries []types.TransactionLogEntry, indexPrice float64) {
+	price := indexPrice
+	if strings.ToLower(config.ContractUnit) == ContractUnitBase {
+		price = 1
+	}
+	var paid float64
+	var settled bool
+	for _, entry := range entries {
+		if entry.InstrumentName == config.Instrument && strings.EqualFold(entry.Type, "settlement") {
+			paid -= entry.InterestPL // interest_pl 为盈亏，负数表示支付
+			settled = true
+		}
+	}
+	if !settled {
+		return
+	}
+	c.RealizedUSD24h = paid * price
+	c.RealizedEstimated = false
+	c.Breached = config.MaxDailyCostUSD > 0 && c.DailyCostUSD() > config.MaxDailyCostUSD
+}
+
+// Tracker 在每个监控周期计算资金费成本，更新指标并在超出每日阈值时通知
+type Tracker struct {
+	config       types.FundingConfig
+	reader       Reader
+	transactions TransactionSource // 为 nil 时已实现成本按当前仓位估算
+	notifier     notify.Notifier
+	metrics      Sink
+	logger       *zap.Logger
+	now          func() time.Time
+
+	breached     bool      // 上一次是否超出阈值
+	lastNotified time.Time // 上一次发出超阈值通知的时间
+}
+
+// Option 资金费跟踪的可选配置
+type Option func(*Tracker)
+
+// WithTransactions 使用已导入的账户流水计算实际支付的资金费
+func WithTransactions(source TransactionSource) Option {
+	return func(t *Tracker) {
+		t.transactions = source
+	}
+}
+
+// NewTracker 创建资金费跟踪，metrics 可为 nil
+func NewTracker(config types.FundingConfig, reader Reader, notifier notify.Notifier, metrics Sink, logger *zap.Logger, opts ...Option) *Tracker {
+	t := &Tracker{
+		config:   config,
+		reader:   reader,
+		notifier: notifier,
+		metrics:  metrics,
+		logger:   logger,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Evaluate 读取仓位和资金费率，计算一次资金费成本
+func (t *Tracker) Evaluate(account string) (*Cost, error) {
+	now := t.now()
+
+	positions, err := t.reader.GetPositions(t.config.Currency, "future")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	// 资金费率每小时一条，取最近 8 小时内最新的一条
+	history, err := t.reader.GetFundingRateHistory(t.config.Instrument, now.Add(-8*time.Hour), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s funding rate history: %w", t.config.Instrument, err)
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("no %s funding rate in the last 8 hours", t.config.Instrument)
+	}
+	latest := history[0]
+	for _, rate := range history[1:] {
+		if rate.Timestamp > latest.Timestamp {
+			latest = rate
+		}
+	}
+
+	realized, err := t.reader.GetFundingRateValue(t.config.Instrument, now.Add(-24*time.Hour), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s funding rate value: %w", t.config.Instrument, err)
+	}
+
+	cost := Calculate(t.config, positions, latest.Interest8h, realized)
+	if cost.IndexPrice == 0 {
+		cost.IndexPrice = latest.IndexPrice
+	}
+	if t.transactions != nil {
+		cost.ApplySettlements(t.config, t.transactions.Transactions(t.config.Currency, now.Add(-24*time.Hour), now), cost.IndexPrice)
+	}
+
+	t.logger.Info("Funding cost evaluation",
+		zap.String("instrument", cost.Instrument),
+		zap.Float64("position_usd", cost.PositionUSD),
+		zap.Float64("rate_8h", cost.Rate8h),
+		zap.Float64("annualized_rate", cost.AnnualizedRate),
+		zap.Float64("realized_usd_24h", cost.RealizedUSD24h),
+		zap.Bool("realized_estimated", cost.RealizedEstimated),
+		zap.Float64("projected_daily_usd", cost.ProjectedDailyUSD),
+		zap.Bool("breached", cost.Breached),
+	)
+
+	if t.metrics != nil {
+		t.metrics.UpdateFundingMetrics(account, cost.Metrics())
+	}
+	t.notify(account, cost, now)
+
+	return cost, nil
+}
+
+// notify 超出每日阈值时通知（持续超出时按间隔重复），回到阈值内时发送恢复通知
+func (t *Tracker) notify(account string, cost *Cost, now time.Time) {
+	interval := time.Duration(t.config.NotifyIntervalSeconds) * time.Second
+
+	realized := "realized"
+	if cost.RealizedEstimated {
+		realized = "estimated"
+	}
+
+	var n *notify.Notification
+	switch {
+	case cost.Breached && (!t.breached || now.Sub(t.lastNotified) >= interval):
+		n = &notify.Notification{
+			Source:   "funding",
+			Severity: notify.SeverityWarning,
+			Title:    fmt.Sprintf("%s funding cost above daily limit", cost.Instrument),
+			Message: fmt.Sprintf("Funding cost %.2f USD/day (%s 24h %.2f, projected %.2f) exceeds %.2f USD/day; position %.0f USD, 8h rate %.6f (%.2f%% annualized)",
+				cost.DailyCostUSD(), realized, cost.RealizedUSD24h, cost.ProjectedDailyUSD, cost.MaxDailyCostUSD, cost.PositionUSD, cost.Rate8h, cost.AnnualizedRate*100),
+			Fields: map[string]interface{}{
+				"account":             account,
+				"rule":                "funding_cost",
+				"instrument":          cost.Instrument,
+				"position_usd":        cost.PositionUSD,
+				"rate_8h":             cost.Rate8h,
+				"realized_usd_24h":    cost.RealizedUSD24h,
+				"realized_estimated":  cost.RealizedEstimated,
+				"projected_daily_usd": cost.ProjectedDailyUSD,
+				"annualized_cost_usd": cost.AnnualizedCostUSD,
+				"max_daily_cost_usd":  cost.MaxDailyCostUSD,
+			},
+		}
+		t.breached = true
+		t.lastNotified = now
+	case !cost.Breached && t.breached:
+		n = &notify.Notification{
+			Source:   "funding",
+			Severity: notify.SeverityInfo,
+			Title:    fmt.Sprintf("%s funding cost back within daily limit", cost.Instrument),
+			Message:  fmt.Sprintf("Funding cost %.2f USD/day within %.2f USD/day", cost.DailyCostUSD(), cost.MaxDailyCostUSD),
+			Fields: map[string]interface{}{
+				"account":            account,
+				"rule":               "funding_cost",
+				"instrument":         cost.Instrument,
+				"max_daily_cost_usd": cost.MaxDailyCostUSD,
+			},
+		}
+		t.breached = false
+	}
+
+	if n == nil {
+		return
+	}
+	if err := t.notifier.Notify(*n); err != nil {
+		t.logger.Error("Failed to send funding notification", zap.Error(err))
+	}
+}
+
+// alertState 超阈值通知的抑制状态，退出时保存
+type alertState struct {
+	Breached     bool      `json:"breached"`
+	LastNotified time.Time `json:"last_notified"`
+}
+
+// MarshalState 导出告警状态
+func (t *Tracker) MarshalState() ([]byte, error) {
+	return json.Marshal(alertState{Breached: t.breached, LastNotified: t.lastNotified})
+}
+
+// UnmarshalState 恢复上次退出时的告警状态，重启后不重复发送间隔内的超阈值通知
+func (t *Tracker) UnmarshalState(data []byte) error {
+	var state alertState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	t.breached = state.Breached
+	t.lastNotified = state.LastNotified
+	return nil
+}
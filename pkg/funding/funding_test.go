@@ -0,0 +1,160 @@
+package funding
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"cs-projects-eth-collar/pkg/notify"
+	"cs-projects-eth-collar/pkg/notify/notifytest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var evaluatedAt = time.Date(2024, 12, 20, 12, 0, 0, 0, time.UTC)
+
+// hourlyRates 返回 evaluatedAt 之前 hours 小时的资金费率，每小时 interest_1h 相同
+func hourlyRates(hours int, interest1h float64) []types.FundingRate {
+	var rates []types.FundingRate
+	for i := hours; i >= 0; i-- {
+		rates = append(rates, types.FundingRate{
+			Timestamp:  evaluatedAt.Add(-time.Duration(i) * time.Hour).UnixMilli(),
+			IndexPrice: 3000,
+			Interest1h: interest1h,
+			Interest8h: interest1h * 8,
+		})
+	}
+	return rates
+}
+
+func newTestTracker(t *testing.T) (*Tracker, *fakederibit.Server, *notifytest.Notifier) {
+	srv := fakederibit.NewServer()
+	t.Cleanup(srv.Close)
+	srv.SetState(fakederibit.State{
+		Positions: []types.Position{
+			{InstrumentName: "ETH-27DEC24-2500-P", Kind: "option", Direction: "buy", Size: 100},
+			{InstrumentName: "ETH-PERPETUAL", Kind: "future", Direction: "buy", Size: 300000, IndexPrice: 3000},
+		},
+		FundingRates: map[string][]types.FundingRate{"ETH-PERPETUAL": hourlyRates(48, 0.0001)},
+	})
+	client, err := deribit.NewClient(types.DeribitConfig{BaseURL: srv.URL(), APIKey: srv.ClientID, APISecret: srv.ClientSecret})
+	require.NoError(t, err)
+
+	config := types.FundingConfig{
+		Currency:              "ETH",
+		Instrument:            "ETH-PERPETUAL",
+		ContractUnit:          ContractUnitUSD,
+		MaxDailyCostUSD:       500,
+		NotifyIntervalSeconds: 3600,
+	}
+	notifier := &notifytest.Notifier{}
+	tracker := NewTracker(config, client, notifier, nil, zap.NewNop())
+	tracker.now = func() time.Time { return evaluatedAt }
+	return tracker, srv, notifier
+}
+
+func TestCalculateLinearShort(t *testing.T) {
+	config := types.FundingConfig{Currency: "USDC", Instrument: "ETH_USDC-PERPETUAL", ContractUnit: ContractUnitBase}
+	positions := []types.Position{{InstrumentName: "ETH_USDC-PERPETUAL", Direction: "sell", Size: -10, IndexPrice: 3000}}
+
+	cost := Calculate(config, positions, 0.0008, 0.0024)
+
+	assert.Equal(t, -30000.0, cost.PositionUSD)
+	assert.InDelta(t, -72, cost.RealizedUSD24h, 1e-9) // 空头在正费率下收取资金费
+	assert.InDelta(t, -72, cost.ProjectedDailyUSD, 1e-9)
+	assert.InDelta(t, 0.876, cost.AnnualizedRate, 1e-9)
+	assert.False(t, cost.Breached) // 未配置阈值
+}
+
+func TestEvaluateAlertsAndRecovers(t *testing.T) {
+	tracker, srv, notifier := newTestTracker(t)
+
+	// 24 小时累计 0.0024，300000 USD 多头每日支付 720 USD
+	cost, err := tracker.Evaluate("desk")
+	require.NoError(t, err)
+	assert.Equal(t, 300000.0, cost.PositionUSD)
+	assert.InDelta(t, 0.0008, cost.Rate8h, 1e-12)
+	assert.InDelta(t, 0.0024, cost.Realized24hRate, 1e-12)
+	assert.InDelta(t, 720, cost.RealizedUSD24h, 1e-6)
+	assert.InDelta(t, 720, cost.ProjectedDailyUSD, 1e-6)
+	assert.InDelta(t, 720*365, cost.AnnualizedCostUSD, 1e-3)
+	assert.True(t, cost.Breached)
+	require.Len(t, notifier.Notifications, 1)
+	assert.Equal(t, notify.SeverityWarning, notifier.Notifications[0].Severity)
+
+	// 持续超出，未到重复间隔不再通知
+	_, err = tracker.Evaluate("desk")
+	require.NoError(t, err)
+	assert.Len(t, notifier.Notifications, 1)
+
+	// 减仓后回到阈值内，发送恢复通知
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Positions[1].Size = 100000
+	})
+	cost, err = tracker.Evaluate("desk")
+	require.NoError(t, err)
+	assert.False(t, cost.Breached)
+	require.Len(t, notifier.Notifications, 2)
+	assert.Equal(t, notify.SeverityInfo, notifier.Notifications[1].Severity)
+}
+
+func TestEvaluateWithoutRecentRate(t *testing.T) {
+	tracker, _, _ := newTestTracker(t)
+	tracker.now = func() time.Time { return evaluatedAt.Add(24 * time.Hour) }
+
+	_, err := tracker.Evaluate("desk")
+	assert.ErrorContains(t, err, "no ETH-PERPETUAL funding rate")
+}
+
+// stubTransactions 固定的账户流水
+type stubTransactions []types.TransactionLogEntry
+
+func (s stubTransactions) Transactions(currency string, from, to time.Time) []types.TransactionLogEntry {
+	return s
+}
+
+func TestApplySettlements(t *testing.T) {
+	entries := []types.TransactionLogEntry{
+		{Type: "settlement", InstrumentName: "ETH-PERPETUAL", InterestPL: -0.1},
+		{Type: "settlement", InstrumentName: "ETH-PERPETUAL", InterestPL: 0.02},
+		{Type: "settlement", InstrumentName: "ETH-27DEC24", InterestPL: -5}, // 其他合约不计入
+		{Type: "trade", InstrumentName: "ETH-PERPETUAL", InterestPL: -5},
+	}
+
+	// 反向合约：interest_pl 以 ETH 计，按指数价格换算
+	inverse := types.FundingConfig{Instrument: "ETH-PERPETUAL", ContractUnit: ContractUnitUSD, MaxDailyCostUSD: 200}
+	cost := Calculate(inverse, nil, 0, 0)
+	cost.ApplySettlements(inverse, entries, 3000)
+	assert.InDelta(t, 240, cost.RealizedUSD24h, 1e-9)
+	assert.False(t, cost.RealizedEstimated)
+	assert.True(t, cost.Breached)
+
+	// 线性合约：interest_pl 以 USDC 计
+	linear := types.FundingConfig{Instrument: "ETH-PERPETUAL", ContractUnit: ContractUnitBase}
+	cost = Calculate(linear, nil, 0, 0)
+	cost.ApplySettlements(linear, entries, 3000)
+	assert.InDelta(t, 0.08, cost.RealizedUSD24h, 1e-9)
+
+	// 没有该合约的结算记录时保留估算值
+	cost = Calculate(linear, nil, 0, 0)
+	cost.ApplySettlements(linear, entries[2:], 3000)
+	assert.True(t, cost.RealizedEstimated)
+}
+
+func TestEvaluateUsesSettlements(t *testing.T) {
+	tracker, _, notifier := newTestTracker(t)
+	// 实际支付 0.05 ETH，低于按当前仓位估算的 720 USD
+	tracker.transactions = stubTransactions{{Type: "settlement", Currency: "ETH", InstrumentName: "ETH-PERPETUAL", InterestPL: -0.05}}
+	tracker.config.MaxDailyCostUSD = 1000
+
+	cost, err := tracker.Evaluate("desk")
+	require.NoError(t, err)
+	assert.False(t, cost.RealizedEstimated)
+	assert.InDelta(t, 150, cost.RealizedUSD24h, 1e-6)
+	assert.InDelta(t, 720, cost.ProjectedDailyUSD, 1e-6)
+	assert.False(t, cost.Breached)
+	assert.Empty(t, notifier.Notifications)
+}
@@ -0,0 +1,270 @@
+// Package hedge 根据账户总 Delta 计算对冲建议。
+//
+// 本模块只做计算和建议：它依赖的 AccountReader 接口只包含只读方法，
+// 不持有任何下单能力，所有建议都通过指标和通知发出，由人工决定是否执行。
+package hedge
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/notify"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+
+	ContractUnitUSD  = "usd"  // 反向合约，数量以 USD 计
+	ContractUnitBase = "base" // 线性合约，数量以币计
+)
+
+// AccountReader 对冲模块需要的只读数据源（deribit.Client 满足该接口）
+type AccountReader interface {
+	GetPositions(currency string, kind ...string) ([]types.Position, error)
+	GetIndexPrice(currency string) (float64, error)
+}
+
+// Sink 接收对冲指标，*metrics.Metrics 满足此接口
+type Sink interface {
+	UpdateHedgeMetrics(currency, account, instrument string, currentDelta, targetDelta, recommendedAmount float64)
+}
+
+// Recommendation 一次对冲建议，DryRun 恒为 true
+type Recommendation struct {
+	Currency          string  `json:"currency"`
+	Instrument        string  `json:"instrument"`
+	IndexPrice        float64 `json:"index_price"`
+	CurrentDelta      float64 `json:"current_delta"`       // 账户总 Delta（DeltaTotal）
+	OptionsDelta      float64 `json:"options_delta"`       // 期权仓位 Delta 之和
+	FuturesDelta      float64 `json:"futures_delta"`       // 期货仓位 Delta 之和
+	ExistingHedgeSize float64 `json:"existing_hedge_size"` // 对冲合约现有仓位（有符号，合约单位）
+	TargetDelta       float64 `json:"target_delta"`
+	LowerBound        float64 `json:"lower_bound"`
+	UpperBound        float64 `json:"upper_bound"`
+	InBand            bool    `json:"in_band"`
+	HedgeDelta        float64 `json:"hedge_delta"` // 需要增加的 Delta（币数量），回到目标值
+	Side              string  `json:"side,omitempty"`
+	Amount            float64 `json:"amount"` // 按合约面值取整后的数量（合约单位）
+	DryRun            bool    `json:"dry_run"`
+}
+
+// SignedAmount 有符号的建议数量：买入为正，卖出为负
+func (r *Recommendation) SignedAmount() float64 {
+	if r.Side == SideSell {
+		return -r.Amount
+	}
+	return r.Amount
+}
+
+// Recommend 根据当前 Delta 和仓位计算对冲建议（纯函数）
+// Delta 在 [target-band, target+band] 内时不给出建议；否则建议把 Delta 拉回目标值
+func Recommend(config types.HedgeConfig, currentDelta, indexPrice float64, positions []types.Position) *Recommendation {
+	rec := &Recommendation{
+		Currency:     config.Currency,
+		Instrument:   config.Instrument,
+		IndexPrice:   indexPrice,
+		CurrentDelta: currentDelta,
+		TargetDelta:  config.TargetDelta,
+		LowerBound:   config.TargetDelta - math.Abs(config.Band),
+		UpperBound:   config.TargetDelta + math.Abs(config.Band),
+		DryRun:       true,
+	}
+
+	for _, position := range positions {
+		switch position.Kind {
+		case "option":
+			rec.OptionsDelta += position.Delta
+		case "future":
+			rec.FuturesDelta += position.Delta
+		}
+		if position.InstrumentName == config.Instrument {
+			size := math.Abs(position.Size)
+			if position.Direction == SideSell {
+				size = -size
+			}
+			rec.ExistingHedgeSize += size
+		}
+	}
+
+	rec.InBand = currentDelta >= rec.LowerBound && currentDelta <= rec.UpperBound
+	if rec.InBand {
+		return rec
+	}
+
+	rec.HedgeDelta = config.TargetDelta - currentDelta
+	rec.Amount = roundToContract(config, math.Abs(rec.HedgeDelta), indexPrice)
+	if rec.Amount == 0 {
+		return rec
+	}
+	if rec.HedgeDelta > 0 {
+		rec.Side = SideBuy
+	} else {
+		rec.Side = SideSell
+	}
+
+	return rec
+}
+
+// roundToContract 把 Delta（币数量）换算成合约单位并按合约面值取整
+func roundToContract(config types.HedgeConfig, delta, indexPrice float64) float64 {
+	amount := delta
+	if strings.ToLower(config.ContractUnit) != ContractUnitBase {
+		if indexPrice <= 0 {
+			return 0
+		}
+		amount = delta * indexPrice
+	}
+
+	contractSize := config.ContractSize
+	if contractSize <= 0 {
+		return amount
+	}
+	return math.Round(amount/contractSize) * contractSize
+}
+
+// Engine 在每个监控周期计算对冲建议，并通过指标和通知发出
+type Engine struct {
+	config   types.HedgeConfig
+	reader   AccountReader
+	metrics  Sink
+	notifier notify.Notifier
+	logger   *zap.Logger
+	now      func() time.Time
+
+	outOfBand    bool      // 上一次是否超出区间
+	lastNotified time.Time // 上一次发出超区间通知的时间
+}
+
+// NewEngine 创建对冲建议引擎
+func NewEngine(config types.HedgeConfig, reader AccountReader, metrics Sink, notifier notify.Notifier, logger *zap.Logger) *Engine {
+	return &Engine{
+		config:   config,
+		reader:   reader,
+		metrics:  metrics,
+		notifier: notifier,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Evaluate 计算一次对冲建议，更新指标（不推送）并按需发出通知
+func (e *Engine) Evaluate(account string, summaries *types.AccountSummaries) (*Recommendation, error) {
+	var summary *types.CurrencySummary
+	for i := range summaries.Summaries {
+		if strings.EqualFold(summaries.Summaries[i].Currency, e.config.Currency) {
+			summary = &summaries.Summaries[i]
+			break
+		}
+	}
+	if summary == nil {
+		return nil, fmt.Errorf("%s currency summary not found in account summaries", e.config.Currency)
+	}
+
+	indexPrice, err := e.reader.GetIndexPrice(strings.ToLower(e.config.Currency))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s index price: %w", e.config.Currency, err)
+	}
+
+	positions, err := e.reader.GetPositions(e.config.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	rec := Recommend(e.config, summary.DeltaTotal, indexPrice, positions)
+
+	e.logger.Info("Delta hedge evaluation",
+		zap.String("currency", rec.Currency),
+		zap.String("instrument", rec.Instrument),
+		zap.Float64("current_delta", rec.CurrentDelta),
+		zap.Float64("options_delta", rec.OptionsDelta),
+		zap.Float64("futures_delta", rec.FuturesDelta),
+		zap.Float64("target_delta", rec.TargetDelta),
+		zap.Bool("in_band", rec.InBand),
+		zap.String("side", rec.Side),
+		zap.Float64("amount", rec.Amount),
+		zap.Bool("dry_run", rec.DryRun),
+	)
+
+	e.metrics.UpdateHedgeMetrics(rec.Currency, account, rec.Instrument, rec.CurrentDelta, rec.TargetDelta, rec.SignedAmount())
+	e.notify(account, rec)
+
+	return rec, nil
+}
+
+// notify 超出区间时通知（持续超出时按间隔重复），回到区间时发送恢复通知
+func (e *Engine) notify(account string, rec *Recommendation) {
+	now := e.now()
+	interval := time.Duration(e.config.NotifyIntervalSeconds) * time.Second
+
+	var n *notify.Notification
+	switch {
+	case rec.Amount > 0 && (!e.outOfBand || now.Sub(e.lastNotified) >= interval):
+		n = &notify.Notification{
+			Source:   "hedge",
+			Severity: notify.SeverityWarning,
+			Title:    fmt.Sprintf("%s delta outside target band", rec.Currency),
+			Message: fmt.Sprintf("Delta %.4f outside [%.4f, %.4f]; recommend %s %.4f %s (dry run, no order placed)",
+				rec.CurrentDelta, rec.LowerBound, rec.UpperBound, rec.Side, rec.Amount, rec.Instrument),
+			Fields: map[string]interface{}{
+				"account":       account,
+				"current_delta": rec.CurrentDelta,
+				"target_delta":  rec.TargetDelta,
+				"hedge_delta":   rec.HedgeDelta,
+				"instrument":    rec.Instrument,
+				"side":          rec.Side,
+				"amount":        rec.Amount,
+				"dry_run":       rec.DryRun,
+			},
+		}
+		e.outOfBand = true
+		e.lastNotified = now
+	case rec.InBand && e.outOfBand:
+		n = &notify.Notification{
+			Source:   "hedge",
+			Severity: notify.SeverityInfo,
+			Title:    fmt.Sprintf("%s delta back within target band", rec.Currency),
+			Message:  fmt.Sprintf("Delta %.4f within [%.4f, %.4f]", rec.CurrentDelta, rec.LowerBound, rec.UpperBound),
+			Fields: map[string]interface{}{
+				"account":       account,
+				"current_delta": rec.CurrentDelta,
+				"target_delta":  rec.TargetDelta,
+			},
+		}
+		e.outOfBand = false
+	}
+
+	if n == nil {
+		return
+	}
+	if err := e.notifier.Notify(*n); err != nil {
+		e.logger.Error("Failed to send hedge notification", zap.Error(err))
+	}
+}
+
+// alertState 超区间通知的抑制状态，退出时保存
+type alertState struct {
+	OutOfBand    bool      `json:"out_of_band"`
+	LastNotified time.Time `json:"last_notified"`
+}
+
+// MarshalState 导出告警状态
+func (e *Engine) MarshalState() ([]byte, error) {
+	return json.Marshal(alertState{OutOfBand: e.outOfBand, LastNotified: e.lastNotified})
+}
+
+// UnmarshalState 恢复上次退出时的告警状态，重启后不重复发送间隔内的超区间通知
+func (e *Engine) UnmarshalState(data []byte) error {
+	var state alertState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	e.outOfBand = state.OutOfBand
+	e.lastNotified = state.LastNotified
+	return nil
+}
@@ -0,0 +1,166 @@
+package hedge
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/notify"
+	"cs-projects-eth-collar/pkg/notify/notifytest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func testHedgeConfig() types.HedgeConfig {
+	return types.HedgeConfig{
+		Currency:     "ETH",
+		TargetDelta:  0,
+		Band:         10,
+		Instrument:   "ETH-PERPETUAL",
+		ContractSize: 1,
+		ContractUnit: ContractUnitUSD,
+	}
+}
+
+func TestRecommendInBand(t *testing.T) {
+	rec := Recommend(testHedgeConfig(), 5, 3000, nil)
+
+	assert.True(t, rec.InBand)
+	assert.Equal(t, 0.0, rec.Amount)
+	assert.Empty(t, rec.Side)
+	assert.True(t, rec.DryRun)
+}
+
+func TestRecommendSellWhenLong(t *testing.T) {
+	positions := []types.Position{
+		{InstrumentName: "ETH-27DEC24-4000-C", Kind: "option", Direction: "sell", Size: 100, Delta: -30},
+		{InstrumentName: "ETH-PERPETUAL", Kind: "future", Direction: "sell", Size: 30000, Delta: -10},
+	}
+	rec := Recommend(testHedgeConfig(), 25.5, 3000, positions)
+
+	assert.False(t, rec.InBand)
+	assert.Equal(t, SideSell, rec.Side)
+	assert.Equal(t, 76500.0, rec.Amount)
+	assert.Equal(t, -76500.0, rec.SignedAmount())
+	assert.Equal(t, -30.0, rec.OptionsDelta)
+	assert.Equal(t, -10.0, rec.FuturesDelta)
+	assert.Equal(t, -30000.0, rec.ExistingHedgeSize)
+}
+
+func TestRecommendBuyLinearContract(t *testing.T) {
+	config := testHedgeConfig()
+	config.Instrument = "ETH_USDC-PERPETUAL"
+	config.ContractUnit = ContractUnitBase
+	config.ContractSize = 0.01
+
+	rec := Recommend(config, -12.345, 3000, nil)
+
+	assert.Equal(t, SideBuy, rec.Side)
+	assert.InDelta(t, 12.35, rec.Amount, 1e-9)
+}
+
+// stubReader 返回固定的仓位和指数价格
+type stubReader struct {
+	positions  []types.Position
+	indexPrice float64
+}
+
+func (r *stubReader) GetPositions(currency string, kind ...string) ([]types.Position, error) {
+	return r.positions, nil
+}
+
+func (r *stubReader) GetIndexPrice(currency string) (float64, error) {
+	return r.indexPrice, nil
+}
+
+// recordingSink 记录最近一次的对冲指标
+type recordingSink struct {
+	currentDelta float64
+	amount       float64
+	updates      int
+}
+
+func (s *recordingSink) UpdateHedgeMetrics(currency, account, instrument string, currentDelta, targetDelta, recommendedAmount float64) {
+	s.currentDelta = currentDelta
+	s.amount = recommendedAmount
+	s.updates++
+}
+
+func newTestEngine(now *time.Time) (*Engine, *recordingSink, *notifytest.Notifier) {
+	config := testHedgeConfig()
+	config.NotifyIntervalSeconds = 3600
+	sink := &recordingSink{}
+	notifier := &notifytest.Notifier{}
+	engine := NewEngine(config, &stubReader{indexPrice: 3000}, sink, notifier, zap.NewNop())
+	engine.now = func() time.Time { return *now }
+	return engine, sink, notifier
+}
+
+func summariesWithDelta(delta float64) *types.AccountSummaries {
+	return &types.AccountSummaries{Summaries: []types.CurrencySummary{{Currency: "ETH", DeltaTotal: delta}}}
+}
+
+func TestEvaluateNotifiesOutOfBandOncePerInterval(t *testing.T) {
+	now := time.Date(2024, 12, 27, 8, 0, 0, 0, time.UTC)
+	engine, sink, notifier := newTestEngine(&now)
+
+	rec, err := engine.Evaluate("desk", summariesWithDelta(25.5))
+	require.NoError(t, err)
+	assert.Equal(t, SideSell, rec.Side)
+	assert.Equal(t, -76500.0, sink.amount)
+	require.Len(t, notifier.Notifications, 1)
+	n := notifier.Notifications[0]
+	assert.Equal(t, "hedge", n.Source)
+	assert.Equal(t, notify.SeverityWarning, n.Severity)
+	assert.Equal(t, "ETH delta outside target band", n.Title)
+	assert.Contains(t, n.Message, "no order placed")
+
+	// 持续超出区间时间隔内不重复通知，指标照常更新
+	now = now.Add(30 * time.Minute)
+	_, err = engine.Evaluate("desk", summariesWithDelta(26))
+	require.NoError(t, err)
+	assert.Len(t, notifier.Notifications, 1)
+	assert.Equal(t, 2, sink.updates)
+	assert.Equal(t, 26.0, sink.currentDelta)
+
+	// 超过间隔后再次通知
+	now = now.Add(30 * time.Minute)
+	_, err = engine.Evaluate("desk", summariesWithDelta(26))
+	require.NoError(t, err)
+	assert.Len(t, notifier.Notifications, 2)
+}
+
+func TestEvaluateNotifiesRecoveryOnce(t *testing.T) {
+	now := time.Date(2024, 12, 27, 8, 0, 0, 0, time.UTC)
+	engine, sink, notifier := newTestEngine(&now)
+
+	// 一直在区间内时不通知
+	_, err := engine.Evaluate("desk", summariesWithDelta(5))
+	require.NoError(t, err)
+	assert.Empty(t, notifier.Notifications)
+
+	_, err = engine.Evaluate("desk", summariesWithDelta(-20))
+	require.NoError(t, err)
+	require.Len(t, notifier.Notifications, 1)
+
+	// 回到区间时发送一次恢复通知，之后不再重复
+	for i := 0; i < 2; i++ {
+		rec, err := engine.Evaluate("desk", summariesWithDelta(3))
+		require.NoError(t, err)
+		assert.True(t, rec.InBand)
+	}
+	assert.Equal(t, []string{"ETH delta outside target band", "ETH delta back within target band"}, notifier.Titles())
+	assert.Equal(t, notify.SeverityInfo, notifier.Notifications[1].Severity)
+	assert.Equal(t, 0.0, sink.amount)
+}
+
+func TestEvaluateMissingCurrency(t *testing.T) {
+	now := time.Date(2024, 12, 27, 8, 0, 0, 0, time.UTC)
+	engine, sink, notifier := newTestEngine(&now)
+
+	_, err := engine.Evaluate("desk", &types.AccountSummaries{Summaries: []types.CurrencySummary{{Currency: "BTC"}}})
+	assert.ErrorContains(t, err, "ETH currency summary not found")
+	assert.Zero(t, sink.updates)
+	assert.Empty(t, notifier.Notifications)
+}
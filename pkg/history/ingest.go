@@ -0,0 +1,59 @@
+package history
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// pageSize 每次请求的流水条数（Deribit 上限为 1000）
+const pageSize = 250
+
+// TransactionReader 读取账户流水，*deribit.Client 满足此接口
+type TransactionReader interface {
+	GetTransactionLog(currency string, start, end time.Time, count int, continuation int64) (*types.TransactionLog, error)
+}
+
+// Ingester 把账户流水增量导入 Store
+type Ingester struct {
+	reader   TransactionReader
+	store    *Store
+	lookback time.Duration
+	now      func() time.Time
+}
+
+// NewIngester 创建流水导入，没有历史记录的币种从 lookbackDays 天前开始导入
+func NewIngester(reader TransactionReader, store *Store, lookbackDays int) *Ingester {
+	return &Ingester{
+		reader:   reader,
+		store:    store,
+		lookback: time.Duration(lookbackDays) * 24 * time.Hour,
+		now:      time.Now,
+	}
+}
+
+// Ingest 从已保存的最新一条流水（含）开始导入到当前时间，返回新增条数
+func (i *Ingester) Ingest(currency string) (int, error) {
+	currency = strings.ToUpper(currency)
+	end := i.now()
+	start := i.store.LastTimestamp(currency)
+	if start.IsZero() {
+		start = end.Add(-i.lookback)
+	}
+
+	var entries []types.TransactionLogEntry
+	var continuation int64
+	for {
+		page, err := i.reader.GetTransactionLog(currency, start, end, pageSize, continuation)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get %s transaction log: %w", currency, err)
+		}
+		entries = append(entries, page.Logs...)
+		if page.Continuation == nil || len(page.Logs) == 0 {
+			break
+		}
+		continuation = *page.Continuation
+	}
+	return i.store.Append(entries)
+}
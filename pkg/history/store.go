@@ -0,0 +1,166 @@
+// Package history 在本地保存账户历史数据（目前为 Deribit 账户流水），供盈亏归因、对账和报告使用。
+//
+// 数据以 JSONL 文件保存，每行一条流水，只追加不修改；按流水 ID 去重，重复导入同一时间段是安全的。
+package history
+
+import (
+	"bufio"
+	"cs-projects-eth-collar/internal/types"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store 账户流水存储，并发安全
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	entries []types.TransactionLogEntry // 按时间、ID 排序
+	ids     map[int64]bool
+}
+
+// Open 打开流水文件，文件不存在时创建，已有记录全部载入内存
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, ids: make(map[int64]bool)}
+
+	entries, err := ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		if !s.ids[entry.ID] {
+			s.ids[entry.ID] = true
+			s.entries = append(s.entries, entry)
+		}
+	}
+	sortEntries(s.entries)
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+	s.file = file
+	return s, nil
+}
+
+// Path 返回流水文件路径
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append 追加尚未保存的流水，返回新增的条数
+func (s *Store) Append(entries []types.TransactionLogEntry) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var added []types.TransactionLogEntry
+	for _, entry := range entries {
+		if s.ids[entry.ID] {
+			continue
+		}
+		s.ids[entry.ID] = true
+		added = append(added, entry)
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+	sortEntries(added)
+
+	var buf []byte
+	for _, entry := range added {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal transaction %d: %w", entry.ID, err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	if _, err := s.file.Write(buf); err != nil {
+		return 0, fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync history: %w", err)
+	}
+
+	s.entries = append(s.entries, added...)
+	sortEntries(s.entries)
+	return len(added), nil
+}
+
+// Transactions 返回指定币种（为空时不限）在 [from, to) 内的流水，零值表示不限
+func (s *Store) Transactions(currency string, from, to time.Time) []types.TransactionLogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []types.TransactionLogEntry
+	for _, entry := range s.entries {
+		if currency != "" && !strings.EqualFold(entry.Currency, currency) {
+			continue
+		}
+		if (!from.IsZero() && entry.Timestamp < from.UnixMilli()) || (!to.IsZero() && entry.Timestamp >= to.UnixMilli()) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// LastTimestamp 返回指定币种最新一条流水的时间，没有记录时返回零值
+func (s *Store) LastTimestamp(currency string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if strings.EqualFold(s.entries[i].Currency, currency) {
+			return time.UnixMilli(s.entries[i].Timestamp).UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// Close 关闭流水文件
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// ReadFile 读取流水文件中的全部记录（不去重）
+func ReadFile(path string) ([]types.TransactionLogEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []types.TransactionLogEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry types.TransactionLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: failed to unmarshal transaction: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func sortEntries(entries []types.TransactionLogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Timestamp != entries[j].Timestamp {
+			return entries[i].Timestamp < entries[j].Timestamp
+		}
+		return entries[i].ID < entries[j].ID
+	})
+}
@@ -0,0 +1,72 @@
+package history
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+
+// transactions 生成 n 条每分钟一条的 ETH 成交流水和一条 BTC 流水
+func transactions(n int) []types.TransactionLogEntry {
+	var entries []types.TransactionLogEntry
+	for i := 1; i <= n; i++ {
+		entries = append(entries, types.TransactionLogEntry{
+			ID: int64(i), Timestamp: start.Add(time.Duration(i) * time.Minute).UnixMilli(), Type: "trade", Currency: "ETH",
+			InstrumentName: "ETH-PERPETUAL", Cashflow: 0.01, Commission: 0.001, Change: 0.009,
+		})
+	}
+	entries = append(entries, types.TransactionLogEntry{ID: int64(n + 1), Timestamp: start.Add(time.Hour).UnixMilli(), Type: "trade", Currency: "BTC"})
+	return entries
+}
+
+func TestIngestPagesAndDeduplicates(t *testing.T) {
+	srv := fakederibit.NewServer()
+	defer srv.Close()
+	srv.SetState(fakederibit.State{Transactions: transactions(600)})
+	client, err := deribit.NewClient(types.DeribitConfig{BaseURL: srv.URL(), APIKey: srv.ClientID, APISecret: srv.ClientSecret})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, err := Open(path)
+	require.NoError(t, err)
+	ingester := NewIngester(client, store, 30)
+	ingester.now = func() time.Time { return start.Add(24 * time.Hour) }
+
+	added, err := ingester.Ingest("eth")
+	require.NoError(t, err)
+	assert.Equal(t, 600, added)
+	assert.Equal(t, 3, srv.Calls("private/get_transaction_log"))
+	assert.Equal(t, start.Add(600*time.Minute), store.LastTimestamp("ETH"))
+	assert.True(t, store.LastTimestamp("BTC").IsZero())
+
+	// 再次导入从最新一条开始，重复的流水被跳过
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Transactions = append(state.Transactions, types.TransactionLogEntry{
+			ID: 700, Timestamp: start.Add(12 * time.Hour).UnixMilli(), Type: "transfer", Currency: "ETH", Change: -5,
+		})
+	})
+	added, err = ingester.Ingest("ETH")
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+	require.NoError(t, store.Close())
+
+	// 重新打开时载入已有记录
+	store, err = Open(path)
+	require.NoError(t, err)
+	defer store.Close()
+	entries := store.Transactions("ETH", start.Add(time.Hour), start.Add(2*time.Hour))
+	require.Len(t, entries, 60)
+	assert.Equal(t, int64(60), entries[0].ID)
+
+	added, err = store.Append(transactions(601)[599:601])
+	require.NoError(t, err)
+	assert.Equal(t, 1, added) // 只有 ID 601 是新的
+}
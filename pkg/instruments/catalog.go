@@ -0,0 +1,244 @@
+// Package instruments 缓存 Deribit 的合约信息（行权价、到期日、合约面值、最小价格变动、结算方式），
+// 供期权腿分析、展期计划等模块查询。
+//
+// 缓存在以下情况刷新：
+//   - 距上次刷新超过 refresh_interval_seconds
+//   - 缓存中最早的到期时间已过（到期合约需要移除）
+//   - 查询到缓存中没有的合约名（可能是新上市合约），两次按未命中触发的刷新至少间隔 miss_refresh_seconds
+package instruments
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// perpetualExpiry Deribit 永续合约的到期时间戳（3000-01-01）
+const perpetualExpiry = 32503708800000
+
+// Lister 获取合约列表和行情摘要，*deribit.Client 满足此接口
+type Lister interface {
+	GetInstruments(currency, kind string, expired bool) ([]types.Instrument, error)
+	GetBookSummaryByCurrency(currency, kind string) ([]types.BookSummary, error)
+}
+
+// Changes 一次刷新中新增和移除的合约名
+type Changes struct {
+	Listed  []string `json:"listed"`
+	Removed []string `json:"removed"` // 到期或下架
+}
+
+// ChainEntry 期权链中的一个合约及其行情摘要
+type ChainEntry struct {
+	Instrument types.Instrument   `json:"instrument"`
+	Book       *types.BookSummary `json:"book,omitempty"` // 没有行情时为 nil
+}
+
+// Catalog 合约信息缓存，并发安全
+type Catalog struct {
+	lister          Lister
+	currencies      []string
+	refreshInterval time.Duration
+	missInterval    time.Duration
+	logger          *zap.Logger
+	now             func() time.Time
+
+	mu          sync.Mutex
+	instruments map[string]types.Instrument
+	refreshedAt time.Time
+	missAt      time.Time
+	nextExpiry  time.Time // 缓存中最早的到期时间
+}
+
+// NewCatalog 创建合约缓存，首次查询时加载
+func NewCatalog(lister Lister, config types.InstrumentsConfig, logger *zap.Logger) *Catalog {
+	currencies := make([]string, 0, len(config.Currencies))
+	for _, currency := range config.Currencies {
+		currencies = append(currencies, strings.ToUpper(currency))
+	}
+	return &Catalog{
+		lister:          lister,
+		currencies:      currencies,
+		refreshInterval: time.Duration(config.RefreshIntervalSeconds) * time.Second,
+		missInterval:    time.Duration(config.MissRefreshSeconds) * time.Second,
+		logger:          logger,
+		now:             time.Now,
+		instruments:     make(map[string]types.Instrument),
+	}
+}
+
+// Refresh 重新加载所有币种的期货和期权合约，返回与上次相比的变化
+func (c *Catalog) Refresh() (*Changes, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refreshLocked()
+}
+
+func (c *Catalog) refreshLocked() (*Changes, error) {
+	loaded := make(map[string]types.Instrument)
+	for _, currency := range c.currencies {
+		for _, kind := range []string{"future", "option"} {
+			list, err := c.lister.GetInstruments(currency, kind, false)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %s %s instruments: %w", currency, kind, err)
+			}
+			for _, instrument := range list {
+				loaded[instrument.InstrumentName] = instrument
+			}
+		}
+	}
+
+	changes := &Changes{}
+	now := c.now()
+	var nextExpiry time.Time
+	for name, instrument := range loaded {
+		if _, ok := c.instruments[name]; !ok && !c.refreshedAt.IsZero() {
+			changes.Listed = append(changes.Listed, name)
+		}
+		if expiry := Expiry(instrument); !expiry.IsZero() && expiry.After(now) && (nextExpiry.IsZero() || expiry.Before(nextExpiry)) {
+			nextExpiry = expiry
+		}
+	}
+	for name := range c.instruments {
+		if _, ok := loaded[name]; !ok {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	sort.Strings(changes.Listed)
+	sort.Strings(changes.Removed)
+
+	c.instruments = loaded
+	c.refreshedAt = now
+	c.nextExpiry = nextExpiry
+
+	if len(changes.Listed) > 0 || len(changes.Removed) > 0 {
+		c.logger.Info("Instrument catalog changed",
+			zap.Strings("listed", changes.Listed),
+			zap.Strings("removed", changes.Removed),
+		)
+	}
+	c.logger.Debug("Instrument catalog refreshed", zap.Int("instruments", len(loaded)))
+	return changes, nil
+}
+
+// ensureFresh 缓存为空、过期或有合约到期时刷新，返回是否刷新了
+func (c *Catalog) ensureFresh() (bool, error) {
+	now := c.now()
+	stale := c.refreshedAt.IsZero() ||
+		(c.refreshInterval > 0 && now.Sub(c.refreshedAt) >= c.refreshInterval) ||
+		(!c.nextExpiry.IsZero() && !now.Before(c.nextExpiry))
+	if !stale {
+		return false, nil
+	}
+	_, err := c.refreshLocked()
+	return err == nil, err
+}
+
+// Get 按合约名查询；缓存中没有时按 miss_refresh_seconds 限速刷新一次，以发现新上市的合约
+func (c *Catalog) Get(name string) (types.Instrument, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	refreshed, err := c.ensureFresh()
+	if err != nil {
+		return types.Instrument{}, false, err
+	}
+	if instrument, ok := c.instruments[name]; ok {
+		return instrument, true, nil
+	}
+
+	now := c.now()
+	if refreshed || (!c.missAt.IsZero() && now.Sub(c.missAt) < c.missInterval) {
+		return types.Instrument{}, false, nil
+	}
+	c.missAt = now
+	if _, err := c.refreshLocked(); err != nil {
+		return types.Instrument{}, false, err
+	}
+	instrument, ok := c.instruments[name]
+	return instrument, ok, nil
+}
+
+// Instruments 返回指定币种和类型（kind 为空时不限）的合约，按到期时间和合约名排序
+func (c *Catalog) Instruments(currency, kind string) ([]types.Instrument, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.ensureFresh(); err != nil {
+		return nil, err
+	}
+	var result []types.Instrument
+	for _, instrument := range c.instruments {
+		if !strings.EqualFold(instrument.BaseCurrency, currency) || (kind != "" && instrument.Kind != kind) {
+			continue
+		}
+		result = append(result, instrument)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ExpirationTimestamp != result[j].ExpirationTimestamp {
+			return result[i].ExpirationTimestamp < result[j].ExpirationTimestamp
+		}
+		return result[i].InstrumentName < result[j].InstrumentName
+	})
+	return result, nil
+}
+
+// Expiries 返回指定币种期权的到期时间，按时间排序
+func (c *Catalog) Expiries(currency string) ([]time.Time, error) {
+	options, err := c.Instruments(currency, "option")
+	if err != nil {
+		return nil, err
+	}
+	var expiries []time.Time
+	for _, option := range options {
+		expiry := Expiry(option)
+		if len(expiries) == 0 || !expiries[len(expiries)-1].Equal(expiry) {
+			expiries = append(expiries, expiry)
+		}
+	}
+	return expiries, nil
+}
+
+// Chain 返回指定到期时间的期权链（按行权价、看涨在前排序），并附上当前行情摘要
+func (c *Catalog) Chain(currency string, expiry time.Time) ([]ChainEntry, error) {
+	options, err := c.Instruments(currency, "option")
+	if err != nil {
+		return nil, err
+	}
+	books, err := c.lister.GetBookSummaryByCurrency(strings.ToUpper(currency), "option")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get option book summaries: %w", err)
+	}
+	bookByName := make(map[string]*types.BookSummary, len(books))
+	for i := range books {
+		bookByName[books[i].InstrumentName] = &books[i]
+	}
+
+	var chain []ChainEntry
+	for _, option := range options {
+		if !Expiry(option).Equal(expiry) {
+			continue
+		}
+		chain = append(chain, ChainEntry{Instrument: option, Book: bookByName[option.InstrumentName]})
+	}
+	sort.SliceStable(chain, func(i, j int) bool {
+		if chain[i].Instrument.Strike != chain[j].Instrument.Strike {
+			return chain[i].Instrument.Strike < chain[j].Instrument.Strike
+		}
+		return chain[i].Instrument.OptionType < chain[j].Instrument.OptionType
+	})
+	return chain, nil
+}
+
+// Expiry 返回合约的到期时间（UTC），永续合约返回零值
+func Expiry(instrument types.Instrument) time.Time {
+	if instrument.ExpirationTimestamp <= 0 || instrument.ExpirationTimestamp >= perpetualExpiry {
+		return time.Time{}
+	}
+	return time.UnixMilli(instrument.ExpirationTimestamp).UTC()
+}
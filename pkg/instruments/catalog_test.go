@@ -0,0 +1,159 @@
+package instruments
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeLister 按当前时间过滤已到期合约，模拟交易所的合约列表
+type fakeLister struct {
+	now         func() time.Time
+	instruments []types.Instrument
+	books       []types.BookSummary
+	calls       int
+}
+
+func (f *fakeLister) GetInstruments(currency, kind string, expired bool) ([]types.Instrument, error) {
+	f.calls++
+	var result []types.Instrument
+	for _, instrument := range f.instruments {
+		if instrument.BaseCurrency != currency || instrument.Kind != kind {
+			continue
+		}
+		if expiry := Expiry(instrument); !expiry.IsZero() && !expiry.After(f.now()) {
+			continue
+		}
+		result = append(result, instrument)
+	}
+	return result, nil
+}
+
+func (f *fakeLister) GetBookSummaryByCurrency(currency, kind string) ([]types.BookSummary, error) {
+	return f.books, nil
+}
+
+var (
+	start   = time.Date(2024, 12, 20, 7, 45, 0, 0, time.UTC)
+	expiry1 = time.Date(2024, 12, 20, 8, 0, 0, 0, time.UTC)
+	expiry2 = time.Date(2024, 12, 27, 8, 0, 0, 0, time.UTC)
+)
+
+func option(name string, expiry time.Time, strike float64, optionType string) types.Instrument {
+	return types.Instrument{InstrumentName: name, Kind: "option", BaseCurrency: "ETH", OptionType: optionType,
+		Strike: strike, ExpirationTimestamp: expiry.UnixMilli(), ContractSize: 1, TickSize: 0.0005, SettlementPeriod: "week"}
+}
+
+func newTestCatalog() (*Catalog, *fakeLister, *time.Time) {
+	now := start
+	lister := &fakeLister{
+		now: func() time.Time { return now },
+		instruments: []types.Instrument{
+			{InstrumentName: "ETH-PERPETUAL", Kind: "future", BaseCurrency: "ETH", ExpirationTimestamp: perpetualExpiry, SettlementPeriod: "perpetual"},
+			option("ETH-20DEC24-3000-C", expiry1, 3000, "call"),
+			option("ETH-27DEC24-3500-C", expiry2, 3500, "call"),
+			option("ETH-27DEC24-2500-P", expiry2, 2500, "put"),
+			option("ETH-27DEC24-3500-P", expiry2, 3500, "put"),
+		},
+	}
+	catalog := NewCatalog(lister, types.InstrumentsConfig{Currencies: []string{"eth"}, RefreshIntervalSeconds: 3600, MissRefreshSeconds: 60}, zap.NewNop())
+	catalog.now = lister.now
+	return catalog, lister, &now
+}
+
+func TestCatalogLoadsOnFirstQuery(t *testing.T) {
+	catalog, _, _ := newTestCatalog()
+
+	options, err := catalog.Instruments("ETH", "option")
+	require.NoError(t, err)
+	require.Len(t, options, 4)
+	assert.Equal(t, "ETH-20DEC24-3000-C", options[0].InstrumentName)
+
+	expiries, err := catalog.Expiries("ETH")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{expiry1, expiry2}, expiries)
+
+	perpetual, ok, err := catalog.Get("ETH-PERPETUAL")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, Expiry(perpetual).IsZero())
+}
+
+func TestCatalogRefreshesOnExpiry(t *testing.T) {
+	catalog, lister, now := newTestCatalog()
+	_, err := catalog.Instruments("ETH", "")
+	require.NoError(t, err)
+	calls := lister.calls
+
+	// 最早的到期时间之前不刷新
+	*now = expiry1.Add(-time.Minute)
+	_, err = catalog.Instruments("ETH", "")
+	require.NoError(t, err)
+	assert.Equal(t, calls, lister.calls)
+
+	// 到期后刷新并移除到期合约
+	*now = expiry1
+	options, err := catalog.Instruments("ETH", "option")
+	require.NoError(t, err)
+	assert.Greater(t, lister.calls, calls)
+	assert.Len(t, options, 3)
+	_, ok, err := catalog.Get("ETH-20DEC24-3000-C")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestCatalogDiscoversNewListings(t *testing.T) {
+	catalog, lister, now := newTestCatalog()
+	_, err := catalog.Refresh()
+	require.NoError(t, err)
+
+	lister.instruments = append(lister.instruments, option("ETH-27DEC24-4000-C", expiry2, 4000, "call"))
+
+	// 未命中时刷新一次即可发现新合约
+	*now = start.Add(time.Minute)
+	instrument, ok, err := catalog.Get("ETH-27DEC24-4000-C")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 4000.0, instrument.Strike)
+
+	// 未命中触发的刷新受 miss_refresh_seconds 限速
+	calls := lister.calls
+	*now = start.Add(90 * time.Second)
+	_, ok, err = catalog.Get("ETH-UNKNOWN")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, _, err = catalog.Get("ETH-UNKNOWN")
+	require.NoError(t, err)
+	assert.Equal(t, calls, lister.calls)
+
+	// 定期刷新报告新上市的合约
+	lister.instruments = append(lister.instruments, option("ETH-27DEC24-4500-C", expiry2, 4500, "call"))
+	changes, err := catalog.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ETH-27DEC24-4500-C"}, changes.Listed)
+	assert.Empty(t, changes.Removed)
+}
+
+func TestCatalogChain(t *testing.T) {
+	catalog, lister, _ := newTestCatalog()
+	bid := 0.012
+	lister.books = []types.BookSummary{
+		{InstrumentName: "ETH-27DEC24-2500-P", BidPrice: &bid, MarkPrice: 0.0125, MarkIV: 61.2, OpenInterest: 1200},
+	}
+
+	chain, err := catalog.Chain("ETH", expiry2)
+	require.NoError(t, err)
+	require.Len(t, chain, 3)
+
+	// 按行权价排序，同一行权价看涨在前
+	assert.Equal(t, "ETH-27DEC24-2500-P", chain[0].Instrument.InstrumentName)
+	require.NotNil(t, chain[0].Book)
+	assert.Equal(t, 61.2, chain[0].Book.MarkIV)
+	assert.Equal(t, "ETH-27DEC24-3500-C", chain[1].Instrument.InstrumentName)
+	assert.Equal(t, "ETH-27DEC24-3500-P", chain[2].Instrument.InstrumentName)
+	assert.Nil(t, chain[2].Book)
+}
@@ -0,0 +1,140 @@
+// Package legs 读取当前持仓各条腿（期权和期货）的行情快照，
+// 用于给领口估值，以及估算展期时的成交成本（买卖价差）。
+package legs
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// Reader 读取仓位和行情，*deribit.Client 满足此接口
+type Reader interface {
+	GetPositions(currency string, kind ...string) ([]types.Position, error)
+	GetTicker(instrumentName string) (*types.Ticker, error)
+}
+
+// Sink 接收每条腿的指标，*metrics.Metrics 满足此接口
+type Sink interface {
+	UpdateLegMetrics(account string, legs []types.LegMetrics)
+}
+
+// Leg 一条持仓腿及其行情
+type Leg struct {
+	Instrument   string  `json:"instrument"`
+	Kind         string  `json:"kind"`
+	Size         float64 `json:"size"` // 有符号，空头为负
+	MarkPrice    float64 `json:"mark_price"`
+	MarkIV       float64 `json:"mark_iv,omitempty"` // 百分比，仅期权
+	BestBid      float64 `json:"best_bid"`          // 0 表示没有买单
+	BestAsk      float64 `json:"best_ask"`          // 0 表示没有卖单
+	OpenInterest float64 `json:"open_interest"`
+	IndexPrice   float64 `json:"index_price"`
+	Delta        float64 `json:"delta,omitempty"` // 单位合约的 Delta，仅期权
+}
+
+// HasQuote 买卖双边都有报价
+func (l Leg) HasQuote() bool {
+	return l.BestBid > 0 && l.BestAsk > 0
+}
+
+// Spread 买卖价差，计价单位与价格相同；没有双边报价时返回 0
+func (l Leg) Spread() float64 {
+	if !l.HasQuote() {
+		return 0
+	}
+	return l.BestAsk - l.BestBid
+}
+
+// RelativeSpread 买卖价差相对中间价的比例；没有双边报价时返回 0
+func (l Leg) RelativeSpread() float64 {
+	if !l.HasQuote() {
+		return 0
+	}
+	return l.Spread() / ((l.BestAsk + l.BestBid) / 2)
+}
+
+// Metrics 转换为指标值
+func (l Leg) Metrics() types.LegMetrics {
+	return types.LegMetrics{
+		Instrument:     l.Instrument,
+		Kind:           l.Kind,
+		Size:           l.Size,
+		MarkPrice:      l.MarkPrice,
+		MarkIV:         l.MarkIV,
+		OpenInterest:   l.OpenInterest,
+		HasQuote:       l.HasQuote(),
+		Spread:         l.Spread(),
+		RelativeSpread: l.RelativeSpread(),
+	}
+}
+
+// Tracker 每个监控周期读取持仓腿的行情并更新指标
+type Tracker struct {
+	currency string
+	reader   Reader
+	metrics  Sink
+	logger   *zap.Logger
+}
+
+// NewTracker 创建持仓腿行情跟踪，metrics 可为 nil
+func NewTracker(currency string, reader Reader, metrics Sink, logger *zap.Logger) *Tracker {
+	return &Tracker{
+		currency: currency,
+		reader:   reader,
+		metrics:  metrics,
+		logger:   logger,
+	}
+}
+
+// Evaluate 读取所有非零仓位的行情；单个合约的行情读取失败只记录日志
+func (t *Tracker) Evaluate(account string) ([]Leg, error) {
+	positions, err := t.reader.GetPositions(t.currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	var legs []Leg
+	for _, position := range positions {
+		if position.Size == 0 || (position.Kind != "option" && position.Kind != "future") {
+			continue
+		}
+		ticker, err := t.reader.GetTicker(position.InstrumentName)
+		if err != nil {
+			t.logger.Warn("Failed to get ticker", zap.String("instrument", position.InstrumentName), zap.Error(err))
+			continue
+		}
+
+		size := position.Size
+		if position.Direction == "sell" && size > 0 {
+			size = -size
+		}
+		leg := Leg{
+			Instrument:   position.InstrumentName,
+			Kind:         position.Kind,
+			Size:         size,
+			MarkPrice:    ticker.MarkPrice,
+			MarkIV:       ticker.MarkIV,
+			BestBid:      ticker.BestBidPrice,
+			BestAsk:      ticker.BestAskPrice,
+			OpenInterest: ticker.OpenInterest,
+			IndexPrice:   ticker.IndexPrice,
+		}
+		if ticker.Greeks != nil {
+			leg.Delta = ticker.Greeks.Delta
+		}
+		legs = append(legs, leg)
+	}
+	sort.Slice(legs, func(i, j int) bool { return legs[i].Instrument < legs[j].Instrument })
+
+	if t.metrics != nil {
+		values := make([]types.LegMetrics, 0, len(legs))
+		for _, leg := range legs {
+			values = append(values, leg.Metrics())
+		}
+		t.metrics.UpdateLegMetrics(account, values)
+	}
+	return legs, nil
+}
@@ -0,0 +1,89 @@
+package legs_test
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"cs-projects-eth-collar/pkg/legs"
+	"cs-projects-eth-collar/pkg/metrics"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func collarState() fakederibit.State {
+	ticker := func(mark, iv, bid, ask, oi float64) types.OrderBook {
+		return types.OrderBook{Ticker: types.Ticker{MarkPrice: mark, MarkIV: iv, BestBidPrice: bid, BestAskPrice: ask,
+			OpenInterest: oi, IndexPrice: 3000, Greeks: &types.Greeks{Delta: -0.25}}}
+	}
+	return fakederibit.State{
+		Positions: []types.Position{
+			{InstrumentName: "ETH-27DEC24-2500-P", Kind: "option", Direction: "buy", Size: 100},
+			{InstrumentName: "ETH-27DEC24-4000-C", Kind: "option", Direction: "sell", Size: -100},
+			{InstrumentName: "ETH-PERPETUAL", Kind: "future", Direction: "sell", Size: -30000},
+			{InstrumentName: "ETH-27DEC24-5000-C", Kind: "option", Direction: "zero", Size: 0},
+		},
+		OrderBooks: map[string]types.OrderBook{
+			"ETH-27DEC24-2500-P": ticker(0.0125, 62.5, 0.012, 0.013, 1500),
+			"ETH-27DEC24-4000-C": ticker(0.02, 55, 0, 0.021, 900), // 没有买单
+			"ETH-PERPETUAL":      {Ticker: types.Ticker{MarkPrice: 3001, BestBidPrice: 3000.5, BestAskPrice: 3001, OpenInterest: 120000000}},
+		},
+	}
+}
+
+func TestTrackerEvaluate(t *testing.T) {
+	srv := fakederibit.NewServer()
+	defer srv.Close()
+	srv.SetState(collarState())
+	client, err := deribit.NewClient(types.DeribitConfig{BaseURL: srv.URL(), APIKey: srv.ClientID, APISecret: srv.ClientSecret})
+	require.NoError(t, err)
+	m := metrics.NewMetrics(types.PrometheusConfig{}, zap.NewNop())
+
+	tracker := legs.NewTracker("ETH", client, m, zap.NewNop())
+	result, err := tracker.Evaluate("desk")
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+
+	put := result[0]
+	assert.Equal(t, "ETH-27DEC24-2500-P", put.Instrument)
+	assert.Equal(t, 100.0, put.Size)
+	assert.InDelta(t, 0.001, put.Spread(), 1e-12)
+	assert.InDelta(t, 0.08, put.RelativeSpread(), 1e-12)
+	assert.Equal(t, -0.25, put.Delta)
+	assert.Equal(t, -100.0, result[1].Size)
+	assert.False(t, result[1].HasQuote())
+
+	labels := prometheus.Labels{"account": "desk", "instrument": "ETH-27DEC24-2500-P", "kind": "option"}
+	assert.Equal(t, 62.5, testutil.ToFloat64(m.LegMarkIV.With(labels)))
+	assert.Equal(t, 1500.0, testutil.ToFloat64(m.LegOpenInterest.With(labels)))
+	assert.InDelta(t, 0.001, testutil.ToFloat64(m.LegBidAskSpread.With(labels)), 1e-12)
+	// 单边报价的腿不输出价差，期货不输出隐含波动率
+	assert.Equal(t, 2, testutil.CollectAndCount(m.LegBidAskSpread))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.LegMarkIV))
+
+	// 平仓后不再输出该腿的指标
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Positions = state.Positions[:1]
+	})
+	_, err = tracker.Evaluate("desk")
+	require.NoError(t, err)
+	assert.Equal(t, 1, testutil.CollectAndCount(m.LegMarkPrice))
+}
+
+func TestTrackerSkipsMissingTicker(t *testing.T) {
+	srv := fakederibit.NewServer()
+	defer srv.Close()
+	state := collarState()
+	delete(state.OrderBooks, "ETH-PERPETUAL")
+	srv.SetState(state)
+	client, err := deribit.NewClient(types.DeribitConfig{BaseURL: srv.URL(), APIKey: srv.ClientID, APISecret: srv.ClientSecret})
+	require.NoError(t, err)
+
+	result, err := legs.NewTracker("ETH", client, nil, zap.NewNop()).Evaluate("desk")
+	require.NoError(t, err)
+	assert.Len(t, result, 2)
+}
@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"cs-projects-eth-collar/pkg/admin"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Levels 全局和各模块的日志级别，可在运行时调整
+type Levels struct {
+	mu      sync.RWMutex
+	global  zapcore.Level
+	modules map[string]zapcore.Level
+}
+
+// LevelsView 当前日志级别，管理接口返回此结构
+type LevelsView struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+// NewLevels 解析全局级别和模块级别，全局级别为空时为 info
+func NewLevels(global string, modules map[string]string) (*Levels, error) {
+	l := &Levels{modules: make(map[string]zapcore.Level)}
+	if err := l.Set("", global); err != nil {
+		return nil, err
+	}
+	for module, level := range modules {
+		if err := l.Set(module, level); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Set 设置模块的级别，module 为空时设置全局级别；设置模块级别为空时删除该模块的设置
+func (l *Levels) Set(module, level string) error {
+	if module != "" && level == "" {
+		l.mu.Lock()
+		delete(l.modules, module)
+		l.mu.Unlock()
+		return nil
+	}
+	if level == "" {
+		level = "info"
+	}
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q for module %q: %w", level, module, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if module == "" {
+		l.global = parsed
+	} else {
+		l.modules[module] = parsed
+	}
+	return nil
+}
+
+// Level 返回 logger 名称对应的级别：先找完全匹配的模块，再逐级去掉 "." 后缀，最后为全局级别
+func (l *Levels) Level(name string) zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for name != "" {
+		if level, ok := l.modules[name]; ok {
+			return level
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return l.global
+}
+
+// Enabled 任一模块或全局级别允许时返回 true，具体 logger 是否输出由 Level 判断
+func (l *Levels) Enabled(level zapcore.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.global.Enabled(level) {
+		return true
+	}
+	for _, moduleLevel := range l.modules {
+		if moduleLevel.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// View 返回当前级别
+func (l *Levels) View() LevelsView {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	view := LevelsView{Level: l.global.String(), Modules: make(map[string]string, len(l.modules))}
+	for module, level := range l.modules {
+		view.Modules[module] = level.String()
+	}
+	return view
+}
+
+// RegisterHandlers 注册日志级别的管理接口
+//
+//	GET /log/level 查看全局和各模块级别
+//	PUT /log/level 调整级别，请求体 {"module": "deribit", "level": "debug"}；module 为空时调整全局级别，level 为空时删除模块设置
+func (l *Levels) RegisterHandlers(server *admin.Server, logger *zap.Logger) {
+	server.Handle("GET /log/level", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, l.View())
+	})
+	server.Handle("PUT /log/level", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Module string `json:"module"`
+			Level  string `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			admin.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+		if err := l.Set(req.Module, req.Level); err != nil {
+			admin.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		logger.Info("Log level changed",
+			zap.String("operator", admin.Operator(r)),
+			zap.String("module", req.Module),
+			zap.String("level", req.Level),
+		)
+		admin.WriteJSON(w, http.StatusOK, l.View())
+	})
+}
+
+// moduleCore 按 logger 名称对应的级别过滤日志
+type moduleCore struct {
+	zapcore.Core
+	levels *Levels
+}
+
+func (c *moduleCore) Enabled(level zapcore.Level) bool {
+	return c.levels.Enabled(level)
+}
+
+func (c *moduleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &moduleCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *moduleCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.Level(entry.LoggerName).Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
@@ -1,38 +1,119 @@
+// Package logger 创建项目使用的 zap 日志：标准输出加可轮转的日志文件，支持按模块设置级别、
+// 重复日志采样，以及通过管理接口在运行时调整级别。
+//
+// 模块名即 logger 名称（zap.Logger.Named），main 为每个包创建 Named 子 logger，
+// 如 "deribit"、"monitor"；"monitor.hedge" 未单独设置时使用 "monitor" 的级别。
 package logger
 
 import (
 	"cs-projects-eth-collar/internal/types"
+	"fmt"
+	"os"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-func NewLogger(config types.LogConfig) (*zap.Logger, error) {
-	var level zapcore.Level
-	switch config.Level {
-	case "debug":
-		level = zapcore.DebugLevel
-	case "info":
-		level = zapcore.InfoLevel
-	case "warn":
-		level = zapcore.WarnLevel
-	case "error":
-		level = zapcore.ErrorLevel
+// 标准输出格式
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// 按时间轮转的间隔
+const (
+	RotateHourly = "hourly"
+	RotateDaily  = "daily"
+)
+
+// NewLogger 按配置创建日志，返回的 Levels 用于运行时调整级别
+func NewLogger(config types.LogConfig) (*zap.Logger, *Levels, error) {
+	levels, err := NewLevels(config.Level, config.Levels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	var stdoutEncoder zapcore.Encoder
+	switch config.Format {
+	case FormatJSON, "":
+		stdoutEncoder = zapcore.NewJSONEncoder(encoderConfig)
+	case FormatConsole:
+		consoleConfig := encoderConfig
+		consoleConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		stdoutEncoder = zapcore.NewConsoleEncoder(consoleConfig)
 	default:
-		level = zapcore.InfoLevel
+		return nil, nil, fmt.Errorf("unknown log format %q, use json or console", config.Format)
+	}
+
+	// 级别由 Levels 统一判断，各输出接受所有级别
+	cores := []zapcore.Core{zapcore.NewCore(stdoutEncoder, zapcore.Lock(os.Stdout), zapcore.DebugLevel)}
+	if config.File != "" {
+		file, err := newFileWriter(config.File, config.Rotation)
+		if err != nil {
+			return nil, nil, err
+		}
+		// 日志文件始终为 JSON，便于采集
+		cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), file, zapcore.DebugLevel))
+	}
+
+	return newLogger(zapcore.NewTee(cores...), levels, config.Sampling), levels, nil
+}
+
+// newLogger 在输出之上加采样和模块级别过滤，先按级别过滤再采样
+func newLogger(core zapcore.Core, levels *Levels, sampling types.LogSamplingConfig) *zap.Logger {
+	if sampling.Enabled {
+		core = zapcore.NewSamplerWithOptions(core, time.Duration(sampling.TickSeconds)*time.Second, sampling.Initial, sampling.Thereafter)
 	}
+	return zap.New(&moduleCore{Core: core, levels: levels},
+		zap.AddCaller(),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+	)
+}
 
-	cfg := zap.Config{
-		Level:            zap.NewAtomicLevelAt(level),
-		Development:      false,
-		Encoding:         "json",
-		EncoderConfig:    zap.NewProductionEncoderConfig(),
-		OutputPaths:      []string{"stdout", config.File},
-		ErrorOutputPaths: []string{"stderr"},
+// newFileWriter 打开按大小轮转的日志文件，配置了 Interval 时另外按 UTC 整点轮转
+func newFileWriter(file string, config types.LogRotationConfig) (zapcore.WriteSyncer, error) {
+	var interval time.Duration
+	switch config.Interval {
+	case "":
+	case RotateHourly:
+		interval = time.Hour
+	case RotateDaily:
+		interval = 24 * time.Hour
+	default:
+		return nil, fmt.Errorf("unknown log rotation interval %q, use hourly or daily", config.Interval)
 	}
 
-	cfg.EncoderConfig.TimeKey = "timestamp"
-	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	writer := &lumberjack.Logger{
+		Filename:   file,
+		MaxSize:    config.MaxSizeMB,
+		MaxBackups: config.MaxBackups,
+		MaxAge:     config.MaxAgeDays,
+		Compress:   config.Compress,
+	}
+	// 提前打开文件，路径不可写时启动即报错
+	if _, err := writer.Write(nil); err != nil {
+		return nil, fmt.Errorf("failed to open log file %s: %w", file, err)
+	}
+	if interval > 0 {
+		go rotateEvery(writer, interval)
+	}
+	return zapcore.AddSync(writer), nil
+}
 
-	return cfg.Build()
+// rotateEvery 在每个 UTC 整点（或零点）轮转日志文件
+func rotateEvery(writer *lumberjack.Logger, interval time.Duration) {
+	for {
+		now := time.Now().UTC()
+		time.Sleep(now.Truncate(interval).Add(interval).Sub(now))
+		if err := writer.Rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to rotate log file: %v\n", err)
+		}
+	}
 }
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestModuleLevels(t *testing.T) {
+	levels, err := NewLevels("info", map[string]string{"deribit": "debug", "monitor": "warn"})
+	require.NoError(t, err)
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := newLogger(core, levels, types.LogSamplingConfig{})
+
+	log.Named("deribit").Debug("request")
+	log.Named("deribit.funding").Debug("funding request") // 继承 deribit
+	log.Named("monitor").Info("cycle")                    // 低于 warn，丢弃
+	log.Named("monitor.hedge").Warn("delta outside band")
+	log.Named("notify").Debug("webhook") // 全局 info，丢弃
+	log.Info("started")
+
+	var messages []string
+	for _, entry := range logs.TakeAll() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"request", "funding request", "delta outside band", "started"}, messages)
+
+	// 运行时调整：删除 deribit 设置后回到全局级别，全局调到 debug
+	require.NoError(t, levels.Set("deribit", ""))
+	log.Named("deribit").Debug("request")
+	assert.Zero(t, logs.Len())
+	require.NoError(t, levels.Set("", "debug"))
+	log.Named("deribit").Debug("request")
+	assert.Equal(t, 1, logs.Len())
+
+	assert.Equal(t, LevelsView{Level: "debug", Modules: map[string]string{"monitor": "warn"}}, levels.View())
+	assert.ErrorContains(t, levels.Set("monitor", "verbose"), `invalid log level "verbose"`)
+}
+
+func TestSampling(t *testing.T) {
+	levels, err := NewLevels("info", nil)
+	require.NoError(t, err)
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := newLogger(core, levels, types.LogSamplingConfig{Enabled: true, TickSeconds: 60, Initial: 3, Thereafter: 10})
+
+	for i := 0; i < 25; i++ {
+		log.Error("request failed")
+	}
+	log.Error("other error")
+
+	// 前 3 条，之后第 10、20 条
+	assert.Equal(t, 5, logs.FilterMessage("request failed").Len())
+	assert.Equal(t, 1, logs.FilterMessage("other error").Len())
+}
+
+func TestNewLogger(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "monitor.log")
+	log, levels, err := NewLogger(types.LogConfig{Level: "warn", File: file, Format: FormatConsole, Levels: map[string]string{"deribit": "debug"}})
+	require.NoError(t, err)
+	assert.Equal(t, "warn", levels.View().Level)
+
+	log.Named("deribit").Debug("request")
+	log.Info("dropped")
+	_ = log.Sync() // 标准输出为终端或管道时 Sync 可能返回错误
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"logger":"deribit"`)
+	assert.NotContains(t, string(data), "dropped")
+
+	_, _, err = NewLogger(types.LogConfig{Format: "xml"})
+	assert.ErrorContains(t, err, `unknown log format "xml"`)
+	_, _, err = NewLogger(types.LogConfig{File: file, Rotation: types.LogRotationConfig{Interval: "weekly"}})
+	assert.ErrorContains(t, err, `unknown log rotation interval "weekly"`)
+}
@@ -1,11 +1,17 @@
 package metrics
 
 import (
+	"context"
 	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/account"
+	"cs-projects-eth-collar/pkg/tracing"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/push"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -22,10 +28,89 @@ type Metrics struct {
 	CollectionTimestamp    *prometheus.GaugeVec // 指标收集时间戳
 	RequiredETHAmount      *prometheus.GaugeVec // 需要补充的ETH数量
 
+	// 账户级别汇总指标（USD）
+	AccountEquityUSD            *prometheus.GaugeVec // 账户权益
+	AccountInitialMarginUSD     *prometheus.GaugeVec // 账户初始保证金
+	AccountMaintenanceMarginUSD *prometheus.GaugeVec // 账户维持保证金
+
+	// 各币种权益和保证金（USD）
+	CurrencyEquityUSD            *prometheus.GaugeVec // 币种权益
+	CurrencyInitialMarginUSD     *prometheus.GaugeVec // 币种初始保证金
+	CurrencyMaintenanceMarginUSD *prometheus.GaugeVec // 币种维持保证金
+	CurrencyMMRatio              *prometheus.GaugeVec // 币种维持保证金比率（以币种本身计价）
+	MissingIndexPrices           *prometheus.GaugeVec // 有余额但缺少指数价格的币种数量
+
+	// 组合希腊值指标
+	OptionsDelta         *prometheus.GaugeVec // 期权 Delta
+	OptionsGamma         *prometheus.GaugeVec // 期权 Gamma
+	OptionsVega          *prometheus.GaugeVec // 期权 Vega
+	OptionsTheta         *prometheus.GaugeVec // 期权 Theta
+	DeltaTotal           *prometheus.GaugeVec // 总 Delta（期权+期货）
+	OptionsGammaByExpiry *prometheus.GaugeVec // 按到期日的期权 Gamma
+	OptionsVegaByExpiry  *prometheus.GaugeVec // 按到期日的期权 Vega
+	OptionsThetaByExpiry *prometheus.GaugeVec // 按到期日的期权 Theta
+	DeltaTotalByExpiry   *prometheus.GaugeVec // 按到期日的总 Delta
+
+	// Delta 对冲建议指标（仅建议，不下单）
+	HedgeCurrentDelta      *prometheus.GaugeVec // 当前总 Delta
+	HedgeTargetDelta       *prometheus.GaugeVec // 目标 Delta
+	HedgeDeltaDeviation    *prometheus.GaugeVec // 当前 Delta 与目标的偏离
+	HedgeRecommendedAmount *prometheus.GaugeVec // 建议对冲数量（合约单位，正数买入，负数卖出）
+
+	// 持仓腿行情指标
+	LegPositionSize      *prometheus.GaugeVec // 仓位数量（有符号）
+	LegMarkPrice         *prometheus.GaugeVec // 标记价格
+	LegMarkIV            *prometheus.GaugeVec // 标记隐含波动率（仅期权）
+	LegBidAskSpread      *prometheus.GaugeVec // 买卖价差（仅双边有报价时）
+	LegBidAskSpreadRatio *prometheus.GaugeVec // 买卖价差 / 中间价
+	LegOpenInterest      *prometheus.GaugeVec // 持仓量
+
+	// 持仓到期指标
+	ExpirySeconds   *prometheus.GaugeVec // 距到期的秒数
+	ExpiryPositions *prometheus.GaugeVec // 该到期日的持仓合约数
+
+	// 当日（UTC）盈亏归因指标
+	PnLByType       *prometheus.GaugeVec // 按类别
+	PnLByInstrument *prometheus.GaugeVec // 按合约（不含转账）
+	PnLDaily        *prometheus.GaugeVec // 合计（不含转账）
+
+	// 永续合约资金费指标（成本以 USD 计，正数为支付）
+	FundingRate8h            *prometheus.GaugeVec // 最新 8 小时资金费率
+	FundingRateAnnualized    *prometheus.GaugeVec // 年化资金费率
+	FundingPositionUSD       *prometheus.GaugeVec // 永续仓位名义价值（有符号）
+	FundingRealizedUSD24h    *prometheus.GaugeVec // 过去 24 小时资金费成本
+	FundingProjectedDailyUSD *prometheus.GaugeVec // 预计每日资金费成本
+	FundingCostAnnualizedUSD *prometheus.GaugeVec // 预计年化资金费成本
+	FundingMaxDailyCostUSD   *prometheus.GaugeVec // 每日成本告警阈值（0 表示不告警）
+
+	// 补充 ETH 请求对账指标
+	TopUpRequests       *prometheus.GaugeVec // 各状态的请求数（含保留期内已关闭的请求）
+	TopUpOutstanding    *prometheus.GaugeVec // 未关闭请求尚未到账的数量
+	TopUpOverdueSeconds *prometheus.GaugeVec // 逾期最久的请求已逾期的秒数
+	AccountFlows24h     *prometheus.GaugeVec // 过去 24 小时的充值、提现和划转数量
+
+	// 跨交易所汇总指标，venue="all" 为合计
+	VenueUp                *prometheus.GaugeVec // 交易所数据是否读取成功
+	VenueNetDelta          *prometheus.GaugeVec // 抵押品 + 衍生品的总敞口（币）
+	VenueDerivativesDelta  *prometheus.GaugeVec // 期货和期权的 Delta（币）
+	VenueEquityUSD         *prometheus.GaugeVec // 权益
+	VenueMaintenanceMargin *prometheus.GaugeVec // 维持保证金（USD）
+	VenueMarginHeadroomUSD *prometheus.GaugeVec // 保证金余量：权益 - 维持保证金
+
+	// 监控程序自身的指标
+	APIRequestDuration   *prometheus.HistogramVec // Deribit 请求耗时（按方法和结果）
+	APIErrors            *prometheus.CounterVec   // Deribit 错误（按方法和错误码）
+	AuthRefreshes        *prometheus.CounterVec   // 访问令牌刷新次数（按结果）
+	CycleDuration        *prometheus.HistogramVec // 监控周期耗时（按结果）
+	LastSuccessTimestamp *prometheus.GaugeVec     // 最近一次成功完成监控周期的时间
+	PushFailures         *prometheus.CounterVec   // 推送 PushGateway 失败次数
+	StoppedTimestamp     *prometheus.GaugeVec     // 监控程序退出的时间，仅在退出时推送
+
 	// 配置和推送相关
-	config   types.PrometheusConfig // Prometheus 配置
-	registry *prometheus.Registry   // 指标注册器
-	logger   *zap.Logger            // 日志记录器
+	config      types.PrometheusConfig // Prometheus 配置
+	registry    *prometheus.Registry   // 指标注册器
+	definitions []Definition           // 已注册指标的定义
+	logger      *zap.Logger            // 日志记录器
 }
 
 // NewMetrics 创建新的 Metrics 实例，初始化所有 Prometheus 指标
@@ -46,84 +131,120 @@ func NewMetrics(config types.PrometheusConfig, logger *zap.Logger) *Metrics {
 	return m
 }
 
-// createMetrics 创建指标 (使用自定义注册器推送到 PushGateway)
-func (m *Metrics) createMetrics() {
-	m.MaintenanceMarginRatio = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "deribit_maintenance_margin_ratio",
-			Help: "Deribit账户维持保证金比率",
-		},
-		[]string{"currency", "account"},
-	)
-	m.ETHEquity = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "deribit_eth_equity",
-			Help: "Deribit账户ETH权益数量",
-		},
-		[]string{"currency", "account"},
-	)
-	m.ETHEquityUSD = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "deribit_eth_equity_usd",
-			Help: "Deribit账户ETH权益美元价值",
-		},
-		[]string{"currency", "account"},
-	)
-	m.TotalEquity = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "deribit_total_equity",
-			Help: "Deribit账户总权益",
-		},
-		[]string{"currency", "account"},
-	)
-	m.MaintenanceMargin = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "deribit_maintenance_margin",
-			Help: "Deribit账户维持保证金",
-		},
-		[]string{"currency", "account"},
-	)
-	m.MarginBalance = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "deribit_margin_balance",
-			Help: "Deribit账户保证金余额",
-		},
-		[]string{"currency", "account"},
-	)
-	m.ETHPriceUSD = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "deribit_eth_price_usd",
-			Help: "ETH现货价格(美元)",
-		},
-		[]string{"currency", "account"},
-	)
-	m.CollectionTimestamp = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "deribit_metrics_collection_timestamp",
-			Help: "指标收集的时间戳",
-		},
-		[]string{"currency", "account"},
-	)
-	m.RequiredETHAmount = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "deribit_required_eth_amount",
-			Help: "需要补充的ETH数量（触发告警时）",
-		},
-		[]string{"currency", "account"},
-	)
+// 指标类型
+const (
+	TypeGauge     = "gauge"
+	TypeCounter   = "counter"
+	TypeHistogram = "histogram"
+)
 
-	// 注册所有指标到自定义注册器
-	m.registry.MustRegister(
-		m.MaintenanceMarginRatio,
-		m.ETHEquity,
-		m.ETHEquityUSD,
-		m.TotalEquity,
-		m.MaintenanceMargin,
-		m.MarginBalance,
-		m.ETHPriceUSD,
-		m.CollectionTimestamp,
-		m.RequiredETHAmount,
-	)
+// Definition 一个指标的名称、类型、说明和标签，用于生成仪表盘和告警规则
+type Definition struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Help   string   `json:"help"`
+	Labels []string `json:"labels"`
+}
+
+// Definitions 已注册的所有指标，按创建顺序
+func (m *Metrics) Definitions() []Definition {
+	return append([]Definition(nil), m.definitions...)
+}
+
+// gauge 创建 Gauge 指标，注册到自定义注册器并记录定义
+func (m *Metrics) gauge(name, help string, labels []string) *prometheus.GaugeVec {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)
+	m.register(gauge, Definition{Name: name, Type: TypeGauge, Help: help, Labels: labels})
+	return gauge
+}
+
+// counter 创建 Counter 指标，注册到自定义注册器并记录定义
+func (m *Metrics) counter(name, help string, labels []string) *prometheus.CounterVec {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	m.register(counter, Definition{Name: name, Type: TypeCounter, Help: help, Labels: labels})
+	return counter
+}
+
+// histogram 创建 Histogram 指标，注册到自定义注册器并记录定义
+func (m *Metrics) histogram(name, help string, buckets []float64, labels []string) *prometheus.HistogramVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+	m.register(histogram, Definition{Name: name, Type: TypeHistogram, Help: help, Labels: labels})
+	return histogram
+}
+
+func (m *Metrics) register(collector prometheus.Collector, def Definition) {
+	m.registry.MustRegister(collector)
+	m.definitions = append(m.definitions, def)
+}
+
+// createMetrics 创建指标并注册到自定义注册器 (使用自定义注册器推送到 PushGateway)
+func (m *Metrics) createMetrics() {
+	m.MaintenanceMarginRatio = m.gauge("deribit_maintenance_margin_ratio", "Deribit账户维持保证金比率", []string{"currency", "account"})
+	m.ETHEquity = m.gauge("deribit_eth_equity", "Deribit账户ETH权益数量", []string{"currency", "account"})
+	m.ETHEquityUSD = m.gauge("deribit_eth_equity_usd", "Deribit账户ETH权益美元价值", []string{"currency", "account"})
+	m.TotalEquity = m.gauge("deribit_total_equity", "Deribit账户总权益", []string{"currency", "account"})
+	m.MaintenanceMargin = m.gauge("deribit_maintenance_margin", "Deribit账户维持保证金", []string{"currency", "account"})
+	m.MarginBalance = m.gauge("deribit_margin_balance", "Deribit账户保证金余额", []string{"currency", "account"})
+	m.ETHPriceUSD = m.gauge("deribit_eth_price_usd", "ETH现货价格(美元)", []string{"currency", "account"})
+	m.CollectionTimestamp = m.gauge("deribit_metrics_collection_timestamp", "指标收集的时间戳", []string{"currency", "account"})
+	m.RequiredETHAmount = m.gauge("deribit_required_eth_amount", "需要补充的ETH数量（触发告警时）", []string{"currency", "account"})
+	m.AccountEquityUSD = m.gauge("deribit_account_equity_usd", "Deribit账户级别权益(美元)", []string{"account", "mode", "margin_model"})
+	m.AccountInitialMarginUSD = m.gauge("deribit_account_initial_margin_usd", "Deribit账户级别初始保证金(美元)", []string{"account", "mode", "margin_model"})
+	m.AccountMaintenanceMarginUSD = m.gauge("deribit_account_maintenance_margin_usd", "Deribit账户级别维持保证金(美元)", []string{"account", "mode", "margin_model"})
+	m.CurrencyEquityUSD = m.gauge("deribit_currency_equity_usd", "Deribit各币种权益(美元)", []string{"currency", "account"})
+	m.CurrencyInitialMarginUSD = m.gauge("deribit_currency_initial_margin_usd", "Deribit各币种初始保证金(美元)", []string{"currency", "account"})
+	m.CurrencyMaintenanceMarginUSD = m.gauge("deribit_currency_maintenance_margin_usd", "Deribit各币种维持保证金(美元)", []string{"currency", "account"})
+	m.CurrencyMMRatio = m.gauge("deribit_currency_maintenance_margin_ratio", "Deribit各币种维持保证金比率", []string{"currency", "account"})
+	m.MissingIndexPrices = m.gauge("deribit_missing_index_prices", "有余额但缺少指数价格、未计入USD汇总的币种数量", []string{"account"})
+	m.OptionsDelta = m.gauge("deribit_options_delta", "Deribit账户期权Delta", []string{"currency", "account"})
+	m.OptionsGamma = m.gauge("deribit_options_gamma", "Deribit账户期权Gamma", []string{"currency", "account"})
+	m.OptionsVega = m.gauge("deribit_options_vega", "Deribit账户期权Vega", []string{"currency", "account"})
+	m.OptionsTheta = m.gauge("deribit_options_theta", "Deribit账户期权Theta", []string{"currency", "account"})
+	m.DeltaTotal = m.gauge("deribit_delta_total", "Deribit账户总Delta（期权+期货）", []string{"currency", "account"})
+	m.OptionsGammaByExpiry = m.gauge("deribit_options_gamma_by_expiry", "Deribit账户按到期日的期权Gamma", []string{"currency", "account", "expiry"})
+	m.OptionsVegaByExpiry = m.gauge("deribit_options_vega_by_expiry", "Deribit账户按到期日的期权Vega", []string{"currency", "account", "expiry"})
+	m.OptionsThetaByExpiry = m.gauge("deribit_options_theta_by_expiry", "Deribit账户按到期日的期权Theta", []string{"currency", "account", "expiry"})
+	m.DeltaTotalByExpiry = m.gauge("deribit_delta_total_by_expiry", "Deribit账户按到期日的总Delta", []string{"currency", "account", "expiry"})
+	m.HedgeCurrentDelta = m.gauge("deribit_hedge_current_delta", "对冲模块观测到的当前总Delta", []string{"currency", "account", "instrument"})
+	m.HedgeTargetDelta = m.gauge("deribit_hedge_target_delta", "对冲模块配置的目标Delta", []string{"currency", "account", "instrument"})
+	m.HedgeDeltaDeviation = m.gauge("deribit_hedge_delta_deviation", "当前Delta与目标Delta的偏离", []string{"currency", "account", "instrument"})
+	m.HedgeRecommendedAmount = m.gauge("deribit_hedge_recommended_amount", "建议对冲数量（合约单位，正数买入，负数卖出，仅建议）", []string{"currency", "account", "instrument"})
+	m.LegPositionSize = m.gauge("deribit_leg_position_size", "持仓腿仓位数量（有符号，空头为负）", []string{"account", "instrument", "kind"})
+	m.LegMarkPrice = m.gauge("deribit_leg_mark_price", "持仓腿标记价格（期权以标的币种计）", []string{"account", "instrument", "kind"})
+	m.LegMarkIV = m.gauge("deribit_leg_mark_iv", "持仓腿标记隐含波动率（百分比，仅期权）", []string{"account", "instrument", "kind"})
+	m.LegBidAskSpread = m.gauge("deribit_leg_bid_ask_spread", "持仓腿买卖价差（仅双边有报价时）", []string{"account", "instrument", "kind"})
+	m.LegBidAskSpreadRatio = m.gauge("deribit_leg_bid_ask_spread_ratio", "持仓腿买卖价差相对中间价的比例", []string{"account", "instrument", "kind"})
+	m.LegOpenInterest = m.gauge("deribit_leg_open_interest", "持仓腿合约持仓量", []string{"account", "instrument", "kind"})
+	m.ExpirySeconds = m.gauge("deribit_expiry_seconds", "持仓合约距到期的秒数（按到期日）", []string{"account", "currency", "expiry"})
+	m.ExpiryPositions = m.gauge("deribit_expiry_positions", "按到期日的持仓合约数", []string{"account", "currency", "expiry"})
+	m.PnLByType = m.gauge("deribit_pnl_attribution", "当日（UTC）按类别的盈亏归因（trades/settlement/funding/fees/deliveries/transfers/other，币计价）", []string{"account", "currency", "type"})
+	m.PnLByInstrument = m.gauge("deribit_pnl_attribution_by_instrument", "当日（UTC）按合约的盈亏归因（不含转账，币计价）", []string{"account", "currency", "instrument"})
+	m.PnLDaily = m.gauge("deribit_pnl_daily", "当日（UTC）盈亏合计（不含转账，币计价）", []string{"account", "currency"})
+	fundingLabels := []string{"account", "currency", "instrument"}
+	m.FundingRate8h = m.gauge("deribit_funding_rate_8h", "永续合约最新 8 小时资金费率", fundingLabels)
+	m.FundingRateAnnualized = m.gauge("deribit_funding_rate_annualized", "永续合约年化资金费率（8 小时费率 × 3 × 365）", fundingLabels)
+	m.FundingPositionUSD = m.gauge("deribit_funding_position_usd", "永续合约仓位名义价值（USD，多头为正）", fundingLabels)
+	m.FundingRealizedUSD24h = m.gauge("deribit_funding_realized_usd_24h", "过去 24 小时资金费成本（USD，正数为支付）；有交易日志时为实际结算金额，否则按当前仓位估算", fundingLabels)
+	m.FundingProjectedDailyUSD = m.gauge("deribit_funding_projected_daily_usd", "按最新费率预计的每日资金费成本（USD，正数为支付）", fundingLabels)
+	m.FundingCostAnnualizedUSD = m.gauge("deribit_funding_cost_annualized_usd", "按最新费率预计的年化资金费成本（USD，正数为支付）", fundingLabels)
+	m.FundingMaxDailyCostUSD = m.gauge("deribit_funding_max_daily_cost_usd", "每日资金费成本告警阈值（USD，0 表示不告警）", fundingLabels)
+	m.TopUpRequests = m.gauge("deribit_topup_requests", "补充 ETH 请求数（按状态，含保留期内已关闭的请求）", []string{"account", "currency", "status"})
+	m.TopUpOutstanding = m.gauge("deribit_topup_outstanding", "未关闭的补充 ETH 请求尚未到账的数量", []string{"account", "currency"})
+	m.TopUpOverdueSeconds = m.gauge("deribit_topup_overdue_seconds", "逾期最久的补充 ETH 请求已逾期的秒数，没有逾期请求时为 0", []string{"account", "currency"})
+	m.AccountFlows24h = m.gauge("deribit_account_flows_24h", "过去 24 小时已完成的充值、提现和划转数量", []string{"account", "currency", "kind"})
+	m.VenueUp = m.gauge("venue_up", "交易所数据是否读取成功（1 成功，0 失败）", []string{"account", "venue"})
+	m.VenueNetDelta = m.gauge("venue_net_delta", "各交易所抵押品与衍生品的总敞口（币），venue=all 为合计", []string{"account", "currency", "venue"})
+	m.VenueDerivativesDelta = m.gauge("venue_derivatives_delta", "各交易所期货和期权的Delta（币）", []string{"account", "currency", "venue"})
+	m.VenueEquityUSD = m.gauge("venue_equity_usd", "各交易所账户权益(美元)，venue=all 为合计", []string{"account", "venue"})
+	m.VenueMaintenanceMargin = m.gauge("venue_maintenance_margin_usd", "各交易所维持保证金(美元)，venue=all 为合计", []string{"account", "venue"})
+	m.APIRequestDuration = m.histogram("deribit_api_request_duration_seconds", "Deribit API 请求耗时（秒），result 为 success/api_error/http_error/network_error/decode_error", prometheus.DefBuckets, []string{"method", "result"})
+	m.APIErrors = m.counter("deribit_api_errors_total", "Deribit API 返回的错误数（按错误码）", []string{"method", "code"})
+	m.AuthRefreshes = m.counter("deribit_auth_refreshes_total", "Deribit 访问令牌刷新次数", []string{"result"})
+	m.CycleDuration = m.histogram("monitor_cycle_duration_seconds", "监控周期耗时（秒）", prometheus.ExponentialBuckets(0.1, 2, 10), []string{"account", "result"})
+	m.LastSuccessTimestamp = m.gauge("monitor_last_success_timestamp_seconds", "最近一次成功完成监控周期的 Unix 时间戳", []string{"account"})
+	m.PushFailures = m.counter("monitor_push_failures_total", "推送 PushGateway 失败次数（下次推送成功后可见）", nil)
+	m.StoppedTimestamp = m.gauge("monitor_stopped_timestamp_seconds", "监控程序退出的 Unix 时间戳（on_shutdown 为 stale 时推送，重启后消失）", []string{"account"})
+	m.VenueMarginHeadroomUSD = m.gauge("venue_margin_headroom_usd", "各交易所保证金余量（权益-维持保证金，美元），venue=all 为合计", []string{"account", "venue"})
 }
 
 // UpdateAccountMetrics 更新账户相关的所有 Prometheus 指标
@@ -140,7 +261,9 @@ func (m *Metrics) createMetrics() {
 //	ethPriceUSD: ETH 现货价格 (美元)
 //	requiredETHAmount: 需要补充的ETH数量
 //	timestamp: Unix 时间戳
-func (m *Metrics) UpdateAccountMetrics(currency, account string, mmRatio, ethEquity, ethEquityUSD, totalEquity, maintenanceMargin, marginBalance, ethPriceUSD, requiredETHAmount float64, timestamp int64) {
+//
+// ctx 为监控周期的 context，推送的 span 挂在它下面
+func (m *Metrics) UpdateAccountMetrics(ctx context.Context, currency, account string, mmRatio, ethEquity, ethEquityUSD, totalEquity, maintenanceMargin, marginBalance, ethPriceUSD, requiredETHAmount float64, timestamp int64) {
 	// 创建标签，用于标识不同的货币和账户
 	labels := prometheus.Labels{"currency": currency, "account": account} // 指标级别标签
 
@@ -156,15 +279,225 @@ func (m *Metrics) UpdateAccountMetrics(currency, account string, mmRatio, ethEqu
 	m.CollectionTimestamp.With(labels).Set(float64(timestamp)) // 设置指标收集时间戳
 
 	// 自动推送指标到 PushGateway
-	if err := m.PushMetrics(); err != nil {
+	if err := m.PushMetrics(ctx); err != nil {
 		m.logger.Error("Failed to push metrics to PushGateway", zap.Error(err))
 	}
 }
 
-// PushMetrics 将指标推送到 PushGateway
-func (m *Metrics) PushMetrics() error {
+// UpdateEquityMetrics 更新账户级别和各币种的权益、保证金指标，只更新不推送
+func (m *Metrics) UpdateEquityMetrics(accountName string, equity *account.Equity) {
+	// 保证金模式变化时清除旧标签组合的序列
+	accountLabels := prometheus.Labels{"account": accountName}
+	m.AccountEquityUSD.DeletePartialMatch(accountLabels)
+	m.AccountInitialMarginUSD.DeletePartialMatch(accountLabels)
+	m.AccountMaintenanceMarginUSD.DeletePartialMatch(accountLabels)
+
+	labels := prometheus.Labels{"account": accountName, "mode": string(equity.Mode), "margin_model": equity.MarginModel}
+	m.AccountEquityUSD.With(labels).Set(equity.EquityUSD)
+	m.AccountInitialMarginUSD.With(labels).Set(equity.InitialMarginUSD)
+	m.AccountMaintenanceMarginUSD.With(labels).Set(equity.MaintenanceMarginUSD)
+	m.MissingIndexPrices.With(accountLabels).Set(float64(len(equity.MissingPrices)))
+
+	for _, currency := range equity.Currencies {
+		currencyLabels := prometheus.Labels{"currency": currency.Currency, "account": accountName}
+		m.CurrencyEquityUSD.With(currencyLabels).Set(currency.EquityUSD)
+		m.CurrencyInitialMarginUSD.With(currencyLabels).Set(currency.InitialMarginUSD)
+		m.CurrencyMaintenanceMarginUSD.With(currencyLabels).Set(currency.MaintenanceMarginUSD)
+		m.CurrencyMMRatio.With(currencyLabels).Set(currency.MMRatio)
+	}
+}
+
+// UpdateGreeksMetrics 更新单个货币的组合希腊值指标
+// 聚合值直接来自账户摘要，按到期日的值来自 *_map 字段（键为到期日，如 "eth_27dec24"）
+// 该方法只更新指标，不推送，推送由随后的 UpdateAccountMetrics 完成
+func (m *Metrics) UpdateGreeksMetrics(account string, summary types.CurrencySummary) {
+	labels := prometheus.Labels{"currency": summary.Currency, "account": account}
+
+	m.OptionsDelta.With(labels).Set(summary.OptionsDelta)
+	m.OptionsGamma.With(labels).Set(summary.OptionsGamma)
+	m.OptionsVega.With(labels).Set(summary.OptionsVega)
+	m.OptionsTheta.With(labels).Set(summary.OptionsTheta)
+	m.DeltaTotal.With(labels).Set(summary.DeltaTotal)
+
+	// 到期日会随时间消失，先清除该货币旧的到期日序列，避免已到期的值一直留在 PushGateway
+	setByExpiry(m.OptionsGammaByExpiry, labels, summary.OptionsGammaMap)
+	setByExpiry(m.OptionsVegaByExpiry, labels, summary.OptionsVegaMap)
+	setByExpiry(m.OptionsThetaByExpiry, labels, summary.OptionsThetaMap)
+	setByExpiry(m.DeltaTotalByExpiry, labels, summary.DeltaTotalMap)
+}
+
+// UpdateHedgeMetrics 更新 Delta 对冲建议指标，只更新不推送
+// recommendedAmount 为有符号的合约数量：正数表示建议买入，负数表示建议卖出，0 表示无需对冲
+func (m *Metrics) UpdateHedgeMetrics(currency, account, instrument string, currentDelta, targetDelta, recommendedAmount float64) {
+	labels := prometheus.Labels{"currency": currency, "account": account, "instrument": instrument}
+
+	m.HedgeCurrentDelta.With(labels).Set(currentDelta)
+	m.HedgeTargetDelta.With(labels).Set(targetDelta)
+	m.HedgeDeltaDeviation.With(labels).Set(currentDelta - targetDelta)
+	m.HedgeRecommendedAmount.With(labels).Set(recommendedAmount)
+}
+
+// UpdateLegMetrics 重置并更新持仓腿行情指标，只更新不推送；已平仓的腿不再出现
+func (m *Metrics) UpdateLegMetrics(account string, positionLegs []types.LegMetrics) {
+	accountLabels := prometheus.Labels{"account": account}
+	for _, gauge := range []*prometheus.GaugeVec{m.LegPositionSize, m.LegMarkPrice, m.LegMarkIV, m.LegBidAskSpread, m.LegBidAskSpreadRatio, m.LegOpenInterest} {
+		gauge.DeletePartialMatch(accountLabels)
+	}
 
-	// 创建 Pusher
+	for _, leg := range positionLegs {
+		labels := prometheus.Labels{"account": account, "instrument": leg.Instrument, "kind": leg.Kind}
+		m.LegPositionSize.With(labels).Set(leg.Size)
+		m.LegMarkPrice.With(labels).Set(leg.MarkPrice)
+		m.LegOpenInterest.With(labels).Set(leg.OpenInterest)
+		if leg.Kind == "option" {
+			m.LegMarkIV.With(labels).Set(leg.MarkIV)
+		}
+		if leg.HasQuote {
+			m.LegBidAskSpread.With(labels).Set(leg.Spread)
+			m.LegBidAskSpreadRatio.With(labels).Set(leg.RelativeSpread)
+		}
+	}
+}
+
+// UpdateExpiryMetrics 重置并更新持仓到期指标（到期日 -> 距到期秒数 / 持仓合约数），只更新不推送；已到期的到期日不再出现
+func (m *Metrics) UpdateExpiryMetrics(account, currency string, secondsToExpiry, positions map[string]float64) {
+	labels := prometheus.Labels{"account": account, "currency": currency}
+	setByExpiry(m.ExpirySeconds, labels, secondsToExpiry)
+	setByExpiry(m.ExpiryPositions, labels, positions)
+}
+
+// UpdatePnLMetrics 重置并更新当日盈亏归因指标，只更新不推送
+func (m *Metrics) UpdatePnLMetrics(account string, days []types.PnLMetrics) {
+	accountLabels := prometheus.Labels{"account": account}
+	for _, gauge := range []*prometheus.GaugeVec{m.PnLByType, m.PnLByInstrument, m.PnLDaily} {
+		gauge.DeletePartialMatch(accountLabels)
+	}
+
+	for _, day := range days {
+		for category, value := range day.ByType {
+			m.PnLByType.With(prometheus.Labels{"account": account, "currency": day.Currency, "type": category}).Set(value)
+		}
+		for instrument, value := range day.ByInstrument {
+			m.PnLByInstrument.With(prometheus.Labels{"account": account, "currency": day.Currency, "instrument": instrument}).Set(value)
+		}
+		m.PnLDaily.With(prometheus.Labels{"account": account, "currency": day.Currency}).Set(day.PnL)
+	}
+}
+
+// UpdateFundingMetrics 更新永续合约资金费指标，只更新不推送
+func (m *Metrics) UpdateFundingMetrics(account string, cost types.FundingMetrics) {
+	labels := prometheus.Labels{"account": account, "currency": cost.Currency, "instrument": cost.Instrument}
+
+	m.FundingRate8h.With(labels).Set(cost.Rate8h)
+	m.FundingRateAnnualized.With(labels).Set(cost.AnnualizedRate)
+	m.FundingPositionUSD.With(labels).Set(cost.PositionUSD)
+	m.FundingRealizedUSD24h.With(labels).Set(cost.RealizedUSD24h)
+	m.FundingProjectedDailyUSD.With(labels).Set(cost.ProjectedDailyUSD)
+	m.FundingCostAnnualizedUSD.With(labels).Set(cost.AnnualizedCostUSD)
+	m.FundingMaxDailyCostUSD.With(labels).Set(cost.MaxDailyCostUSD)
+}
+
+// UpdateReconcileMetrics 更新补充 ETH 请求对账指标（状态 -> 请求数，类型 -> 过去 24 小时流水），只更新不推送
+func (m *Metrics) UpdateReconcileMetrics(account, currency string, requests map[string]float64, outstanding, overdueSeconds float64, flows24h map[string]float64) {
+	for status, count := range requests {
+		m.TopUpRequests.With(prometheus.Labels{"account": account, "currency": currency, "status": status}).Set(count)
+	}
+	labels := prometheus.Labels{"account": account, "currency": currency}
+	m.TopUpOutstanding.With(labels).Set(outstanding)
+	m.TopUpOverdueSeconds.With(labels).Set(overdueSeconds)
+	for kind, amount := range flows24h {
+		m.AccountFlows24h.With(prometheus.Labels{"account": account, "currency": currency, "kind": kind}).Set(amount)
+	}
+}
+
+// UpdateVenueMetrics 更新跨交易所汇总指标，只更新不推送；读取失败的交易所只更新 venue_up，合计以 venue="all" 输出
+func (m *Metrics) UpdateVenueMetrics(account, currency string, venues []types.VenueMetrics, total types.VenueMetrics) {
+	for _, v := range venues {
+		if !v.Up {
+			m.VenueUp.With(prometheus.Labels{"account": account, "venue": v.Venue}).Set(0)
+			continue
+		}
+		m.VenueUp.With(prometheus.Labels{"account": account, "venue": v.Venue}).Set(1)
+		m.setVenueValues(account, currency, v)
+	}
+	total.Venue = "all"
+	m.setVenueValues(account, currency, total)
+}
+
+func (m *Metrics) setVenueValues(account, currency string, v types.VenueMetrics) {
+	deltaLabels := prometheus.Labels{"account": account, "currency": currency, "venue": v.Venue}
+	labels := prometheus.Labels{"account": account, "venue": v.Venue}
+
+	m.VenueNetDelta.With(deltaLabels).Set(v.NetDelta)
+	m.VenueDerivativesDelta.With(deltaLabels).Set(v.DerivativesDelta)
+	m.VenueEquityUSD.With(labels).Set(v.EquityUSD)
+	m.VenueMaintenanceMargin.With(labels).Set(v.MaintenanceMarginUSD)
+	m.VenueMarginHeadroomUSD.With(labels).Set(v.HeadroomUSD)
+}
+
+// setByExpiry 用到期日 map 重置按到期日的指标
+func setByExpiry(gauge *prometheus.GaugeVec, labels prometheus.Labels, values map[string]float64) {
+	gauge.DeletePartialMatch(labels)
+	for expiry, value := range values {
+		gauge.With(prometheus.Labels{
+			"currency": labels["currency"],
+			"account":  labels["account"],
+			"expiry":   expiry,
+		}).Set(value)
+	}
+}
+
+// ObserveRequest 记录一次 Deribit 请求的耗时和结果
+func (m *Metrics) ObserveRequest(method, result string, duration time.Duration) {
+	m.APIRequestDuration.WithLabelValues(method, result).Observe(duration.Seconds())
+}
+
+// ObserveAPIError 记录一次 Deribit 错误
+func (m *Metrics) ObserveAPIError(method string, code int) {
+	m.APIErrors.WithLabelValues(method, strconv.Itoa(code)).Inc()
+}
+
+// ObserveAuthRefresh 记录一次访问令牌刷新
+func (m *Metrics) ObserveAuthRefresh(result string) {
+	m.AuthRefreshes.WithLabelValues(result).Inc()
+}
+
+// ObserveCycle 记录一个监控周期的耗时和结果
+// 成功的周期已在 UpdateAccountMetrics 中推送，失败的周期没有推送过，这里推送一次使失败可见
+func (m *Metrics) ObserveCycle(account string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.CycleDuration.WithLabelValues(account, result).Observe(duration.Seconds())
+	if err == nil {
+		m.LastSuccessTimestamp.WithLabelValues(account).SetToCurrentTime()
+		return
+	}
+	if pushErr := m.PushMetrics(context.Background()); pushErr != nil {
+		m.logger.Error("Failed to push metrics to PushGateway", zap.Error(pushErr))
+	}
+}
+
+// 退出时对 PushGateway 分组的处理
+const (
+	OnShutdownKeep   = "keep"   // 保留最后的值
+	OnShutdownStale  = "stale"  // 推送 monitor_stopped_timestamp_seconds，保留最后的值
+	OnShutdownDelete = "delete" // 删除分组
+)
+
+// ValidateOnShutdown 检查 on_shutdown 配置，启动时调用以便尽早发现错误
+func ValidateOnShutdown(mode string) error {
+	switch mode {
+	case OnShutdownKeep, OnShutdownStale, OnShutdownDelete, "":
+		return nil
+	default:
+		return fmt.Errorf("unknown prometheus.push_gateway.on_shutdown %q, use keep, stale or delete", mode)
+	}
+}
+
+// pusher 本实例的 PushGateway 分组
+func (m *Metrics) pusher() *push.Pusher {
 	pusher := push.New(m.config.PushGateway.URL, m.config.PushGateway.JobName).
 		Gatherer(m.registry).
 		Grouping("instance", m.config.PushGateway.Instance) // PushGateway级别标签
@@ -173,11 +506,25 @@ func (m *Metrics) PushMetrics() error {
 	for key, value := range m.config.PushGateway.Labels {
 		pusher = pusher.Grouping(key, value)
 	}
+	return pusher
+}
+
+// PushMetrics 将指标推送到 PushGateway，span 挂在 ctx 下，ctx 取消时推送中止
+func (m *Metrics) PushMetrics(ctx context.Context) error {
+	pusher := m.pusher()
 
 	// 推送指标到 PushGateway
-	if err := pusher.Push(); err != nil {
-		return fmt.Errorf("failed to push metrics: %w", err)
+	_, span := tracing.Start(ctx, "prometheus.push",
+		attribute.String("pushgateway.url", m.config.PushGateway.URL),
+		attribute.String("pushgateway.job", m.config.PushGateway.JobName),
+	)
+	if err := pusher.PushContext(ctx); err != nil {
+		m.PushFailures.WithLabelValues().Inc()
+		err = fmt.Errorf("failed to push metrics: %w", err)
+		tracing.End(span, err)
+		return err
 	}
+	tracing.End(span, nil)
 
 	m.logger.Debug("Successfully pushed metrics to PushGateway",
 		zap.String("url", m.config.PushGateway.URL),
@@ -187,3 +534,26 @@ func (m *Metrics) PushMetrics() error {
 
 	return nil
 }
+
+// Shutdown 退出时按 on_shutdown 处理本实例的 PushGateway 分组，所有监控周期结束后调用
+func (m *Metrics) Shutdown(account string) error {
+	if err := ValidateOnShutdown(m.config.PushGateway.OnShutdown); err != nil {
+		return err
+	}
+	switch m.config.PushGateway.OnShutdown {
+	case OnShutdownStale:
+		m.StoppedTimestamp.WithLabelValues(account).SetToCurrentTime()
+		return m.PushMetrics(context.Background())
+	case OnShutdownDelete:
+		if err := m.pusher().Delete(); err != nil {
+			return fmt.Errorf("failed to delete PushGateway group: %w", err)
+		}
+		m.logger.Info("Deleted PushGateway group",
+			zap.String("job", m.config.PushGateway.JobName),
+			zap.String("instance", m.config.PushGateway.Instance),
+		)
+		return nil
+	default: // keep：推送最后一次的值
+		return m.PushMetrics(context.Background())
+	}
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestShutdown(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string // 方法和是否包含停止时间
+	pushGateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+strconv.FormatBool(strings.Contains(string(body), "monitor_stopped_timestamp_seconds")))
+		mu.Unlock()
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(pushGateway.Close)
+
+	for _, mode := range []string{OnShutdownKeep, OnShutdownStale, OnShutdownDelete} {
+		m := NewMetrics(types.PrometheusConfig{
+			PushGateway: types.PushGatewayConfig{URL: pushGateway.URL, JobName: "test", Instance: "desk", OnShutdown: mode},
+		}, zap.NewNop())
+		require.NoError(t, m.Shutdown("desk"), mode)
+	}
+	assert.Equal(t, []string{
+		"PUT /metrics/job/test/instance/desk false",
+		"PUT /metrics/job/test/instance/desk true",
+		"DELETE /metrics/job/test/instance/desk false",
+	}, requests)
+
+	assert.ErrorContains(t, ValidateOnShutdown("drop"), `unknown prometheus.push_gateway.on_shutdown "drop"`)
+}
+
+func TestUpdateVenueMetrics(t *testing.T) {
+	m := NewMetrics(types.PrometheusConfig{}, zap.NewNop())
+	venues := []types.VenueMetrics{
+		{Venue: "deribit", Up: true, NetDelta: 130, EquityUSD: 1000000},
+		{Venue: "okx"},
+	}
+	m.UpdateVenueMetrics("desk", "ETH", venues, types.VenueMetrics{NetDelta: 130, EquityUSD: 1000000})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.VenueUp.WithLabelValues("desk", "deribit")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.VenueUp.WithLabelValues("desk", "okx")))
+	assert.Equal(t, 130.0, testutil.ToFloat64(m.VenueNetDelta.WithLabelValues("desk", "ETH", "all")))
+	// 读取失败的交易所不输出取值
+	assert.Equal(t, 2, testutil.CollectAndCount(m.VenueNetDelta))
+}
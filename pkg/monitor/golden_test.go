@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// 重新生成 golden 文件：go test ./pkg/monitor -run Golden -update
+var update = flag.Bool("update", false, "regenerate golden files")
+
+// evaluateCase testdata/evaluate 下的 YAML 用例
+type evaluateCase struct {
+	Description string                  `json:"description"`
+	Config      types.MonitorConfig     `json:"config"`
+	Prices      map[string]float64      `json:"prices"` // 币种（大写）到 USD 指数价格
+	Summaries   []types.CurrencySummary `json:"summaries"`
+}
+
+// loadEvaluateCase 读取 YAML 用例，字段名与 Deribit API 的 JSON 字段一致
+func loadEvaluateCase(t *testing.T, path string) evaluateCase {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// 先解析为通用结构再转成 JSON，使 YAML 用例复用 types 中的 json 标签
+	var raw interface{}
+	require.NoError(t, yaml.Unmarshal(content, &raw))
+	data, err := json.Marshal(raw)
+	require.NoError(t, err)
+
+	var c evaluateCase
+	require.NoError(t, json.Unmarshal(data, &c))
+	return c
+}
+
+// assertGolden 与 golden 文件比较，-update 时改为写入
+func assertGolden(t *testing.T, path string, actual interface{}) {
+	data, err := json.MarshalIndent(actual, "", "  ")
+	require.NoError(t, err)
+	data = append(data, '\n')
+
+	if *update {
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		return
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err, "golden file missing, run with -update to create it")
+	assert.JSONEq(t, string(expected), string(data))
+}
+
+func evaluateCases(t *testing.T) []string {
+	paths, err := filepath.Glob(filepath.Join("testdata", "evaluate", "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+	return paths
+}
+
+func goldenPath(casePath string) string {
+	return strings.TrimSuffix(casePath, ".yaml") + ".golden.json"
+}
+
+func TestEvaluateGolden(t *testing.T) {
+	for _, path := range evaluateCases(t) {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".yaml"), func(t *testing.T) {
+			c := loadEvaluateCase(t, path)
+			service := &Service{config: c.Config, logger: zap.NewNop()}
+
+			evaluation, err := service.evaluate(c.Summaries, c.Prices)
+			require.NoError(t, err)
+			assertGolden(t, goldenPath(path), evaluation)
+		})
+	}
+}
+
+// 同一组用例经模拟 Deribit 走完整的 checkPositions，推送的指标应与 golden 一致
+func TestCheckPositionsGolden(t *testing.T) {
+	if *update {
+		t.Skip("golden files are written by TestEvaluateGolden")
+	}
+	for _, path := range evaluateCases(t) {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".yaml"), func(t *testing.T) {
+			c := loadEvaluateCase(t, path)
+			service, srv, m := newTestService(t)
+			service.config = c.Config
+
+			indexPrices := make(map[string]float64)
+			for currency, price := range c.Prices {
+				indexPrices[strings.ToLower(currency)+"_usd"] = price
+			}
+			srv.SetState(fakederibit.State{
+				Summaries:   types.AccountSummaries{Summaries: c.Summaries},
+				IndexPrices: indexPrices,
+			})
+			require.NoError(t, service.checkPositions())
+
+			content, err := os.ReadFile(goldenPath(path))
+			require.NoError(t, err)
+			var expected Evaluation
+			require.NoError(t, json.Unmarshal(content, &expected))
+
+			labels := prometheus.Labels{"currency": "ETH", "account": c.Config.Account}
+			assert.Equal(t, expected.MMRatio, testutil.ToFloat64(m.MaintenanceMarginRatio.With(labels)))
+			assert.Equal(t, expected.RequiredETH, testutil.ToFloat64(m.RequiredETHAmount.With(labels)))
+			assert.Equal(t, expected.ETHEquityUSD, testutil.ToFloat64(m.ETHEquityUSD.With(labels)))
+		})
+	}
+}
@@ -1,35 +1,74 @@
 package monitor
 
 import (
+	"context"
 	"cs-projects-eth-collar/internal/types"
-	"cs-projects-eth-collar/pkg/deribit"
-	"cs-projects-eth-collar/pkg/metrics"
+	"cs-projects-eth-collar/pkg/account"
+	"cs-projects-eth-collar/pkg/tracing"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
 type Service struct {
 	config        types.MonitorConfig
-	deribitClient *deribit.Client
-	metrics       *metrics.Metrics
+	accounts      AccountSource
+	prices        PriceSource
+	metrics       MetricsSink
 	logger        *zap.Logger
+	hooks         []CycleHook   // 每个周期评估完成后运行的模块
+	auditLogger   AuditRecorder // 可选：审计日志
+	cycleObserver CycleObserver // 可选：监控周期耗时和结果
+	leadership    Leadership    // 可选：高可用模式，只有 leader 执行监控周期
+
+	stop     chan struct{} // Stop 关闭后 Start 在当前周期结束后返回
+	stopOnce sync.Once
+	mu       sync.Mutex
+	status   types.MonitorStatus // 运行状态，退出时保存
+}
+
+// RuleOutcome 单条告警规则的评估结果
+type RuleOutcome struct {
+	Rule        string  `json:"rule"`               // 规则名称
+	Fired       bool    `json:"fired"`              // 是否触发
+	Value       float64 `json:"value"`              // 规则观测值
+	Threshold   float64 `json:"threshold"`          // 触发阈值
+	Target      float64 `json:"target"`             // 补充后的目标值
+	RequiredETH float64 `json:"required_eth"`       // 该规则要求补充的 ETH 数量
+	Currency    string  `json:"currency,omitempty"` // 分币种保证金下 MM 比率最高的币种
 }
 
-func NewService(config types.MonitorConfig, deribitClient *deribit.Client, metrics *metrics.Metrics, logger *zap.Logger) *Service {
-	return &Service{
-		config:        config,
-		deribitClient: deribitClient,
-		metrics:       metrics,
-		logger:        logger,
+const (
+	RuleMMRatio       = "mm_ratio"        // MM > 50% 补 ETH 至 MM = 30%
+	RuleETHEquityLoss = "eth_equity_loss" // ETH equity * spot < -0.7m USD 补 ETH 至 equity = 200
+)
+
+// NewService 创建监控服务，source 同时提供账户摘要和指数价格（如 *deribit.Client）
+func NewService(config types.MonitorConfig, source DataSource, metrics MetricsSink, logger *zap.Logger, opts ...Option) *Service {
+	s := &Service{
+		config:   config,
+		accounts: source,
+		prices:   source,
+		metrics:  metrics,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		status:   types.MonitorStatus{Account: config.Account},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
+// Start 认证后按间隔执行监控周期，直到 Stop 被调用；进行中的周期会执行完再返回
 func (s *Service) Start() error {
-	// 首先进行 Deribit API 认证
+	// 首先进行 API 认证
 	s.logger.Info("Authenticating with Deribit API")
-	if err := s.deribitClient.Authenticate(); err != nil {
+	if err := s.accounts.Authenticate(); err != nil {
 		return fmt.Errorf("failed to authenticate with Deribit: %w", err)
 	}
 	s.logger.Info("Successfully authenticated with Deribit API")
@@ -52,29 +91,69 @@ func (s *Service) Start() error {
 	for {
 		select {
 		case <-ticker.C:
-			if err := s.checkPositions(); err != nil {
-				s.logger.Error("Failed to check positions", zap.Error(err))
-			}
+			s.runCycle()
+		case <-s.stop:
+			s.logger.Info("Position monitor stopped", zap.String("account", s.config.Account))
+			return nil
 		}
 	}
 }
 
-func (s *Service) checkPositions() error {
-	// 获取整个账户的摘要信息
-	accountSummaries, err := s.deribitClient.GetAccountSummaries()
+// Stop 通知 Start 在当前周期结束后返回，可重复调用
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Status 返回运行状态
+func (s *Service) Status() types.MonitorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// runCycle 执行一个监控周期并记录耗时和结果
+func (s *Service) runCycle() {
+	if s.leadership != nil && !s.leadership.IsLeader() {
+		s.logger.Debug("Not the leader, skipping monitoring cycle", zap.String("account", s.config.Account))
+		return
+	}
+
+	start := time.Now()
+	err := s.checkPositions()
 	if err != nil {
-		return fmt.Errorf("failed to get account summaries: %w", err)
+		s.logger.Error("Failed to check positions", zap.Error(err))
 	}
 
-	// 查找ETH货币的摘要
-	var ethSummary *types.CurrencySummary
-	for i := range accountSummaries.Summaries {
-		if accountSummaries.Summaries[i].Currency == "ETH" {
-			ethSummary = &accountSummaries.Summaries[i]
-			break
-		}
+	s.mu.Lock()
+	s.status.Cycles++
+	s.status.LastCycleAt = time.Now()
+	if err != nil {
+		s.status.LastError = err.Error()
+	} else {
+		s.status.LastSuccessAt = s.status.LastCycleAt
+		s.status.LastError = ""
 	}
-	if ethSummary == nil {
+	s.mu.Unlock()
+
+	if s.cycleObserver != nil {
+		s.cycleObserver.ObserveCycle(s.config.Account, time.Since(start), err)
+	}
+}
+
+func (s *Service) checkPositions() (err error) {
+	// 本周期的 span，Deribit 请求、各模块和指标推送的 span 都通过 ctx 挂在它下面
+	ctx, endCycle := tracing.StartCycle("monitor.cycle",
+		attribute.String("account", s.config.Account),
+		attribute.String("currency", "ETH"),
+	)
+	defer func() { endCycle(err) }()
+
+	// 获取整个账户的摘要信息
+	accountSummaries, err := s.accounts.GetAccountSummariesContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get account summaries: %w", err)
+	}
+	if findSummary(accountSummaries.Summaries, "ETH") == nil {
 		return fmt.Errorf("ETH currency summary not found in account summaries")
 	}
 
@@ -82,80 +161,262 @@ func (s *Service) checkPositions() error {
 	timestamp := time.Now().Unix() // 获取当前时间戳
 
 	// 从 Deribit API 获取 ETH 现货价格
-	ethPriceUSD, err := s.deribitClient.GetIndexPrice("eth")
-	if err != nil {
+	ethPriceUSD, err := s.prices.GetIndexPriceContext(ctx, "eth")
+	fallbackPrice := err != nil
+	if fallbackPrice {
 		s.logger.Error("Failed to get ETH price, using fallback", zap.Error(err))
-		ethPriceUSD = 3000.0 // 备用价格
+		ethPriceUSD = 3000.0 // 备用价格，只用于指标，不用于补充提案和对账
 	}
-	//ethEquityUSD := ethSummary.Equity * ethPriceUSD // 计算 ETH 权益的美元价值
 
-	ethEquityUSD := ethSummary.Equity * ethPriceUSD //ethSummary.TotalEquityUSD // ETH个数
-	var totalEquityUSD float64                      // 所有币Equity之和
-	var totalMaintenanceMarginUSD float64           // 所有币维持保证金之和
-	for _, summary := range accountSummaries.Summaries {
-		if summary.TotalMaintenanceMarginUSD > 0 {
-			totalMaintenanceMarginUSD += summary.TotalMaintenanceMarginUSD
-			totalEquityUSD = summary.TotalEquityUSD
-			break
+	prices := s.collectPrices(ctx, accountSummaries.Summaries, ethPriceUSD)
+	_, span := tracing.Start(ctx, "monitor.evaluate_rules", attribute.String("account", s.config.Account))
+	evaluation, err := s.evaluate(accountSummaries.Summaries, prices)
+	if err == nil {
+		span.SetAttributes(
+			attribute.Float64("mm_ratio", evaluation.MMRatio),
+			attribute.Float64("eth_equity_usd", evaluation.ETHEquityUSD),
+			attribute.Float64("required_eth", evaluation.RequiredETH),
+		)
+		for _, rule := range evaluation.Rules {
+			span.SetAttributes(attribute.Bool("rule."+rule.Rule+".fired", rule.Fired))
 		}
 	}
-
-	// 计算正确的维持保证金比率：整个账户的维持保证金 / 整个账户的总权益
-	mmRatio := 0.0
-	if totalEquityUSD != 0 {
-		mmRatio = totalMaintenanceMarginUSD / totalEquityUSD
+	tracing.End(span, err)
+	if err != nil {
+		return err
+	}
+	evaluation.FallbackPrice = fallbackPrice
+	accountEquity := evaluation.Account
+	if len(accountEquity.MissingPrices) > 0 {
+		s.logger.Error("Missing index prices, currencies excluded from USD totals",
+			zap.Strings("currencies", accountEquity.MissingPrices),
+			zap.String("mode", string(accountEquity.Mode)),
+		)
 	}
-
-	// 计算需要补充的ETH数量
-	requiredETHAmount := s.calculateRequiredETH(mmRatio, totalMaintenanceMarginUSD, totalEquityUSD, ethSummary.Equity, ethEquityUSD, ethPriceUSD)
 
 	// 记录账户状态信息
 	s.logger.Info("Account status check",
 		zap.String("currency", "ETH"),
 		zap.String("account", s.config.Account),
-		zap.Float64("eth_price_usd", ethPriceUSD),
-		zap.Float64("eth_equity", ethSummary.Equity),
-		zap.Float64("eth_equity_usd", ethEquityUSD),
-		zap.Float64("eth_margin_balance", ethSummary.MarginBalance),
-		zap.Float64("eth_maintenance_margin", ethSummary.MaintenanceMargin),
-		zap.Float64("total_maintenance_margin_usd", totalMaintenanceMarginUSD),
-		zap.Float64("total_equity_usd", totalEquityUSD),
-		zap.Float64("mm_ratio", mmRatio),
-		zap.Float64("required_eth_amount", requiredETHAmount),
+		zap.Float64("eth_price_usd", evaluation.ETHPriceUSD),
+		zap.Float64("eth_equity", evaluation.ETHEquity),
+		zap.Float64("eth_equity_usd", evaluation.ETHEquityUSD),
+		zap.Float64("eth_margin_balance", evaluation.ETHMarginBalance),
+		zap.Float64("eth_maintenance_margin", evaluation.ETHMaintenanceMargin),
+		zap.Float64("total_maintenance_margin_usd", accountEquity.MaintenanceMarginUSD),
+		zap.Float64("total_equity_usd", accountEquity.EquityUSD),
+		zap.Float64("total_initial_margin_usd", accountEquity.InitialMarginUSD),
+		zap.String("margin_mode", string(accountEquity.Mode)),
+		zap.String("margin_model", accountEquity.MarginModel),
+		zap.Float64("mm_ratio", evaluation.MMRatio),
+		zap.Float64("required_eth_amount", evaluation.RequiredETH),
 	)
 
+	// 更新账户级别和各币种的权益、保证金指标
+	s.metrics.UpdateEquityMetrics(s.config.Account, accountEquity)
+
+	// 更新各货币的组合希腊值指标
+	for _, summary := range accountSummaries.Summaries {
+		s.metrics.UpdateGreeksMetrics(s.config.Account, summary)
+	}
+
+	// 记录本次评估的输入和规则结果，便于事后追溯告警原因
+	s.recordEvaluation(map[string]interface{}{
+		"account":                      s.config.Account,
+		"currency":                     "ETH",
+		"eth_price_usd":                evaluation.ETHPriceUSD,
+		"eth_equity":                   evaluation.ETHEquity,
+		"eth_equity_usd":               evaluation.ETHEquityUSD,
+		"eth_margin_balance":           evaluation.ETHMarginBalance,
+		"eth_maintenance_margin":       evaluation.ETHMaintenanceMargin,
+		"eth_delta_total":              findSummary(accountSummaries.Summaries, "ETH").DeltaTotal,
+		"total_maintenance_margin_usd": accountEquity.MaintenanceMarginUSD,
+		"total_equity_usd":             accountEquity.EquityUSD,
+		"account_equity":               accountEquity,
+		"mm_ratio":                     evaluation.MMRatio,
+		"required_eth_amount":          evaluation.RequiredETH,
+		"fallback_price":               evaluation.FallbackPrice,
+		"rules":                        evaluation.Rules,
+	})
+
+	// 依次运行各模块；使用备用价格时需要补充的数量不可信，依赖指数价格的模块（补充提案、对账）本周期跳过
+	cycle := &Cycle{Account: s.config.Account, Summaries: accountSummaries, Evaluation: evaluation}
+	for _, hook := range s.hooks {
+		if _, ok := hook.(priceDependent); ok && evaluation.FallbackPrice {
+			s.logger.Warn("ETH price unavailable, skipping hook this cycle",
+				zap.String("hook", hook.Name()),
+				zap.Float64("required_eth_amount", evaluation.RequiredETH),
+			)
+			continue
+		}
+		s.runHook(ctx, hook, cycle)
+	}
+
 	// 更新 Prometheus 指标
 	// 将账户数据推送到 Prometheus，供监控和告警使用
 	s.metrics.UpdateAccountMetrics(
-		"ETH",                        // 货币类型
-		s.config.Account,             // 账户标识
-		mmRatio,                      // 维持保证金比率
-		ethSummary.Equity,            // ETH 权益数量
-		ethEquityUSD,                 // ETH 权益美元价值
-		ethSummary.Equity,            // 总权益 (这里与 ETH 权益相同)
-		ethSummary.MaintenanceMargin, // 维持保证金
-		ethSummary.MarginBalance,     // 保证金余额
-		ethPriceUSD,                  // ETH 现货价格
-		requiredETHAmount,            // 需要补充的ETH数量
-		timestamp,                    // 时间戳
+		ctx,
+		"ETH",                           // 货币类型
+		s.config.Account,                // 账户标识
+		evaluation.MMRatio,              // 维持保证金比率
+		evaluation.ETHEquity,            // ETH 权益数量
+		evaluation.ETHEquityUSD,         // ETH 权益美元价值
+		evaluation.ETHEquity,            // 总权益 (这里与 ETH 权益相同)
+		evaluation.ETHMaintenanceMargin, // 维持保证金
+		evaluation.ETHMarginBalance,     // 保证金余额
+		evaluation.ETHPriceUSD,          // ETH 现货价格
+		evaluation.RequiredETH,          // 需要补充的ETH数量
+		timestamp,                       // 时间戳
 	)
 
 	return nil
 }
 
+// runHook 在单独的 span 中运行一个模块，错误只记录日志
+func (s *Service) runHook(ctx context.Context, hook CycleHook, cycle *Cycle) {
+	ctx, span := tracing.Start(ctx, "monitor.hook", attribute.String("hook", hook.Name()))
+	cycle.Context = ctx
+	err := hook.OnCycle(cycle)
+	tracing.End(span, err)
+	if err != nil {
+		s.logger.Error("Cycle hook failed", zap.String("hook", hook.Name()), zap.Error(err))
+	}
+}
+
+// Evaluation 一次监控评估的结果
+type Evaluation struct {
+	ETHPriceUSD          float64         `json:"eth_price_usd"`
+	ETHEquity            float64         `json:"eth_equity"`
+	ETHEquityUSD         float64         `json:"eth_equity_usd"` // ETH 权益的美元价值
+	ETHMarginBalance     float64         `json:"eth_margin_balance"`
+	ETHMaintenanceMargin float64         `json:"eth_maintenance_margin"`
+	Account              *account.Equity `json:"account_equity"`           // 整个账户的权益和维持保证金（USD）
+	MMRatio              float64         `json:"mm_ratio"`                 // 整个账户的维持保证金 / 整个账户的总权益
+	RequiredETH          float64         `json:"required_eth"`             // 需要补充的 ETH 数量
+	FallbackPrice        bool            `json:"fallback_price,omitempty"` // ETH 指数价格获取失败，使用了备用价格
+	Rules                []RuleOutcome   `json:"rules"`
+}
+
+// evaluate 根据账户摘要和指数价格（币种大写，必须包含 ETH）计算维持保证金比率和需要补充的 ETH，不访问网络
+func (s *Service) evaluate(summaries []types.CurrencySummary, prices map[string]float64) (*Evaluation, error) {
+	ethSummary := findSummary(summaries, "ETH")
+	if ethSummary == nil {
+		return nil, fmt.Errorf("ETH currency summary not found in account summaries")
+	}
+	ethPriceUSD, ok := prices["ETH"]
+	if !ok {
+		return nil, fmt.Errorf("ETH index price is required")
+	}
+
+	// 汇总整个账户的权益和维持保证金（按跨币种/分币种保证金模式分别处理）
+	accountEquity := account.Aggregate(summaries, prices)
+
+	evaluation := &Evaluation{
+		ETHPriceUSD:          ethPriceUSD,
+		ETHEquity:            ethSummary.Equity,
+		ETHEquityUSD:         ethSummary.Equity * ethPriceUSD,
+		ETHMarginBalance:     ethSummary.MarginBalance,
+		ETHMaintenanceMargin: ethSummary.MaintenanceMargin,
+		Account:              accountEquity,
+		MMRatio:              accountEquity.MMRatio,
+	}
+
+	// 分币种保证金下补充的 ETH 只进入 ETH 的保证金池，补充数量按 ETH 自身的维持保证金和权益计算
+	maintenanceMarginUSD, equityUSD := accountEquity.MaintenanceMarginUSD, accountEquity.EquityUSD
+	if accountEquity.Mode == account.ModeSegregated {
+		maintenanceMarginUSD, equityUSD = ethSummary.MaintenanceMargin*ethPriceUSD, evaluation.ETHEquityUSD
+	}
+
+	// 计算需要补充的ETH数量
+	evaluation.RequiredETH, evaluation.Rules = s.calculateRequiredETH(evaluation.MMRatio, maintenanceMarginUSD,
+		equityUSD, evaluation.ETHEquity, evaluation.ETHEquityUSD, ethPriceUSD)
+	if accountEquity.WorstCurrency != "" {
+		mmRule := &evaluation.Rules[0]
+		mmRule.Currency = accountEquity.WorstCurrency
+		// 其他币种的保证金池超过阈值时告警照常触发，但补充 ETH 只能降低 ETH 自身的比率；
+		// ETH 保证金池未超过阈值时不补充
+		if ethPool := findBreakdown(accountEquity.Currencies, "ETH"); mmRule.Fired && accountEquity.WorstCurrency != "ETH" &&
+			(ethPool == nil || ethPool.MMRatio <= mmRule.Threshold) {
+			mmRule.RequiredETH = 0
+			evaluation.RequiredETH = selectRequiredETH(evaluation.Rules)
+			s.logger.Warn("MM ratio breached in a non-ETH margin pool, ETH top-up does not reduce it",
+				zap.String("currency", accountEquity.WorstCurrency),
+				zap.Float64("mm_ratio", accountEquity.MMRatio),
+			)
+		}
+	}
+	return evaluation, nil
+}
+
+// findBreakdown 查找指定币种的权益和保证金
+func findBreakdown(currencies []account.CurrencyBreakdown, currency string) *account.CurrencyBreakdown {
+	for i := range currencies {
+		if currencies[i].Currency == currency {
+			return &currencies[i]
+		}
+	}
+	return nil
+}
+
+// findSummary 查找指定货币的摘要
+func findSummary(summaries []types.CurrencySummary, currency string) *types.CurrencySummary {
+	for i := range summaries {
+		if summaries[i].Currency == currency {
+			return &summaries[i]
+		}
+	}
+	return nil
+}
+
+// collectPrices 获取汇总账户权益所需的各币种指数价格，ETH 使用已获取的价格
+// 获取失败的币种不放入结果，由汇总层记录为缺少价格
+func (s *Service) collectPrices(ctx context.Context, summaries []types.CurrencySummary, ethPriceUSD float64) map[string]float64 {
+	prices := map[string]float64{"ETH": ethPriceUSD}
+	for _, summary := range summaries {
+		currency := strings.ToUpper(summary.Currency)
+		if _, ok := prices[currency]; ok || !account.PriceNeeded(summary) {
+			continue
+		}
+		price, err := s.prices.GetIndexPriceContext(ctx, strings.ToLower(currency))
+		if err != nil {
+			s.logger.Warn("Failed to get index price", zap.String("currency", currency), zap.Error(err))
+			continue
+		}
+		prices[currency] = price
+	}
+	return prices
+}
+
+// recordEvaluation 写入一条评估审计记录
+func (s *Service) recordEvaluation(data map[string]interface{}) {
+	if s.auditLogger == nil {
+		return
+	}
+	if err := s.auditLogger.Record("evaluation", data); err != nil {
+		s.logger.Error("Failed to write evaluation audit entry", zap.Error(err))
+	}
+}
+
 // calculateRequiredETH 计算需要补充的ETH数量
 // 这个函数需要从外部传入总账户的维持保证金和权益信息
-func (s *Service) calculateRequiredETH(mmRatio, totalMaintenanceMarginUSD, totalEquityUSD, ethEquity, ethEquityUSD, ethPriceUSD float64) float64 {
-	// 算法1: MM > 50%报警，推送补ETH至MM=30%需要的ETH数量
-	if mmRatio > 0.5 {
+// 两条规则都会评估并返回结果，需要补充的数量取第一条触发且需要补充的规则
+// 分币种保证金下 MM 比率可能来自其他币种，ETH 保证金池本身未超过阈值时由 evaluate 把 MM 规则的补充数量置 0
+func (s *Service) calculateRequiredETH(mmRatio, totalMaintenanceMarginUSD, totalEquityUSD, ethEquity, ethEquityUSD, ethPriceUSD float64) (float64, []RuleOutcome) {
+	rules := s.config.Rules.WithDefaults()
+
+	// 算法1: MM > 50%报警，推送补ETH至MM=30%需要的ETH数量（阈值和目标见 monitor.rules）
+	mmRule := RuleOutcome{Rule: RuleMMRatio, Value: mmRatio, Threshold: rules.MMRatioThreshold, Target: rules.MMRatioTarget}
+	if mmRatio > mmRule.Threshold {
+		mmRule.Fired = true
 		// 目标维持保证金比率 = 0.3
 		// 0.3 = Total_MM_USD / (Total_Equity_USD + 新增的ETH价值)
 		// 新增的ETH价值 = Total_MM_USD / 0.3 - Total_Equity_USD
-		targetMMRatio := 0.3
+		targetMMRatio := mmRule.Target
 		requiredETHValueUSD := totalMaintenanceMarginUSD/targetMMRatio - totalEquityUSD
 
 		if requiredETHValueUSD > 0 {
 			requiredETHAmount := requiredETHValueUSD / ethPriceUSD
+			mmRule.RequiredETH = requiredETHAmount
 			s.logger.Warn("MM ratio alert triggered",
 				zap.Float64("current_mm_ratio", mmRatio),
 				zap.Float64("target_mm_ratio", targetMMRatio),
@@ -164,24 +425,35 @@ func (s *Service) calculateRequiredETH(mmRatio, totalMaintenanceMarginUSD, total
 				zap.Float64("required_eth_amount", requiredETHAmount),
 				zap.Float64("required_eth_value_usd", requiredETHValueUSD),
 			)
-			return requiredETHAmount
 		}
 	}
 
 	// 算法2: ETH equity * ETH spot < -0.7m USD报警，补ETH至 ETH equity = 200
 	// 当ETH equity为负数时，乘以价格得到负的美元价值，表示亏损
-	if ethEquityUSD < -700000 { // -0.7M USD
-		requiredETHAmount := 200 - ethEquity
+	equityRule := RuleOutcome{Rule: RuleETHEquityLoss, Value: ethEquityUSD, Threshold: rules.ETHEquityLossUSD, Target: rules.ETHEquityTargetETH}
+	if ethEquityUSD < equityRule.Threshold {
+		requiredETHAmount := equityRule.Target - ethEquity
+		equityRule.Fired = true
+		equityRule.RequiredETH = requiredETHAmount
 		s.logger.Warn("ETH equity loss alert triggered",
 			zap.Float64("current_eth_equity", ethEquity),
 			zap.Float64("current_eth_equity_usd", ethEquityUSD),
-			zap.Float64("target_eth_equity", 200),
+			zap.Float64("target_eth_equity", equityRule.Target),
 			zap.Float64("required_eth_amount", requiredETHAmount),
-			zap.Float64("loss_threshold_usd", -700000),
+			zap.Float64("loss_threshold_usd", equityRule.Threshold),
 		)
-		return requiredETHAmount
 	}
 
-	// 没有触发告警条件，返回0
+	outcomes := []RuleOutcome{mmRule, equityRule}
+	return selectRequiredETH(outcomes), outcomes
+}
+
+// selectRequiredETH 取第一条触发且需要补充的规则的数量，没有时返回 0
+func selectRequiredETH(outcomes []RuleOutcome) float64 {
+	for _, outcome := range outcomes {
+		if outcome.Fired && outcome.RequiredETH > 0 {
+			return outcome.RequiredETH
+		}
+	}
 	return 0
 }
@@ -0,0 +1,216 @@
+package monitor
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"cs-projects-eth-collar/pkg/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+// newTestService 创建连接到模拟 Deribit 和模拟 PushGateway 的监控服务
+func newTestService(t *testing.T) (*Service, *fakederibit.Server, *metrics.Metrics) {
+	srv := fakederibit.NewServer()
+	t.Cleanup(srv.Close)
+
+	pushGateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(pushGateway.Close)
+
+	client, err := deribit.NewClient(types.DeribitConfig{
+		BaseURL:   srv.URL(),
+		APIKey:    srv.ClientID,
+		APISecret: srv.ClientSecret,
+	})
+	require.NoError(t, err)
+
+	m := metrics.NewMetrics(types.PrometheusConfig{
+		Enabled:     true,
+		PushGateway: types.PushGatewayConfig{URL: pushGateway.URL, JobName: "test", Instance: "test"},
+	}, zap.NewNop())
+
+	service := NewService(types.MonitorConfig{Interval: 30, Account: "test"}, client, m, zap.NewNop())
+	return service, srv, m
+}
+
+func crossCollateralState(totalEquityUSD, totalMaintenanceMarginUSD, ethEquity float64) fakederibit.State {
+	return fakederibit.State{
+		Summaries: types.AccountSummaries{
+			Summaries: []types.CurrencySummary{
+				{Currency: "ETH", Equity: ethEquity, MaintenanceMargin: 10, MarginModel: "cross_pm", CrossCollateralEnabled: true,
+					TotalEquityUSD: totalEquityUSD, TotalMaintenanceMarginUSD: totalMaintenanceMarginUSD},
+				{Currency: "BTC", Equity: 1, MarginModel: "cross_pm", CrossCollateralEnabled: true,
+					TotalEquityUSD: totalEquityUSD, TotalMaintenanceMarginUSD: totalMaintenanceMarginUSD},
+			},
+		},
+		IndexPrices: map[string]float64{"eth_usd": 2000, "btc_usd": 60000},
+	}
+}
+
+func TestCheckPositionsEndToEnd(t *testing.T) {
+	service, srv, m := newTestService(t)
+	srv.SetState(crossCollateralState(1000000, 200000, 300))
+
+	require.NoError(t, service.checkPositions())
+
+	labels := prometheus.Labels{"currency": "ETH", "account": "test"}
+	assert.InDelta(t, 0.2, testutil.ToFloat64(m.MaintenanceMarginRatio.With(labels)), 1e-12)
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.RequiredETHAmount.With(labels)))
+	assert.Equal(t, 600000.0, testutil.ToFloat64(m.ETHEquityUSD.With(labels)))
+
+	// 保证金突增 3 倍：MM = 60% > 50%，需要补 ETH 至 MM = 30%
+	srv.MarginSpike("ETH", 3)
+	require.NoError(t, service.checkPositions())
+
+	assert.InDelta(t, 0.6, testutil.ToFloat64(m.MaintenanceMarginRatio.With(labels)), 1e-12)
+	assert.InDelta(t, (600000/0.3-1000000)/2000, testutil.ToFloat64(m.RequiredETHAmount.With(labels)), 1e-9)
+}
+
+func TestCheckPositionsAPIError(t *testing.T) {
+	service, srv, _ := newTestService(t)
+	srv.SetState(crossCollateralState(1000000, 200000, 300))
+	srv.InjectFault("private/get_account_summaries", fakederibit.Fault{Code: fakederibit.ErrCodeTooManyRequests, Message: "too_many_requests"})
+
+	err := service.checkPositions()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too_many_requests")
+}
+
+func TestCalculateRequiredETHConfiguredRules(t *testing.T) {
+	service := &Service{
+		config: types.MonitorConfig{Rules: types.RulesConfig{MMRatioThreshold: 0.4, MMRatioTarget: 0.2, ETHEquityLossUSD: -100000, ETHEquityTargetETH: 50}},
+		logger: zap.NewNop(),
+	}
+
+	// MM = 45%：默认阈值 50% 不触发，配置阈值 40% 触发并补至 20%
+	required, outcomes := service.calculateRequiredETH(0.45, 450000, 1000000, 10, 20000, 2000)
+	require.Len(t, outcomes, 2)
+	assert.True(t, outcomes[0].Fired)
+	assert.Equal(t, 0.4, outcomes[0].Threshold)
+	assert.InDelta(t, (450000/0.2-1000000)/2000, required, 1e-9)
+
+	_, outcomes = service.calculateRequiredETH(0.1, 100000, 1000000, -60, -120000, 2000)
+	assert.True(t, outcomes[1].Fired)
+	assert.Equal(t, 110.0, outcomes[1].RequiredETH)
+}
+
+func TestCalculateRequiredETHPartialRules(t *testing.T) {
+	// 只配置阈值，目标为 0 时按默认目标 30% 计算，而不是无穷大
+	service := &Service{config: types.MonitorConfig{Rules: types.RulesConfig{MMRatioThreshold: 0.4}}, logger: zap.NewNop()}
+
+	required, outcomes := service.calculateRequiredETH(0.45, 450000, 1000000, 10, 20000, 2000)
+	assert.Equal(t, 0.4, outcomes[0].Threshold)
+	assert.Equal(t, 0.3, outcomes[0].Target)
+	assert.InDelta(t, (450000/0.3-1000000)/2000, required, 1e-9)
+	assert.Equal(t, -700000.0, outcomes[1].Threshold)
+}
+
+func TestRunCycleObservesDurationAndLastSuccess(t *testing.T) {
+	service, srv, m := newTestService(t)
+	service.cycleObserver = m
+	srv.SetState(crossCollateralState(1000000, 200000, 300))
+
+	service.runCycle()
+	lastSuccess := testutil.ToFloat64(m.LastSuccessTimestamp.WithLabelValues("test"))
+	assert.InDelta(t, float64(time.Now().Unix()), lastSuccess, 5)
+
+	srv.InjectFault("private/get_account_summaries", fakederibit.Fault{Code: fakederibit.ErrCodeTooManyRequests, Message: "too_many_requests", Times: 1})
+	service.runCycle()
+
+	// 失败的周期计入耗时，不更新最近成功时间
+	assert.Equal(t, lastSuccess, testutil.ToFloat64(m.LastSuccessTimestamp.WithLabelValues("test")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.CycleDuration, "monitor_cycle_duration_seconds"))
+
+	status := service.Status()
+	assert.Equal(t, 2, status.Cycles)
+	assert.True(t, status.LastSuccessAt.Before(status.LastCycleAt))
+	assert.Contains(t, status.LastError, "too_many_requests")
+}
+
+type stubLeadership bool
+
+func (l *stubLeadership) IsLeader() bool { return bool(*l) }
+
+func TestRunCycleSkippedWhenNotLeader(t *testing.T) {
+	service, srv, m := newTestService(t)
+	service.cycleObserver = m
+	leader := stubLeadership(false)
+	service.leadership = &leader
+	srv.SetState(crossCollateralState(1000000, 200000, 300))
+
+	// 备用实例不执行周期，也不推送指标
+	service.runCycle()
+	assert.Equal(t, 0, service.Status().Cycles)
+	assert.Equal(t, 0, testutil.CollectAndCount(m.CycleDuration, "monitor_cycle_duration_seconds"))
+
+	leader = true
+	service.runCycle()
+	assert.Equal(t, 1, service.Status().Cycles)
+}
+
+func TestStartReturnsAfterStop(t *testing.T) {
+	service, srv, _ := newTestService(t)
+	service.config.Interval = 1
+	srv.SetState(crossCollateralState(1000000, 200000, 300))
+
+	done := make(chan error, 1)
+	go func() { done <- service.Start() }()
+	require.Eventually(t, func() bool { return service.Status().Cycles > 0 }, 5*time.Second, 50*time.Millisecond)
+
+	service.Stop()
+	service.Stop() // 可重复调用
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after Stop")
+	}
+	assert.Empty(t, service.Status().LastError)
+}
+
+func TestCheckPositionsTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	service, srv, _ := newTestService(t)
+	srv.SetState(crossCollateralState(1000000, 200000, 300))
+	require.NoError(t, service.checkPositions())
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	cycle := spans["monitor.cycle"]
+	require.NotNil(t, cycle)
+	assert.Contains(t, cycle.Attributes(), attribute.String("account", "test"))
+
+	// Deribit 请求、规则评估和指标推送都是周期的子 span
+	for _, name := range []string{"public/auth", "private/get_account_summaries", "public/get_index_price", "monitor.evaluate_rules", "prometheus.push"} {
+		require.Contains(t, spans, name)
+		assert.Equal(t, cycle.SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+	}
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans["public/get_index_price"].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	assert.Equal(t, "success", attrs["deribit.result"].AsString())
+	assert.Positive(t, attrs["deribit.us_in"].AsInt64())
+	assert.GreaterOrEqual(t, attrs["deribit.us_out"].AsInt64(), attrs["deribit.us_in"].AsInt64())
+}
@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"go.uber.org/zap"
+)
+
+// accountState calculateRequiredETH 的随机输入，取值范围覆盖正常和极端的账户状态
+type accountState struct {
+	TotalEquityUSD            float64
+	TotalMaintenanceMarginUSD float64
+	ETHEquity                 float64
+	ETHPriceUSD               float64
+}
+
+func (accountState) Generate(r *rand.Rand, _ int) reflect.Value {
+	state := accountState{
+		TotalEquityUSD: 1 + r.Float64()*1e8,
+		ETHPriceUSD:    1 + r.Float64()*20000,
+		ETHEquity:      (r.Float64()*2 - 1) * 1e5,
+	}
+	// MM 比率在 0 到 150% 之间
+	state.TotalMaintenanceMarginUSD = state.TotalEquityUSD * r.Float64() * 1.5
+	return reflect.ValueOf(state)
+}
+
+func (a accountState) calculate() (float64, []RuleOutcome) {
+	service := &Service{logger: zap.NewNop()}
+	mmRatio := a.TotalMaintenanceMarginUSD / a.TotalEquityUSD
+	return service.calculateRequiredETH(mmRatio, a.TotalMaintenanceMarginUSD, a.TotalEquityUSD,
+		a.ETHEquity, a.ETHEquity*a.ETHPriceUSD, a.ETHPriceUSD)
+}
+
+func TestPropertyRequiredETHNeverNegative(t *testing.T) {
+	property := func(a accountState) bool {
+		required, outcomes := a.calculate()
+		if required < 0 {
+			return false
+		}
+		for _, outcome := range outcomes {
+			if outcome.RequiredETH < 0 || (!outcome.Fired && outcome.RequiredETH != 0) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 5000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyTopUpRestoresTargetMMRatio(t *testing.T) {
+	property := func(a accountState) bool {
+		_, outcomes := a.calculate()
+		mmRule := outcomes[0]
+		if !mmRule.Fired {
+			return mmRule.Value <= mmRule.Threshold
+		}
+		// 补充的 ETH 计入权益后，MM 比率回到目标值
+		resulting := a.TotalMaintenanceMarginUSD / (a.TotalEquityUSD + mmRule.RequiredETH*a.ETHPriceUSD)
+		return math.Abs(resulting-mmRule.Target) < 1e-9
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 5000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyTopUpRestoresTargetETHEquity(t *testing.T) {
+	property := func(a accountState) bool {
+		_, outcomes := a.calculate()
+		equityRule := outcomes[1]
+		if !equityRule.Fired {
+			return a.ETHEquity*a.ETHPriceUSD >= equityRule.Threshold
+		}
+		return math.Abs(a.ETHEquity+equityRule.RequiredETH-equityRule.Target) < 1e-6
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 5000}); err != nil {
+		t.Error(err)
+	}
+}
+
+// 返回的补充数量总是第一条触发规则的数量
+func TestPropertyFirstFiredRuleWins(t *testing.T) {
+	property := func(a accountState) bool {
+		required, outcomes := a.calculate()
+		for _, outcome := range outcomes {
+			if outcome.Fired {
+				return required == outcome.RequiredETH
+			}
+		}
+		return required == 0
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 5000}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"context"
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/account"
+	"time"
+)
+
+// AccountSource 提供账户摘要，请求的 span 挂在 ctx（监控周期）下
+type AccountSource interface {
+	Authenticate() error
+	GetAccountSummariesContext(ctx context.Context, extended ...bool) (*types.AccountSummaries, error)
+}
+
+// PriceSource 提供指数价格，currency 为小写币种（如 "eth"）
+type PriceSource interface {
+	GetIndexPriceContext(ctx context.Context, currency string) (float64, error)
+}
+
+// DataSource 同时提供账户摘要和指数价格，*deribit.Client 满足此接口
+type DataSource interface {
+	AccountSource
+	PriceSource
+}
+
+// MetricsSink 接收每个监控周期的指标，UpdateAccountMetrics 在周期末调用，负责推送
+type MetricsSink interface {
+	UpdateEquityMetrics(accountName string, equity *account.Equity)
+	UpdateGreeksMetrics(account string, summary types.CurrencySummary)
+	UpdateAccountMetrics(ctx context.Context, currency, account string, mmRatio, ethEquity, ethEquityUSD, totalEquity, maintenanceMargin, marginBalance, ethPriceUSD, requiredETHAmount float64, timestamp int64)
+}
+
+// CycleObserver 记录每个监控周期的耗时和结果，*metrics.Metrics 满足此接口
+type CycleObserver interface {
+	ObserveCycle(account string, duration time.Duration, err error)
+}
+
+// Cycle 一个监控周期的评估结果，传给各 CycleHook
+type Cycle struct {
+	Context    context.Context // 当前 hook 的 span 所在的 context，hook 内的请求可以挂在它下面
+	Account    string
+	Summaries  *types.AccountSummaries
+	Evaluation *Evaluation
+}
+
+// CycleHook 每个监控周期评估完成后、推送指标前依次运行的可选模块（对冲建议、展期计划、补充提案等）
+// 返回的错误只记录日志，不影响本周期的其他模块和指标推送
+type CycleHook interface {
+	Name() string
+	OnCycle(cycle *Cycle) error
+}
+
+// Hook 用名称和函数构造 CycleHook，名称用于日志
+func Hook(name string, fn func(cycle *Cycle) error) CycleHook {
+	return hookFunc{name: name, fn: fn}
+}
+
+type hookFunc struct {
+	name string
+	fn   func(cycle *Cycle) error
+}
+
+func (h hookFunc) Name() string { return h.name }
+
+func (h hookFunc) OnCycle(cycle *Cycle) error { return h.fn(cycle) }
+
+// RequireIndexPrice 标记依赖 ETH 指数价格的 hook（补充提案、对账），使用备用价格的周期跳过
+func RequireIndexPrice(hook CycleHook) CycleHook {
+	return priceHook{hook}
+}
+
+type priceHook struct {
+	CycleHook
+}
+
+// priceDependent 由 RequireIndexPrice 包装的 hook 实现
+type priceDependent interface {
+	requiresIndexPrice()
+}
+
+func (priceHook) requiresIndexPrice() {}
+
+// AuditRecorder 写入审计记录，*audit.Logger 满足此接口
+type AuditRecorder interface {
+	Record(entryType string, data interface{}) error
+}
+
+// Leadership 高可用模式下判断本实例是否为 leader，*election.Elector 满足此接口
+type Leadership interface {
+	IsLeader() bool
+}
+
+// Option 监控服务的可选配置
+type Option func(*Service)
+
+// WithPriceSource 使用单独的指数价格来源（如带缓存的包装或其他交易所），默认使用账户数据来源
+func WithPriceSource(prices PriceSource) Option {
+	return func(s *Service) {
+		s.prices = prices
+	}
+}
+
+// WithAuditLogger 启用审计日志，每次评估的输入和规则结果都会写入
+func WithAuditLogger(logger AuditRecorder) Option {
+	return func(s *Service) {
+		s.auditLogger = logger
+	}
+}
+
+// WithHooks 追加每个监控周期运行的模块，按追加顺序运行
+func WithHooks(hooks ...CycleHook) Option {
+	return func(s *Service) {
+		s.hooks = append(s.hooks, hooks...)
+	}
+}
+
+// WithCycleObserver 记录每个监控周期的耗时、结果和最近一次成功的时间
+func WithCycleObserver(observer CycleObserver) Option {
+	return func(s *Service) {
+		s.cycleObserver = observer
+	}
+}
+
+// WithLeadership 启用高可用模式，本实例不是 leader 时跳过监控周期，不推送指标也不发送通知
+func WithLeadership(leadership Leadership) Option {
+	return func(s *Service) {
+		s.leadership = leadership
+	}
+}
@@ -0,0 +1,185 @@
+package monitor
+
+import (
+	"context"
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/account"
+	"cs-projects-eth-collar/pkg/tracing"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+// staticSource 返回固定数据的账户和价格来源
+type staticSource struct {
+	summaries types.AccountSummaries
+	prices    map[string]float64
+}
+
+func (s *staticSource) Authenticate() error { return nil }
+
+func (s *staticSource) GetAccountSummariesContext(ctx context.Context, extended ...bool) (*types.AccountSummaries, error) {
+	return &s.summaries, nil
+}
+
+func (s *staticSource) GetIndexPriceContext(ctx context.Context, currency string) (float64, error) {
+	price, ok := s.prices[currency]
+	if !ok {
+		return 0, fmt.Errorf("no index price for %s", currency)
+	}
+	return price, nil
+}
+
+// recordingSink 记录最近一次推送的账户指标
+type recordingSink struct {
+	mmRatio     float64
+	requiredETH float64
+	equity      *account.Equity
+	pushes      int
+}
+
+func (r *recordingSink) UpdateEquityMetrics(accountName string, equity *account.Equity) {
+	r.equity = equity
+}
+
+func (r *recordingSink) UpdateGreeksMetrics(account string, summary types.CurrencySummary) {}
+
+func (r *recordingSink) UpdateAccountMetrics(ctx context.Context, currency, account string, mmRatio, ethEquity, ethEquityUSD, totalEquity, maintenanceMargin, marginBalance, ethPriceUSD, requiredETHAmount float64, timestamp int64) {
+	r.mmRatio = mmRatio
+	r.requiredETH = requiredETHAmount
+	r.pushes++
+}
+
+// recordingHook 记录每个周期收到的需要补充的数量
+type recordingHook struct {
+	name    string
+	amounts []float64
+}
+
+func (r *recordingHook) Name() string { return r.name }
+
+func (r *recordingHook) OnCycle(cycle *Cycle) error {
+	r.amounts = append(r.amounts, cycle.Evaluation.RequiredETH)
+	return nil
+}
+
+type recordingAudit struct {
+	types []string
+}
+
+func (r *recordingAudit) Record(entryType string, data interface{}) error {
+	r.types = append(r.types, entryType)
+	return nil
+}
+
+func breachSource() *staticSource {
+	summary := func(currency string, equity float64) types.CurrencySummary {
+		return types.CurrencySummary{Currency: currency, Equity: equity, MarginModel: "cross_pm", CrossCollateralEnabled: true,
+			TotalEquityUSD: 1000000, TotalMaintenanceMarginUSD: 600000}
+	}
+	return &staticSource{
+		summaries: types.AccountSummaries{Summaries: []types.CurrencySummary{summary("ETH", 300), summary("BTC", 1)}},
+		prices:    map[string]float64{"eth": 2000, "btc": 60000},
+	}
+}
+
+func TestServiceWithFakes(t *testing.T) {
+	sink := &recordingSink{}
+	proposer := &recordingHook{name: "topup"}
+	auditLog := &recordingAudit{}
+	service := NewService(types.MonitorConfig{Account: "desk"}, breachSource(), sink, zap.NewNop(),
+		WithHooks(proposer),
+		WithAuditLogger(auditLog),
+	)
+
+	require.NoError(t, service.checkPositions())
+
+	assert.Equal(t, 1, sink.pushes)
+	assert.InDelta(t, 0.6, sink.mmRatio, 1e-12)
+	assert.InDelta(t, 500, sink.requiredETH, 1e-9)
+	assert.Equal(t, account.ModeCrossCollateral, sink.equity.Mode)
+	assert.Equal(t, []string{"evaluation"}, auditLog.types)
+	require.Len(t, proposer.amounts, 1)
+	assert.InDelta(t, 500, proposer.amounts[0], 1e-9)
+}
+
+func TestFallbackPriceSkipsTopUp(t *testing.T) {
+	sink := &recordingSink{}
+	proposer := &recordingHook{name: "topup"}
+	hedge := &recordingHook{name: "hedge"}
+	source := breachSource()
+	delete(source.prices, "eth")
+	service := NewService(types.MonitorConfig{Account: "desk"}, source, sink, zap.NewNop(), WithHooks(RequireIndexPrice(proposer), hedge))
+
+	require.NoError(t, service.checkPositions())
+
+	// 指标仍按备用价格推送，但依赖指数价格的模块不运行
+	assert.Equal(t, 1, sink.pushes)
+	assert.Greater(t, sink.requiredETH, 0.0)
+	assert.Empty(t, proposer.amounts)
+	assert.Len(t, hedge.amounts, 1)
+}
+
+func TestHookErrorDoesNotFailCycle(t *testing.T) {
+	sink := &recordingSink{}
+	var order []string
+	failing := Hook("roll", func(cycle *Cycle) error {
+		order = append(order, "roll")
+		return fmt.Errorf("instrument lookup failed")
+	})
+	next := Hook("funding", func(cycle *Cycle) error {
+		order = append(order, "funding")
+		assert.Equal(t, "desk", cycle.Account)
+		assert.Len(t, cycle.Summaries.Summaries, 2)
+		return nil
+	})
+	service := NewService(types.MonitorConfig{Account: "desk"}, breachSource(), sink, zap.NewNop(), WithHooks(failing, next))
+
+	// 模块失败只记录日志，后续模块照常运行，指标照常推送
+	require.NoError(t, service.checkPositions())
+	assert.Equal(t, []string{"roll", "funding"}, order)
+	assert.Equal(t, 1, sink.pushes)
+}
+
+func TestWithPriceSource(t *testing.T) {
+	sink := &recordingSink{}
+	prices := &staticSource{prices: map[string]float64{"eth": 4000, "btc": 60000}}
+	service := NewService(types.MonitorConfig{Account: "desk"}, breachSource(), sink, zap.NewNop(), WithPriceSource(prices))
+
+	require.NoError(t, service.checkPositions())
+
+	// 价格来自单独的价格来源：(600000/0.3 - 1000000) / 4000
+	assert.InDelta(t, 250, sink.requiredETH, 1e-9)
+}
+
+func TestCycleSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	// 模块内的请求通过 cycle.Context 挂在该模块的 span 下
+	hook := Hook("legs", func(cycle *Cycle) error {
+		_, span := tracing.Start(cycle.Context, "legs.request")
+		span.End()
+		return nil
+	})
+	service := NewService(types.MonitorConfig{Account: "desk"}, breachSource(), &recordingSink{}, zap.NewNop(), WithHooks(hook))
+	require.NoError(t, service.checkPositions())
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	require.Len(t, spans, 4)
+	cycle := spans["monitor.cycle"].SpanContext().SpanID()
+	assert.Equal(t, cycle, spans["monitor.evaluate_rules"].Parent().SpanID())
+	assert.Equal(t, cycle, spans["monitor.hook"].Parent().SpanID())
+	assert.Equal(t, spans["monitor.hook"].SpanContext().SpanID(), spans["legs.request"].Parent().SpanID())
+}
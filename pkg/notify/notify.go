@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/tracing"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// Severity 通知级别
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Notification 一条通知消息
+type Notification struct {
+	Source    string                 `json:"source"`   // 产生通知的模块，如 "hedge"
+	Severity  Severity               `json:"severity"` // 通知级别
+	Title     string                 `json:"title"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"` // 结构化附加信息
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Notifier 通知发送接口
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// NewNotifier 根据配置创建通知器：始终写日志，另外发送到配置的所有 webhook
+func NewNotifier(config types.NotifyConfig, logger *zap.Logger) Notifier {
+	notifiers := []Notifier{NewLogNotifier(logger)}
+	for _, webhook := range config.Webhooks {
+		notifiers = append(notifiers, NewWebhookNotifier(webhook))
+	}
+	return Multi(notifiers...)
+}
+
+// multiNotifier 将通知依次发送给多个通知器
+type multiNotifier struct {
+	notifiers []Notifier
+}
+
+// Multi 组合多个通知器，单个通知器失败不影响其他通知器
+func Multi(notifiers ...Notifier) Notifier {
+	return &multiNotifier{notifiers: notifiers}
+}
+
+func (m *multiNotifier) Notify(n Notification) error {
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now().UTC()
+	}
+
+	// Notifier 接口不带 context，每次通知是单独的 trace
+	_, span := tracing.Start(context.Background(), "notify",
+		attribute.String("notify.source", n.Source),
+		attribute.String("notify.severity", string(n.Severity)),
+		attribute.String("notify.title", n.Title),
+		attribute.Int("notify.channels", len(m.notifiers)),
+	)
+	if account, ok := n.Fields["account"].(string); ok {
+		span.SetAttributes(attribute.String("account", account))
+	}
+
+	var errs []error
+	for _, notifier := range m.notifiers {
+		if err := notifier.Notify(n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	err := errors.Join(errs...)
+	tracing.End(span, err)
+	return err
+}
+
+// LogNotifier 将通知写入日志
+type LogNotifier struct {
+	logger *zap.Logger
+}
+
+func NewLogNotifier(logger *zap.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (l *LogNotifier) Notify(n Notification) error {
+	fields := []zap.Field{
+		zap.String("source", n.Source),
+		zap.String("severity", string(n.Severity)),
+		zap.String("title", n.Title),
+		zap.String("message", n.Message),
+	}
+	for key, value := range n.Fields {
+		fields = append(fields, zap.Any(key, value))
+	}
+
+	switch n.Severity {
+	case SeverityCritical:
+		l.logger.Error("Notification", fields...)
+	case SeverityWarning:
+		l.logger.Warn("Notification", fields...)
+	default:
+		l.logger.Info("Notification", fields...)
+	}
+	return nil
+}
+
+// WebhookNotifier 将通知以 JSON POST 到 webhook 地址
+type WebhookNotifier struct {
+	config     types.WebhookConfig
+	httpClient *http.Client
+}
+
+func NewWebhookNotifier(config types.WebhookConfig) *WebhookNotifier {
+	return &WebhookNotifier{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (w *WebhookNotifier) Notify(n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", w.config.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s request failed: %w", w.config.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("webhook %s HTTP error %d: %s", w.config.Name, resp.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
+
+// Recorder 记录已发送的通知（audit.Logger 满足该接口）
+type Recorder interface {
+	Record(entryType string, data interface{}) error
+}
+
+// recordingNotifier 发送通知后把通知内容和发送结果写入记录器
+type recordingNotifier struct {
+	next     Notifier
+	recorder Recorder
+}
+
+// WithRecorder 包装通知器，每条通知发送后写入 "notification" 记录
+func WithRecorder(next Notifier, recorder Recorder) Notifier {
+	return &recordingNotifier{next: next, recorder: recorder}
+}
+
+func (r *recordingNotifier) Notify(n Notification) error {
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now().UTC()
+	}
+
+	err := r.next.Notify(n)
+
+	record := struct {
+		Notification
+		Error string `json:"error,omitempty"`
+	}{Notification: n}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if recordErr := r.recorder.Record("notification", record); recordErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to record notification: %w", recordErr))
+	}
+	return err
+}
+
+// gatedNotifier allow 返回 false 时丢弃通知
+type gatedNotifier struct {
+	next  Notifier
+	allow func() bool
+}
+
+// WithGate 包装通知器，只在 allow 返回 true 时发送，用于高可用模式下只由 leader 发送通知
+func WithGate(next Notifier, allow func() bool) Notifier {
+	return &gatedNotifier{next: next, allow: allow}
+}
+
+func (g *gatedNotifier) Notify(n Notification) error {
+	if !g.allow() {
+		return nil
+	}
+	return g.next.Notify(n)
+}
@@ -0,0 +1,24 @@
+// Package notifytest 测试用的通知器，记录收到的通知
+package notifytest
+
+import "cs-projects-eth-collar/pkg/notify"
+
+// Notifier 按顺序记录收到的通知，不并发安全
+type Notifier struct {
+	Notifications []notify.Notification
+}
+
+// Notify 记录通知
+func (n *Notifier) Notify(notification notify.Notification) error {
+	n.Notifications = append(n.Notifications, notification)
+	return nil
+}
+
+// Titles 返回已记录通知的标题
+func (n *Notifier) Titles() []string {
+	var titles []string
+	for _, notification := range n.Notifications {
+		titles = append(titles, notification.Title)
+	}
+	return titles
+}
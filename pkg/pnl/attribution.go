@@ -0,0 +1,158 @@
+// Package pnl 根据账户流水按日（UTC）、按类型和按合约归因盈亏。
+//
+// 每条流水按类型拆分到以下类别（金额以流水币种计价）：
+//   - trades: 成交产生的现金流（期权权利金、期货已实现盈亏）
+//   - settlement: 每日结算的盈亏，不含资金费
+//   - funding: 永续合约资金费（结算流水的 interest_pl）
+//   - fees: 手续费（所有流水的 commission，支出为负）
+//   - deliveries: 到期交割的现金流
+//   - transfers: 充值、提现和划转，不计入盈亏
+//   - other: 其他类型（如 swap、correction）的余额变化
+package pnl
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 归因类别
+const (
+	TypeTrades     = "trades"
+	TypeSettlement = "settlement"
+	TypeFunding    = "funding"
+	TypeFees       = "fees"
+	TypeDeliveries = "deliveries"
+	TypeTransfers  = "transfers"
+	TypeOther      = "other"
+)
+
+// Types 归因类别，按报告中的顺序
+var Types = []string{TypeTrades, TypeSettlement, TypeFunding, TypeFees, TypeDeliveries, TypeTransfers, TypeOther}
+
+// noInstrument 没有合约的流水（如转账）在按合约归因中使用的名称
+const noInstrument = "-"
+
+// Day 一个币种一天（UTC）的盈亏归因
+type Day struct {
+	Date         time.Time                     `json:"date"`
+	Currency     string                        `json:"currency"`
+	ByType       map[string]float64            `json:"by_type"`
+	ByInstrument map[string]map[string]float64 `json:"by_instrument"` // 合约名 -> 类别 -> 金额
+	PnL          float64                       `json:"pnl"`           // 除 transfers 外各类别之和
+	Transfers    float64                       `json:"transfers"`
+	Entries      int                           `json:"entries"`
+}
+
+// InstrumentPnL 合约的盈亏（不含 transfers）
+func (d *Day) InstrumentPnL(instrument string) float64 {
+	var total float64
+	for category, amount := range d.ByInstrument[instrument] {
+		if category != TypeTransfers {
+			total += amount
+		}
+	}
+	return total
+}
+
+// Metrics 转换为指标值：包含所有类别，按合约的盈亏不含只有转账的合约
+func (d *Day) Metrics() types.PnLMetrics {
+	values := types.PnLMetrics{
+		Currency:     d.Currency,
+		ByType:       make(map[string]float64, len(Types)),
+		ByInstrument: make(map[string]float64, len(d.ByInstrument)),
+		PnL:          d.PnL,
+	}
+	for _, category := range Types {
+		values.ByType[category] = d.ByType[category]
+	}
+	for instrument, parts := range d.ByInstrument {
+		if _, ok := parts[TypeTransfers]; ok && len(parts) == 1 {
+			continue // 只有转账
+		}
+		values.ByInstrument[instrument] = d.InstrumentPnL(instrument)
+	}
+	return values
+}
+
+// Instruments 按合约名排序的合约列表
+func (d *Day) Instruments() []string {
+	names := make([]string, 0, len(d.ByInstrument))
+	for name := range d.ByInstrument {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Split 把一条流水拆分到各归因类别
+func Split(entry types.TransactionLogEntry) map[string]float64 {
+	parts := make(map[string]float64)
+	add := func(category string, amount float64) {
+		if amount != 0 {
+			parts[category] += amount
+		}
+	}
+
+	add(TypeFees, -entry.Commission)
+	switch strings.ToLower(entry.Type) {
+	case "trade":
+		add(TypeTrades, entry.Cashflow)
+	case "settlement":
+		add(TypeFunding, entry.InterestPL)
+		add(TypeSettlement, entry.Cashflow-entry.InterestPL)
+	case "delivery":
+		add(TypeDeliveries, entry.Cashflow)
+	case "transfer", "deposit", "withdrawal":
+		add(TypeTransfers, entry.Change+entry.Commission)
+	default:
+		add(TypeOther, entry.Change+entry.Commission)
+	}
+	return parts
+}
+
+// Attribute 按币种和日期（UTC）汇总流水，结果按日期、币种排序
+func Attribute(entries []types.TransactionLogEntry) []Day {
+	days := make(map[string]*Day)
+	for _, entry := range entries {
+		date := time.UnixMilli(entry.Timestamp).UTC().Truncate(24 * time.Hour)
+		currency := strings.ToUpper(entry.Currency)
+		key := date.Format("2006-01-02") + "/" + currency
+		day, ok := days[key]
+		if !ok {
+			day = &Day{Date: date, Currency: currency, ByType: make(map[string]float64), ByInstrument: make(map[string]map[string]float64)}
+			days[key] = day
+		}
+		day.Entries++
+
+		instrument := entry.InstrumentName
+		if instrument == "" {
+			instrument = noInstrument
+		}
+		for category, amount := range Split(entry) {
+			day.ByType[category] += amount
+			if day.ByInstrument[instrument] == nil {
+				day.ByInstrument[instrument] = make(map[string]float64)
+			}
+			day.ByInstrument[instrument][category] += amount
+			if category == TypeTransfers {
+				day.Transfers += amount
+			} else {
+				day.PnL += amount
+			}
+		}
+	}
+
+	result := make([]Day, 0, len(days))
+	for _, day := range days {
+		result = append(result, *day)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Date.Equal(result[j].Date) {
+			return result[i].Date.Before(result[j].Date)
+		}
+		return result[i].Currency < result[j].Currency
+	})
+	return result
+}
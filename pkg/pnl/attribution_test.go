@@ -0,0 +1,122 @@
+package pnl
+
+import (
+	"bytes"
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/history"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var day1 = time.Date(2024, 12, 27, 0, 0, 0, 0, time.UTC)
+
+func at(day time.Time, hour int) int64 {
+	return day.Add(time.Duration(hour) * time.Hour).UnixMilli()
+}
+
+// collarDay 展期当天的流水：卖出看涨、买入看跌、永续结算（含资金费）、交割、划入保证金
+func collarDay() []types.TransactionLogEntry {
+	return []types.TransactionLogEntry{
+		{ID: 1, Timestamp: at(day1, 1), Type: "trade", Currency: "ETH", InstrumentName: "ETH-28MAR25-3600-C", Cashflow: 3.3, Commission: 0.03, Change: 3.27},
+		{ID: 2, Timestamp: at(day1, 1), Type: "trade", Currency: "ETH", InstrumentName: "ETH-28MAR25-2600-P", Cashflow: -3.4, Commission: 0.03, Change: -3.43},
+		{ID: 3, Timestamp: at(day1, 8), Type: "settlement", Currency: "ETH", InstrumentName: "ETH-PERPETUAL", Cashflow: -0.5, InterestPL: -0.02, Change: -0.5},
+		{ID: 4, Timestamp: at(day1, 8), Type: "delivery", Currency: "ETH", InstrumentName: "ETH-27DEC24-3500-C", Cashflow: -2.7778, Commission: 0.0125, Change: -2.7903},
+		{ID: 5, Timestamp: at(day1, 9), Type: "transfer", Currency: "ETH", Change: 10},
+		{ID: 6, Timestamp: at(day1, 9), Type: "swap", Currency: "ETH", Change: 0.001},
+		{ID: 7, Timestamp: at(day1, 25), Type: "trade", Currency: "ETH", InstrumentName: "ETH-PERPETUAL", Cashflow: 0.2, Commission: 0.001, Change: 0.199},
+	}
+}
+
+func TestSplit(t *testing.T) {
+	entries := collarDay()
+
+	assert.Equal(t, map[string]float64{TypeTrades: 3.3, TypeFees: -0.03}, Split(entries[0]))
+	assert.Equal(t, map[string]float64{TypeSettlement: -0.48, TypeFunding: -0.02}, Split(entries[2]))
+	assert.Equal(t, map[string]float64{TypeDeliveries: -2.7778, TypeFees: -0.0125}, Split(entries[3]))
+	assert.Equal(t, map[string]float64{TypeTransfers: 10}, Split(entries[4]))
+	assert.Equal(t, map[string]float64{TypeOther: 0.001}, Split(entries[5]))
+}
+
+func TestAttribute(t *testing.T) {
+	days := Attribute(collarDay())
+	require.Len(t, days, 2)
+
+	day := days[0]
+	assert.Equal(t, day1, day.Date)
+	assert.Equal(t, "ETH", day.Currency)
+	assert.Equal(t, 6, day.Entries)
+	assert.InDelta(t, -0.1, day.ByType[TypeTrades], 1e-12)
+	assert.InDelta(t, -0.0725, day.ByType[TypeFees], 1e-12)
+	assert.InDelta(t, -0.02, day.ByType[TypeFunding], 1e-12)
+	assert.Equal(t, 10.0, day.Transfers)
+	// 盈亏合计等于除转账外的余额变化
+	assert.InDelta(t, 3.27-3.43-0.5-2.7903+0.001, day.PnL, 1e-12)
+	assert.InDelta(t, -2.7903, day.InstrumentPnL("ETH-27DEC24-3500-C"), 1e-12)
+	assert.Equal(t, []string{"-", "ETH-27DEC24-3500-C", "ETH-28MAR25-2600-P", "ETH-28MAR25-3600-C", "ETH-PERPETUAL"}, day.Instruments())
+
+	assert.Equal(t, day1.Add(24*time.Hour), days[1].Date)
+
+	// 指标值包含所有类别，转账不计入按合约的盈亏
+	values := day.Metrics()
+	assert.Len(t, values.ByType, len(Types))
+	assert.InDelta(t, 0.001, values.ByInstrument["-"], 1e-12)
+	transferOnly := Day{ByInstrument: map[string]map[string]float64{"-": {TypeTransfers: 10}}}
+	assert.Empty(t, transferOnly.Metrics().ByInstrument)
+
+	var report bytes.Buffer
+	require.NoError(t, WriteReport(&report, days, true))
+	assert.Contains(t, report.String(), "ETH-28MAR25-3600-C")
+	assert.Contains(t, report.String(), "total")
+}
+
+type recordingSink struct {
+	days []types.PnLMetrics
+}
+
+func (r *recordingSink) UpdatePnLMetrics(account string, days []types.PnLMetrics) {
+	r.days = days
+}
+
+type countingIngester struct {
+	calls int
+}
+
+func (c *countingIngester) Ingest(currency string) (int, error) {
+	c.calls++
+	return 0, nil
+}
+
+func TestTrackerIngestsOnInterval(t *testing.T) {
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.jsonl"))
+	require.NoError(t, err)
+	defer store.Close()
+	_, err = store.Append(collarDay())
+	require.NoError(t, err)
+
+	ingester := &countingIngester{}
+	sink := &recordingSink{}
+	tracker := NewTracker([]string{"eth"}, 5*time.Minute, ingester, store, sink, zap.NewNop())
+	now := day1.Add(10 * time.Hour)
+	tracker.now = func() time.Time { return now }
+
+	days, err := tracker.Evaluate("desk")
+	require.NoError(t, err)
+	require.Len(t, days, 1) // 只有当天
+	assert.Equal(t, []types.PnLMetrics{days[0].Metrics()}, sink.days)
+	assert.Equal(t, 1, ingester.calls)
+
+	now = now.Add(time.Minute)
+	_, err = tracker.Evaluate("desk")
+	require.NoError(t, err)
+	assert.Equal(t, 1, ingester.calls)
+
+	now = now.Add(5 * time.Minute)
+	_, err = tracker.Evaluate("desk")
+	require.NoError(t, err)
+	assert.Equal(t, 2, ingester.calls)
+}
@@ -0,0 +1,59 @@
+package pnl
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteReport 以文本表格输出每日盈亏归因；byInstrument 为 true 时每天再按合约展开
+func WriteReport(w io.Writer, days []Day, byInstrument bool) error {
+	if len(days) == 0 {
+		_, err := fmt.Fprintln(w, "No transactions in the selected range.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "DATE\tCURRENCY\tINSTRUMENT\t")
+	for _, category := range Types {
+		fmt.Fprintf(tw, "%s\t", category)
+	}
+	fmt.Fprintln(tw, "PNL\t")
+
+	writeRow := func(day Day, instrument string, values map[string]float64, total float64) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t", day.Date.Format("2006-01-02"), day.Currency, instrument)
+		for _, category := range Types {
+			fmt.Fprintf(tw, "%.6f\t", values[category])
+		}
+		fmt.Fprintf(tw, "%.6f\t\n", total)
+	}
+
+	totals := make(map[string]map[string]float64) // 币种 -> 类别 -> 金额
+	pnlTotals := make(map[string]float64)
+	var currencies []string
+	for _, day := range days {
+		if byInstrument {
+			for _, instrument := range day.Instruments() {
+				writeRow(day, instrument, day.ByInstrument[instrument], day.InstrumentPnL(instrument))
+			}
+		}
+		writeRow(day, "all", day.ByType, day.PnL)
+
+		if totals[day.Currency] == nil {
+			totals[day.Currency] = make(map[string]float64)
+			currencies = append(currencies, day.Currency)
+		}
+		for category, amount := range day.ByType {
+			totals[day.Currency][category] += amount
+		}
+		pnlTotals[day.Currency] += day.PnL
+	}
+	for _, currency := range currencies {
+		fmt.Fprintf(tw, "total\t%s\tall\t", currency)
+		for _, category := range Types {
+			fmt.Fprintf(tw, "%.6f\t", totals[currency][category])
+		}
+		fmt.Fprintf(tw, "%.6f\t\n", pnlTotals[currency])
+	}
+	return tw.Flush()
+}
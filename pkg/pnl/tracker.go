@@ -0,0 +1,83 @@
+package pnl
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/history"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Ingester 增量导入账户流水，*history.Ingester 满足此接口
+type Ingester interface {
+	Ingest(currency string) (int, error)
+}
+
+// Sink 接收当日盈亏归因指标，*metrics.Metrics 满足此接口
+type Sink interface {
+	UpdatePnLMetrics(account string, days []types.PnLMetrics)
+}
+
+// Tracker 按间隔导入账户流水，并在每个监控周期更新当日（UTC）的盈亏归因指标
+type Tracker struct {
+	currencies []string
+	interval   time.Duration
+	ingester   Ingester
+	store      *history.Store
+	metrics    Sink
+	logger     *zap.Logger
+	now        func() time.Time
+
+	lastIngest time.Time
+}
+
+// NewTracker 创建盈亏归因，metrics 可为 nil
+func NewTracker(currencies []string, interval time.Duration, ingester Ingester, store *history.Store, metrics Sink, logger *zap.Logger) *Tracker {
+	normalized := make([]string, 0, len(currencies))
+	for _, currency := range currencies {
+		normalized = append(normalized, strings.ToUpper(currency))
+	}
+	return &Tracker{
+		currencies: normalized,
+		interval:   interval,
+		ingester:   ingester,
+		store:      store,
+		metrics:    metrics,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Evaluate 距上次导入超过间隔时导入流水（单个币种失败只记录日志），返回各币种当日的盈亏归因
+func (t *Tracker) Evaluate(account string) ([]Day, error) {
+	now := t.now()
+	if t.lastIngest.IsZero() || now.Sub(t.lastIngest) >= t.interval {
+		for _, currency := range t.currencies {
+			added, err := t.ingester.Ingest(currency)
+			if err != nil {
+				t.logger.Error("Failed to ingest transaction log", zap.String("currency", currency), zap.Error(err))
+				continue
+			}
+			if added > 0 {
+				t.logger.Info("Transaction log ingested", zap.String("currency", currency), zap.Int("entries", added))
+			}
+		}
+		t.lastIngest = now
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	var days []Day
+	for _, currency := range t.currencies {
+		days = append(days, Attribute(t.store.Transactions(currency, today, today.Add(24*time.Hour)))...)
+	}
+
+	if t.metrics != nil {
+		values := make([]types.PnLMetrics, 0, len(days))
+		for i := range days {
+			values = append(values, days[i].Metrics())
+		}
+		t.metrics.UpdatePnLMetrics(account, values)
+	}
+	return days, nil
+}
@@ -0,0 +1,103 @@
+package reconcile
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// 资金流水类型
+const (
+	KindDeposit     = "deposit"
+	KindWithdrawal  = "withdrawal"
+	KindTransferIn  = "transfer_in"
+	KindTransferOut = "transfer_out"
+)
+
+// Kinds 资金流水类型，按指标中的顺序
+var Kinds = []string{KindDeposit, KindWithdrawal, KindTransferIn, KindTransferOut}
+
+// Reader 读取充值、提现和划转记录，*deribit.Client 满足此接口
+type Reader interface {
+	GetDeposits(currency string, count, offset int) (*types.Deposits, error)
+	GetWithdrawals(currency string, count, offset int) (*types.Withdrawals, error)
+	GetTransfers(currency string, count, offset int) (*types.Transfers, error)
+}
+
+// Flow 一笔已完成的资金流水，Amount 恒为正数，方向由 Kind 决定
+type Flow struct {
+	ID           string  `json:"id"` // 类型前缀 + Deribit 记录 ID，如 deposit:0x3f...
+	Kind         string  `json:"kind"`
+	Currency     string  `json:"currency"`
+	Amount       float64 `json:"amount"`
+	Timestamp    int64   `json:"timestamp"` // 毫秒
+	Counterparty string  `json:"counterparty,omitempty"`
+	MatchedTo    string  `json:"matched_to,omitempty"` // 匹配到的补充请求 ID
+}
+
+// Credit 是否为转入账户的资金（充值或转入划转）
+func (f Flow) Credit() bool {
+	return f.Kind == KindDeposit || f.Kind == KindTransferIn
+}
+
+// Time 流水时间
+func (f Flow) Time() time.Time {
+	return time.UnixMilli(f.Timestamp).UTC()
+}
+
+// Fetch 读取最近 count 条充值、提现和划转记录，只返回已完成的流水
+func Fetch(reader Reader, currency string, count int) ([]Flow, error) {
+	var flows []Flow
+
+	deposits, err := reader.GetDeposits(currency, count, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s deposits: %w", currency, err)
+	}
+	for _, d := range deposits.Data {
+		if d.State != "completed" {
+			continue
+		}
+		id := d.TransactionID
+		if id == "" {
+			id = fmt.Sprintf("%s@%d", d.Address, d.ReceivedTimestamp)
+		}
+		flows = append(flows, Flow{ID: KindDeposit + ":" + id, Kind: KindDeposit, Currency: strings.ToUpper(d.Currency),
+			Amount: math.Abs(d.Amount), Timestamp: d.ReceivedTimestamp, Counterparty: d.Address})
+	}
+
+	withdrawals, err := reader.GetWithdrawals(currency, count, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s withdrawals: %w", currency, err)
+	}
+	for _, w := range withdrawals.Data {
+		if w.State != "completed" && w.State != "confirmed" {
+			continue
+		}
+		timestamp := w.ConfirmedTimestamp
+		if timestamp == 0 {
+			timestamp = w.CreatedTimestamp
+		}
+		flows = append(flows, Flow{ID: fmt.Sprintf("%s:%d", KindWithdrawal, w.ID), Kind: KindWithdrawal, Currency: strings.ToUpper(w.Currency),
+			Amount: math.Abs(w.Amount), Timestamp: timestamp, Counterparty: w.Address})
+	}
+
+	transfers, err := reader.GetTransfers(currency, count, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s transfers: %w", currency, err)
+	}
+	for _, t := range transfers.Data {
+		if t.State != "confirmed" {
+			continue
+		}
+		kind := KindTransferOut
+		if t.Direction == "income" {
+			kind = KindTransferIn
+		}
+		flows = append(flows, Flow{ID: fmt.Sprintf("transfer:%d", t.ID), Kind: kind, Currency: strings.ToUpper(t.Currency),
+			Amount: math.Abs(t.Amount), Timestamp: t.CreatedTimestamp, Counterparty: t.OtherSide})
+	}
+
+	return flows, nil
+}
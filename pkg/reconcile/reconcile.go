@@ -0,0 +1,429 @@
+// Package reconcile 把账户的充值和转入划转与监控发出的补充 ETH 建议对账。
+//
+// 监控计算出需要补充 ETH 时开启一个补充请求，之后每个周期读取最近的充值、提现和划转记录，
+// 把请求开启后到账的充值和转入按时间顺序匹配到最早的未完成请求：
+//   - pending: 尚未到账
+//   - partial: 部分到账
+//   - fulfilled: 到账数量达到请求数量的 (1 - tolerance)
+//   - overdue: 超过 due_seconds 仍未完成，按 escalate_interval_seconds 升级通知
+//   - cleared: 未完成但监控已不再要求补充（如价格回升）
+//
+// 请求和资金流水保存在本地 JSON 文件中，重启后继续对账。
+package reconcile
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/admin"
+	"cs-projects-eth-collar/pkg/notify"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 补充请求状态
+const (
+	StatusPending   = "pending"
+	StatusPartial   = "partial"
+	StatusFulfilled = "fulfilled"
+	StatusOverdue   = "overdue"
+	StatusCleared   = "cleared"
+)
+
+// Statuses 补充请求状态，按指标中的顺序
+var Statuses = []string{StatusPending, StatusPartial, StatusFulfilled, StatusOverdue, StatusCleared}
+
+// Sink 接收对账指标，*metrics.Metrics 满足此接口
+type Sink interface {
+	UpdateReconcileMetrics(account, currency string, requests map[string]float64, outstanding, overdueSeconds float64, flows24h map[string]float64)
+}
+
+// Match 匹配到补充请求的一笔到账
+type Match struct {
+	FlowID string    `json:"flow_id"`
+	Kind   string    `json:"kind"`
+	Amount float64   `json:"amount"`
+	At     time.Time `json:"at"`
+}
+
+// Request 一次补充 ETH 请求
+type Request struct {
+	ID             string     `json:"id"`
+	Account        string     `json:"account"`
+	Currency       string     `json:"currency"`
+	Required       float64    `json:"required"`        // 需要补充的数量，缺口扩大时上调
+	LatestRequired float64    `json:"latest_required"` // 最近一个周期监控计算的需要补充数量
+	Received       float64    `json:"received"`
+	Matches        []Match    `json:"matches,omitempty"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DueAt          time.Time  `json:"due_at"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty"`
+	Escalations    int        `json:"escalations"`
+	LastEscalated  time.Time  `json:"last_escalated,omitempty"`
+}
+
+// Open 请求是否仍未关闭
+func (r *Request) Open() bool {
+	return r.ClosedAt == nil
+}
+
+// Remaining 尚未到账的数量
+func (r *Request) Remaining() float64 {
+	return math.Max(r.Required-r.Received, 0)
+}
+
+// Summary 一次对账的结果
+type Summary struct {
+	Currency       string             `json:"currency"`
+	Requests       []Request          `json:"requests"`        // 保留的全部请求，按创建时间排序
+	Counts         map[string]int     `json:"counts"`          // 状态 -> 请求数
+	Outstanding    float64            `json:"outstanding"`     // 未关闭请求尚未到账的数量之和
+	OverdueSeconds float64            `json:"overdue_seconds"` // 逾期最久的请求已逾期的秒数
+	Flows24h       map[string]float64 `json:"flows_24h"`       // 类型 -> 过去 24 小时的数量
+}
+
+// state 保存到文件的对账状态
+type state struct {
+	Requests []*Request `json:"requests"`
+	Flows    []Flow     `json:"flows"`
+	Seq      int        `json:"seq"`
+}
+
+// Reconciler 跟踪补充请求并与资金流水对账
+type Reconciler struct {
+	config   types.ReconcileConfig
+	reader   Reader
+	notifier notify.Notifier
+	metrics  Sink
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu    sync.Mutex
+	state state
+	flows map[string]bool // 已保存的流水 ID
+}
+
+// NewReconciler 创建对账，从状态文件恢复请求和流水；metrics 可为 nil
+func NewReconciler(config types.ReconcileConfig, reader Reader, notifier notify.Notifier, metrics Sink, logger *zap.Logger) (*Reconciler, error) {
+	config.Currency = strings.ToUpper(config.Currency)
+	r := &Reconciler{
+		config:   config,
+		reader:   reader,
+		notifier: notifier,
+		metrics:  metrics,
+		logger:   logger,
+		now:      time.Now,
+		flows:    make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(config.File)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read reconcile state %s: %w", config.File, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &r.state); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reconcile state %s: %w", config.File, err)
+		}
+	}
+	for _, flow := range r.state.Flows {
+		r.flows[flow.ID] = true
+	}
+	return r, nil
+}
+
+// Evaluate 用本周期需要补充的数量更新请求，导入最近的资金流水并对账
+func (r *Reconciler) Evaluate(account string, requiredAmount float64) (*Summary, error) {
+	flows, err := Fetch(r.reader, r.config.Currency, r.config.FetchCount)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now().UTC()
+	r.ingestLocked(flows)
+	r.matchLocked(now)
+	r.updateLocked(account, requiredAmount, now)
+	r.escalateLocked(now)
+	r.pruneLocked(now)
+
+	if err := r.saveLocked(); err != nil {
+		r.logger.Error("Failed to save reconcile state", zap.Error(err))
+	}
+
+	summary := r.summaryLocked(now)
+	r.logger.Info("Top-up reconciliation",
+		zap.String("currency", summary.Currency),
+		zap.Float64("required_amount", requiredAmount),
+		zap.Float64("outstanding", summary.Outstanding),
+		zap.Int("overdue", summary.Counts[StatusOverdue]),
+	)
+	if r.metrics != nil {
+		requests := make(map[string]float64, len(Statuses))
+		for _, status := range Statuses {
+			requests[status] = float64(summary.Counts[status])
+		}
+		flows := make(map[string]float64, len(Kinds))
+		for _, kind := range Kinds {
+			flows[kind] = summary.Flows24h[kind]
+		}
+		r.metrics.UpdateReconcileMetrics(account, summary.Currency, requests, summary.Outstanding, summary.OverdueSeconds, flows)
+	}
+	return summary, nil
+}
+
+// Requests 返回保留的全部请求
+func (r *Reconciler) Requests() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.copyRequestsLocked()
+}
+
+// ingestLocked 保存尚未见过的流水
+func (r *Reconciler) ingestLocked(flows []Flow) {
+	for _, flow := range flows {
+		if r.flows[flow.ID] {
+			continue
+		}
+		r.flows[flow.ID] = true
+		r.state.Flows = append(r.state.Flows, flow)
+	}
+	sort.SliceStable(r.state.Flows, func(i, j int) bool { return r.state.Flows[i].Timestamp < r.state.Flows[j].Timestamp })
+}
+
+// matchLocked 把未匹配的到账按时间顺序匹配到最早的、在到账前开启的未完成请求
+func (r *Reconciler) matchLocked(now time.Time) {
+	for i := range r.state.Flows {
+		flow := &r.state.Flows[i]
+		if !flow.Credit() || flow.MatchedTo != "" {
+			continue
+		}
+		for _, req := range r.state.Requests {
+			if !req.Open() || flow.Time().Before(req.CreatedAt) {
+				continue
+			}
+			flow.MatchedTo = req.ID
+			req.Received += flow.Amount
+			req.Matches = append(req.Matches, Match{FlowID: flow.ID, Kind: flow.Kind, Amount: flow.Amount, At: flow.Time()})
+
+			if req.Remaining() <= req.Required*r.config.Tolerance {
+				req.Status = StatusFulfilled
+				req.ClosedAt = &now
+				r.send(notify.SeverityInfo, fmt.Sprintf("%s top-up fulfilled", req.Currency),
+					fmt.Sprintf("Request %s: received %.4f of %.4f %s (%d transfers)", req.ID, req.Received, req.Required, req.Currency, len(req.Matches)),
+					req)
+			} else {
+				if req.Status == StatusPending {
+					req.Status = StatusPartial
+				}
+				r.send(notify.SeverityInfo, fmt.Sprintf("%s top-up partially received", req.Currency),
+					fmt.Sprintf("Request %s: received %.4f of %.4f %s, %.4f outstanding", req.ID, req.Received, req.Required, req.Currency, req.Remaining()),
+					req)
+			}
+			break
+		}
+	}
+}
+
+// updateLocked 需要补充时开启或更新请求；不再需要补充时关闭未完成的请求
+func (r *Reconciler) updateLocked(account string, requiredAmount float64, now time.Time) {
+	var open *Request
+	for _, req := range r.state.Requests {
+		if req.Open() {
+			open = req
+		}
+	}
+
+	switch {
+	case requiredAmount > 0 && open == nil:
+		r.state.Seq++
+		req := &Request{
+			ID:             fmt.Sprintf("request-%s-%d", now.Format("20060102T150405"), r.state.Seq),
+			Account:        account,
+			Currency:       r.config.Currency,
+			Required:       requiredAmount,
+			LatestRequired: requiredAmount,
+			Status:         StatusPending,
+			CreatedAt:      now,
+			DueAt:          now.Add(time.Duration(r.config.DueSeconds) * time.Second),
+		}
+		r.state.Requests = append(r.state.Requests, req)
+		r.logger.Info("Top-up request opened", zap.String("request_id", req.ID), zap.Float64("required", req.Required))
+	case requiredAmount > 0:
+		// 已到账的部分已经反映在本周期的需要补充数量中，两者之和超过原请求说明缺口扩大
+		open.LatestRequired = requiredAmount
+		open.Required = math.Max(open.Required, open.Received+requiredAmount)
+	case open != nil:
+		for _, req := range r.state.Requests {
+			if !req.Open() {
+				continue
+			}
+			req.Status = StatusCleared
+			req.ClosedAt = &now
+			req.LatestRequired = 0
+			r.send(notify.SeverityInfo, fmt.Sprintf("%s top-up no longer required", req.Currency),
+				fmt.Sprintf("Request %s cleared: received %.4f of %.4f %s before the requirement went away", req.ID, req.Received, req.Required, req.Currency),
+				req)
+		}
+	}
+}
+
+// escalateLocked 逾期请求首次发送 warning，之后按间隔以 critical 升级
+func (r *Reconciler) escalateLocked(now time.Time) {
+	interval := time.Duration(r.config.EscalateIntervalSeconds) * time.Second
+	for _, req := range r.state.Requests {
+		if !req.Open() || now.Before(req.DueAt) {
+			continue
+		}
+		if req.Status == StatusOverdue && now.Sub(req.LastEscalated) < interval {
+			continue
+		}
+
+		severity := notify.SeverityWarning
+		if req.Status == StatusOverdue {
+			severity = notify.SeverityCritical
+		}
+		req.Status = StatusOverdue
+		req.Escalations++
+		req.LastEscalated = now
+		r.send(severity, fmt.Sprintf("%s top-up overdue", req.Currency),
+			fmt.Sprintf("Request %s: %.4f of %.4f %s received, %.4f outstanding %s after it was due",
+				req.ID, req.Received, req.Required, req.Currency, req.Remaining(), now.Sub(req.DueAt).Round(time.Second)),
+			req)
+	}
+}
+
+// pruneLocked 删除超过保留期的已关闭请求和流水；最早的未关闭请求之后的流水始终保留，避免重新导入后重复匹配
+func (r *Reconciler) pruneLocked(now time.Time) {
+	cutoff := now.Add(-time.Duration(r.config.RetentionDays) * 24 * time.Hour)
+
+	requests := r.state.Requests[:0]
+	for _, req := range r.state.Requests {
+		if req.Open() {
+			if req.CreatedAt.Before(cutoff) {
+				cutoff = req.CreatedAt
+			}
+			requests = append(requests, req)
+		} else if req.ClosedAt.After(cutoff) {
+			requests = append(requests, req)
+		}
+	}
+	r.state.Requests = requests
+
+	flows := r.state.Flows[:0]
+	for _, flow := range r.state.Flows {
+		if flow.Time().Before(cutoff) {
+			delete(r.flows, flow.ID)
+			continue
+		}
+		flows = append(flows, flow)
+	}
+	r.state.Flows = flows
+}
+
+// saveLocked 原子地写入状态文件
+func (r *Reconciler) saveLocked() error {
+	data, err := json.MarshalIndent(r.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal reconcile state: %w", err)
+	}
+	tmp := r.config.File + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write reconcile state: %w", err)
+	}
+	if err := os.Rename(tmp, r.config.File); err != nil {
+		return fmt.Errorf("failed to replace reconcile state: %w", err)
+	}
+	return nil
+}
+
+func (r *Reconciler) summaryLocked(now time.Time) *Summary {
+	summary := &Summary{
+		Currency: r.config.Currency,
+		Requests: r.copyRequestsLocked(),
+		Counts:   make(map[string]int),
+		Flows24h: make(map[string]float64),
+	}
+	for _, req := range r.state.Requests {
+		summary.Counts[req.Status]++
+		if !req.Open() {
+			continue
+		}
+		summary.Outstanding += req.Remaining()
+		if req.Status == StatusOverdue {
+			summary.OverdueSeconds = math.Max(summary.OverdueSeconds, now.Sub(req.DueAt).Seconds())
+		}
+	}
+	since := now.Add(-24 * time.Hour)
+	for _, flow := range r.state.Flows {
+		if !flow.Time().Before(since) {
+			summary.Flows24h[flow.Kind] += flow.Amount
+		}
+	}
+	return summary
+}
+
+func (r *Reconciler) copyRequestsLocked() []Request {
+	requests := make([]Request, 0, len(r.state.Requests))
+	for _, req := range r.state.Requests {
+		c := *req
+		c.Matches = append([]Match(nil), req.Matches...)
+		requests = append(requests, c)
+	}
+	return requests
+}
+
+func (r *Reconciler) send(severity notify.Severity, title, message string, req *Request) {
+	n := notify.Notification{
+		Source:   "reconcile",
+		Severity: severity,
+		Title:    title,
+		Message:  message,
+		Fields: map[string]interface{}{
+			"account":    req.Account,
+			"request_id": req.ID,
+			"status":     req.Status,
+			"required":   req.Required,
+			"received":   req.Received,
+			"due_at":     req.DueAt,
+		},
+	}
+	if err := r.notifier.Notify(n); err != nil {
+		r.logger.Error("Failed to send reconcile notification", zap.Error(err))
+	}
+}
+
+// ReadRequests 读取状态文件中保留的全部请求（供报告使用）
+func ReadRequests(path string) ([]Request, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reconcile state %s: %w", path, err)
+	}
+	requests := make([]Request, 0, len(st.Requests))
+	for _, req := range st.Requests {
+		requests = append(requests, *req)
+	}
+	return requests, nil
+}
+
+// RegisterHandlers 注册对账相关的管理接口
+//
+//	GET /topups/requests 列出补充请求及匹配的到账
+func (r *Reconciler) RegisterHandlers(server *admin.Server) {
+	server.Handle("GET /topups/requests", func(w http.ResponseWriter, req *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, r.Requests())
+	})
+}
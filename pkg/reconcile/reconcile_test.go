@@ -0,0 +1,147 @@
+package reconcile
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"cs-projects-eth-collar/pkg/notify"
+	"cs-projects-eth-collar/pkg/notify/notifytest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var openedAt = time.Date(2024, 12, 20, 12, 0, 0, 0, time.UTC)
+
+func testConfig(dir string) types.ReconcileConfig {
+	return types.ReconcileConfig{
+		Currency:                "eth",
+		File:                    filepath.Join(dir, "topups.json"),
+		DueSeconds:              3600,
+		EscalateIntervalSeconds: 1800,
+		Tolerance:               0.01,
+		FetchCount:              50,
+		RetentionDays:           30,
+	}
+}
+
+func newTestReconciler(t *testing.T) (*Reconciler, *fakederibit.Server, *notifytest.Notifier, *time.Time) {
+	srv := fakederibit.NewServer()
+	t.Cleanup(srv.Close)
+	srv.SetState(fakederibit.State{
+		// 请求开启前的充值不参与匹配
+		Deposits: []types.Deposit{{Amount: 5, Currency: "ETH", State: "completed", TransactionID: "0xold", ReceivedTimestamp: openedAt.Add(-time.Hour).UnixMilli()}},
+	})
+	client, err := deribit.NewClient(types.DeribitConfig{BaseURL: srv.URL(), APIKey: srv.ClientID, APISecret: srv.ClientSecret})
+	require.NoError(t, err)
+
+	notifier := &notifytest.Notifier{}
+	reconciler, err := NewReconciler(testConfig(t.TempDir()), client, notifier, nil, zap.NewNop())
+	require.NoError(t, err)
+
+	now := openedAt
+	reconciler.now = func() time.Time { return now }
+	return reconciler, srv, notifier, &now
+}
+
+func TestPartialThenFulfilled(t *testing.T) {
+	reconciler, srv, notifier, now := newTestReconciler(t)
+
+	summary, err := reconciler.Evaluate("desk", 35)
+	require.NoError(t, err)
+	require.Len(t, summary.Requests, 1)
+	assert.Equal(t, StatusPending, summary.Requests[0].Status)
+	assert.Equal(t, 35.0, summary.Outstanding)
+	assert.Equal(t, 5.0, summary.Flows24h[KindDeposit])
+
+	// 转入 20（部分到账），待确认的充值和转出不计入
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Transfers = []types.Transfer{
+			{ID: 1, Amount: 20, Currency: "ETH", Direction: "income", State: "confirmed", CreatedTimestamp: openedAt.Add(10 * time.Minute).UnixMilli()},
+			{ID: 2, Amount: 3, Currency: "ETH", Direction: "payment", State: "confirmed", CreatedTimestamp: openedAt.Add(11 * time.Minute).UnixMilli()},
+		}
+		state.Deposits = append(state.Deposits, types.Deposit{Amount: 15, Currency: "ETH", State: "pending", TransactionID: "0xnew"})
+	})
+	*now = openedAt.Add(15 * time.Minute)
+	summary, err = reconciler.Evaluate("desk", 15)
+	require.NoError(t, err)
+	req := summary.Requests[0]
+	assert.Equal(t, StatusPartial, req.Status)
+	assert.Equal(t, 20.0, req.Received)
+	assert.Equal(t, 15.0, summary.Outstanding)
+	assert.Equal(t, 3.0, summary.Flows24h[KindTransferOut])
+
+	// 充值确认后完成
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Deposits[1].State = "completed"
+		state.Deposits[1].ReceivedTimestamp = openedAt.Add(20 * time.Minute).UnixMilli()
+	})
+	*now = openedAt.Add(25 * time.Minute)
+	summary, err = reconciler.Evaluate("desk", 0)
+	require.NoError(t, err)
+	req = summary.Requests[0]
+	assert.Equal(t, StatusFulfilled, req.Status)
+	assert.Len(t, req.Matches, 2)
+	assert.Equal(t, 0.0, summary.Outstanding)
+	assert.Equal(t, []string{"ETH top-up partially received", "ETH top-up fulfilled"}, notifier.Titles())
+}
+
+func TestOverdueEscalation(t *testing.T) {
+	reconciler, _, notifier, now := newTestReconciler(t)
+
+	_, err := reconciler.Evaluate("desk", 35)
+	require.NoError(t, err)
+
+	*now = openedAt.Add(61 * time.Minute)
+	summary, err := reconciler.Evaluate("desk", 35)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Counts[StatusOverdue])
+	assert.Equal(t, 60.0, summary.OverdueSeconds)
+	require.Len(t, notifier.Notifications, 1)
+	assert.Equal(t, notify.SeverityWarning, notifier.Notifications[0].Severity)
+
+	// 未到升级间隔不重复
+	*now = openedAt.Add(80 * time.Minute)
+	_, err = reconciler.Evaluate("desk", 40)
+	require.NoError(t, err)
+	assert.Len(t, notifier.Notifications, 1)
+
+	*now = openedAt.Add(92 * time.Minute)
+	summary, err = reconciler.Evaluate("desk", 40)
+	require.NoError(t, err)
+	require.Len(t, notifier.Notifications, 2)
+	assert.Equal(t, notify.SeverityCritical, notifier.Notifications[1].Severity)
+	assert.Equal(t, 40.0, summary.Requests[0].Required) // 缺口扩大
+	assert.Equal(t, 2, summary.Requests[0].Escalations)
+
+	// 不再需要补充时关闭
+	*now = openedAt.Add(100 * time.Minute)
+	summary, err = reconciler.Evaluate("desk", 0)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCleared, summary.Requests[0].Status)
+	assert.Equal(t, 0.0, summary.Outstanding)
+}
+
+func TestStateSurvivesRestart(t *testing.T) {
+	reconciler, srv, _, _ := newTestReconciler(t)
+	_, err := reconciler.Evaluate("desk", 10)
+	require.NoError(t, err)
+
+	restarted, err := NewReconciler(reconciler.config, reconciler.reader, &notifytest.Notifier{}, nil, zap.NewNop())
+	require.NoError(t, err)
+	restarted.now = func() time.Time { return openedAt.Add(5 * time.Minute) }
+	srv.UpdateState(func(state *fakederibit.State) {
+		state.Deposits = append(state.Deposits, types.Deposit{Amount: 10, Currency: "ETH", State: "completed", TransactionID: "0xnew",
+			ReceivedTimestamp: openedAt.Add(time.Minute).UnixMilli()})
+	})
+
+	summary, err := restarted.Evaluate("desk", 0)
+	require.NoError(t, err)
+	require.Len(t, summary.Requests, 1)
+	assert.Equal(t, StatusFulfilled, summary.Requests[0].Status)
+	assert.Equal(t, 10.0, summary.Requests[0].Received) // 旧充值没有被重复匹配
+}
@@ -0,0 +1,623 @@
+// Package remediation 在保证金不足时从资金账户自动补充 ETH。
+//
+// 监控周期只会生成补充提案，提案需要至少两名不同操作员通过管理接口或命令行审批后才会执行；
+// 默认只演练（dry_run），执行金额受单笔和每日上限约束，所有动作都写入审计日志。
+package remediation
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/admin"
+	"cs-projects-eth-collar/pkg/audit"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/notify"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	StatusPending   = "pending"   // 等待审批
+	StatusExecuted  = "executed"  // 已划转
+	StatusDryRun    = "dry_run"   // 演练完成，未划转
+	StatusRejected  = "rejected"  // 被操作员拒绝
+	StatusExpired   = "expired"   // 超过有效期未完成审批
+	StatusFailed    = "failed"    // 划转失败（确定没有执行）
+	StatusExecuting = "executing" // 已占用额度，正在提交划转
+	StatusUnknown   = "unknown"   // 划转结果未知（超时、5xx），额度保持占用，直到在划转记录中确认
+
+	TransferTypeSubaccount = "subaccount"
+	TransferTypeUser       = "user"
+
+	// minApprovals 两人审批原则，配置不能低于该值
+	minApprovals = 2
+
+	// unknownResolveWindow 结果未知的划转超过该时间仍不在划转记录中时视为没有执行，释放额度
+	unknownResolveWindow = 10 * time.Minute
+	// transferHistoryCount 确认结果未知的划转时读取的划转记录条数
+	transferHistoryCount = 100
+	// closedRetention 已结束的提案保留的时间，之后从内存中删除（审计日志中仍有完整记录）
+	closedRetention = 24 * time.Hour
+)
+
+var (
+	ErrNotFound        = errors.New("top-up proposal not found")
+	ErrNotPending      = errors.New("top-up proposal is not pending")
+	ErrAlreadyApproved = errors.New("operator has already approved this proposal")
+	ErrDailyCapReached = errors.New("daily top-up cap reached")
+	// ErrUnresolvedTransfer 之前的划转结果未知且尚未确认，确认前不提交新的划转
+	ErrUnresolvedTransfer = errors.New("previous top-up transfer outcome unknown")
+
+	// errNotSubmitted 划转没有提交到交易所
+	errNotSubmitted = errors.New("transfer not submitted")
+)
+
+// Transferer 资金账户的划转能力（deribit.Client 满足该接口）
+// 交易所拒绝划转时返回的错误包含 *deribit.APIError，其他错误视为结果未知
+type Transferer interface {
+	SubmitTransferToSubaccount(currency string, amount float64, destination int64) (*types.Transfer, error)
+	SubmitTransferToUser(currency string, amount float64, destination string) (*types.Transfer, error)
+	GetTransfers(currency string, count, offset int) (*types.Transfers, error)
+}
+
+// Approval 一次操作员审批
+type Approval struct {
+	Operator string    `json:"operator"`
+	At       time.Time `json:"at"`
+}
+
+// Proposal 一笔补充保证金提案
+type Proposal struct {
+	ID              string     `json:"id"`
+	Account         string     `json:"account"`
+	Currency        string     `json:"currency"`
+	Destination     string     `json:"destination"`
+	RequestedAmount float64    `json:"requested_amount"` // 监控计算出的需要补充数量
+	Amount          float64    `json:"amount"`           // 按上限截断后的划转数量
+	Reason          string     `json:"reason"`
+	Status          string     `json:"status"`
+	DryRun          bool       `json:"dry_run"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	Approvals       []Approval `json:"approvals"`
+	RejectedBy      string     `json:"rejected_by,omitempty"`
+	SubmittedAt     time.Time  `json:"submitted_at,omitempty"` // 占用额度并提交划转的时间
+	ClosedAt        time.Time  `json:"closed_at,omitempty"`    // 拒绝、过期、演练、执行或失败的时间
+	TransferID      int64      `json:"transfer_id,omitempty"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// Executor 管理补充提案的审批和执行
+type Executor struct {
+	config     types.RemediationConfig
+	transferer Transferer
+	audit      *audit.Logger
+	notifier   notify.Notifier
+	logger     *zap.Logger
+
+	mu         sync.Mutex
+	proposals  map[string]*Proposal
+	dailyUsed  map[string]float64 // UTC 日期 -> 已执行、演练、正在执行和结果未知的数量
+	seq        int
+	last       *Proposal // 最近结束的提案，决定冷却期和拒绝是否仍然有效
+	capAlertAt time.Time // 最近一次额度耗尽通知的时间
+}
+
+// NewExecutor 创建执行器，单笔和每日上限必须为正数：未设置上限等于不限额，不允许启动
+func NewExecutor(config types.RemediationConfig, transferer Transferer, auditLogger *audit.Logger, notifier notify.Notifier, logger *zap.Logger) (*Executor, error) {
+	if config.MaxPerTransfer <= 0 || config.MaxPerDay <= 0 {
+		return nil, fmt.Errorf("remediation.max_per_transfer (%g) and remediation.max_per_day (%g) must both be positive", config.MaxPerTransfer, config.MaxPerDay)
+	}
+	if config.RequiredApprovals < minApprovals {
+		logger.Warn("Top-up approvals below two-person minimum, using minimum",
+			zap.Int("configured", config.RequiredApprovals),
+			zap.Int("minimum", minApprovals),
+		)
+		config.RequiredApprovals = minApprovals
+	}
+
+	e := &Executor{
+		config:     config,
+		transferer: transferer,
+		audit:      auditLogger,
+		notifier:   notifier,
+		logger:     logger,
+		proposals:  make(map[string]*Proposal),
+		dailyUsed:  make(map[string]float64),
+	}
+	e.restoreDailyUsage()
+	return e, nil
+}
+
+// Propose 根据监控计算出的需要补充数量生成提案
+// 已有待审批、正在执行或结果未知的提案时不重复生成，直接返回已有提案；
+// 上一笔提案被拒绝且需要补充的数量变化不超过 rejection_change_ratio，或上一笔提案结束后仍在冷却期内时不生成，返回 nil
+func (e *Executor) Propose(account string, requiredAmount float64, reason string) (*Proposal, error) {
+	if err := e.resolveUnknown(); err != nil {
+		e.logger.Warn("Top-up transfer outcome still unknown", zap.Error(err))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now().UTC()
+	e.expireLocked(now)
+	e.pruneLocked(now)
+
+	for _, p := range e.proposals {
+		if p.Status == StatusPending || p.Status == StatusExecuting || p.Status == StatusUnknown {
+			return e.copyOf(p), nil
+		}
+	}
+	if reason := e.suppressedLocked(requiredAmount, now); reason != "" {
+		e.logger.Debug("Top-up proposal suppressed", zap.String("reason", reason), zap.Float64("required_amount", requiredAmount))
+		return nil, nil
+	}
+
+	amount := math.Min(requiredAmount, e.config.MaxPerTransfer)
+	amount = math.Min(amount, e.config.MaxPerDay-e.dailyUsed[dayKey(now)])
+	if amount <= 0 {
+		// 额度耗尽时每个冷却期只通知一次
+		if now.Sub(e.capAlertAt) < e.cooldown() {
+			return nil, ErrDailyCapReached
+		}
+		e.capAlertAt = now
+		e.record("topup.cap_reached", map[string]interface{}{
+			"account":         account,
+			"required_amount": requiredAmount,
+			"daily_used":      e.dailyUsed[dayKey(now)],
+			"max_per_day":     e.config.MaxPerDay,
+		})
+		e.send(notify.SeverityCritical, "Daily top-up cap reached",
+			fmt.Sprintf("%.4f %s required but daily cap %.4f is exhausted; manual action needed", requiredAmount, e.config.Currency, e.config.MaxPerDay),
+			map[string]interface{}{"account": account, "required_amount": requiredAmount})
+		return nil, ErrDailyCapReached
+	}
+
+	e.seq++
+	p := &Proposal{
+		ID:              fmt.Sprintf("topup-%s-%d", now.Format("20060102T150405"), e.seq),
+		Account:         account,
+		Currency:        e.config.Currency,
+		Destination:     e.config.Destination,
+		RequestedAmount: requiredAmount,
+		Amount:          amount,
+		Reason:          reason,
+		Status:          StatusPending,
+		DryRun:          e.config.DryRun,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(time.Duration(e.config.ApprovalTTLSeconds) * time.Second),
+	}
+	e.proposals[p.ID] = p
+
+	e.record("topup.proposed", p)
+	e.send(notify.SeverityWarning, "Top-up awaiting approval",
+		fmt.Sprintf("Proposal %s: transfer %.4f %s to %s (requested %.4f, dry_run=%t); needs %d operator approvals before %s",
+			p.ID, p.Amount, p.Currency, p.Destination, p.RequestedAmount, p.DryRun, e.config.RequiredApprovals, p.ExpiresAt.Format(time.RFC3339)),
+		map[string]interface{}{"proposal_id": p.ID, "amount": p.Amount, "reason": reason})
+
+	return e.copyOf(p), nil
+}
+
+// Approve 记录一名操作员的审批，审批人数达到要求后立即执行
+// 提交划转时不持有锁，其他请求（包括管理接口）不会被划转请求阻塞
+func (e *Executor) Approve(id, operator string) (*Proposal, error) {
+	p, submit, err := e.approve(id, operator)
+	if err != nil || !submit {
+		return p, err
+	}
+	return e.execute(id), nil
+}
+
+// approve 记录审批，审批人数达到要求时占用额度，需要提交划转时返回 true
+func (e *Executor) approve(id, operator string) (*Proposal, bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now().UTC()
+	e.expireLocked(now)
+
+	p, ok := e.proposals[id]
+	if !ok {
+		return nil, false, ErrNotFound
+	}
+	if p.Status != StatusPending {
+		return e.copyOf(p), false, ErrNotPending
+	}
+	for _, approval := range p.Approvals {
+		if approval.Operator == operator {
+			return e.copyOf(p), false, ErrAlreadyApproved
+		}
+	}
+
+	p.Approvals = append(p.Approvals, Approval{Operator: operator, At: now})
+	e.record("topup.approved", map[string]interface{}{
+		"proposal_id": p.ID,
+		"operator":    operator,
+		"approvals":   len(p.Approvals),
+		"required":    e.config.RequiredApprovals,
+	})
+
+	submit := len(p.Approvals) >= e.config.RequiredApprovals && e.startLocked(p, now)
+	return e.copyOf(p), submit, nil
+}
+
+// Reject 拒绝一笔待审批提案
+func (e *Executor) Reject(id, operator string) (*Proposal, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.expireLocked(time.Now().UTC())
+
+	p, ok := e.proposals[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if p.Status != StatusPending {
+		return e.copyOf(p), ErrNotPending
+	}
+
+	e.closeLocked(p, StatusRejected)
+	p.RejectedBy = operator
+	e.record("topup.rejected", map[string]interface{}{"proposal_id": p.ID, "operator": operator})
+
+	return e.copyOf(p), nil
+}
+
+// Proposals 返回全部提案，按创建时间倒序
+func (e *Executor) Proposals() []Proposal {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.expireLocked(time.Now().UTC())
+
+	proposals := make([]Proposal, 0, len(e.proposals))
+	for _, p := range e.proposals {
+		proposals = append(proposals, *e.copyOf(p))
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		return proposals[i].CreatedAt.After(proposals[j].CreatedAt)
+	})
+	return proposals
+}
+
+// startLocked 检查每日上限并在提交前占用额度，调用方需持有锁
+// 演练直接完成；需要划转时标记为 executing 并返回 true，由 execute 在不持有锁时提交
+func (e *Executor) startLocked(p *Proposal, now time.Time) bool {
+	day := dayKey(now)
+	if e.dailyUsed[day]+p.Amount > e.config.MaxPerDay {
+		e.closeLocked(p, StatusFailed)
+		p.Error = ErrDailyCapReached.Error()
+		e.record("topup.failed", p)
+		e.send(notify.SeverityCritical, "Top-up blocked by daily cap", fmt.Sprintf("Proposal %s: %s", p.ID, p.Error),
+			map[string]interface{}{"proposal_id": p.ID})
+		return false
+	}
+
+	e.dailyUsed[day] += p.Amount
+	p.SubmittedAt = now
+	if p.DryRun {
+		e.closeLocked(p, StatusDryRun)
+		e.record("topup.dry_run", p)
+		e.send(notify.SeverityInfo, "Top-up approved (dry run)",
+			fmt.Sprintf("Proposal %s: would transfer %.4f %s to %s; no transfer submitted", p.ID, p.Amount, p.Currency, p.Destination),
+			map[string]interface{}{"proposal_id": p.ID, "amount": p.Amount})
+		return false
+	}
+
+	// 先写入审计日志再提交，进程在划转过程中退出时重启后仍计入当天额度
+	p.Status = StatusExecuting
+	e.record("topup.executing", p)
+	return true
+}
+
+// execute 确认之前结果未知的划转后提交划转，不持有锁
+func (e *Executor) execute(id string) *Proposal {
+	if err := e.resolveUnknown(); err != nil {
+		return e.finish(id, nil, fmt.Errorf("%w: %w", errNotSubmitted, err))
+	}
+
+	e.mu.Lock()
+	p := e.copyOf(e.proposals[id])
+	e.mu.Unlock()
+
+	transfer, err := e.transfer(p)
+	return e.finish(id, transfer, err)
+}
+
+// finish 记录划转结果：交易所拒绝或没有提交时释放额度；其他错误结果未知，额度保持占用
+func (e *Executor) finish(id string, transfer *types.Transfer, err error) *Proposal {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	p := e.proposals[id]
+	var apiErr *deribit.APIError
+	switch {
+	case err == nil:
+		e.executedLocked(p, transfer)
+	case errors.As(err, &apiErr), errors.Is(err, errNotSubmitted):
+		e.closeLocked(p, StatusFailed)
+		p.Error = err.Error()
+		e.dailyUsed[dayKey(p.SubmittedAt)] -= p.Amount
+		e.record("topup.failed", p)
+		e.send(notify.SeverityCritical, "Top-up transfer failed", fmt.Sprintf("Proposal %s: %v", p.ID, err),
+			map[string]interface{}{"proposal_id": p.ID, "amount": p.Amount})
+	default:
+		p.Status = StatusUnknown
+		p.Error = err.Error()
+		e.record("topup.unknown", p)
+		e.send(notify.SeverityCritical, "Top-up transfer outcome unknown",
+			fmt.Sprintf("Proposal %s: %v; %.4f %s stays reserved and no further transfer is submitted until it is found in the transfer history or %s pass",
+				p.ID, err, p.Amount, p.Currency, unknownResolveWindow),
+			map[string]interface{}{"proposal_id": p.ID, "amount": p.Amount})
+	}
+	return e.copyOf(p)
+}
+
+// executedLocked 标记划转已执行，调用方需持有锁
+func (e *Executor) executedLocked(p *Proposal, transfer *types.Transfer) {
+	e.closeLocked(p, StatusExecuted)
+	p.TransferID = transfer.ID
+	p.Error = ""
+	e.record("topup.executed", map[string]interface{}{"proposal": p, "transfer": transfer})
+	e.send(notify.SeverityInfo, "Top-up transfer submitted",
+		fmt.Sprintf("Proposal %s: transferred %.4f %s to %s (transfer %d, state %s)", p.ID, p.Amount, p.Currency, p.Destination, transfer.ID, transfer.State),
+		map[string]interface{}{"proposal_id": p.ID, "transfer_id": transfer.ID, "amount": p.Amount})
+}
+
+// resolveUnknown 在划转记录中确认结果未知的划转：找到时标记为已执行；
+// 超过 unknownResolveWindow 仍找不到时标记为失败并释放额度。仍有未确认的划转时返回 ErrUnresolvedTransfer
+func (e *Executor) resolveUnknown() error {
+	e.mu.Lock()
+	unknown := 0
+	for _, p := range e.proposals {
+		if p.Status == StatusUnknown {
+			unknown++
+		}
+	}
+	e.mu.Unlock()
+	if unknown == 0 {
+		return nil
+	}
+
+	transfers, err := e.transferer.GetTransfers(e.config.Currency, transferHistoryCount, 0)
+	if err != nil {
+		return fmt.Errorf("%w: failed to get transfers: %v", ErrUnresolvedTransfer, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now().UTC()
+	var unresolved []string
+	for _, p := range e.proposals {
+		if p.Status != StatusUnknown {
+			continue
+		}
+		if transfer := e.matchTransferLocked(p, transfers.Data); transfer != nil {
+			e.executedLocked(p, transfer)
+			continue
+		}
+		if now.Sub(p.SubmittedAt) > unknownResolveWindow {
+			e.closeLocked(p, StatusFailed)
+			p.Error = fmt.Sprintf("transfer not found in transfer history %s after submission: %s", unknownResolveWindow, p.Error)
+			e.dailyUsed[dayKey(p.SubmittedAt)] -= p.Amount
+			e.record("topup.failed", p)
+			e.send(notify.SeverityWarning, "Top-up transfer not executed", fmt.Sprintf("Proposal %s: %s", p.ID, p.Error),
+				map[string]interface{}{"proposal_id": p.ID, "amount": p.Amount})
+			continue
+		}
+		unresolved = append(unresolved, p.ID)
+	}
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		return fmt.Errorf("%w: %s", ErrUnresolvedTransfer, strings.Join(unresolved, ", "))
+	}
+	return nil
+}
+
+// matchTransferLocked 查找与提案对应的转出记录：币种、数量和类型一致，提交后创建，且没有对应到其他提案
+func (e *Executor) matchTransferLocked(p *Proposal, transfers []types.Transfer) *types.Transfer {
+	used := make(map[int64]bool)
+	for _, other := range e.proposals {
+		if other.TransferID != 0 {
+			used[other.TransferID] = true
+		}
+	}
+	// 交易所时间与本地时间可能有偏差，放宽一分钟
+	since := p.SubmittedAt.Add(-time.Minute).UnixMilli()
+	for i := range transfers {
+		t := &transfers[i]
+		if used[t.ID] || t.Direction != "payment" || !strings.EqualFold(t.Currency, p.Currency) ||
+			math.Abs(t.Amount-p.Amount) > 1e-9 || t.CreatedTimestamp < since {
+			continue
+		}
+		if t.Type != "" && t.Type != e.config.TransferType {
+			continue
+		}
+		return t
+	}
+	return nil
+}
+
+func (e *Executor) transfer(p *Proposal) (*types.Transfer, error) {
+	switch e.config.TransferType {
+	case TransferTypeSubaccount:
+		destination, err := strconv.ParseInt(p.Destination, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid subaccount id %q: %v", errNotSubmitted, p.Destination, err)
+		}
+		return e.transferer.SubmitTransferToSubaccount(p.Currency, p.Amount, destination)
+	case TransferTypeUser:
+		return e.transferer.SubmitTransferToUser(p.Currency, p.Amount, p.Destination)
+	default:
+		return nil, fmt.Errorf("%w: unsupported transfer type %q", errNotSubmitted, e.config.TransferType)
+	}
+}
+
+// suppressedLocked 返回不生成新提案的原因，调用方需持有锁
+func (e *Executor) suppressedLocked(requiredAmount float64, now time.Time) string {
+	if e.last == nil {
+		return ""
+	}
+	// 拒绝一直有效，直到需要补充的数量明显变化
+	if e.last.Status == StatusRejected {
+		change := math.Abs(requiredAmount-e.last.RequestedAmount) / e.last.RequestedAmount
+		if change <= e.config.RejectionChangeRatio {
+			return fmt.Sprintf("proposal %s rejected by %s and required amount changed by %.1f%%", e.last.ID, e.last.RejectedBy, change*100)
+		}
+		return ""
+	}
+	if now.Sub(e.last.ClosedAt) < e.cooldown() {
+		return fmt.Sprintf("proposal %s %s at %s, in cooldown", e.last.ID, e.last.Status, e.last.ClosedAt.Format(time.RFC3339))
+	}
+	return ""
+}
+
+func (e *Executor) cooldown() time.Duration {
+	return time.Duration(e.config.CooldownSeconds) * time.Second
+}
+
+// closeLocked 结束提案并记为最近结束的提案，调用方需持有锁
+func (e *Executor) closeLocked(p *Proposal, status string) {
+	p.Status = status
+	p.ClosedAt = time.Now().UTC()
+	e.last = p
+}
+
+// pruneLocked 删除结束超过 closedRetention 的提案，调用方需持有锁
+func (e *Executor) pruneLocked(now time.Time) {
+	for id, p := range e.proposals {
+		if !p.ClosedAt.IsZero() && now.Sub(p.ClosedAt) > closedRetention {
+			delete(e.proposals, id)
+		}
+	}
+}
+
+// expireLocked 将超过有效期的待审批提案标记为过期，调用方需持有锁
+func (e *Executor) expireLocked(now time.Time) {
+	for _, p := range e.proposals {
+		if p.Status == StatusPending && now.After(p.ExpiresAt) {
+			e.closeLocked(p, StatusExpired)
+			e.record("topup.expired", map[string]interface{}{"proposal_id": p.ID, "approvals": p.Approvals})
+		}
+	}
+}
+
+// restoreDailyUsage 从审计日志恢复当天占用的额度，避免重启后绕过每日上限
+// 按提案汇总最后一条记录：演练、正在执行、结果未知和已执行的计入，之后失败的不计入
+func (e *Executor) restoreDailyUsage() {
+	entries, err := audit.ReadEntries(e.audit.Path())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			e.logger.Warn("Failed to restore daily top-up usage from audit log", zap.Error(err))
+		}
+		return
+	}
+
+	reserved := make(map[string]Proposal)
+	for _, entry := range entries {
+		var p Proposal
+		switch entry.Type {
+		case "topup.dry_run", "topup.executing", "topup.unknown", "topup.failed":
+			if err := json.Unmarshal(entry.Data, &p); err != nil {
+				continue
+			}
+		case "topup.executed":
+			var data struct {
+				Proposal Proposal `json:"proposal"`
+			}
+			if err := json.Unmarshal(entry.Data, &data); err != nil {
+				continue
+			}
+			p = data.Proposal
+		default:
+			continue
+		}
+		if entry.Type == "topup.failed" {
+			delete(reserved, p.ID)
+			continue
+		}
+		if p.SubmittedAt.IsZero() {
+			p.SubmittedAt = entry.Timestamp
+		}
+		reserved[p.ID] = p
+	}
+
+	today := dayKey(time.Now().UTC())
+	for _, p := range reserved {
+		if dayKey(p.SubmittedAt) == today {
+			e.dailyUsed[today] += p.Amount
+		}
+	}
+}
+
+func (e *Executor) record(entryType string, data interface{}) {
+	if err := e.audit.Record(entryType, data); err != nil {
+		e.logger.Error("Failed to write audit entry", zap.String("type", entryType), zap.Error(err))
+	}
+}
+
+func (e *Executor) send(severity notify.Severity, title, message string, fields map[string]interface{}) {
+	n := notify.Notification{
+		Source:   "remediation",
+		Severity: severity,
+		Title:    title,
+		Message:  message,
+		Fields:   fields,
+	}
+	if err := e.notifier.Notify(n); err != nil {
+		e.logger.Error("Failed to send remediation notification", zap.Error(err))
+	}
+}
+
+func (e *Executor) copyOf(p *Proposal) *Proposal {
+	c := *p
+	c.Approvals = append([]Approval(nil), p.Approvals...)
+	return &c
+}
+
+func dayKey(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// RegisterHandlers 注册审批相关的管理接口
+//
+//	GET  /topups              列出提案
+//	POST /topups/{id}/approve 以当前操作员身份审批
+//	POST /topups/{id}/reject  以当前操作员身份拒绝
+func (e *Executor) RegisterHandlers(server *admin.Server) {
+	server.Handle("GET /topups", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, e.Proposals())
+	})
+	server.Handle("POST /topups/{id}/approve", func(w http.ResponseWriter, r *http.Request) {
+		p, err := e.Approve(r.PathValue("id"), admin.Operator(r))
+		writeProposal(w, p, err)
+	})
+	server.Handle("POST /topups/{id}/reject", func(w http.ResponseWriter, r *http.Request) {
+		p, err := e.Reject(r.PathValue("id"), admin.Operator(r))
+		writeProposal(w, p, err)
+	})
+}
+
+func writeProposal(w http.ResponseWriter, p *Proposal, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		admin.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrNotPending), errors.Is(err, ErrAlreadyApproved):
+		admin.WriteError(w, http.StatusConflict, err)
+	case err != nil:
+		admin.WriteError(w, http.StatusInternalServerError, err)
+	default:
+		admin.WriteJSON(w, http.StatusOK, p)
+	}
+}
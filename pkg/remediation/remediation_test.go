@@ -0,0 +1,332 @@
+package remediation
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/audit"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/notify"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeTransferer struct {
+	mu        sync.Mutex
+	transfers []float64
+	history   []types.Transfer
+	err       error         // 下一次划转返回的错误，ambiguous 为 true 时划转仍然执行
+	ambiguous bool          // 模拟交易所已执行但响应丢失
+	block     chan struct{} // 非空时划转等待关闭
+}
+
+func (f *fakeTransferer) submit(currency string, amount float64) (*types.Transfer, error) {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.err
+	f.err = nil
+	if err != nil && !f.ambiguous {
+		return nil, err
+	}
+	f.transfers = append(f.transfers, amount)
+	transfer := types.Transfer{ID: int64(len(f.transfers)), Amount: amount, Currency: currency, Direction: "payment",
+		State: "confirmed", Type: TransferTypeSubaccount, CreatedTimestamp: time.Now().UnixMilli()}
+	f.history = append(f.history, transfer)
+	return &transfer, err
+}
+
+func (f *fakeTransferer) SubmitTransferToSubaccount(currency string, amount float64, destination int64) (*types.Transfer, error) {
+	return f.submit(currency, amount)
+}
+
+func (f *fakeTransferer) SubmitTransferToUser(currency string, amount float64, destination string) (*types.Transfer, error) {
+	return f.submit(currency, amount)
+}
+
+func (f *fakeTransferer) GetTransfers(currency string, count, offset int) (*types.Transfers, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &types.Transfers{Count: len(f.history), Data: append([]types.Transfer(nil), f.history...)}, nil
+}
+
+type nopNotifier struct{}
+
+func (nopNotifier) Notify(n notify.Notification) error { return nil }
+
+func newTestExecutor(t *testing.T, config types.RemediationConfig) (*Executor, *fakeTransferer) {
+	auditLogger, err := audit.NewLogger(filepath.Join(t.TempDir(), "audit.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() { auditLogger.Close() })
+
+	transferer := &fakeTransferer{}
+	executor, err := NewExecutor(config, transferer, auditLogger, nopNotifier{}, zap.NewNop())
+	require.NoError(t, err)
+	return executor, transferer
+}
+
+func TestTwoPersonApproval(t *testing.T) {
+	executor, transferer := newTestExecutor(t, types.RemediationConfig{
+		Currency:           "ETH",
+		TransferType:       TransferTypeSubaccount,
+		Destination:        "42",
+		MaxPerTransfer:     50,
+		MaxPerDay:          80,
+		RequiredApprovals:  1, // 低于两人原则时会被提升为 2
+		ApprovalTTLSeconds: 3600,
+	})
+
+	p, err := executor.Propose("default", 120, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, p.Amount)
+
+	// 已有待审批提案时不会重复生成
+	again, err := executor.Propose("default", 120, "test")
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, again.ID)
+
+	p, err = executor.Approve(p.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, p.Status)
+
+	_, err = executor.Approve(p.ID, "alice")
+	assert.ErrorIs(t, err, ErrAlreadyApproved)
+
+	p, err = executor.Approve(p.ID, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, StatusExecuted, p.Status)
+	assert.Equal(t, []float64{50}, transferer.transfers)
+
+	// 第二笔受每日上限截断
+	p, err = executor.Propose("default", 120, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, p.Amount)
+
+	_, err = executor.Reject(p.ID, "carol")
+	require.NoError(t, err)
+
+	count, err := audit.Verify(executor.audit.Path())
+	require.NoError(t, err)
+	assert.Equal(t, 7, count)
+}
+
+func TestDryRunDoesNotTransfer(t *testing.T) {
+	executor, transferer := newTestExecutor(t, types.RemediationConfig{
+		DryRun:             true,
+		Currency:           "ETH",
+		TransferType:       TransferTypeUser,
+		Destination:        "collar-account",
+		MaxPerTransfer:     10,
+		MaxPerDay:          10,
+		RequiredApprovals:  2,
+		ApprovalTTLSeconds: 3600,
+	})
+
+	p, err := executor.Propose("default", 10, "test")
+	require.NoError(t, err)
+	_, err = executor.Approve(p.ID, "alice")
+	require.NoError(t, err)
+	p, err = executor.Approve(p.ID, "bob")
+	require.NoError(t, err)
+
+	assert.Equal(t, StatusDryRun, p.Status)
+	assert.Empty(t, transferer.transfers)
+
+	// 演练同样计入每日上限
+	_, err = executor.Propose("default", 1, "test")
+	assert.ErrorIs(t, err, ErrDailyCapReached)
+}
+
+func TestCapsRequired(t *testing.T) {
+	auditLogger, err := audit.NewLogger(filepath.Join(t.TempDir(), "audit.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() { auditLogger.Close() })
+
+	// 未设置上限等于不限额，拒绝启动
+	for _, config := range []types.RemediationConfig{
+		{MaxPerDay: 100},
+		{MaxPerTransfer: 50},
+		{MaxPerTransfer: 50, MaxPerDay: -1},
+	} {
+		_, err := NewExecutor(config, &fakeTransferer{}, auditLogger, nopNotifier{}, zap.NewNop())
+		assert.ErrorContains(t, err, "must both be positive")
+	}
+}
+
+var liveConfig = types.RemediationConfig{
+	Currency:           "ETH",
+	TransferType:       TransferTypeSubaccount,
+	Destination:        "42",
+	MaxPerTransfer:     50,
+	MaxPerDay:          80,
+	RequiredApprovals:  2,
+	ApprovalTTLSeconds: 3600,
+}
+
+// approveTwice 两名操作员审批提案
+func approveTwice(t *testing.T, executor *Executor, id string) *Proposal {
+	_, err := executor.Approve(id, "alice")
+	require.NoError(t, err)
+	p, err := executor.Approve(id, "bob")
+	require.NoError(t, err)
+	return p
+}
+
+func TestRejectedTransferReleasesCap(t *testing.T) {
+	executor, transferer := newTestExecutor(t, liveConfig)
+	transferer.err = &deribit.APIError{Code: 10004, Message: "not_enough_funds"}
+
+	p, err := executor.Propose("default", 120, "test")
+	require.NoError(t, err)
+	p = approveTwice(t, executor, p.ID)
+	assert.Equal(t, StatusFailed, p.Status)
+	assert.Contains(t, p.Error, "not_enough_funds")
+
+	// 交易所拒绝时没有划转，额度释放
+	p, err = executor.Propose("default", 120, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, p.Amount)
+}
+
+func TestAmbiguousTransferKeepsReservation(t *testing.T) {
+	executor, transferer := newTestExecutor(t, liveConfig)
+	transferer.err = errors.New("HTTP request failed: context deadline exceeded")
+	transferer.ambiguous = true
+
+	p, err := executor.Propose("default", 120, "test")
+	require.NoError(t, err)
+	p = approveTwice(t, executor, p.ID)
+	assert.Equal(t, StatusUnknown, p.Status)
+	unknownID := p.ID
+
+	// 下一个周期在划转记录中确认已执行，额度仍然占用
+	p, err = executor.Propose("default", 120, "test")
+	require.NoError(t, err)
+	assert.NotEqual(t, unknownID, p.ID)
+	assert.Equal(t, 30.0, p.Amount)
+	for _, proposal := range executor.Proposals() {
+		if proposal.ID == unknownID {
+			assert.Equal(t, StatusExecuted, proposal.Status)
+			assert.Equal(t, int64(1), proposal.TransferID)
+		}
+	}
+
+	// 重启后从审计日志恢复占用的额度
+	restarted, err := NewExecutor(liveConfig, transferer, executor.audit, nopNotifier{}, zap.NewNop())
+	require.NoError(t, err)
+	p, err = restarted.Propose("default", 120, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, p.Amount)
+}
+
+func TestUnknownTransferBlocksNextTransfer(t *testing.T) {
+	executor, transferer := newTestExecutor(t, liveConfig)
+	transferer.err = errors.New("HTTP error 502")
+
+	p, err := executor.Propose("default", 10, "test")
+	require.NoError(t, err)
+	p = approveTwice(t, executor, p.ID)
+	require.Equal(t, StatusUnknown, p.Status)
+
+	// 结果未知的提案确认前不生成新提案
+	again, err := executor.Propose("default", 10, "test")
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, again.ID)
+
+	// 超过确认时间仍不在划转记录中，视为没有执行并释放额度
+	executor.mu.Lock()
+	executor.proposals[p.ID].SubmittedAt = time.Now().Add(-unknownResolveWindow - time.Minute)
+	executor.mu.Unlock()
+	next, err := executor.Propose("default", 100, "test")
+	require.NoError(t, err)
+	assert.NotEqual(t, p.ID, next.ID)
+	assert.Equal(t, 50.0, next.Amount)
+	assert.Empty(t, transferer.transfers)
+}
+
+func TestTransferDoesNotHoldLock(t *testing.T) {
+	executor, transferer := newTestExecutor(t, liveConfig)
+	transferer.block = make(chan struct{})
+
+	p, err := executor.Propose("default", 10, "test")
+	require.NoError(t, err)
+	_, err = executor.Approve(p.ID, "alice")
+	require.NoError(t, err)
+
+	done := make(chan *Proposal)
+	go func() {
+		p, _ := executor.Approve(p.ID, "bob")
+		done <- p
+	}()
+
+	// 划转进行中时仍可查看提案
+	require.Eventually(t, func() bool {
+		proposals := executor.Proposals()
+		return len(proposals) == 1 && proposals[0].Status == StatusExecuting
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = executor.Reject(p.ID, "carol")
+	assert.ErrorIs(t, err, ErrNotPending)
+
+	close(transferer.block)
+	assert.Equal(t, StatusExecuted, (<-done).Status)
+}
+
+func TestRejectionSticksAndCooldown(t *testing.T) {
+	config := liveConfig
+	config.DryRun = true
+	config.CooldownSeconds = 1800
+	config.RejectionChangeRatio = 0.25
+	executor, _ := newTestExecutor(t, config)
+
+	p, err := executor.Propose("default", 40, "test")
+	require.NoError(t, err)
+	_, err = executor.Reject(p.ID, "carol")
+	require.NoError(t, err)
+
+	// 需要补充的数量没有明显变化时拒绝一直有效
+	again, err := executor.Propose("default", 45, "test")
+	require.NoError(t, err)
+	assert.Nil(t, again)
+
+	p, err = executor.Propose("default", 60, "test")
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	p = approveTwice(t, executor, p.ID)
+	require.Equal(t, StatusDryRun, p.Status)
+
+	// 演练完成后进入冷却期
+	again, err = executor.Propose("default", 60, "test")
+	require.NoError(t, err)
+	assert.Nil(t, again)
+
+	executor.mu.Lock()
+	executor.last.ClosedAt = time.Now().Add(-time.Hour)
+	executor.mu.Unlock()
+	again, err = executor.Propose("default", 60, "test")
+	require.NoError(t, err)
+	require.NotNil(t, again)
+	assert.Equal(t, 30.0, again.Amount) // 演练计入每日上限
+}
+
+func TestClosedProposalsPruned(t *testing.T) {
+	executor, _ := newTestExecutor(t, liveConfig)
+	p, err := executor.Propose("default", 10, "test")
+	require.NoError(t, err)
+	_, err = executor.Reject(p.ID, "carol")
+	require.NoError(t, err)
+	require.Len(t, executor.Proposals(), 1)
+
+	executor.mu.Lock()
+	executor.proposals[p.ID].ClosedAt = time.Now().Add(-closedRetention - time.Minute)
+	executor.mu.Unlock()
+	_, err = executor.Propose("default", 10, "test")
+	require.NoError(t, err)
+	assert.Empty(t, executor.Proposals())
+}
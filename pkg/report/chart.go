@@ -0,0 +1,56 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth   = 640
+	chartHeight  = 180
+	chartPadding = 40
+)
+
+// lineChart 生成内嵌的 SVG 折线图，纵轴标注最小和最大值，横轴标注起止时间
+func lineChart(title string, times []time.Time, values []float64, format string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-label="%s">`,
+		chartWidth, chartHeight, chartWidth, chartHeight, html.EscapeString(title))
+	fmt.Fprintf(&b, `<text x="%d" y="16" font-size="13" font-weight="bold">%s</text>`, chartPadding, html.EscapeString(title))
+
+	if len(values) == 0 {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="12" fill="#888">no data</text></svg>`, chartPadding, chartHeight/2)
+		return b.String()
+	}
+
+	stats := statsOf(values)
+	span := stats.Max - stats.Min
+	if span == 0 {
+		span = 1
+	}
+	start, end := times[0], times[len(times)-1]
+	duration := end.Sub(start).Seconds()
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+
+	points := make([]string, 0, len(values))
+	for i, v := range values {
+		x := float64(chartPadding)
+		if duration > 0 {
+			x += times[i].Sub(start).Seconds() / duration * plotWidth
+		}
+		y := float64(chartPadding) + (stats.Max-v)/span*plotHeight
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="none" stroke="#ddd"/>`, chartPadding, chartPadding, plotWidth, plotHeight)
+	fmt.Fprintf(&b, `<polyline fill="none" stroke="#1f77b4" stroke-width="1.5" points="%s"/>`, strings.Join(points, " "))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11" text-anchor="end">%s</text>`, chartPadding-4, chartPadding+4, fmt.Sprintf(format, stats.Max))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11" text-anchor="end">%s</text>`, chartPadding-4, chartHeight-chartPadding+4, fmt.Sprintf(format, stats.Min))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11">%s</text>`, chartPadding, chartHeight-chartPadding+16, start.Format("01-02 15:04"))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11" text-anchor="end">%s</text>`, chartWidth-chartPadding, chartHeight-chartPadding+16, end.Format("01-02 15:04"))
+	b.WriteString(`</svg>`)
+	return b.String()
+}
@@ -0,0 +1,362 @@
+package report
+
+import (
+	"cs-projects-eth-collar/pkg/pnl"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 输出格式
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+	FormatCSV      = "csv"
+)
+
+// Extension 输出格式对应的文件扩展名
+func Extension(format string) string {
+	switch format {
+	case FormatMarkdown:
+		return "md"
+	default:
+		return format
+	}
+}
+
+// ValidateFormat 检查输出格式是否支持
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatHTML, FormatMarkdown, FormatCSV:
+		return nil
+	default:
+		return fmt.Errorf("unknown report format %q, use html, markdown or csv", format)
+	}
+}
+
+// Title 报告标题
+func (r *Report) Title() string {
+	name := "Daily"
+	if r.Period == PeriodWeekly {
+		name = "Weekly"
+	}
+	return fmt.Sprintf("%s risk report %s (%s)", name, r.To.Format("2006-01-02"), r.Account)
+}
+
+// Summary 报告摘要，用于通知正文；完整报告只写入文件，不进入通知和审计日志
+func (r *Report) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s – %s UTC, %d evaluations", r.From.Format("2006-01-02 15:04"), r.To.Format("2006-01-02 15:04"), r.MMRatio.Count)
+	if r.MMRatio.Count > 0 {
+		fmt.Fprintf(&b, "; MM ratio end %.2f%% (max %.2f%%)", r.MMRatio.Last*100, r.MMRatio.Max*100)
+	}
+	fmt.Fprintf(&b, "; %d alerts, %d top-ups", len(r.Alerts), len(r.TopUps))
+	var worst *StressScenario
+	for i := range r.Stress {
+		if worst == nil || r.Stress[i].MMRatio > worst.MMRatio {
+			worst = &r.Stress[i]
+		}
+	}
+	if worst != nil {
+		fmt.Fprintf(&b, "; worst stress ETH %+.0f%%: MM ratio %.2f%%", worst.Shock*100, worst.MMRatio*100)
+		if worst.Breach {
+			b.WriteString(" (breach)")
+		}
+	}
+	return b.String()
+}
+
+// Render 按格式输出报告
+func (r *Report) Render(w io.Writer, format string) error {
+	switch format {
+	case FormatHTML:
+		return r.WriteHTML(w)
+	case FormatMarkdown:
+		return r.WriteMarkdown(w)
+	case FormatCSV:
+		return r.WriteCSV(w)
+	default:
+		return ValidateFormat(format)
+	}
+}
+
+// WriteMarkdown 输出 Markdown 报告
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Title())
+	fmt.Fprintf(&b, "Period: %s – %s (UTC), %d evaluations\n\n", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339), r.MMRatio.Count)
+
+	b.WriteString("## Margin and equity\n\n")
+	b.WriteString("| Metric | Min | Max | Avg | Start | End | Change |\n|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, row := range r.statRows() {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n", row.name,
+			row.format(row.stats.Min), row.format(row.stats.Max), row.format(row.stats.Avg),
+			row.format(row.stats.First), row.format(row.stats.Last), row.format(row.stats.Change()))
+	}
+
+	fmt.Fprintf(&b, "\n## Alerts (%d)\n\n", len(r.Alerts))
+	if len(r.Alerts) == 0 {
+		b.WriteString("No alerts.\n")
+	} else {
+		b.WriteString("| Time | Severity | Source | Title |\n|---|---|---|---|\n")
+		for _, alert := range r.Alerts {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", alert.Time.Format("01-02 15:04"), alert.Severity, alert.Source, markdownCell(alert.Title))
+		}
+	}
+
+	b.WriteString("\n## Top-ups\n\n")
+	if len(r.TopUps) == 0 && len(r.Requests) == 0 {
+		b.WriteString("No top-ups.\n")
+	}
+	if len(r.TopUps) > 0 {
+		b.WriteString("| Time | Proposal | Amount | Dry run |\n|---|---|---:|---|\n")
+		for _, topUp := range r.TopUps {
+			fmt.Fprintf(&b, "| %s | %s | %.4f %s | %t |\n", topUp.Time.Format("01-02 15:04"), topUp.ProposalID, topUp.Amount, topUp.Currency, topUp.DryRun)
+		}
+		b.WriteString("\n")
+	}
+	if len(r.Requests) > 0 {
+		b.WriteString("| Request | Opened | Status | Required | Received |\n|---|---|---|---:|---:|\n")
+		for _, req := range r.Requests {
+			fmt.Fprintf(&b, "| %s | %s | %s | %.4f | %.4f |\n", req.ID, req.CreatedAt.Format("01-02 15:04"), req.Status, req.Required, req.Received)
+		}
+	}
+
+	b.WriteString("\n## Stress scenarios\n\n")
+	if len(r.Stress) == 0 {
+		b.WriteString("No evaluations to stress.\n")
+	} else {
+		b.WriteString(r.stressBaseline() + "\n\n")
+		b.WriteString("| ETH move | ETH price | Equity (USD) | Maintenance margin (USD) | MM ratio | Breach |\n|---:|---:|---:|---:|---:|---|\n")
+		for _, scenario := range r.Stress {
+			fmt.Fprintf(&b, "| %+.0f%% | %.2f | %.2f | %.2f | %.2f%% | %t |\n", scenario.Shock*100, scenario.PriceUSD,
+				scenario.EquityUSD, scenario.MaintenanceMarginUSD, scenario.MMRatio*100, scenario.Breach)
+		}
+	}
+
+	b.WriteString("\n## Collar\n\n")
+	b.WriteString(r.collarSummary() + "\n")
+
+	b.WriteString("\n## PnL attribution\n\n")
+	if len(r.PnL) == 0 {
+		b.WriteString("No transactions.\n")
+	} else {
+		b.WriteString("| Date | Currency | " + strings.Join(pnl.Types, " | ") + " | PnL |\n|---|---|" + strings.Repeat("---:|", len(pnl.Types)+1) + "\n")
+		for _, day := range r.PnL {
+			fmt.Fprintf(&b, "| %s | %s |", day.Date.Format("2006-01-02"), day.Currency)
+			for _, category := range pnl.Types {
+				fmt.Fprintf(&b, " %.6f |", day.ByType[category])
+			}
+			fmt.Fprintf(&b, " %.6f |\n", day.PnL)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV 输出 CSV 报告，每行一个数据点：section, time, name, value, detail
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	rows := [][]string{{"section", "time", "name", "value", "detail"}}
+
+	for _, row := range r.statRows() {
+		for _, stat := range []struct {
+			name  string
+			value float64
+		}{{"min", row.stats.Min}, {"max", row.stats.Max}, {"avg", row.stats.Avg}, {"start", row.stats.First}, {"end", row.stats.Last}} {
+			rows = append(rows, []string{"summary", r.To.Format(time.RFC3339), row.key + "_" + stat.name, format(stat.value), ""})
+		}
+	}
+	for _, sample := range r.Samples {
+		t := sample.Time.Format(time.RFC3339)
+		rows = append(rows,
+			[]string{"sample", t, "mm_ratio", format(sample.MMRatio), ""},
+			[]string{"sample", t, "eth_equity", format(sample.ETHEquity), ""},
+			[]string{"sample", t, "total_equity_usd", format(sample.TotalEquityUSD), ""},
+		)
+	}
+	for _, alert := range r.Alerts {
+		rows = append(rows, []string{"alert", alert.Time.Format(time.RFC3339), alert.Source, alert.Severity, alert.Title})
+	}
+	for _, topUp := range r.TopUps {
+		rows = append(rows, []string{"topup", topUp.Time.Format(time.RFC3339), topUp.ProposalID, format(topUp.Amount), fmt.Sprintf("dry_run=%t", topUp.DryRun)})
+	}
+	for _, req := range r.Requests {
+		rows = append(rows, []string{"request", req.CreatedAt.Format(time.RFC3339), req.ID, format(req.Received), fmt.Sprintf("status=%s required=%s", req.Status, format(req.Required))})
+	}
+	for _, scenario := range r.Stress {
+		rows = append(rows, []string{"stress", r.StressBase.Time.Format(time.RFC3339), "mm_ratio_" + format(scenario.Shock), format(scenario.MMRatio),
+			fmt.Sprintf("price=%s equity_usd=%s maintenance_margin_usd=%s breach=%t",
+				format(scenario.PriceUSD), format(scenario.EquityUSD), format(scenario.MaintenanceMarginUSD), scenario.Breach)})
+	}
+	if r.Collar != nil {
+		rows = append(rows, []string{"collar", r.GeneratedAt.Format(time.RFC3339), "status", "", r.collarSummary()})
+	}
+	for _, day := range r.PnL {
+		date := day.Date.Format("2006-01-02")
+		for _, category := range pnl.Types {
+			rows = append(rows, []string{"pnl", date, category, format(day.ByType[category]), day.Currency})
+		}
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write csv report: %w", err)
+	}
+	return nil
+}
+
+// WriteHTML 输出 HTML 报告，图表以 SVG 内嵌，不依赖外部资源
+func (r *Report) WriteHTML(w io.Writer) error {
+	times := make([]time.Time, len(r.Samples))
+	mmRatio := make([]float64, len(r.Samples))
+	ethEquity := make([]float64, len(r.Samples))
+	totalEquity := make([]float64, len(r.Samples))
+	for i, sample := range r.Samples {
+		times[i] = sample.Time
+		mmRatio[i] = sample.MMRatio * 100 // 按百分比显示
+		ethEquity[i] = sample.ETHEquity
+		totalEquity[i] = sample.TotalEquityUSD
+	}
+
+	data := struct {
+		*Report
+		Stats        []statRow
+		Charts       []template.HTML
+		CollarStatus string
+		StressBase   string
+		Types        []string
+	}{
+		Report: r,
+		Stats:  r.statRows(),
+		Charts: []template.HTML{
+			template.HTML(lineChart("MM ratio", times, mmRatio, "%.2f%%")),
+			template.HTML(lineChart("ETH equity", times, ethEquity, "%.2f")),
+			template.HTML(lineChart("Total equity (USD)", times, totalEquity, "%.0f")),
+		},
+		CollarStatus: r.collarSummary(),
+		StressBase:   r.stressBaseline(),
+		Types:        pnl.Types,
+	}
+
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render html report: %w", err)
+	}
+	return nil
+}
+
+type statRow struct {
+	key    string
+	name   string
+	stats  Stats
+	format func(float64) string
+}
+
+// Cells HTML 模板使用的单元格
+func (s statRow) Cells() []string {
+	return []string{s.format(s.stats.Min), s.format(s.stats.Max), s.format(s.stats.Avg), s.format(s.stats.First), s.format(s.stats.Last), s.format(s.stats.Change())}
+}
+
+// Name HTML 模板使用的指标名
+func (s statRow) Name() string {
+	return s.name
+}
+
+func (r *Report) statRows() []statRow {
+	percent := func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) }
+	number := func(v float64) string { return fmt.Sprintf("%.4f", v) }
+	usd := func(v float64) string { return fmt.Sprintf("%.2f", v) }
+	return []statRow{
+		{key: "mm_ratio", name: "MM ratio", stats: r.MMRatio, format: percent},
+		{key: "eth_equity", name: "ETH equity", stats: r.ETHEquity, format: number},
+		{key: "total_equity_usd", name: "Total equity (USD)", stats: r.TotalEquityUSD, format: usd},
+		{key: "eth_price_usd", name: "ETH price (USD)", stats: r.ETHPriceUSD, format: usd},
+	}
+}
+
+func (r *Report) collarSummary() string {
+	switch {
+	case r.Collar == nil:
+		return "Not tracked (roll planner disabled)."
+	case r.Collar.Error != "":
+		return "Unavailable: " + r.Collar.Error
+	case r.Collar.Current == nil:
+		return "No collar position."
+	}
+	c := r.Collar.Current
+	summary := fmt.Sprintf("put %.0f / call %.0f, size %.2f, expires %s (%.1f days), index %.2f",
+		c.Put.Strike, c.Call.Strike, c.Put.Size, c.Expiry.Format("2006-01-02 15:04 UTC"), c.DaysToExpiry, r.Collar.IndexPrice)
+	if r.Collar.InWindow {
+		summary += "; inside roll window"
+	}
+	return summary
+}
+
+// stressBaseline 压力测试的基准和估算方式说明
+func (r *Report) stressBaseline() string {
+	base := r.StressBase
+	return fmt.Sprintf("Baseline %s: ETH %.2f, equity %.2f USD, maintenance margin %.2f USD, ETH equity %.4f, ETH delta %.4f. "+
+		"First-order estimate; USD maintenance margin scales with the ETH price.",
+		base.Time.Format("2006-01-02 15:04 UTC"), base.ETHPriceUSD, base.TotalEquityUSD, base.MaintenanceMarginUSD, base.ETHEquity, base.ETHDelta)
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.Format("01-02 15:04") },
+	"date": func(t time.Time) string { return t.Format("2006-01-02") },
+	"num":  func(v float64) string { return fmt.Sprintf("%.4f", v) },
+	"usd":  func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"pct":  func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) },
+	"move": func(v float64) string { return fmt.Sprintf("%+.0f%%", v*100) },
+	"cat":  func(m map[string]float64, k string) string { return fmt.Sprintf("%.6f", m[k]) },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title>
+<style>
+body{font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;margin:24px;color:#222}
+table{border-collapse:collapse;margin:8px 0 16px}
+th,td{border:1px solid #ddd;padding:4px 8px;font-size:13px}
+td.n{text-align:right}
+.critical{color:#c0392b}.warning{color:#d68910}
+</style></head><body>
+<h1>{{.Title}}</h1>
+<p>Period: {{.From.Format "2006-01-02 15:04"}} – {{.To.Format "2006-01-02 15:04"}} UTC, {{.MMRatio.Count}} evaluations</p>
+<h2>Margin and equity</h2>
+<table><tr><th>Metric</th><th>Min</th><th>Max</th><th>Avg</th><th>Start</th><th>End</th><th>Change</th></tr>
+{{range .Stats}}<tr><td>{{.Name}}</td>{{range .Cells}}<td class="n">{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{range .Charts}}<div>{{.}}</div>
+{{end}}
+<h2>Alerts ({{len .Alerts}})</h2>
+{{if .Alerts}}<table><tr><th>Time</th><th>Severity</th><th>Source</th><th>Title</th></tr>
+{{range .Alerts}}<tr><td>{{time .Time}}</td><td class="{{.Severity}}">{{.Severity}}</td><td>{{.Source}}</td><td>{{.Title}}</td></tr>
+{{end}}</table>{{else}}<p>No alerts.</p>{{end}}
+<h2>Top-ups</h2>
+{{if .TopUps}}<table><tr><th>Time</th><th>Proposal</th><th>Amount</th><th>Dry run</th></tr>
+{{range .TopUps}}<tr><td>{{time .Time}}</td><td>{{.ProposalID}}</td><td class="n">{{num .Amount}} {{.Currency}}</td><td>{{.DryRun}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Requests}}<table><tr><th>Request</th><th>Opened</th><th>Status</th><th>Required</th><th>Received</th></tr>
+{{range .Requests}}<tr><td>{{.ID}}</td><td>{{time .CreatedAt}}</td><td>{{.Status}}</td><td class="n">{{num .Required}}</td><td class="n">{{num .Received}}</td></tr>
+{{end}}</table>{{end}}
+{{if not (or .TopUps .Requests)}}<p>No top-ups.</p>{{end}}
+<h2>Stress scenarios</h2>
+{{if .Stress}}<p>{{.StressBase}}</p>
+<table><tr><th>ETH move</th><th>ETH price</th><th>Equity (USD)</th><th>Maintenance margin (USD)</th><th>MM ratio</th><th>Breach</th></tr>
+{{range .Stress}}<tr><td class="n">{{move .Shock}}</td><td class="n">{{usd .PriceUSD}}</td><td class="n">{{usd .EquityUSD}}</td><td class="n">{{usd .MaintenanceMarginUSD}}</td><td class="n{{if .Breach}} critical{{end}}">{{pct .MMRatio}}</td><td>{{.Breach}}</td></tr>
+{{end}}</table>{{else}}<p>No evaluations to stress.</p>{{end}}
+<h2>Collar</h2>
+<p>{{.CollarStatus}}</p>
+<h2>PnL attribution</h2>
+{{if .PnL}}<table><tr><th>Date</th><th>Currency</th>{{range .Types}}<th>{{.}}</th>{{end}}<th>PnL</th></tr>
+{{range $day := .PnL}}<tr><td>{{date $day.Date}}</td><td>{{$day.Currency}}</td>{{range $.Types}}<td class="n">{{cat $day.ByType .}}</td>{{end}}<td class="n">{{printf "%.6f" $day.PnL}}</td></tr>
+{{end}}</table>{{else}}<p>No transactions.</p>{{end}}
+<p style="color:#888;font-size:12px">Generated {{.GeneratedAt.Format "2006-01-02 15:04:05"}} UTC</p>
+</body></html>
+`))
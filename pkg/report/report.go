@@ -0,0 +1,391 @@
+// Package report 根据本地保存的历史数据生成日报和周报。
+//
+// 数据来源：
+//   - 审计日志：每个监控周期的评估记录（MM 比率、权益）、发出的通知（告警）和补充保证金记录
+//   - 账户流水历史：盈亏归因
+//   - 补充请求对账状态：补充 ETH 是否到账
+//   - 展期计划（可选）：当前领口状态
+//   - 压力测试：以周期内最后一次评估为基准，按配置的 ETH 价格变动估算权益和 MM 比率
+//
+// 报告可渲染为 HTML（内嵌 SVG 图表）、Markdown 或 CSV。
+package report
+
+import (
+	"cs-projects-eth-collar/pkg/audit"
+	"cs-projects-eth-collar/pkg/history"
+	"cs-projects-eth-collar/pkg/pnl"
+	"cs-projects-eth-collar/pkg/reconcile"
+	"cs-projects-eth-collar/pkg/roll"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// 报告周期
+const (
+	PeriodDaily  = "daily"
+	PeriodWeekly = "weekly"
+)
+
+// PeriodDuration 报告周期的时长
+func PeriodDuration(period string) (time.Duration, error) {
+	switch period {
+	case PeriodDaily:
+		return 24 * time.Hour, nil
+	case PeriodWeekly:
+		return 7 * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unknown report period %q, use daily or weekly", period)
+	}
+}
+
+// CollarSource 提供当前领口状态，*roll.Planner 满足此接口
+type CollarSource interface {
+	Plan() (*roll.Plan, error)
+}
+
+// Sources 报告的数据来源，路径为空或来源为 nil 时跳过对应部分
+type Sources struct {
+	AuditFile     string       // 审计日志（评估、通知、补充保证金）
+	HistoryFile   string       // 账户流水历史
+	Currencies    []string     // 盈亏归因的币种
+	ReconcileFile string       // 补充请求对账状态
+	Collar        CollarSource // 当前领口状态
+
+	StressShocks     []float64 // 压力测试的 ETH 价格变动比例，为空时不做压力测试
+	MMRatioThreshold float64   // 压力测试中 MM 比率超过该值视为触发告警，0 表示不判断
+}
+
+// Stats 一个指标在报告周期内的统计
+type Stats struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	First float64 `json:"first"`
+	Last  float64 `json:"last"`
+	Count int     `json:"count"`
+}
+
+// Change 期末相对期初的变化
+func (s Stats) Change() float64 {
+	return s.Last - s.First
+}
+
+// Sample 一次监控评估的快照
+type Sample struct {
+	Time           time.Time `json:"time"`
+	MMRatio        float64   `json:"mm_ratio"`
+	ETHEquity      float64   `json:"eth_equity"`
+	ETHEquityUSD   float64   `json:"eth_equity_usd"`
+	TotalEquityUSD float64   `json:"total_equity_usd"`
+	ETHPriceUSD    float64   `json:"eth_price_usd"`
+	RequiredETH    float64   `json:"required_eth"`
+
+	MaintenanceMarginUSD float64 `json:"maintenance_margin_usd"` // 账户维持保证金（USD）
+	ETHDelta             float64 `json:"eth_delta"`              // ETH 持仓 Delta（期权 + 期货）
+}
+
+// Alert 报告周期内发出的一条告警（warning 及以上的通知）
+type Alert struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Severity string    `json:"severity"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+}
+
+// TopUp 报告周期内执行（或演练）的一笔补充保证金划转
+type TopUp struct {
+	Time       time.Time `json:"time"`
+	ProposalID string    `json:"proposal_id"`
+	Amount     float64   `json:"amount"`
+	Currency   string    `json:"currency"`
+	DryRun     bool      `json:"dry_run"`
+}
+
+// StressScenario ETH 价格变动后的账户状态估算
+type StressScenario struct {
+	Shock                float64 `json:"shock"` // 价格变动比例，如 -0.2
+	PriceUSD             float64 `json:"price_usd"`
+	EquityUSD            float64 `json:"equity_usd"`
+	MaintenanceMarginUSD float64 `json:"maintenance_margin_usd"`
+	MMRatio              float64 `json:"mm_ratio"`
+	Breach               bool    `json:"breach"` // MM 比率超过阈值
+}
+
+// Collar 当前领口状态
+type Collar struct {
+	Current    *roll.Collar `json:"current,omitempty"`
+	IndexPrice float64      `json:"index_price"`
+	InWindow   bool         `json:"in_window"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// Report 一份风险报告
+type Report struct {
+	Account     string    `json:"account"`
+	Period      string    `json:"period"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	GeneratedAt time.Time `json:"generated_at"`
+
+	Samples        []Sample `json:"samples"`
+	MMRatio        Stats    `json:"mm_ratio"`
+	ETHEquity      Stats    `json:"eth_equity"`
+	TotalEquityUSD Stats    `json:"total_equity_usd"`
+	ETHPriceUSD    Stats    `json:"eth_price_usd"`
+
+	Alerts      []Alert             `json:"alerts"`
+	AlertCounts map[string]int      `json:"alert_counts"` // 级别 -> 告警数
+	TopUps      []TopUp             `json:"topups"`
+	Requests    []reconcile.Request `json:"requests"` // 报告周期内开启或关闭的补充请求
+	PnL         []pnl.Day           `json:"pnl"`
+	Collar      *Collar             `json:"collar,omitempty"`
+	Stress      []StressScenario    `json:"stress"` // 以 StressBase 为基准的价格冲击情景
+	StressBase  *Sample             `json:"stress_base,omitempty"`
+}
+
+// Generate 生成截至 to 的报告，周期为 period
+func Generate(account, period string, to time.Time, sources Sources) (*Report, error) {
+	duration, err := PeriodDuration(period)
+	if err != nil {
+		return nil, err
+	}
+	to = to.UTC()
+	r := &Report{
+		Account:     account,
+		Period:      period,
+		From:        to.Add(-duration),
+		To:          to,
+		GeneratedAt: time.Now().UTC(),
+		AlertCounts: make(map[string]int),
+	}
+
+	if sources.AuditFile != "" {
+		if err := r.readAudit(sources.AuditFile); err != nil {
+			return nil, err
+		}
+	}
+	if sources.HistoryFile != "" {
+		if err := r.readHistory(sources.HistoryFile, sources.Currencies); err != nil {
+			return nil, err
+		}
+	}
+	if sources.ReconcileFile != "" {
+		if err := r.readRequests(sources.ReconcileFile); err != nil {
+			return nil, err
+		}
+	}
+	if sources.Collar != nil {
+		r.Collar = &Collar{}
+		if plan, err := sources.Collar.Plan(); err != nil {
+			r.Collar.Error = err.Error()
+		} else {
+			r.Collar.Current = plan.Current
+			r.Collar.IndexPrice = plan.IndexPrice
+			r.Collar.InWindow = plan.InWindow
+		}
+	}
+
+	r.summarize()
+	r.stress(sources.StressShocks, sources.MMRatioThreshold)
+	return r, nil
+}
+
+func (r *Report) contains(t time.Time) bool {
+	return !t.Before(r.From) && t.Before(r.To)
+}
+
+// readAudit 读取评估、告警和补充保证金记录
+func (r *Report) readAudit(path string) error {
+	entries, err := audit.ReadEntries(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+
+	for _, entry := range entries {
+		if !r.contains(entry.Timestamp) {
+			continue
+		}
+		switch entry.Type {
+		case "evaluation":
+			var data struct {
+				Account              string  `json:"account"`
+				MMRatio              float64 `json:"mm_ratio"`
+				ETHEquity            float64 `json:"eth_equity"`
+				ETHEquityUSD         float64 `json:"eth_equity_usd"`
+				TotalEquityUSD       float64 `json:"total_equity_usd"`
+				ETHPriceUSD          float64 `json:"eth_price_usd"`
+				RequiredETH          float64 `json:"required_eth_amount"`
+				MaintenanceMarginUSD float64 `json:"total_maintenance_margin_usd"`
+				ETHDelta             float64 `json:"eth_delta_total"`
+			}
+			if err := json.Unmarshal(entry.Data, &data); err != nil || (data.Account != "" && data.Account != r.Account) {
+				continue
+			}
+			r.Samples = append(r.Samples, Sample{
+				Time:                 entry.Timestamp.UTC(),
+				MMRatio:              data.MMRatio,
+				ETHEquity:            data.ETHEquity,
+				ETHEquityUSD:         data.ETHEquityUSD,
+				TotalEquityUSD:       data.TotalEquityUSD,
+				ETHPriceUSD:          data.ETHPriceUSD,
+				RequiredETH:          data.RequiredETH,
+				MaintenanceMarginUSD: data.MaintenanceMarginUSD,
+				ETHDelta:             data.ETHDelta,
+			})
+		case "notification":
+			var data struct {
+				Source   string `json:"source"`
+				Severity string `json:"severity"`
+				Title    string `json:"title"`
+				Message  string `json:"message"`
+			}
+			if err := json.Unmarshal(entry.Data, &data); err != nil || data.Severity == "info" || data.Source == "report" {
+				continue
+			}
+			r.Alerts = append(r.Alerts, Alert{Time: entry.Timestamp.UTC(), Source: data.Source, Severity: data.Severity, Title: data.Title, Message: data.Message})
+			r.AlertCounts[data.Severity]++
+		case "topup.executed", "topup.dry_run":
+			var proposal struct {
+				ID       string  `json:"id"`
+				Amount   float64 `json:"amount"`
+				Currency string  `json:"currency"`
+				DryRun   bool    `json:"dry_run"`
+			}
+			// topup.executed 的提案包在 proposal 字段中，topup.dry_run 直接是提案
+			var wrapped struct {
+				Proposal json.RawMessage `json:"proposal"`
+			}
+			raw := entry.Data
+			if entry.Type == "topup.executed" && json.Unmarshal(entry.Data, &wrapped) == nil && len(wrapped.Proposal) > 0 {
+				raw = wrapped.Proposal
+			}
+			if err := json.Unmarshal(raw, &proposal); err != nil {
+				continue
+			}
+			r.TopUps = append(r.TopUps, TopUp{Time: entry.Timestamp.UTC(), ProposalID: proposal.ID, Amount: proposal.Amount,
+				Currency: proposal.Currency, DryRun: entry.Type == "topup.dry_run"})
+		}
+	}
+	return nil
+}
+
+// readHistory 按日归因报告周期内的账户流水
+func (r *Report) readHistory(path string, currencies []string) error {
+	entries, err := history.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+
+	wanted := make(map[string]bool)
+	for _, currency := range currencies {
+		wanted[strings.ToUpper(currency)] = true
+	}
+	seen := make(map[int64]bool)
+	var selected = entries[:0]
+	for _, entry := range entries {
+		if seen[entry.ID] || !r.contains(time.UnixMilli(entry.Timestamp)) {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[strings.ToUpper(entry.Currency)] {
+			continue
+		}
+		seen[entry.ID] = true
+		selected = append(selected, entry)
+	}
+	r.PnL = pnl.Attribute(selected)
+	return nil
+}
+
+// readRequests 读取报告周期内开启或关闭的补充请求，以及仍未关闭的请求
+func (r *Report) readRequests(path string) error {
+	requests, err := reconcile.ReadRequests(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read reconcile state %s: %w", path, err)
+	}
+	for _, req := range requests {
+		if req.Account != "" && req.Account != r.Account {
+			continue
+		}
+		if req.CreatedAt.After(r.To) {
+			continue
+		}
+		if r.contains(req.CreatedAt) || req.Open() || r.contains(*req.ClosedAt) {
+			r.Requests = append(r.Requests, req)
+		}
+	}
+	return nil
+}
+
+func (r *Report) summarize() {
+	var mmRatio, ethEquity, totalEquity, price []float64
+	for _, sample := range r.Samples {
+		mmRatio = append(mmRatio, sample.MMRatio)
+		ethEquity = append(ethEquity, sample.ETHEquity)
+		totalEquity = append(totalEquity, sample.TotalEquityUSD)
+		price = append(price, sample.ETHPriceUSD)
+	}
+	r.MMRatio = statsOf(mmRatio)
+	r.ETHEquity = statsOf(ethEquity)
+	r.TotalEquityUSD = statsOf(totalEquity)
+	r.ETHPriceUSD = statsOf(price)
+}
+
+// stress 以周期内最后一次评估为基准估算 ETH 价格变动后的权益和 MM 比率
+//
+// 一阶近似：账户 USD 权益变化 = (ETH 权益 + ETH 持仓 Delta) × 价格 × 变动比例，ETH 权益视为以 ETH 持有的抵押品；
+// 维持保证金以 ETH 计价近似不变，USD 值随价格缩放。结果只用于判断方向和量级，不代替交易所的风险矩阵。
+// MM 比率按账户 USD 合计计算；权益不为正时按 100%（强平线）计。
+func (r *Report) stress(shocks []float64, threshold float64) {
+	if len(shocks) == 0 || len(r.Samples) == 0 {
+		return
+	}
+	base := r.Samples[len(r.Samples)-1]
+	if base.ETHPriceUSD <= 0 {
+		return
+	}
+	r.StressBase = &base
+	for _, shock := range shocks {
+		scenario := StressScenario{
+			Shock:                shock,
+			PriceUSD:             base.ETHPriceUSD * (1 + shock),
+			EquityUSD:            base.TotalEquityUSD + (base.ETHEquity+base.ETHDelta)*base.ETHPriceUSD*shock,
+			MaintenanceMarginUSD: base.MaintenanceMarginUSD * (1 + shock),
+		}
+		switch {
+		case scenario.EquityUSD > 0:
+			scenario.MMRatio = scenario.MaintenanceMarginUSD / scenario.EquityUSD
+		case scenario.MaintenanceMarginUSD > 0:
+			scenario.MMRatio = 1
+		}
+		scenario.Breach = threshold > 0 && scenario.MMRatio > threshold
+		r.Stress = append(r.Stress, scenario)
+	}
+}
+
+func statsOf(values []float64) Stats {
+	if len(values) == 0 {
+		return Stats{}
+	}
+	stats := Stats{Min: values[0], Max: values[0], First: values[0], Last: values[len(values)-1], Count: len(values)}
+	var sum float64
+	for _, v := range values {
+		stats.Min = min(stats.Min, v)
+		stats.Max = max(stats.Max, v)
+		sum += v
+	}
+	stats.Avg = sum / float64(len(values))
+	return stats
+}
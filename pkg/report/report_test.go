@@ -0,0 +1,188 @@
+package report
+
+import (
+	"bytes"
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/audit"
+	"cs-projects-eth-collar/pkg/notify"
+	"cs-projects-eth-collar/pkg/notify/notifytest"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writeAuditLog 写入三次评估、两条通知和一次演练补充，记录时间为当前时间
+func writeAuditLog(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := audit.NewLogger(path)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	for _, mmRatio := range []float64{0.2, 0.55, 0.35} {
+		require.NoError(t, logger.Record("evaluation", map[string]interface{}{
+			"account": "desk", "mm_ratio": mmRatio, "eth_equity": 100 + mmRatio*10, "total_equity_usd": 300000 * (1 - mmRatio), "eth_price_usd": 3000,
+			"total_maintenance_margin_usd": mmRatio * 300000 * (1 - mmRatio), "eth_delta_total": -50.0,
+		}))
+	}
+	require.NoError(t, logger.Record("evaluation", map[string]interface{}{"account": "other", "mm_ratio": 0.9}))
+	require.NoError(t, logger.Record("notification", notify.Notification{Source: "hedge", Severity: notify.SeverityWarning, Title: "ETH delta | outside band"}))
+	require.NoError(t, logger.Record("notification", notify.Notification{Source: "hedge", Severity: notify.SeverityInfo, Title: "ETH delta back within band"}))
+	require.NoError(t, logger.Record("topup.dry_run", map[string]interface{}{"id": "topup-1", "amount": 35, "currency": "ETH", "dry_run": true}))
+	return path
+}
+
+func TestGenerate(t *testing.T) {
+	sources := Sources{AuditFile: writeAuditLog(t), HistoryFile: filepath.Join(t.TempDir(), "missing.jsonl")}
+
+	r, err := Generate("desk", PeriodDaily, time.Now().Add(time.Minute), sources)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, r.MMRatio.Count)
+	assert.Equal(t, 0.2, r.MMRatio.Min)
+	assert.Equal(t, 0.55, r.MMRatio.Max)
+	assert.InDelta(t, 0.3667, r.MMRatio.Avg, 1e-4)
+	assert.InDelta(t, 0.15, r.MMRatio.Change(), 1e-12)
+	require.Len(t, r.Alerts, 1) // info 通知不计入告警
+	assert.Equal(t, 1, r.AlertCounts["warning"])
+	require.Len(t, r.TopUps, 1)
+	assert.Equal(t, 35.0, r.TopUps[0].Amount)
+	assert.True(t, r.TopUps[0].DryRun)
+	assert.Nil(t, r.Collar)
+
+	// 报告周期之外的记录不计入
+	r, err = Generate("desk", PeriodDaily, time.Now().Add(-time.Hour), sources)
+	require.NoError(t, err)
+	assert.Zero(t, r.MMRatio.Count)
+	assert.Empty(t, r.Alerts)
+}
+
+func TestStress(t *testing.T) {
+	sources := Sources{AuditFile: writeAuditLog(t), StressShocks: []float64{-0.3, 0.1}, MMRatioThreshold: 0.5}
+
+	r, err := Generate("desk", PeriodDaily, time.Now().Add(time.Minute), sources)
+	require.NoError(t, err)
+
+	// 基准为最后一次评估：权益 195000，维持保证金 68250，ETH 权益 103.5，Delta -50，价格 3000
+	require.NotNil(t, r.StressBase)
+	assert.Equal(t, 0.35, r.StressBase.MMRatio)
+	require.Len(t, r.Stress, 2)
+
+	down := r.Stress[0]
+	assert.InDelta(t, 2100, down.PriceUSD, 1e-9)
+	assert.InDelta(t, 195000-53.5*3000*0.3, down.EquityUSD, 1e-6)
+	assert.InDelta(t, 68250*0.7, down.MaintenanceMarginUSD, 1e-6)
+	assert.InDelta(t, 47775.0/146850, down.MMRatio, 1e-9)
+	assert.False(t, down.Breach)
+	assert.InDelta(t, 68250*1.1/(195000+53.5*300), r.Stress[1].MMRatio, 1e-9)
+
+	// 阈值以上标记为触发
+	sources.MMRatioThreshold = 0.3
+	r, err = Generate("desk", PeriodDaily, time.Now().Add(time.Minute), sources)
+	require.NoError(t, err)
+	assert.True(t, r.Stress[0].Breach)
+
+	// 没有评估记录时不做压力测试
+	sources.AuditFile = ""
+	r, err = Generate("desk", PeriodDaily, time.Now().Add(time.Minute), sources)
+	require.NoError(t, err)
+	assert.Empty(t, r.Stress)
+	assert.Nil(t, r.StressBase)
+}
+
+func TestRender(t *testing.T) {
+	r, err := Generate("desk", PeriodWeekly, time.Now().Add(time.Minute), Sources{AuditFile: writeAuditLog(t), StressShocks: []float64{-0.2}, MMRatioThreshold: 0.5})
+	require.NoError(t, err)
+
+	var md bytes.Buffer
+	require.NoError(t, r.Render(&md, FormatMarkdown))
+	assert.Contains(t, md.String(), "# Weekly risk report")
+	assert.Contains(t, md.String(), "| MM ratio | 20.00% | 55.00% |")
+	assert.Contains(t, md.String(), `ETH delta \| outside band`)
+	assert.Contains(t, md.String(), "Not tracked (roll planner disabled).")
+	assert.Contains(t, md.String(), "## Stress scenarios")
+	assert.Contains(t, md.String(), "| -20% | 2400.00 |")
+	assert.Contains(t, r.Summary(), "worst stress ETH -20%")
+
+	var page bytes.Buffer
+	require.NoError(t, r.Render(&page, FormatHTML))
+	assert.Contains(t, page.String(), "<svg")
+	assert.Contains(t, page.String(), "<polyline")
+	assert.Contains(t, page.String(), "ETH delta | outside band")
+	assert.Contains(t, page.String(), "<h2>Stress scenarios</h2>")
+
+	var out bytes.Buffer
+	require.NoError(t, r.Render(&out, FormatCSV))
+	rows, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"section", "time", "name", "value", "detail"}, rows[0])
+	assert.Contains(t, rows, []string{"summary", r.To.Format(time.RFC3339), "mm_ratio_max", "0.55", ""})
+	assert.Equal(t, "stress", rows[len(rows)-1][0])
+	assert.Equal(t, "mm_ratio_-0.2", rows[len(rows)-1][2])
+
+	assert.Error(t, r.Render(&out, "pdf"))
+}
+
+func TestSchedulerRunDue(t *testing.T) {
+	dir := t.TempDir()
+	config := types.ReportConfig{Periods: []string{PeriodDaily, PeriodWeekly}, Format: FormatMarkdown, Hour: 8, Weekday: "monday", OutputDir: dir, Notify: true}
+	notifier := &notifytest.Notifier{}
+	scheduler, err := NewScheduler(config, "desk", Sources{}, notifier, zap.NewNop())
+	require.NoError(t, err)
+
+	// 2024-12-18 是周三，计划时刻前取前一天，周报取周一
+	scheduler.now = func() time.Time { return time.Date(2024, 12, 18, 7, 30, 0, 0, time.UTC) }
+	written, err := scheduler.RunDue()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "daily-desk-2024-12-17.md"),
+		filepath.Join(dir, "weekly-desk-2024-12-16.md"),
+	}, written)
+	require.Len(t, notifier.Notifications, 2)
+	assert.Equal(t, "Daily risk report 2024-12-17 (desk)", notifier.Notifications[0].Title)
+	// 通知只带摘要和文件路径，不带完整报告
+	assert.Contains(t, notifier.Notifications[0].Message, "0 evaluations; 0 alerts, 0 top-ups")
+	assert.Contains(t, notifier.Notifications[0].Message, "Report: "+filepath.Join(dir, "daily-desk-2024-12-17.md"))
+	assert.NotContains(t, notifier.Notifications[0].Message, "# Daily risk report")
+
+	// 已生成的报告不重复
+	written, err = scheduler.RunDue()
+	require.NoError(t, err)
+	assert.Empty(t, written)
+
+	_, err = os.Stat(filepath.Join(dir, "daily-desk-2024-12-17.md"))
+	assert.NoError(t, err)
+
+	config.Format = "pdf"
+	_, err = NewScheduler(config, "desk", Sources{}, notifier, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestSchedulerFollowerSkips(t *testing.T) {
+	dir := t.TempDir()
+	config := types.ReportConfig{Periods: []string{PeriodDaily}, Format: FormatMarkdown, Hour: 8, Weekday: "monday", OutputDir: dir, Notify: true}
+	notifier := &notifytest.Notifier{}
+	leader := false
+	scheduler, err := NewScheduler(config, "desk", Sources{}, notifier, zap.NewNop(), WithLeadership(func() bool { return leader }))
+	require.NoError(t, err)
+	scheduler.now = func() time.Time { return time.Date(2024, 12, 18, 7, 30, 0, 0, time.UTC) }
+
+	// follower 不写报告文件，否则接管后的 leader 会认为报告已生成而不发送
+	written, err := scheduler.RunDue()
+	require.NoError(t, err)
+	assert.Empty(t, written)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	leader = true
+	written, err = scheduler.RunDue()
+	require.NoError(t, err)
+	assert.Len(t, written, 1)
+	assert.Len(t, notifier.Notifications, 1)
+}
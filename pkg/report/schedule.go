@@ -0,0 +1,186 @@
+package report
+
+import (
+	"bytes"
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/notify"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// checkInterval 检查是否有到期报告的间隔
+const checkInterval = time.Minute
+
+// Scheduler 按配置的时刻生成日报和周报，写入文件并通过通知渠道发送摘要和文件路径
+//
+// 报告文件已存在时不重复生成，重启后会补发最近一次错过的报告。
+type Scheduler struct {
+	config   types.ReportConfig
+	account  string
+	sources  Sources
+	notifier notify.Notifier
+	logger   *zap.Logger
+	now      func() time.Time
+	isLeader func() bool // 高可用模式下判断本实例是否为 leader，为 nil 时总是生成
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// SchedulerOption 定时报告的可选配置
+type SchedulerOption func(*Scheduler)
+
+// WithLeadership 启用高可用模式，本实例不是 leader 时不生成报告，避免 follower 写入报告文件后 leader 认为已生成而不发送
+func WithLeadership(isLeader func() bool) SchedulerOption {
+	return func(s *Scheduler) {
+		s.isLeader = isLeader
+	}
+}
+
+// NewScheduler 创建定时报告
+func NewScheduler(config types.ReportConfig, account string, sources Sources, notifier notify.Notifier, logger *zap.Logger, opts ...SchedulerOption) (*Scheduler, error) {
+	for _, period := range config.Periods {
+		if _, err := PeriodDuration(period); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := parseWeekday(config.Weekday); err != nil {
+		return nil, err
+	}
+	if err := ValidateFormat(config.Format); err != nil {
+		return nil, err
+	}
+	s := &Scheduler{
+		config:   config,
+		account:  account,
+		sources:  sources,
+		notifier: notifier,
+		logger:   logger,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Start 每分钟检查一次是否有到期的报告，阻塞运行直到 Stop 被调用；正在生成的报告会完成后再返回
+func (s *Scheduler) Start() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunDue(); err != nil {
+			s.logger.Error("Failed to generate scheduled report", zap.Error(err))
+		}
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Stop 通知 Start 返回，可重复调用
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// RunDue 生成所有已到期但尚未生成的报告，返回写入的文件；本实例不是 leader 时什么都不做
+func (s *Scheduler) RunDue() ([]string, error) {
+	if s.isLeader != nil && !s.isLeader() {
+		return nil, nil
+	}
+	var written []string
+	var errs []error
+	for _, period := range s.config.Periods {
+		to := s.lastScheduled(period, s.now().UTC())
+		path := filepath.Join(s.config.OutputDir, fmt.Sprintf("%s-%s-%s.%s", period, s.account, to.Format("2006-01-02"), Extension(s.config.Format)))
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+
+		if err := s.generate(period, to, path); err != nil {
+			errs = append(errs, fmt.Errorf("%s report: %w", period, err))
+			continue
+		}
+		written = append(written, path)
+	}
+	return written, errors.Join(errs...)
+}
+
+func (s *Scheduler) generate(period string, to time.Time, path string) error {
+	r, err := Generate(s.account, period, to, s.sources)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := r.Render(&buf, s.config.Format); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.config.OutputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	s.logger.Info("Report generated", zap.String("period", period), zap.String("file", path))
+
+	if !s.config.Notify {
+		return nil
+	}
+	n := notify.Notification{
+		Source:   "report",
+		Severity: notify.SeverityInfo,
+		Title:    r.Title(),
+		Message:  r.Summary() + "\nReport: " + path,
+		Fields: map[string]interface{}{
+			"account": s.account,
+			"period":  period,
+			"format":  s.config.Format,
+			"file":    path,
+			"from":    r.From,
+			"to":      r.To,
+			"alerts":  len(r.Alerts),
+		},
+	}
+	if err := s.notifier.Notify(n); err != nil {
+		s.logger.Error("Failed to send report notification", zap.Error(err))
+	}
+	return nil
+}
+
+// lastScheduled 不晚于 now 的最近一次计划时刻：日报为每天 hour 点，周报为每周 weekday 的 hour 点（UTC）
+func (s *Scheduler) lastScheduled(period string, now time.Time) time.Time {
+	at := time.Date(now.Year(), now.Month(), now.Day(), s.config.Hour, 0, 0, 0, time.UTC)
+	if period == PeriodWeekly {
+		weekday, _ := parseWeekday(s.config.Weekday)
+		at = at.AddDate(0, 0, -((int(at.Weekday()) - int(weekday) + 7) % 7))
+		if at.After(now) {
+			at = at.AddDate(0, 0, -7)
+		}
+		return at
+	}
+	if at.After(now) {
+		at = at.AddDate(0, 0, -1)
+	}
+	return at
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
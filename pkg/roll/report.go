@@ -0,0 +1,49 @@
+package roll
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteReport 以文本表格输出展期计划
+func (p *Plan) WriteReport(w io.Writer) error {
+	fmt.Fprintf(w, "Collar roll plan for %s at %s (dry run, no orders placed)\n", p.Currency, p.GeneratedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	if p.Current == nil {
+		_, err := fmt.Fprintln(w, "No collar position (long put + short call with the same expiry) found.")
+		return err
+	}
+
+	current := p.Current
+	fmt.Fprintf(w, "Index price: %.2f\n", p.IndexPrice)
+	fmt.Fprintf(w, "Current collar: %s (%+.1f) / %s (%+.1f), expires %s (%.1f days)\n\n",
+		current.Put.Instrument, current.Put.Size, current.Call.Instrument, current.Call.Size,
+		current.Expiry.Format("2006-01-02"), current.DaysToExpiry)
+
+	if len(p.Proposals) == 0 {
+		_, err := fmt.Fprintln(w, "No replacement collar found in the configured expiry range.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tEXPIRY\tDAYS\tPUT\tDELTA\tCALL\tDELTA\tFLOOR\tCAP\tOPEN/CONTRACT\tNET PREMIUM\tNET USD\tMARGIN IMPACT\tZERO COST")
+	for i, proposal := range p.Proposals {
+		zeroCost := "no"
+		if proposal.ZeroCost {
+			zeroCost = "yes"
+		}
+		if proposal.IndicativePricing {
+			zeroCost += " (mark)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%.0f\t%s\t%.2f\t%s\t%.2f\t%.0f (%+.0f)\t%.0f (%+.0f)\t%.4f\t%.4f\t%.2f\t%.4f\t%s\n",
+			i+1, proposal.Expiry.Format("2006-01-02"), proposal.DaysToExpiry,
+			proposal.Put.Instrument, proposal.Put.Delta, proposal.Call.Instrument, proposal.Call.Delta,
+			proposal.Put.Strike, proposal.FloorChange, proposal.Call.Strike, proposal.CapChange,
+			proposal.OpenPremium, proposal.NetPremium, proposal.NetPremiumUSD, proposal.MarginImpact, zeroCost)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\nPremiums in %s, positive = received. Margin impact is the change in short call maintenance margin under standard margin.\n", p.Currency)
+	return err
+}
@@ -0,0 +1,471 @@
+// Package roll 在领口（买入看跌 + 卖出看涨）临近到期时生成展期计划。
+//
+// 本模块只做计算和建议：Reader 和 Catalog 接口只包含只读方法，不持有任何下单能力，
+// 计划通过报告和通知发出，由人工决定是否执行。
+//
+// 候选合约先用期权链的标记隐含波动率按 Black-76 估算 Delta，选出最接近目标 Delta 的看跌和看涨期权，
+// 再读取这两个合约的 ticker，报告中使用交易所给出的 Delta 和买卖价。
+package roll
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/instruments"
+	"cs-projects-eth-collar/pkg/notify"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// shortOptionMarginRate Deribit 标准保证金下卖出期权的维持保证金 = 0.075 + 标记价格（每张合约，币计价）
+const shortOptionMarginRate = 0.075
+
+// Reader 读取仓位和行情，*deribit.Client 满足此接口
+type Reader interface {
+	GetPositions(currency string, kind ...string) ([]types.Position, error)
+	GetTicker(instrumentName string) (*types.Ticker, error)
+}
+
+// Catalog 查询合约信息和期权链，*instruments.Catalog 满足此接口
+type Catalog interface {
+	Get(name string) (types.Instrument, bool, error)
+	Expiries(currency string) ([]time.Time, error)
+	Chain(currency string, expiry time.Time) ([]instruments.ChainEntry, error)
+}
+
+// Leg 领口的一条腿
+type Leg struct {
+	Instrument string    `json:"instrument"`
+	OptionType string    `json:"option_type"`
+	Strike     float64   `json:"strike"`
+	Expiry     time.Time `json:"expiry"`
+	Size       float64   `json:"size"` // 有符号，卖出为负
+	Delta      float64   `json:"delta"`
+	Bid        float64   `json:"bid"` // 0 表示没有买单
+	Ask        float64   `json:"ask"` // 0 表示没有卖单
+	Mark       float64   `json:"mark"`
+}
+
+// SellPrice 卖出价：有买单时用买价，否则用标记价格
+func (l Leg) SellPrice() float64 {
+	if l.Bid > 0 {
+		return l.Bid
+	}
+	return l.Mark
+}
+
+// BuyPrice 买入价：有卖单时用卖价，否则用标记价格
+func (l Leg) BuyPrice() float64 {
+	if l.Ask > 0 {
+		return l.Ask
+	}
+	return l.Mark
+}
+
+// Quoted 买卖双边都有报价
+func (l Leg) Quoted() bool {
+	return l.Bid > 0 && l.Ask > 0
+}
+
+// Collar 当前持有的领口
+type Collar struct {
+	Put          Leg       `json:"put"`
+	Call         Leg       `json:"call"`
+	Expiry       time.Time `json:"expiry"`
+	DaysToExpiry float64   `json:"days_to_expiry"`
+}
+
+// Proposal 一个展期方案：平掉当前领口，在新的到期日按目标 Delta 建立新领口
+//
+// 权利金均为币计价，正数表示收入；成交价按当前最优买卖价估算，没有报价时使用标记价格。
+type Proposal struct {
+	Expiry             time.Time `json:"expiry"`
+	DaysToExpiry       float64   `json:"days_to_expiry"`
+	Put                Leg       `json:"put"`
+	Call               Leg       `json:"call"`
+	OpenPremium        float64   `json:"open_premium"`         // 新领口每张合约的净权利金：卖出看涨的收入（SellPrice）- 买入看跌的成本（BuyPrice）
+	ClosePremium       float64   `json:"close_premium"`        // 平掉当前领口的净权利金（按仓位数量）
+	NetPremium         float64   `json:"net_premium"`          // 展期总净权利金：平仓 + 开仓（按仓位数量）
+	NetPremiumUSD      float64   `json:"net_premium_usd"`      // 按指数价格折算
+	ZeroCost           bool      `json:"zero_cost"`            // |OpenPremium| <= zero_cost_tolerance
+	FloorChange        float64   `json:"floor_change"`         // 新看跌行权价 - 当前看跌行权价
+	CapChange          float64   `json:"cap_change"`           // 新看涨行权价 - 当前看涨行权价
+	MarginImpact       float64   `json:"margin_impact"`        // 卖出看涨期权的维持保证金变化估算（标准保证金，币计价）
+	MarginImpactUSD    float64   `json:"margin_impact_usd"`    // 按指数价格折算
+	IndicativePricing  bool      `json:"indicative_pricing"`   // 至少一条腿缺少报价，使用了标记价格
+	EstimatedPutDelta  float64   `json:"estimated_put_delta"`  // 选择合约时按隐含波动率估算的 Delta
+	EstimatedCallDelta float64   `json:"estimated_call_delta"` // 同上
+}
+
+// Plan 一次展期计划，DryRun 恒为 true
+type Plan struct {
+	Currency    string     `json:"currency"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	IndexPrice  float64    `json:"index_price"`
+	Current     *Collar    `json:"current,omitempty"` // 没有领口仓位时为 nil
+	InWindow    bool       `json:"in_window"`         // 当前领口已进入展期窗口
+	Proposals   []Proposal `json:"proposals"`
+	DryRun      bool       `json:"dry_run"`
+}
+
+// Best 排在第一位的方案，没有方案时返回 nil
+func (p *Plan) Best() *Proposal {
+	if len(p.Proposals) == 0 {
+		return nil
+	}
+	return &p.Proposals[0]
+}
+
+// Planner 生成领口展期计划
+type Planner struct {
+	config   types.RollConfig
+	reader   Reader
+	catalog  Catalog
+	notifier notify.Notifier
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu           sync.Mutex
+	lastPlan     *Plan
+	lastNotified time.Time
+}
+
+// NewPlanner 创建展期计划，notifier 可为 nil（只生成报告）
+func NewPlanner(config types.RollConfig, reader Reader, catalog Catalog, notifier notify.Notifier, logger *zap.Logger) *Planner {
+	config.Currency = strings.ToUpper(config.Currency)
+	return &Planner{
+		config:   config,
+		reader:   reader,
+		catalog:  catalog,
+		notifier: notifier,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Evaluate 在每个监控周期调用：当前领口进入展期窗口后，按 notify_interval_seconds 重新生成计划并通知；
+// 间隔内返回上一次的计划，不重复读取期权链
+func (p *Planner) Evaluate(account string) (*Plan, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	plan, err := p.current()
+	if err != nil {
+		return nil, err
+	}
+	if !plan.InWindow {
+		p.lastPlan = nil
+		return plan, nil
+	}
+
+	interval := time.Duration(p.config.NotifyIntervalSeconds) * time.Second
+	if p.lastPlan != nil && plan.GeneratedAt.Sub(p.lastNotified) < interval {
+		return p.lastPlan, nil
+	}
+
+	if err := p.propose(plan); err != nil {
+		return nil, err
+	}
+	p.lastPlan = plan
+	p.lastNotified = plan.GeneratedAt
+	p.notify(account, plan)
+	return plan, nil
+}
+
+// Plan 不论是否进入展期窗口，立即生成完整的展期计划（命令行报告使用）
+func (p *Planner) Plan() (*Plan, error) {
+	plan, err := p.current()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.propose(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// current 从仓位中找出最近到期的领口（买入看跌 + 卖出看涨，同一到期日），并读取两条腿的行情
+func (p *Planner) current() (*Plan, error) {
+	now := p.now()
+	plan := &Plan{Currency: p.config.Currency, GeneratedAt: now, DryRun: true}
+
+	positions, err := p.reader.GetPositions(p.config.Currency, "option")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get option positions: %w", err)
+	}
+
+	puts := make(map[time.Time]types.Position)
+	calls := make(map[time.Time]types.Position)
+	instrumentByName := make(map[string]types.Instrument)
+	for _, position := range positions {
+		if position.Kind != "option" || position.Size == 0 {
+			continue
+		}
+		instrument, ok, err := p.catalog.Get(position.InstrumentName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", position.InstrumentName, err)
+		}
+		if !ok {
+			p.logger.Warn("Option position not found in instrument catalog", zap.String("instrument", position.InstrumentName))
+			continue
+		}
+		instrumentByName[position.InstrumentName] = instrument
+		expiry := instruments.Expiry(instrument)
+		short := position.Direction == "sell" || position.Size < 0
+		// 同一到期日有多个合约时取数量最大的一个
+		switch {
+		case instrument.OptionType == "put" && !short:
+			if existing, ok := puts[expiry]; !ok || math.Abs(position.Size) > math.Abs(existing.Size) {
+				puts[expiry] = position
+			}
+		case instrument.OptionType == "call" && short:
+			if existing, ok := calls[expiry]; !ok || math.Abs(position.Size) > math.Abs(existing.Size) {
+				calls[expiry] = position
+			}
+		}
+	}
+
+	var expiry time.Time
+	for candidate := range puts {
+		if _, ok := calls[candidate]; ok && (expiry.IsZero() || candidate.Before(expiry)) {
+			expiry = candidate
+		}
+	}
+	if expiry.IsZero() {
+		return plan, nil
+	}
+
+	put, err := p.leg(instrumentByName[puts[expiry].InstrumentName], math.Abs(puts[expiry].Size))
+	if err != nil {
+		return nil, err
+	}
+	call, err := p.leg(instrumentByName[calls[expiry].InstrumentName], -math.Abs(calls[expiry].Size))
+	if err != nil {
+		return nil, err
+	}
+	plan.IndexPrice = put.indexPrice
+	plan.Current = &Collar{
+		Put:          put.Leg,
+		Call:         call.Leg,
+		Expiry:       expiry,
+		DaysToExpiry: expiry.Sub(now).Hours() / 24,
+	}
+	plan.InWindow = plan.Current.DaysToExpiry <= float64(p.config.RollWindowDays)
+	return plan, nil
+}
+
+// quotedLeg 带指数价格的腿
+type quotedLeg struct {
+	Leg
+	indexPrice float64
+}
+
+// leg 读取合约的 ticker
+func (p *Planner) leg(instrument types.Instrument, size float64) (*quotedLeg, error) {
+	ticker, err := p.reader.GetTicker(instrument.InstrumentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s ticker: %w", instrument.InstrumentName, err)
+	}
+	leg := &quotedLeg{
+		Leg: Leg{
+			Instrument: instrument.InstrumentName,
+			OptionType: instrument.OptionType,
+			Strike:     instrument.Strike,
+			Expiry:     instruments.Expiry(instrument),
+			Size:       size,
+			Bid:        ticker.BestBidPrice,
+			Ask:        ticker.BestAskPrice,
+			Mark:       ticker.MarkPrice,
+		},
+		indexPrice: ticker.IndexPrice,
+	}
+	if ticker.Greeks != nil {
+		leg.Delta = ticker.Greeks.Delta
+	}
+	return leg, nil
+}
+
+// propose 为满足天数范围的每个到期日生成一个方案，零成本方案在前，其余按到期时间排序
+func (p *Planner) propose(plan *Plan) error {
+	if plan.Current == nil {
+		return nil
+	}
+
+	expiries, err := p.catalog.Expiries(p.config.Currency)
+	if err != nil {
+		return fmt.Errorf("failed to get option expiries: %w", err)
+	}
+
+	for _, expiry := range expiries {
+		days := expiry.Sub(plan.GeneratedAt).Hours() / 24
+		if !expiry.After(plan.Current.Expiry) || days < float64(p.config.MinDaysToExpiry) ||
+			(p.config.MaxDaysToExpiry > 0 && days > float64(p.config.MaxDaysToExpiry)) {
+			continue
+		}
+		proposal, err := p.proposeExpiry(plan, expiry, days)
+		if err != nil {
+			p.logger.Warn("Failed to build roll proposal", zap.Time("expiry", expiry), zap.Error(err))
+			continue
+		}
+		if proposal != nil {
+			plan.Proposals = append(plan.Proposals, *proposal)
+		}
+	}
+
+	sort.SliceStable(plan.Proposals, func(i, j int) bool {
+		if plan.Proposals[i].ZeroCost != plan.Proposals[j].ZeroCost {
+			return plan.Proposals[i].ZeroCost
+		}
+		return plan.Proposals[i].Expiry.Before(plan.Proposals[j].Expiry)
+	})
+	if p.config.MaxProposals > 0 && len(plan.Proposals) > p.config.MaxProposals {
+		plan.Proposals = plan.Proposals[:p.config.MaxProposals]
+	}
+	return nil
+}
+
+// proposeExpiry 在一个到期日的期权链中选出最接近目标 Delta 的看跌和看涨期权
+func (p *Planner) proposeExpiry(plan *Plan, expiry time.Time, days float64) (*Proposal, error) {
+	chain, err := p.catalog.Chain(p.config.Currency, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	years := days / 365
+	var put, call *instruments.ChainEntry
+	var putDelta, callDelta float64
+	for i := range chain {
+		entry := &chain[i]
+		if entry.Book == nil || entry.Book.MarkIV <= 0 || entry.Book.UnderlyingPrice <= 0 {
+			continue
+		}
+		delta := blackDelta(entry.Instrument.OptionType, entry.Book.UnderlyingPrice, entry.Instrument.Strike, entry.Book.MarkIV/100, years)
+		switch entry.Instrument.OptionType {
+		case "put":
+			if put == nil || math.Abs(delta-p.config.PutDeltaTarget) < math.Abs(putDelta-p.config.PutDeltaTarget) {
+				put, putDelta = entry, delta
+			}
+		case "call":
+			if call == nil || math.Abs(delta-p.config.CallDeltaTarget) < math.Abs(callDelta-p.config.CallDeltaTarget) {
+				call, callDelta = entry, delta
+			}
+		}
+	}
+	if put == nil || call == nil {
+		return nil, nil
+	}
+
+	current := plan.Current
+	newPut, err := p.leg(put.Instrument, current.Put.Size)
+	if err != nil {
+		return nil, err
+	}
+	newCall, err := p.leg(call.Instrument, current.Call.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	putSize, callSize := math.Abs(current.Put.Size), math.Abs(current.Call.Size)
+	proposal := &Proposal{
+		Expiry:             expiry,
+		DaysToExpiry:       days,
+		Put:                newPut.Leg,
+		Call:               newCall.Leg,
+		OpenPremium:        newCall.SellPrice() - newPut.BuyPrice(),
+		ClosePremium:       current.Put.SellPrice()*putSize - current.Call.BuyPrice()*callSize,
+		FloorChange:        newPut.Strike - current.Put.Strike,
+		CapChange:          newCall.Strike - current.Call.Strike,
+		MarginImpact:       (shortOptionMarginRate+newCall.Mark)*callSize - (shortOptionMarginRate+current.Call.Mark)*callSize,
+		IndicativePricing:  !newPut.Quoted() || !newCall.Quoted() || !current.Put.Quoted() || !current.Call.Quoted(),
+		EstimatedPutDelta:  putDelta,
+		EstimatedCallDelta: callDelta,
+	}
+	proposal.NetPremium = proposal.ClosePremium + newCall.SellPrice()*callSize - newPut.BuyPrice()*putSize
+	proposal.ZeroCost = math.Abs(proposal.OpenPremium) <= p.config.ZeroCostTolerance
+	proposal.NetPremiumUSD = proposal.NetPremium * plan.IndexPrice
+	proposal.MarginImpactUSD = proposal.MarginImpact * plan.IndexPrice
+	return proposal, nil
+}
+
+// notify 发出展期计划通知
+func (p *Planner) notify(account string, plan *Plan) {
+	if p.notifier == nil {
+		return
+	}
+
+	current := plan.Current
+	n := notify.Notification{
+		Source:   "roll",
+		Severity: notify.SeverityWarning,
+		Title:    fmt.Sprintf("%s collar expires in %.1f days", plan.Currency, current.DaysToExpiry),
+		Fields: map[string]interface{}{
+			"account":        account,
+			"current_put":    current.Put.Instrument,
+			"current_call":   current.Call.Instrument,
+			"days_to_expiry": current.DaysToExpiry,
+			"proposals":      len(plan.Proposals),
+			"dry_run":        plan.DryRun,
+		},
+	}
+	if best := plan.Best(); best != nil {
+		n.Message = fmt.Sprintf("Roll to %s / %s: floor %.0f -> %.0f, cap %.0f -> %.0f, net premium %.4f %s (%.2f USD), margin impact %.4f %s (dry run, no order placed)",
+			best.Put.Instrument, best.Call.Instrument,
+			current.Put.Strike, best.Put.Strike, current.Call.Strike, best.Call.Strike,
+			best.NetPremium, plan.Currency, best.NetPremiumUSD, best.MarginImpact, plan.Currency)
+		n.Fields["put"] = best.Put.Instrument
+		n.Fields["call"] = best.Call.Instrument
+		n.Fields["net_premium"] = best.NetPremium
+		n.Fields["zero_cost"] = best.ZeroCost
+		n.Fields["margin_impact"] = best.MarginImpact
+	} else {
+		n.Message = fmt.Sprintf("No replacement collar found between %d and %d days to expiry", p.config.MinDaysToExpiry, p.config.MaxDaysToExpiry)
+	}
+
+	if err := p.notifier.Notify(n); err != nil {
+		p.logger.Error("Failed to send roll notification", zap.Error(err))
+	}
+}
+
+// blackDelta Black-76 模型下以远期价格计的 Delta（Deribit 期权 Greeks 的口径）
+func blackDelta(optionType string, forward, strike, vol, years float64) float64 {
+	if forward <= 0 || strike <= 0 || vol <= 0 || years <= 0 {
+		return 0
+	}
+	d1 := (math.Log(forward/strike) + vol*vol*years/2) / (vol * math.Sqrt(years))
+	delta := 0.5 * math.Erfc(-d1/math.Sqrt2)
+	if optionType == "put" {
+		return delta - 1
+	}
+	return delta
+}
+
+// alertState 上一次通知的展期计划，退出时保存
+type alertState struct {
+	LastPlan     *Plan     `json:"last_plan,omitempty"`
+	LastNotified time.Time `json:"last_notified"`
+}
+
+// MarshalState 导出告警状态
+func (p *Planner) MarshalState() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Marshal(alertState{LastPlan: p.lastPlan, LastNotified: p.lastNotified})
+}
+
+// UnmarshalState 恢复上次退出时的告警状态，重启后在通知间隔内沿用上一次的计划
+func (p *Planner) UnmarshalState(data []byte) error {
+	var state alertState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastPlan = state.LastPlan
+	p.lastNotified = state.LastNotified
+	return nil
+}
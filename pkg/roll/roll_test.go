@@ -0,0 +1,221 @@
+package roll
+
+import (
+	"bytes"
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/instruments"
+	"cs-projects-eth-collar/pkg/notify/notifytest"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var (
+	now       = time.Date(2025, 1, 20, 8, 0, 0, 0, time.UTC)
+	expiryJan = time.Date(2025, 1, 24, 8, 0, 0, 0, time.UTC)
+	expiryFeb = time.Date(2025, 2, 28, 8, 0, 0, 0, time.UTC)
+	expiryMar = time.Date(2025, 3, 28, 8, 0, 0, 0, time.UTC)
+	expiryJun = time.Date(2025, 6, 27, 8, 0, 0, 0, time.UTC) // 超出 max_days_to_expiry
+)
+
+type stubReader struct {
+	positions []types.Position
+	tickers   map[string]types.Ticker
+}
+
+func (r *stubReader) GetPositions(currency string, kind ...string) ([]types.Position, error) {
+	return r.positions, nil
+}
+
+func (r *stubReader) GetTicker(instrumentName string) (*types.Ticker, error) {
+	ticker, ok := r.tickers[instrumentName]
+	if !ok {
+		return nil, fmt.Errorf("unknown instrument %s", instrumentName)
+	}
+	return &ticker, nil
+}
+
+type stubCatalog struct {
+	instruments map[string]types.Instrument
+	chains      map[time.Time][]instruments.ChainEntry
+	chainCalls  int
+}
+
+func (c *stubCatalog) Get(name string) (types.Instrument, bool, error) {
+	instrument, ok := c.instruments[name]
+	return instrument, ok, nil
+}
+
+func (c *stubCatalog) Expiries(currency string) ([]time.Time, error) {
+	return []time.Time{expiryJan, expiryFeb, expiryMar, expiryJun}, nil
+}
+
+func (c *stubCatalog) Chain(currency string, expiry time.Time) ([]instruments.ChainEntry, error) {
+	c.chainCalls++
+	return c.chains[expiry], nil
+}
+
+func option(expiry time.Time, strike float64, optionType string) types.Instrument {
+	name := fmt.Sprintf("ETH-%s-%.0f-%s", strings.ToUpper(expiry.Format("2Jan06")), strike, map[string]string{"put": "P", "call": "C"}[optionType])
+	return types.Instrument{InstrumentName: name, Kind: "option", BaseCurrency: "ETH", OptionType: optionType,
+		Strike: strike, ExpirationTimestamp: expiry.UnixMilli(), ContractSize: 1}
+}
+
+// newTestPlanner 当前领口 1 月 24 日到期；2 月和 3 月的期权链隐含波动率均为 60%，远期价格 3000
+func newTestPlanner() (*Planner, *stubReader, *stubCatalog, *notifytest.Notifier) {
+	catalog := &stubCatalog{instruments: map[string]types.Instrument{}, chains: map[time.Time][]instruments.ChainEntry{}}
+	catalog.add(option(expiryJan, 2500, "put"))
+	catalog.add(option(expiryJan, 3500, "call"))
+	for _, expiry := range []time.Time{expiryFeb, expiryMar, expiryJun} {
+		for _, strike := range []float64{2400, 2600, 2800} {
+			catalog.add(option(expiry, strike, "put"))
+		}
+		for _, strike := range []float64{3200, 3400, 3600, 3800} {
+			catalog.add(option(expiry, strike, "call"))
+		}
+	}
+
+	quote := func(bid, ask, delta float64) types.Ticker {
+		return types.Ticker{BestBidPrice: bid, BestAskPrice: ask, MarkPrice: (bid + ask) / 2, IndexPrice: 3000, Greeks: &types.Greeks{Delta: delta}}
+	}
+	reader := &stubReader{
+		positions: []types.Position{
+			{InstrumentName: "ETH-24JAN25-2500-P", Kind: "option", Direction: "buy", Size: 100},
+			{InstrumentName: "ETH-24JAN25-3500-C", Kind: "option", Direction: "sell", Size: -100},
+			{InstrumentName: "ETH-PERPETUAL", Kind: "future", Direction: "sell", Size: -30000},
+		},
+		tickers: map[string]types.Ticker{
+			"ETH-24JAN25-2500-P": quote(0.0001, 0.0003, -0.01),
+			"ETH-24JAN25-3500-C": quote(0.0002, 0.0004, 0.02),
+			// 2 月：新领口净支出 0.0045，不满足零成本
+			"ETH-28FEB25-2600-P": quote(0.018, 0.019, -0.21),
+			"ETH-28FEB25-3400-C": quote(0.0145, 0.0155, 0.29),
+			// 3 月：新领口净支出 0.001，满足零成本
+			"ETH-28MAR25-2600-P": quote(0.033, 0.034, -0.25),
+			"ETH-28MAR25-3600-C": {BestBidPrice: 0.033, MarkPrice: 0.0335, IndexPrice: 3000, Greeks: &types.Greeks{Delta: 0.28}},
+		},
+	}
+
+	config := types.RollConfig{
+		Currency:              "eth",
+		PutDeltaTarget:        -0.25,
+		CallDeltaTarget:       0.25,
+		ZeroCostTolerance:     0.002,
+		MinDaysToExpiry:       30,
+		MaxDaysToExpiry:       120,
+		RollWindowDays:        7,
+		MaxProposals:          3,
+		NotifyIntervalSeconds: 3600,
+	}
+	notifier := &notifytest.Notifier{}
+	planner := NewPlanner(config, reader, catalog, notifier, zap.NewNop())
+	planner.now = func() time.Time { return now }
+	return planner, reader, catalog, notifier
+}
+
+func (c *stubCatalog) add(instrument types.Instrument) {
+	c.instruments[instrument.InstrumentName] = instrument
+	expiry := instruments.Expiry(instrument)
+	book := &types.BookSummary{InstrumentName: instrument.InstrumentName, MarkIV: 60, UnderlyingPrice: 3000}
+	c.chains[expiry] = append(c.chains[expiry], instruments.ChainEntry{Instrument: instrument, Book: book})
+}
+
+func TestPlanProposals(t *testing.T) {
+	planner, _, _, _ := newTestPlanner()
+
+	plan, err := planner.Plan()
+	require.NoError(t, err)
+	require.NotNil(t, plan.Current)
+	assert.True(t, plan.DryRun)
+	assert.True(t, plan.InWindow)
+	assert.Equal(t, 4.0, plan.Current.DaysToExpiry)
+	assert.Equal(t, -100.0, plan.Current.Call.Size)
+	require.Len(t, plan.Proposals, 2)
+
+	// 零成本方案排在前面
+	march := plan.Proposals[0]
+	assert.Equal(t, expiryMar, march.Expiry)
+	assert.Equal(t, "ETH-28MAR25-2600-P", march.Put.Instrument)
+	assert.Equal(t, "ETH-28MAR25-3600-C", march.Call.Instrument)
+	assert.True(t, march.ZeroCost)
+	assert.True(t, march.IndicativePricing) // 新看涨期权没有卖单
+	assert.InDelta(t, -0.001, march.OpenPremium, 1e-12)
+	assert.Equal(t, 100.0, march.FloorChange)
+	assert.Equal(t, 100.0, march.CapChange)
+	assert.Equal(t, -0.25, march.Put.Delta)
+	assert.InDelta(t, -0.247, march.EstimatedPutDelta, 1e-3)
+
+	february := plan.Proposals[1]
+	assert.Equal(t, "ETH-28FEB25-2600-P", february.Put.Instrument)
+	assert.Equal(t, "ETH-28FEB25-3400-C", february.Call.Instrument)
+	assert.False(t, february.ZeroCost)
+	assert.Equal(t, -100.0, february.CapChange)
+	// 平仓：卖出看跌 0.0001 - 买回看涨 0.0004；开仓：卖出看涨 0.0145 - 买入看跌 0.019
+	assert.InDelta(t, -0.03, february.ClosePremium, 1e-12)
+	assert.InDelta(t, -0.03-0.45, february.NetPremium, 1e-12)
+	assert.InDelta(t, (-0.03-0.45)*3000, february.NetPremiumUSD, 1e-9)
+	// 看涨期权标记价格 0.015 -> 0.0003
+	assert.InDelta(t, (0.015-0.0003)*100, february.MarginImpact, 1e-12)
+
+	var report bytes.Buffer
+	require.NoError(t, plan.WriteReport(&report))
+	assert.Contains(t, report.String(), "ETH-28MAR25-3600-C")
+	assert.Contains(t, report.String(), "yes (mark)")
+}
+
+func TestEvaluateOutsideWindow(t *testing.T) {
+	planner, _, catalog, notifier := newTestPlanner()
+	planner.now = func() time.Time { return expiryJan.Add(-10 * 24 * time.Hour) }
+
+	plan, err := planner.Evaluate("desk")
+	require.NoError(t, err)
+	assert.False(t, plan.InWindow)
+	assert.Empty(t, plan.Proposals)
+	assert.Zero(t, catalog.chainCalls)
+	assert.Empty(t, notifier.Notifications)
+}
+
+func TestEvaluateNotifiesOncePerInterval(t *testing.T) {
+	planner, _, catalog, notifier := newTestPlanner()
+
+	first, err := planner.Evaluate("desk")
+	require.NoError(t, err)
+	require.Len(t, notifier.Notifications, 1)
+	n := notifier.Notifications[0]
+	assert.Equal(t, "roll", n.Source)
+	assert.Equal(t, "ETH-28MAR25-2600-P", n.Fields["put"])
+	assert.Contains(t, n.Message, "no order placed")
+	chainCalls := catalog.chainCalls
+
+	// 间隔内返回上一次的计划，不重新读取期权链
+	planner.now = func() time.Time { return now.Add(30 * time.Minute) }
+	second, err := planner.Evaluate("desk")
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, chainCalls, catalog.chainCalls)
+	assert.Len(t, notifier.Notifications, 1)
+
+	planner.now = func() time.Time { return now.Add(time.Hour) }
+	_, err = planner.Evaluate("desk")
+	require.NoError(t, err)
+	assert.Len(t, notifier.Notifications, 2)
+}
+
+func TestPlanWithoutCollar(t *testing.T) {
+	planner, reader, _, _ := newTestPlanner()
+	reader.positions = reader.positions[:1] // 只有看跌期权
+
+	plan, err := planner.Plan()
+	require.NoError(t, err)
+	assert.Nil(t, plan.Current)
+	assert.Empty(t, plan.Proposals)
+
+	var report bytes.Buffer
+	require.NoError(t, plan.WriteReport(&report))
+	assert.Contains(t, report.String(), "No collar position")
+}
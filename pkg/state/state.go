@@ -0,0 +1,89 @@
+// Package state 保存退出时的运行状态和各模块的告警状态，重启后恢复告警状态，
+// 避免重启后重复发送仍在抑制间隔内的告警或丢失已发送的提醒。
+package state
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Component 需要跨重启保存告警状态的模块
+type Component interface {
+	MarshalState() ([]byte, error)
+	UnmarshalState(data []byte) error
+}
+
+// snapshot 状态文件内容
+type snapshot struct {
+	SavedAt    time.Time                  `json:"saved_at"`
+	Monitor    *types.MonitorStatus       `json:"monitor,omitempty"`
+	Components map[string]json.RawMessage `json:"components,omitempty"`
+}
+
+// Store 状态文件
+type Store struct {
+	file       string
+	previous   snapshot
+	components map[string]Component
+}
+
+// Open 读取上次保存的状态，文件不存在时为空
+func Open(file string) (*Store, error) {
+	s := &Store{file: file, components: make(map[string]Component)}
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read state %s: %w", file, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.previous); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal state %s: %w", file, err)
+		}
+	}
+	return s, nil
+}
+
+// Previous 上次退出时的运行状态，没有时为 nil
+func (s *Store) Previous() *types.MonitorStatus {
+	return s.previous.Monitor
+}
+
+// Register 注册模块，并恢复上次保存的该模块状态
+func (s *Store) Register(name string, component Component) error {
+	s.components[name] = component
+	data, ok := s.previous.Components[name]
+	if !ok {
+		return nil
+	}
+	if err := component.UnmarshalState(data); err != nil {
+		return fmt.Errorf("failed to restore %s state: %w", name, err)
+	}
+	return nil
+}
+
+// Save 原子地写入运行状态和所有已注册模块的告警状态，在监控周期全部结束后调用
+func (s *Store) Save(status types.MonitorStatus) error {
+	current := snapshot{SavedAt: time.Now().UTC(), Monitor: &status, Components: make(map[string]json.RawMessage, len(s.components))}
+	for name, component := range s.components {
+		data, err := component.MarshalState()
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s state: %w", name, err)
+		}
+		current.Components[name] = data
+	}
+
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	tmp := s.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(tmp, s.file); err != nil {
+		return fmt.Errorf("failed to replace state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counter 保存一个计数的模块
+type counter struct {
+	value string
+}
+
+func (c *counter) MarshalState() ([]byte, error) {
+	return []byte(c.value), nil
+}
+
+func (c *counter) UnmarshalState(data []byte) error {
+	c.value = string(data)
+	return nil
+}
+
+func TestSaveAndRestore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+	store, err := Open(file)
+	require.NoError(t, err)
+	assert.Nil(t, store.Previous())
+
+	hedge := &counter{}
+	require.NoError(t, store.Register("hedge", hedge))
+	assert.Empty(t, hedge.value) // 没有保存过的状态
+
+	hedge.value = `{"out_of_band":true}`
+	stoppedAt := time.Date(2024, 12, 27, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Save(types.MonitorStatus{Account: "desk", Cycles: 3, LastError: "timeout", StoppedAt: stoppedAt, Reason: "terminated"}))
+
+	reopened, err := Open(file)
+	require.NoError(t, err)
+	require.NotNil(t, reopened.Previous())
+	assert.Equal(t, 3, reopened.Previous().Cycles)
+	assert.Equal(t, "terminated", reopened.Previous().Reason)
+	assert.True(t, stoppedAt.Equal(reopened.Previous().StoppedAt))
+
+	restored := &counter{}
+	require.NoError(t, reopened.Register("hedge", restored))
+	assert.JSONEq(t, `{"out_of_band":true}`, restored.value)
+
+	// 非法的模块状态返回错误
+	restored.value = "not json"
+	assert.Error(t, reopened.Save(types.MonitorStatus{}))
+}
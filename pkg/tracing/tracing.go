@@ -0,0 +1,118 @@
+// Package tracing 配置 OpenTelemetry 链路追踪。
+//
+// 父 span 只通过 context 传递：监控周期用 StartCycle 开始根 span，返回的 context 显式传给
+// 本周期的 Deribit 请求、规则评估、各模块和指标推送；不带 context 的调用各自生成独立的 trace。
+package tracing
+
+import (
+	"context"
+	"cs-projects-eth-collar/internal/types"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// 导出方式
+const (
+	ExporterOTLPHTTP = "otlphttp" // OTLP/HTTP，发送到本地或远程 collector
+	ExporterStdout   = "stdout"   // 输出到标准输出，用于调试
+)
+
+// instrumentationName 本项目的 tracer 名称
+const instrumentationName = "cs-projects-eth-collar"
+
+// Tracer 返回本项目的 tracer，未启用追踪时为 no-op
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup 按配置设置全局 TracerProvider，返回的函数在退出前调用以发送剩余的 span
+// 未启用时保持 OpenTelemetry 默认的 no-op provider
+func Setup(config types.TracingConfig, logger *zap.Logger) (func(context.Context) error, error) {
+	if !config.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(config)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", config.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("OpenTelemetry error", zap.Error(err))
+	}))
+
+	logger.Info("Tracing enabled",
+		zap.String("exporter", config.Exporter),
+		zap.String("endpoint", config.Endpoint),
+		zap.Float64("sample_ratio", config.SampleRatio),
+	)
+	return provider.Shutdown, nil
+}
+
+func newExporter(config types.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case ExporterOTLPHTTP, "":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint), otlptracehttp.WithTimeout(10 * time.Second)}
+		if config.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(config.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(config.Headers))
+		}
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP/HTTP exporter: %w", err)
+		}
+		return exporter, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, use otlphttp or stdout", config.Exporter)
+	}
+}
+
+// StartCycle 开始一个监控周期的根 span，返回的 context 需要传给本周期内的调用
+// 返回的函数结束 span，err 不为 nil 时标记为错误
+func StartCycle(name string, attrs ...attribute.KeyValue) (context.Context, func(err error)) {
+	ctx, span := Tracer().Start(context.Background(), name, trace.WithAttributes(attrs...))
+	return ctx, func(err error) { End(span, err) }
+}
+
+// Start 在 ctx 中的 span 下开始一个子 span，ctx 不带 span 时为新的 trace
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End 结束 span，err 不为 nil 时记录错误并标记状态
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
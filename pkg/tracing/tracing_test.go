@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"cs-projects-eth-collar/internal/types"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+// useRecorder 把全局 TracerProvider 换成内存记录器，测试结束后恢复
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestStartCycleParentsSpans(t *testing.T) {
+	recorder := useRecorder(t)
+
+	// 不带周期 context 的 span 没有父 span
+	_, outside := Start(context.Background(), "outside")
+	End(outside, nil)
+
+	ctx, endCycle := StartCycle("cycle")
+	childCtx, child := Start(ctx, "child")
+	_, grandchild := Start(childCtx, "grandchild")
+	End(grandchild, nil)
+	End(child, errors.New("boom"))
+	endCycle(nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	assert.False(t, spans[0].Parent().IsValid())
+	cycle := spans[3]
+	assert.Equal(t, cycle.SpanContext().SpanID(), spans[2].Parent().SpanID())
+	assert.Equal(t, spans[2].SpanContext().SpanID(), spans[1].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+	assert.Len(t, spans[2].Events(), 1)
+	assert.Equal(t, codes.Unset, cycle.Status().Code)
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(types.TracingConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	shutdown, err = Setup(types.TracingConfig{Enabled: true, Exporter: ExporterStdout, ServiceName: "test", SampleRatio: 1}, zap.NewNop())
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(types.TracingConfig{Enabled: true, Exporter: "jaeger"}, zap.NewNop())
+	assert.ErrorContains(t, err, `unknown tracing exporter "jaeger"`)
+}
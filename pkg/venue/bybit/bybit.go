@@ -0,0 +1,362 @@
+// Package bybit 是 Bybit v5 统一账户 REST API 的 venue 适配器。
+//
+// 私有接口按 v5 规则签名：HMAC-SHA256(timestamp + api_key + recv_window + query)，
+// 数值字段以字符串返回。OKX 等统一账户 API 的结构类似，可按同样方式实现适配器。
+package bybit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/venue"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProductionURL Bybit 生产环境地址
+const ProductionURL = "https://api.bybit.com"
+
+// Client Bybit v5 客户端，实现 venue.Venue
+type Client struct {
+	apiKey     string
+	apiSecret  string
+	baseURL    string
+	recvWindow int
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewClient 创建 Bybit 客户端
+func NewClient(config types.BybitConfig) *Client {
+	baseURL := strings.TrimRight(config.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = ProductionURL
+	}
+	recvWindow := config.RecvWindowMs
+	if recvWindow <= 0 {
+		recvWindow = 5000
+	}
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Client{
+		apiKey:     config.APIKey,
+		apiSecret:  config.APISecret,
+		baseURL:    baseURL,
+		recvWindow: recvWindow,
+		httpClient: &http.Client{Timeout: timeout},
+		now:        time.Now,
+	}
+}
+
+func (c *Client) Name() string {
+	return "bybit"
+}
+
+// number Bybit 以字符串返回的数值，空字符串视为 0
+type number float64
+
+func (n *number) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q: %w", s, err)
+	}
+	*n = number(v)
+	return nil
+}
+
+// APIError Bybit 返回的业务错误
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("bybit API error: %s (code: %d)", e.Message, e.Code)
+}
+
+// AccountSummary 读取统一账户的钱包余额，账户级别的值已由 Bybit 换算为 USD
+func (c *Client) AccountSummary() (*venue.Account, error) {
+	var result struct {
+		List []struct {
+			AccountType            string `json:"accountType"`
+			TotalEquity            number `json:"totalEquity"`
+			TotalInitialMargin     number `json:"totalInitialMargin"`
+			TotalMaintenanceMargin number `json:"totalMaintenanceMargin"`
+			Coin                   []struct {
+				Coin     string `json:"coin"`
+				Equity   number `json:"equity"`
+				USDValue number `json:"usdValue"`
+			} `json:"coin"`
+		} `json:"list"`
+	}
+	if err := c.get("/v5/account/wallet-balance", url.Values{"accountType": {"UNIFIED"}}, true, &result); err != nil {
+		return nil, err
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("unified account not found in wallet balance")
+	}
+
+	wallet := result.List[0]
+	account := &venue.Account{
+		Venue:                c.Name(),
+		EquityUSD:            float64(wallet.TotalEquity),
+		InitialMarginUSD:     float64(wallet.TotalInitialMargin),
+		MaintenanceMarginUSD: float64(wallet.TotalMaintenanceMargin),
+	}
+	for _, coin := range wallet.Coin {
+		account.Balances = append(account.Balances, venue.Balance{
+			Currency:  strings.ToUpper(coin.Coin),
+			Equity:    float64(coin.Equity),
+			EquityUSD: float64(coin.USDValue),
+		})
+	}
+	return account, nil
+}
+
+// Positions 读取期权和 USDT 永续/交割合约仓位
+// 线性合约的数量以标的币种计，Delta 即有符号的数量；期权使用 Bybit 返回的仓位 Delta
+func (c *Client) Positions(currency string) ([]venue.Position, error) {
+	currency = strings.ToUpper(currency)
+	queries := []struct {
+		category string
+		query    url.Values
+	}{
+		{"option", url.Values{"category": {"option"}, "baseCoin": {currency}}},
+		{"linear", url.Values{"category": {"linear"}, "settleCoin": {"USDT"}}},
+	}
+
+	var positions []venue.Position
+	for _, q := range queries {
+		var list []struct {
+			Symbol    string `json:"symbol"`
+			Side      string `json:"side"` // Buy / Sell / 空字符串表示无仓位
+			Size      number `json:"size"`
+			MarkPrice number `json:"markPrice"`
+			Delta     number `json:"delta"`
+		}
+		if err := c.getPaged("/v5/position/list", q.query, true, &list); err != nil {
+			return nil, err
+		}
+
+		for _, item := range list {
+			if item.Side == "" || item.Size == 0 || symbolBaseCoin(item.Symbol) != currency {
+				continue
+			}
+			size := float64(item.Size)
+			if item.Side == "Sell" {
+				size = -size
+			}
+			position := venue.Position{
+				Venue:      c.Name(),
+				Instrument: item.Symbol,
+				Kind:       venue.KindFuture,
+				Currency:   currency,
+				Size:       size,
+				Delta:      size,
+				MarkPrice:  float64(item.MarkPrice),
+			}
+			if q.category == "option" {
+				position.Kind = venue.KindOption
+				position.Delta = float64(item.Delta)
+			}
+			positions = append(positions, position)
+		}
+	}
+	return positions, nil
+}
+
+// IndexPrice 使用 USDT 永续合约行情中的指数价格
+func (c *Client) IndexPrice(currency string) (float64, error) {
+	symbol := strings.ToUpper(currency) + "USDT"
+	var result struct {
+		List []struct {
+			Symbol     string `json:"symbol"`
+			IndexPrice number `json:"indexPrice"`
+		} `json:"list"`
+	}
+	if err := c.get("/v5/market/tickers", url.Values{"category": {"linear"}, "symbol": {symbol}}, false, &result); err != nil {
+		return 0, err
+	}
+	for _, ticker := range result.List {
+		if ticker.Symbol == symbol {
+			return float64(ticker.IndexPrice), nil
+		}
+	}
+	return 0, fmt.Errorf("ticker %s not found", symbol)
+}
+
+// Instruments 返回期权和线性合约，kind 为 venue.KindOption 或 venue.KindFuture 时只返回对应类型
+func (c *Client) Instruments(currency, kind string) ([]venue.Instrument, error) {
+	currency = strings.ToUpper(currency)
+	var categories []string
+	switch kind {
+	case "":
+		categories = []string{"option", "linear"}
+	case venue.KindOption:
+		categories = []string{"option"}
+	case venue.KindFuture:
+		categories = []string{"linear"}
+	default:
+		return nil, fmt.Errorf("unsupported instrument kind %q", kind)
+	}
+
+	var instruments []venue.Instrument
+	for _, category := range categories {
+		var list []struct {
+			Symbol       string `json:"symbol"`
+			Status       string `json:"status"`
+			BaseCoin     string `json:"baseCoin"`
+			OptionsType  string `json:"optionsType"` // Call / Put
+			DeliveryTime number `json:"deliveryTime"`
+			PriceFilter  struct {
+				TickSize number `json:"tickSize"`
+			} `json:"priceFilter"`
+		}
+		query := url.Values{"category": {category}, "baseCoin": {currency}}
+		if err := c.getPaged("/v5/market/instruments-info", query, false, &list); err != nil {
+			return nil, err
+		}
+
+		for _, item := range list {
+			if item.Status != "" && item.Status != "Trading" {
+				continue
+			}
+			instrument := venue.Instrument{
+				Venue:        c.Name(),
+				Name:         item.Symbol,
+				Kind:         venue.KindFuture,
+				Currency:     strings.ToUpper(item.BaseCoin),
+				ContractSize: 1,
+				TickSize:     float64(item.PriceFilter.TickSize),
+			}
+			if item.DeliveryTime > 0 {
+				instrument.Expiry = time.UnixMilli(int64(item.DeliveryTime)).UTC()
+			}
+			if category == "option" {
+				instrument.Kind = venue.KindOption
+				instrument.OptionType = strings.ToLower(item.OptionsType)
+				instrument.Strike = optionStrike(item.Symbol)
+			}
+			instruments = append(instruments, instrument)
+		}
+	}
+	return instruments, nil
+}
+
+// symbolBaseCoin 从合约名解析标的币种，按完整币种比较，避免 ETH 匹配到 ETHFI、ETHW 等
+// 期权和 USDC 交割合约（ETH-27DEC24-2500-P、ETH-27DEC24）取第一个 "-" 之前的部分，
+// USDT 永续/交割合约（ETHUSDT、ETHUSDT-27DEC24）和 USDC 永续（ETHPERP）再去掉结算后缀
+func symbolBaseCoin(symbol string) string {
+	base, _, _ := strings.Cut(strings.ToUpper(symbol), "-")
+	for _, suffix := range []string{"USDT", "USDC", "PERP"} {
+		if coin := strings.TrimSuffix(base, suffix); coin != base && coin != "" {
+			return coin
+		}
+	}
+	return base
+}
+
+// optionStrike 从期权合约名（如 ETH-27DEC24-2500-P）解析行权价
+func optionStrike(symbol string) float64 {
+	parts := strings.Split(symbol, "-")
+	if len(parts) < 4 {
+		return 0
+	}
+	strike, _ := strconv.ParseFloat(parts[2], 64)
+	return strike
+}
+
+// getPaged 按 nextPageCursor 翻页读取 result.list
+func (c *Client) getPaged(path string, query url.Values, private bool, list interface{}) error {
+	var items []json.RawMessage
+	query.Set("limit", "200")
+	for {
+		var page struct {
+			List           []json.RawMessage `json:"list"`
+			NextPageCursor string            `json:"nextPageCursor"`
+		}
+		if err := c.get(path, query, private, &page); err != nil {
+			return err
+		}
+		items = append(items, page.List...)
+		if page.NextPageCursor == "" {
+			break
+		}
+		query.Set("cursor", page.NextPageCursor)
+	}
+
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, list)
+}
+
+// get 发送 GET 请求并解析 result，private 为 true 时签名
+func (c *Client) get(path string, query url.Values, private bool, result interface{}) error {
+	rawQuery := query.Encode()
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path+"?"+rawQuery, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if private {
+		timestamp := strconv.FormatInt(c.now().UnixMilli(), 10)
+		recvWindow := strconv.Itoa(c.recvWindow)
+		req.Header.Set("X-BAPI-API-KEY", c.apiKey)
+		req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+		req.Header.Set("X-BAPI-RECV-WINDOW", recvWindow)
+		req.Header.Set("X-BAPI-SIGN-TYPE", "2")
+		req.Header.Set("X-BAPI-SIGN", Sign(c.apiSecret, timestamp+c.apiKey+recvWindow+rawQuery))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if response.RetCode != 0 {
+		return &APIError{Code: response.RetCode, Message: response.RetMsg}
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("failed to unmarshal result: %w", err)
+	}
+	return nil
+}
+
+// Sign 计算 v5 签名（十六进制 HMAC-SHA256）
+func Sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
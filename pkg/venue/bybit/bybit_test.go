@@ -0,0 +1,165 @@
+package bybit
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/venue"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKey    = "bybit-key"
+	testSecret = "bybit-secret"
+)
+
+// stubResponses 按路径和 category（或 cursor）返回的 result
+var stubResponses = map[string]string{
+	"/v5/account/wallet-balance": `{"list":[{"accountType":"UNIFIED","totalEquity":"850000.5","totalInitialMargin":"300000","totalMaintenanceMargin":"170000.1","coin":[
+		{"coin":"ETH","equity":"120.5","usdValue":"361500","walletBalance":"120"},
+		{"coin":"USDT","equity":"488500.5","usdValue":"488500.5","walletBalance":"488500.5"}]}]}`,
+	"/v5/position/list?option": `{"category":"option","nextPageCursor":"page2","list":[
+		{"symbol":"ETH-27DEC24-2500-P","side":"Buy","size":"100","markPrice":"85.5","delta":"-25.5"}]}`,
+	"/v5/position/list?option&page2": `{"category":"option","nextPageCursor":"","list":[
+		{"symbol":"ETH-27DEC24-4000-C","side":"Sell","size":"100","markPrice":"60","delta":"-18"},
+		{"symbol":"BTC-27DEC24-80000-C","side":"Buy","size":"1","markPrice":"900","delta":"0.4"}]}`,
+	"/v5/position/list?linear": `{"category":"linear","nextPageCursor":"","list":[
+		{"symbol":"ETHUSDT","side":"Sell","size":"30.5","markPrice":"3000"},
+		{"symbol":"ETHFIUSDT","side":"Buy","size":"5000","markPrice":"1.5"},
+		{"symbol":"ETHWUSDT","side":"Buy","size":"200","markPrice":"2.1"},
+		{"symbol":"ETHUSDT-27DEC24","side":"Buy","size":"2","markPrice":"3010"},
+		{"symbol":"BTCUSDT","side":"Buy","size":"0.1","markPrice":"60000"},
+		{"symbol":"SOLUSDT","side":"","size":"0","markPrice":"150"}]}`,
+	"/v5/market/tickers": `{"category":"linear","list":[{"symbol":"ETHUSDT","indexPrice":"3000.25","markPrice":"3001"}]}`,
+	"/v5/market/instruments-info?option": `{"category":"option","nextPageCursor":"","list":[
+		{"symbol":"ETH-27DEC24-2500-P","status":"Trading","baseCoin":"ETH","optionsType":"Put","deliveryTime":"1735286400000","priceFilter":{"tickSize":"0.1"}},
+		{"symbol":"ETH-20DEC24-2500-P","status":"Delivering","baseCoin":"ETH","optionsType":"Put","deliveryTime":"1734681600000","priceFilter":{"tickSize":"0.1"}}]}`,
+	"/v5/market/instruments-info?linear": `{"category":"linear","nextPageCursor":"","list":[
+		{"symbol":"ETHUSDT","status":"Trading","baseCoin":"ETH","deliveryTime":"0","priceFilter":{"tickSize":"0.01"}}]}`,
+}
+
+// newStubServer 模拟 Bybit v5 API，私有接口校验签名
+func newStubServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		private := r.URL.Path == "/v5/account/wallet-balance" || r.URL.Path == "/v5/position/list"
+		if private {
+			payload := r.Header.Get("X-BAPI-TIMESTAMP") + r.Header.Get("X-BAPI-API-KEY") + r.Header.Get("X-BAPI-RECV-WINDOW") + r.URL.RawQuery
+			if r.Header.Get("X-BAPI-API-KEY") != testKey || r.Header.Get("X-BAPI-SIGN") != Sign(testSecret, payload) {
+				fmt.Fprint(w, `{"retCode":10004,"retMsg":"error sign!","result":{}}`)
+				return
+			}
+		}
+
+		key := r.URL.Path
+		if category := query.Get("category"); category != "" && r.URL.Path != "/v5/market/tickers" {
+			key += "?" + category
+		}
+		if cursor := query.Get("cursor"); cursor != "" {
+			key += "&" + cursor
+		}
+		result, ok := stubResponses[key]
+		if !ok {
+			fmt.Fprint(w, `{"retCode":10001,"retMsg":"params error","result":{}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"retCode":0,"retMsg":"OK","result":%s,"time":%d}`, result, time.Now().UnixMilli())
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestClient(t *testing.T) *Client {
+	srv := newStubServer(t)
+	return NewClient(types.BybitConfig{APIKey: testKey, APISecret: testSecret, BaseURL: srv.URL})
+}
+
+func TestAccountSummary(t *testing.T) {
+	client := newTestClient(t)
+
+	account, err := client.AccountSummary()
+	require.NoError(t, err)
+	assert.Equal(t, "bybit", account.Venue)
+	assert.Equal(t, 850000.5, account.EquityUSD)
+	assert.Equal(t, 170000.1, account.MaintenanceMarginUSD)
+	assert.Equal(t, 120.5, account.Balance("ETH").Equity)
+	assert.Equal(t, 361500.0, account.Balance("ETH").EquityUSD)
+}
+
+func TestPositions(t *testing.T) {
+	client := newTestClient(t)
+
+	positions, err := client.Positions("eth")
+	require.NoError(t, err)
+	require.Len(t, positions, 4) // ETHFIUSDT、ETHWUSDT 不是 ETH 的合约
+
+	// 期权翻页读取，使用 Bybit 返回的 Delta
+	assert.Equal(t, venue.Position{Venue: "bybit", Instrument: "ETH-27DEC24-2500-P", Kind: venue.KindOption, Currency: "ETH",
+		Size: 100, Delta: -25.5, MarkPrice: 85.5}, positions[0])
+	assert.Equal(t, -100.0, positions[1].Size)
+	// 线性合约的 Delta 为有符号的数量
+	assert.Equal(t, venue.KindFuture, positions[2].Kind)
+	assert.Equal(t, -30.5, positions[2].Delta)
+	assert.Equal(t, "ETHUSDT-27DEC24", positions[3].Instrument)
+}
+
+func TestSymbolBaseCoin(t *testing.T) {
+	for symbol, coin := range map[string]string{
+		"ETH-27DEC24-2500-P": "ETH",
+		"ETH-27DEC24":        "ETH",
+		"ETHUSDT":            "ETH",
+		"ETHUSDT-27DEC24":    "ETH",
+		"ETHPERP":            "ETH",
+		"ETHFIUSDT":          "ETHFI",
+		"ETHWUSDT":           "ETHW",
+		"USDCUSDT":           "USDC",
+	} {
+		assert.Equal(t, coin, symbolBaseCoin(symbol), symbol)
+	}
+}
+
+func TestIndexPrice(t *testing.T) {
+	client := newTestClient(t)
+
+	price, err := client.IndexPrice("ETH")
+	require.NoError(t, err)
+	assert.Equal(t, 3000.25, price)
+}
+
+func TestInstruments(t *testing.T) {
+	client := newTestClient(t)
+
+	instruments, err := client.Instruments("ETH", "")
+	require.NoError(t, err)
+	require.Len(t, instruments, 2)
+
+	put := instruments[0]
+	assert.Equal(t, venue.KindOption, put.Kind)
+	assert.Equal(t, "put", put.OptionType)
+	assert.Equal(t, 2500.0, put.Strike)
+	assert.Equal(t, time.Date(2024, 12, 27, 8, 0, 0, 0, time.UTC), put.Expiry)
+
+	perpetual := instruments[1]
+	assert.Equal(t, venue.KindFuture, perpetual.Kind)
+	assert.True(t, perpetual.Expiry.IsZero())
+	assert.Equal(t, 0.01, perpetual.TickSize)
+}
+
+func TestInvalidSignature(t *testing.T) {
+	srv := newStubServer(t)
+	client := NewClient(types.BybitConfig{APIKey: testKey, APISecret: "wrong", BaseURL: srv.URL})
+
+	_, err := client.AccountSummary()
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 10004, apiErr.Code)
+}
+
+func TestClientImplementsVenue(t *testing.T) {
+	var _ venue.Venue = NewClient(types.BybitConfig{})
+}
@@ -0,0 +1,127 @@
+package venue
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/account"
+	"cs-projects-eth-collar/pkg/instruments"
+	"fmt"
+	"strings"
+)
+
+// DeribitClient Deribit 适配器使用的客户端方法，*deribit.Client 满足此接口
+type DeribitClient interface {
+	GetAccountSummaries(extended ...bool) (*types.AccountSummaries, error)
+	GetPositions(currency string, kind ...string) ([]types.Position, error)
+	GetIndexPrice(currency string) (float64, error)
+	GetInstruments(currency, kind string, expired bool) ([]types.Instrument, error)
+}
+
+// Deribit 基于 deribit.Client 的适配器
+type Deribit struct {
+	client DeribitClient
+}
+
+// NewDeribit 创建 Deribit 适配器
+func NewDeribit(client DeribitClient) *Deribit {
+	return &Deribit{client: client}
+}
+
+func (d *Deribit) Name() string {
+	return "deribit"
+}
+
+// AccountSummary 按保证金模式汇总账户权益，分币种保证金需要各币种的指数价格
+func (d *Deribit) AccountSummary() (*Account, error) {
+	summaries, err := d.client.GetAccountSummaries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account summaries: %w", err)
+	}
+
+	prices := make(map[string]float64)
+	for _, summary := range summaries.Summaries {
+		currency := strings.ToUpper(summary.Currency)
+		if !account.PriceNeeded(summary) {
+			continue
+		}
+		price, err := d.client.GetIndexPrice(strings.ToLower(currency))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s index price: %w", currency, err)
+		}
+		prices[currency] = price
+	}
+
+	equity := account.Aggregate(summaries.Summaries, prices)
+	result := &Account{
+		Venue:                d.Name(),
+		EquityUSD:            equity.EquityUSD,
+		InitialMarginUSD:     equity.InitialMarginUSD,
+		MaintenanceMarginUSD: equity.MaintenanceMarginUSD,
+	}
+	for _, breakdown := range equity.Currencies {
+		result.Balances = append(result.Balances, Balance{
+			Currency:  breakdown.Currency,
+			Equity:    breakdown.Equity,
+			EquityUSD: breakdown.EquityUSD,
+		})
+	}
+	return result, nil
+}
+
+// Positions 返回期货和期权仓位，反向期货的数量按 size_currency 换算为币数量
+func (d *Deribit) Positions(currency string) ([]Position, error) {
+	positions, err := d.client.GetPositions(currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	var result []Position
+	for _, position := range positions {
+		if position.Kind != KindFuture && position.Kind != KindOption {
+			continue
+		}
+		size := position.Size
+		if position.SizeCurrency != 0 {
+			size = position.SizeCurrency
+		}
+		result = append(result, Position{
+			Venue:      d.Name(),
+			Instrument: position.InstrumentName,
+			Kind:       position.Kind,
+			Currency:   strings.ToUpper(currency),
+			Size:       size,
+			Delta:      position.Delta,
+			MarkPrice:  position.MarkPrice,
+		})
+	}
+	return result, nil
+}
+
+func (d *Deribit) IndexPrice(currency string) (float64, error) {
+	return d.client.GetIndexPrice(strings.ToLower(currency))
+}
+
+func (d *Deribit) Instruments(currency, kind string) ([]Instrument, error) {
+	list, err := d.client.GetInstruments(strings.ToUpper(currency), kind, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instruments: %w", err)
+	}
+
+	var result []Instrument
+	for _, instrument := range list {
+		if instrument.Kind != KindFuture && instrument.Kind != KindOption {
+			continue
+		}
+		result = append(result, Instrument{
+			Venue:        d.Name(),
+			Name:         instrument.InstrumentName,
+			Kind:         instrument.Kind,
+			Currency:     strings.ToUpper(instrument.BaseCurrency),
+			OptionType:   instrument.OptionType,
+			Strike:       instrument.Strike,
+			Expiry:       instruments.Expiry(instrument),
+			ContractSize: instrument.ContractSize,
+			TickSize:     instrument.TickSize,
+		})
+	}
+	return result, nil
+}
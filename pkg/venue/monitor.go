@@ -0,0 +1,162 @@
+package venue
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// VenueExposure 单个交易所的敞口和保证金余量
+type VenueExposure struct {
+	Venue                string  `json:"venue"`
+	Collateral           float64 `json:"collateral"`        // 持有的标的币种权益（币）
+	DerivativesDelta     float64 `json:"derivatives_delta"` // 期货和期权的 Delta（币）
+	NetDelta             float64 `json:"net_delta"`         // 抵押品 + 衍生品的总敞口（币）
+	EquityUSD            float64 `json:"equity_usd"`
+	MaintenanceMarginUSD float64 `json:"maintenance_margin_usd"`
+	HeadroomUSD          float64 `json:"headroom_usd"` // 权益 - 维持保证金
+	MMRatio              float64 `json:"mm_ratio"`
+	Error                string  `json:"error,omitempty"` // 读取失败时的错误，此时不计入合计
+}
+
+// Exposure 跨交易所汇总结果
+type Exposure struct {
+	Currency             string          `json:"currency"`
+	IndexPrice           float64         `json:"index_price"` // 第一个成功返回的交易所指数价格
+	Venues               []VenueExposure `json:"venues"`
+	NetDelta             float64         `json:"net_delta"`
+	NetDeltaUSD          float64         `json:"net_delta_usd"`
+	EquityUSD            float64         `json:"equity_usd"`
+	MaintenanceMarginUSD float64         `json:"maintenance_margin_usd"`
+	HeadroomUSD          float64         `json:"headroom_usd"`
+	MMRatio              float64         `json:"mm_ratio"`
+}
+
+// Metrics 转换为每个交易所和合计的指标值，读取失败的交易所 Up 为 false
+func (e *Exposure) Metrics() ([]types.VenueMetrics, types.VenueMetrics) {
+	venues := make([]types.VenueMetrics, 0, len(e.Venues))
+	var derivativesDelta float64
+	for _, v := range e.Venues {
+		derivativesDelta += v.DerivativesDelta
+		venues = append(venues, types.VenueMetrics{
+			Venue:                v.Venue,
+			Up:                   v.Error == "",
+			NetDelta:             v.NetDelta,
+			DerivativesDelta:     v.DerivativesDelta,
+			EquityUSD:            v.EquityUSD,
+			MaintenanceMarginUSD: v.MaintenanceMarginUSD,
+			HeadroomUSD:          v.HeadroomUSD,
+		})
+	}
+	total := types.VenueMetrics{
+		Up:                   true,
+		NetDelta:             e.NetDelta,
+		DerivativesDelta:     derivativesDelta,
+		EquityUSD:            e.EquityUSD,
+		MaintenanceMarginUSD: e.MaintenanceMarginUSD,
+		HeadroomUSD:          e.HeadroomUSD,
+	}
+	return venues, total
+}
+
+// ExposureSink 接收跨交易所汇总指标，*metrics.Metrics 满足此接口
+type ExposureSink interface {
+	UpdateVenueMetrics(account, currency string, venues []types.VenueMetrics, total types.VenueMetrics)
+}
+
+// Monitor 汇总多个交易所的同一币种敞口和保证金余量
+type Monitor struct {
+	currency string
+	venues   []Venue
+	metrics  ExposureSink
+	logger   *zap.Logger
+}
+
+// NewMonitor 创建跨交易所监控，currency 为汇总敞口的币种（如 "ETH"），metrics 可为 nil
+func NewMonitor(currency string, venues []Venue, metrics ExposureSink, logger *zap.Logger) *Monitor {
+	return &Monitor{
+		currency: strings.ToUpper(currency),
+		venues:   venues,
+		metrics:  metrics,
+		logger:   logger,
+	}
+}
+
+// Evaluate 读取各交易所的数据并汇总；单个交易所失败只记录错误，全部失败时返回错误
+func (m *Monitor) Evaluate(account string) (*Exposure, error) {
+	exposure := &Exposure{Currency: m.currency}
+
+	succeeded := 0
+	for _, v := range m.venues {
+		venueExposure, price, err := m.evaluateVenue(v)
+		if err != nil {
+			m.logger.Error("Failed to read venue", zap.String("venue", v.Name()), zap.Error(err))
+			exposure.Venues = append(exposure.Venues, VenueExposure{Venue: v.Name(), Error: err.Error()})
+			continue
+		}
+		succeeded++
+		if exposure.IndexPrice == 0 {
+			exposure.IndexPrice = price
+		}
+		exposure.Venues = append(exposure.Venues, *venueExposure)
+		exposure.NetDelta += venueExposure.NetDelta
+		exposure.EquityUSD += venueExposure.EquityUSD
+		exposure.MaintenanceMarginUSD += venueExposure.MaintenanceMarginUSD
+	}
+	if succeeded == 0 && len(m.venues) > 0 {
+		return exposure, fmt.Errorf("all %d venues failed", len(m.venues))
+	}
+
+	exposure.NetDeltaUSD = exposure.NetDelta * exposure.IndexPrice
+	exposure.HeadroomUSD = exposure.EquityUSD - exposure.MaintenanceMarginUSD
+	if exposure.EquityUSD != 0 {
+		exposure.MMRatio = exposure.MaintenanceMarginUSD / exposure.EquityUSD
+	}
+
+	m.logger.Info("Cross-venue exposure",
+		zap.String("currency", m.currency),
+		zap.Float64("net_delta", exposure.NetDelta),
+		zap.Float64("net_delta_usd", exposure.NetDeltaUSD),
+		zap.Float64("equity_usd", exposure.EquityUSD),
+		zap.Float64("headroom_usd", exposure.HeadroomUSD),
+		zap.Float64("mm_ratio", exposure.MMRatio),
+	)
+	if m.metrics != nil {
+		venues, total := exposure.Metrics()
+		m.metrics.UpdateVenueMetrics(account, exposure.Currency, venues, total)
+	}
+	return exposure, nil
+}
+
+func (m *Monitor) evaluateVenue(v Venue) (*VenueExposure, float64, error) {
+	account, err := v.AccountSummary()
+	if err != nil {
+		return nil, 0, err
+	}
+	positions, err := v.Positions(m.currency)
+	if err != nil {
+		return nil, 0, err
+	}
+	price, err := v.IndexPrice(m.currency)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := &VenueExposure{
+		Venue:                v.Name(),
+		Collateral:           account.Balance(m.currency).Equity,
+		EquityUSD:            account.EquityUSD,
+		MaintenanceMarginUSD: account.MaintenanceMarginUSD,
+		HeadroomUSD:          account.EquityUSD - account.MaintenanceMarginUSD,
+	}
+	for _, position := range positions {
+		result.DerivativesDelta += position.Delta
+	}
+	result.NetDelta = result.Collateral + result.DerivativesDelta
+	if account.EquityUSD != 0 {
+		result.MMRatio = account.MaintenanceMarginUSD / account.EquityUSD
+	}
+	return result, price, nil
+}
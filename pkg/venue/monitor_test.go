@@ -0,0 +1,136 @@
+package venue
+
+import (
+	"cs-projects-eth-collar/internal/types"
+	"cs-projects-eth-collar/pkg/deribit"
+	"cs-projects-eth-collar/pkg/deribit/fakederibit"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubVenue 返回固定数据的交易所
+type stubVenue struct {
+	name      string
+	account   *Account
+	positions []Position
+	price     float64
+	err       error
+}
+
+func (v *stubVenue) Name() string { return v.name }
+
+func (v *stubVenue) AccountSummary() (*Account, error) { return v.account, v.err }
+
+func (v *stubVenue) Positions(currency string) ([]Position, error) { return v.positions, v.err }
+
+func (v *stubVenue) IndexPrice(currency string) (float64, error) { return v.price, v.err }
+
+func (v *stubVenue) Instruments(currency, kind string) ([]Instrument, error) { return nil, v.err }
+
+type recordingSink struct {
+	currency string
+	venues   []types.VenueMetrics
+	total    types.VenueMetrics
+}
+
+func (r *recordingSink) UpdateVenueMetrics(account, currency string, venues []types.VenueMetrics, total types.VenueMetrics) {
+	r.currency, r.venues, r.total = currency, venues, total
+}
+
+func TestMonitorAggregatesVenues(t *testing.T) {
+	venues := []Venue{
+		&stubVenue{
+			name:      "deribit",
+			account:   &Account{EquityUSD: 1000000, MaintenanceMarginUSD: 300000, Balances: []Balance{{Currency: "ETH", Equity: 250}}},
+			positions: []Position{{Delta: -100}, {Delta: -20}},
+			price:     2000,
+		},
+		&stubVenue{
+			name:      "bybit",
+			account:   &Account{EquityUSD: 500000, MaintenanceMarginUSD: 100000, Balances: []Balance{{Currency: "ETH", Equity: 50}}},
+			positions: []Position{{Delta: -30}},
+			price:     2001,
+		},
+		&stubVenue{name: "okx", err: errors.New("connection refused")},
+	}
+	sink := &recordingSink{}
+
+	exposure, err := NewMonitor("eth", venues, sink, zap.NewNop()).Evaluate("desk")
+	require.NoError(t, err)
+
+	require.Len(t, exposure.Venues, 3)
+	assert.Equal(t, 130.0, exposure.Venues[0].NetDelta)
+	assert.Equal(t, 700000.0, exposure.Venues[0].HeadroomUSD)
+	assert.Equal(t, 20.0, exposure.Venues[1].NetDelta)
+	assert.Equal(t, "connection refused", exposure.Venues[2].Error)
+
+	assert.Equal(t, "ETH", exposure.Currency)
+	assert.Equal(t, 150.0, exposure.NetDelta)
+	assert.Equal(t, 150.0*2000, exposure.NetDeltaUSD)
+	assert.Equal(t, 1100000.0, exposure.HeadroomUSD)
+	assert.InDelta(t, 400000.0/1500000.0, exposure.MMRatio, 1e-12)
+
+	// 读取失败的交易所只标记不可用，合计的衍生品 Delta 为各交易所之和
+	assert.Equal(t, "ETH", sink.currency)
+	require.Len(t, sink.venues, 3)
+	assert.True(t, sink.venues[0].Up)
+	assert.False(t, sink.venues[2].Up)
+	assert.Equal(t, -150.0, sink.total.DerivativesDelta)
+	assert.Equal(t, 150.0, sink.total.NetDelta)
+}
+
+func TestMonitorAllVenuesFailed(t *testing.T) {
+	venues := []Venue{&stubVenue{name: "deribit", err: errors.New("timeout")}}
+
+	_, err := NewMonitor("ETH", venues, nil, zap.NewNop()).Evaluate("desk")
+	assert.Error(t, err)
+}
+
+func TestDeribitAdapter(t *testing.T) {
+	srv := fakederibit.NewServer()
+	defer srv.Close()
+	expiry := time.Now().Add(48 * time.Hour).Truncate(time.Millisecond).UTC()
+	srv.SetState(fakederibit.State{
+		Summaries: types.AccountSummaries{Summaries: []types.CurrencySummary{
+			{Currency: "ETH", Equity: 300, MaintenanceMargin: 60, MarginModel: "segregated_sm"},
+			{Currency: "USDC", Equity: 100000, MarginModel: "segregated_sm"},
+		}},
+		Positions: []types.Position{
+			{InstrumentName: "ETH-PERPETUAL", Kind: "future", Size: -30000, SizeCurrency: -10, Delta: -10},
+			{InstrumentName: "ETH-27DEC24-2500-P", Kind: "option", Size: 100, Delta: -25},
+		},
+		IndexPrices: map[string]float64{"eth_usd": 2000},
+		Instruments: []types.Instrument{
+			{InstrumentName: "ETH-PERPETUAL", Kind: "future", BaseCurrency: "ETH", ExpirationTimestamp: 32503708800000, ContractSize: 1, TickSize: 0.05},
+			{InstrumentName: "ETH-TEST-2500-P", Kind: "option", BaseCurrency: "ETH", OptionType: "put", Strike: 2500,
+				ExpirationTimestamp: expiry.UnixMilli(), ContractSize: 1, TickSize: 0.0005},
+		},
+	})
+	client, err := deribit.NewClient(types.DeribitConfig{BaseURL: srv.URL(), APIKey: srv.ClientID, APISecret: srv.ClientSecret})
+	require.NoError(t, err)
+	adapter := NewDeribit(client)
+
+	account, err := adapter.AccountSummary()
+	require.NoError(t, err)
+	assert.Equal(t, 700000.0, account.EquityUSD)
+	assert.Equal(t, 120000.0, account.MaintenanceMarginUSD)
+	assert.Equal(t, 300.0, account.Balance("ETH").Equity)
+
+	positions, err := adapter.Positions("ETH")
+	require.NoError(t, err)
+	require.Len(t, positions, 2)
+	assert.Equal(t, -10.0, positions[0].Size) // 反向期货按币数量
+	assert.Equal(t, 100.0, positions[1].Size)
+
+	instruments, err := adapter.Instruments("ETH", "")
+	require.NoError(t, err)
+	require.Len(t, instruments, 2)
+	assert.True(t, instruments[0].Expiry.IsZero())
+	assert.Equal(t, expiry, instruments[1].Expiry)
+	assert.Equal(t, 2500.0, instruments[1].Strike)
+}
@@ -0,0 +1,79 @@
+// Package venue 把不同交易所的账户、仓位、指数价格和合约信息统一为与交易所无关的结构。
+//
+// 每个交易所实现一个 Venue 适配器：Deribit 适配器包装现有的 deribit.Client，
+// bybit 子包对接 Bybit v5 统一账户（OKX 等统一账户 API 结构类似）。
+// 跨交易所的 ETH 敞口和保证金余量由 Monitor 汇总。
+package venue
+
+import "time"
+
+// 合约类型
+const (
+	KindFuture = "future" // 期货（含永续）
+	KindOption = "option"
+	KindSpot   = "spot"
+)
+
+// Venue 交易所适配器
+type Venue interface {
+	// Name 交易所名称，用作指标和日志的标签
+	Name() string
+	// AccountSummary 返回账户级别的权益和保证金（USD）
+	AccountSummary() (*Account, error)
+	// Positions 返回指定币种（如 "ETH"）的期货和期权仓位
+	Positions(currency string) ([]Position, error)
+	// IndexPrice 返回指定币种的 USD 指数价格
+	IndexPrice(currency string) (float64, error)
+	// Instruments 返回指定币种和类型的未到期合约，kind 为空时返回期货和期权
+	Instruments(currency, kind string) ([]Instrument, error)
+}
+
+// Account 账户级别的权益和保证金
+type Account struct {
+	Venue                string    `json:"venue"`
+	EquityUSD            float64   `json:"equity_usd"`
+	InitialMarginUSD     float64   `json:"initial_margin_usd"`
+	MaintenanceMarginUSD float64   `json:"maintenance_margin_usd"`
+	Balances             []Balance `json:"balances"`
+}
+
+// Balance 单个币种的权益
+type Balance struct {
+	Currency  string  `json:"currency"`
+	Equity    float64 `json:"equity"` // 以该币种计
+	EquityUSD float64 `json:"equity_usd"`
+}
+
+// Balance 返回指定币种的权益，没有时返回 0
+func (a *Account) Balance(currency string) Balance {
+	for _, balance := range a.Balances {
+		if balance.Currency == currency {
+			return balance
+		}
+	}
+	return Balance{Currency: currency}
+}
+
+// Position 统一的仓位
+type Position struct {
+	Venue      string  `json:"venue"`
+	Instrument string  `json:"instrument"`
+	Kind       string  `json:"kind"`
+	Currency   string  `json:"currency"`   // 标的币种
+	Size       float64 `json:"size"`       // 以标的币种计的数量，多头为正，空头为负
+	Delta      float64 `json:"delta"`      // 以标的币种计的 Delta
+	MarkPrice  float64 `json:"mark_price"` // 交易所的标记价格，计价单位因交易所和合约而异
+}
+
+// Instrument 统一的合约信息
+type Instrument struct {
+	Venue        string    `json:"venue"`
+	Name         string    `json:"name"`
+	Kind         string    `json:"kind"`
+	Currency     string    `json:"currency"`              // 标的币种
+	OptionType   string    `json:"option_type,omitempty"` // call / put
+	Strike       float64   `json:"strike,omitempty"`
+	Expiry       time.Time `json:"expiry"` // 永续合约为零值
+	ContractSize float64   `json:"contract_size"`
+	TickSize     float64   `json:"tick_size"`
+}